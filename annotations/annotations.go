@@ -0,0 +1,536 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package annotations defines the IDL annotations understood by the generator
+// and how they are interpreted, so that other tools can read them identically.
+package annotations
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/thrift_reflection"
+)
+
+const (
+	ApiGet           = "api.get"
+	ApiPost          = "api.post"
+	ApiPut           = "api.put"
+	ApiPatch         = "api.patch"
+	ApiDelete        = "api.delete"
+	ApiOptions       = "api.options"
+	ApiHEAD          = "api.head"
+	ApiAny           = "api.any"
+	ApiQuery         = "api.query"
+	ApiForm          = "api.form"
+	ApiPath          = "api.path"
+	ApiHeader        = "api.header"
+	ApiCookie        = "api.cookie"
+	ApiBody          = "api.body"
+	ApiRawBody       = "api.raw_body"
+	ApiBaseDomain    = "api.base_domain"
+	ApiBaseURL       = "api.baseurl"
+	ApiHttpCode      = "api.http_code"
+	OpenapiOperation = "openapi.operation"
+	OpenapiProperty  = "openapi.property"
+	OpenapiSchema    = "openapi.schema"
+	OpenapiParameter = "openapi.parameter"
+	OpenapiDocument  = "openapi.document"
+
+	OpenapiServer             = "openapi.server"
+	OpenapiInfo               = "openapi.info"
+	OpenapiExtension          = "openapi.extension"
+	OpenapiSecurityScheme     = "openapi.security_scheme"
+	OpenapiServerVariables    = "openapi.server_variables"
+	OpenapiGatewayIntegration = "openapi.gateway_integration"
+	OpenapiOnlyIf             = "openapi.only_if"
+	OpenapiBodyInline         = "openapi.body_inline"
+	OpenapiLintIgnore         = "openapi.lint_ignore"
+	OpenapiIgnore             = "openapi.ignore"
+	OpenapiSkip               = "openapi.skip"
+	OpenapiEnum               = "openapi.enum"
+	OpenapiBasePath           = "openapi.base_path"
+	OpenapiResponse           = "openapi.response"
+	OpenapiExamples           = "openapi.examples"
+	OpenapiAudience           = "openapi.audience"
+	OpenapiSunset             = "openapi.sunset"
+)
+
+const (
+	AudiencePublic   = "public"
+	AudienceInternal = "internal"
+	AudienceAll      = "all"
+)
+
+// knownAnnotations are the api and openapi annotations read by the generator, and
+// the ones of hz and Kitex it leaves to them.
+var knownAnnotations = []string{
+	ApiGet, ApiPost, ApiPut, ApiPatch, ApiDelete, ApiOptions, ApiHEAD, ApiAny,
+	ApiQuery, ApiForm, ApiPath, ApiHeader, ApiCookie, ApiBody, ApiRawBody,
+	ApiBaseDomain, ApiBaseURL, ApiHttpCode,
+	OpenapiOperation, OpenapiProperty, OpenapiSchema, OpenapiParameter, OpenapiDocument,
+	OpenapiServer, OpenapiInfo, OpenapiExtension, OpenapiSecurityScheme, OpenapiServerVariables,
+	OpenapiGatewayIntegration, OpenapiOnlyIf, OpenapiBodyInline, OpenapiLintIgnore, OpenapiIgnore,
+	OpenapiSkip, OpenapiEnum, OpenapiBasePath, OpenapiResponse, OpenapiExamples, OpenapiAudience,
+	OpenapiSunset,
+
+	"api.raw_uri", "api.go_tag", "api.vd", "api.none", "api.js_conv", "api.file_name",
+	"api.serializer", "api.param", "api.handler_path", "api.version", "api.category",
+	"api.api_level", "api.tag",
+}
+
+// Known reports whether key is a known annotation, the keys of the HTTP methods
+// being case-insensitive. The keys outside the api and openapi namespaces are
+// always known.
+func Known(key string) bool {
+	lower := strings.ToLower(key)
+	if !strings.HasPrefix(lower, "api.") && !strings.HasPrefix(lower, "openapi.") {
+		return true
+	}
+	if _, ok := HttpMethodAnnotations[lower]; ok {
+		return true
+	}
+	for _, known := range knownAnnotations {
+		if key == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Suggest returns the known annotation closest to key, e.g. api.query for
+// api.querry, empty when none is within two edits.
+func Suggest(key string) string {
+	var suggestion string
+	best := 3
+	for _, known := range knownAnnotations {
+		if d := editDistance(strings.ToLower(key), known); d < best {
+			suggestion, best = known, d
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+var HttpMethodAnnotations = map[string]string{
+	ApiGet:     "GET",
+	ApiPost:    "POST",
+	ApiPut:     "PUT",
+	ApiPatch:   "PATCH",
+	ApiDelete:  "DELETE",
+	ApiOptions: "OPTIONS",
+	ApiHEAD:    "HEAD",
+	ApiAny:     "ANY",
+}
+
+// Route is an HTTP route declared on a function by a method annotation.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Routes returns the routes declared on the function in declaration order.
+// Method annotation keys are case-insensitive and only the first occurrence of
+// each one counts.
+func Routes(f *parser.Function) []Route {
+	var routes []Route
+	seen := make(map[string]bool)
+	for _, anno := range f.Annotations {
+		key := strings.ToLower(anno.Key)
+		method, ok := HttpMethodAnnotations[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if len(anno.Values) == 0 {
+			continue
+		}
+		routes = append(routes, Route{Method: method, Path: anno.Values[0]})
+	}
+	return routes
+}
+
+// HTTPMethod returns the verb and the path of the first route declared on the function.
+func HTTPMethod(f *parser.Function) (verb, path string, ok bool) {
+	routes := Routes(f)
+	if len(routes) == 0 {
+		return "", "", false
+	}
+	return routes[0].Method, routes[0].Path, true
+}
+
+// MaxPathLength is the longest path accepted in a method annotation.
+const MaxPathLength = 2048
+
+// ValidatePath reports why the path of a method annotation cannot be routed, e.g.
+// an unnamed wildcard, which the router of the generated server rejects.
+func ValidatePath(path string) error {
+	if len(path) > MaxPathLength {
+		return fmt.Errorf("path is longer than %d bytes", MaxPathLength)
+	}
+	if !utf8.ValidString(path) {
+		return fmt.Errorf("path is not valid UTF-8")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path does not begin with '/'")
+	}
+	for _, r := range path {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("path contains the character %q", r)
+		}
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == ":" || segment == "*":
+			return fmt.Errorf("wildcard '%s' of the path has no name", segment)
+		case strings.HasPrefix(segment, "*") && i != len(segments)-1:
+			return fmt.Errorf("catch-all wildcard '%s' is not the last segment of the path", segment)
+		}
+	}
+	return nil
+}
+
+// ParseBaseURL splits the value of api.baseurl or api.base_domain, a URL or a host
+// with an optional port and path, e.g. gateway.internal:8080/v2/, into the scheme and
+// host, http when the value has no scheme, and the path without trailing slash.
+func ParseBaseURL(value string) (server, path string, err error) {
+	raw := value
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err == nil && u.Host == "" {
+		err = fmt.Errorf("no host")
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base URL %.64q: %s", value, err)
+	}
+	return u.Scheme + "://" + u.Host, strings.TrimSuffix(u.Path, "/"), nil
+}
+
+// WildcardName returns the name of the catch-all wildcard ending the path, e.g.
+// filepath for /files/*filepath, and "" when the path has none.
+func WildcardName(path string) string {
+	segment := path[strings.LastIndex(path, "/")+1:]
+	if strings.HasPrefix(segment, "*") {
+		return segment[1:]
+	}
+	return ""
+}
+
+// BasePath returns the path prefix declared by the openapi.base_path annotation of a
+// service, without trailing slash, and "" when there is none. The prefix is a plain
+// path, it can not have wildcards.
+func BasePath(values []string) (string, error) {
+	if len(values) == 0 || values[0] == "" || values[0] == "/" {
+		return "", nil
+	}
+	err := ValidatePath(values[0])
+	if err == nil && strings.ContainsAny(values[0], ":*") {
+		err = fmt.Errorf("path has a wildcard")
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %.64q: %s", OpenapiBasePath, values[0], err)
+	}
+	return strings.TrimSuffix(values[0], "/"), nil
+}
+
+// Locations of a field binding.
+const (
+	InQuery   = "query"
+	InPath    = "path"
+	InCookie  = "cookie"
+	InHeader  = "header"
+	InBody    = "body"
+	InForm    = "form"
+	InRawBody = "raw_body"
+)
+
+// Binding tells where a field is carried in an HTTP request or response.
+type Binding struct {
+	// Annotation is the annotation key declaring the binding, e.g. api.query.
+	Annotation string
+	// In is the location of the field.
+	In string
+	// Name is the name of the field in that location.
+	Name string
+}
+
+// IsParameter reports whether the binding is an OpenAPI parameter rather than
+// a property of the body.
+func (b Binding) IsParameter() bool {
+	switch b.In {
+	case InQuery, InPath, InCookie, InHeader:
+		return true
+	}
+	return false
+}
+
+var bindingAnnotations = []struct {
+	annotation string
+	in         string
+}{
+	{ApiQuery, InQuery},
+	{ApiPath, InPath},
+	{ApiCookie, InCookie},
+	{ApiHeader, InHeader},
+	{ApiBody, InBody},
+	{ApiForm, InForm},
+	{ApiRawBody, InRawBody},
+}
+
+// Bindings returns the bindings declared on the field, parameters first. Parameter
+// annotations with an empty value are ignored, while body annotations with an
+// empty value, or none, fall back to the field name. When a field declares several parameter
+// bindings the last one is the one used for the operation, see ParameterBinding.
+func Bindings(field *thrift_reflection.FieldDescriptor) []Binding {
+	var bindings []Binding
+	for _, b := range bindingAnnotations {
+		values, ok := field.Annotations[b.annotation]
+		if !ok {
+			continue
+		}
+		binding := Binding{Annotation: b.annotation, In: b.in}
+		if len(values) > 0 {
+			binding.Name = values[0]
+		}
+		if binding.Name == "" {
+			if binding.IsParameter() {
+				continue
+			}
+			binding.Name = field.GetName()
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings
+}
+
+// ParameterBinding returns the binding of a field used as an OpenAPI parameter, the
+// last parameter binding of Bindings: api.header over api.cookie over api.path over
+// api.query.
+func ParameterBinding(bindings []Binding) (Binding, bool) {
+	for i := len(bindings) - 1; i >= 0; i-- {
+		if bindings[i].IsParameter() {
+			return bindings[i], true
+		}
+	}
+	return Binding{}, false
+}
+
+// ConflictingBindings returns the annotations of the bindings of a field when they
+// cannot coexist, nil otherwise: several parameter locations, a parameter location
+// and a body location, or api.raw_body and api.body or api.form. A field may be both
+// in api.body and api.form, it is then a property of the JSON and of the form bodies.
+func ConflictingBindings(bindings []Binding) []string {
+	var parameters, bodies, rawBodies int
+	for _, b := range bindings {
+		switch {
+		case b.IsParameter():
+			parameters++
+		case b.In == InRawBody:
+			rawBodies++
+		default:
+			bodies++
+		}
+	}
+	if parameters < 2 && (parameters == 0 || bodies+rawBodies == 0) && (rawBodies == 0 || bodies == 0) {
+		return nil
+	}
+	conflicting := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		conflicting = append(conflicting, b.Annotation)
+	}
+	return conflicting
+}
+
+// PropertyName returns the name of the field in the schema of its struct, the last
+// non-empty value of api.header, api.body, api.form and api.raw_body, or the field name.
+func PropertyName(field *thrift_reflection.FieldDescriptor) string {
+	name := field.GetName()
+	for _, annotation := range []string{ApiHeader, ApiBody, ApiForm, ApiRawBody} {
+		if values := field.Annotations[annotation]; len(values) > 0 && values[0] != "" {
+			name = values[0]
+		}
+	}
+	return name
+}
+
+// DefaultExceptionStatus is the HTTP status of an exception without api.http_code.
+const DefaultExceptionStatus = 500
+
+// ExceptionStatus returns the HTTP status declared by the api.http_code annotation
+// of an exception, or DefaultExceptionStatus when there is none or it is not an
+// error status.
+func ExceptionStatus(values []string) (int, error) {
+	if len(values) == 0 || values[0] == "" {
+		return DefaultExceptionStatus, nil
+	}
+	status, err := strconv.Atoi(values[0])
+	if err != nil || status < 400 || status > 599 {
+		return DefaultExceptionStatus, fmt.Errorf("invalid %s '%s', expected an HTTP error status", ApiHttpCode, values[0])
+	}
+	return status, nil
+}
+
+// IsIgnored reports whether the openapi.ignore annotation of a service or a function
+// leaves it out of the documentation.
+func IsIgnored(values []string) (bool, error) {
+	return parseFlag(OpenapiIgnore, values)
+}
+
+// IsSkipped reports whether the openapi.skip annotation of a function leaves it out
+// of the documentation.
+func IsSkipped(values []string) (bool, error) {
+	return parseFlag(OpenapiSkip, values)
+}
+
+// parseFlag returns the value of a boolean annotation, "true" or "false", which is
+// false when the annotation is absent, empty or invalid.
+func parseFlag(key string, values []string) (bool, error) {
+	if len(values) == 0 || values[0] == "" {
+		return false, nil
+	}
+	switch values[0] {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid %s '%s', use 'true' or 'false'", key, values[0])
+}
+
+// Audience returns the audience of the openapi.audience annotation of a function or
+// a service, public or internal, and fallback when there is none or it is invalid.
+func Audience(values []string, fallback string) (string, error) {
+	if len(values) == 0 {
+		return fallback, nil
+	}
+	switch values[0] {
+	case AudiencePublic, AudienceInternal:
+		return values[0], nil
+	}
+	return fallback, fmt.Errorf("invalid %s '%s', use '%s' or '%s'", OpenapiAudience, values[0], AudiencePublic, AudienceInternal)
+}
+
+// Sunset returns the retirement date of the openapi.sunset annotation of a function,
+// an RFC 3339 date such as 2025-12-31 or date-time, the zero time when there is none.
+func Sunset(values []string) (time.Time, error) {
+	if len(values) == 0 {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse("2006-01-02", values[0]); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, values[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s '%s', use an RFC 3339 date such as 2025-12-31", OpenapiSunset, values[0])
+	}
+	return date, nil
+}
+
+// AudienceSelected reports whether an operation of the audience is documented for
+// the filter, every operation is for an empty filter or all.
+func AudienceSelected(audience, filter string) bool {
+	return filter == "" || filter == AudienceAll || audience == filter
+}
+
+// EnumValues returns the values allowed by the openapi.enum annotation of a string
+// field, separated by commas, e.g. "pending,active,closed", and nil when there is
+// none. Values are trimmed and must be neither empty nor repeated.
+func EnumValues(values []string) ([]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var enum []string
+	for _, value := range strings.Split(values[0], ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("invalid %s '%s', a value is empty", OpenapiEnum, values[0])
+		}
+		for _, v := range enum {
+			if v == value {
+				return nil, fmt.Errorf("invalid %s '%s', '%s' is repeated", OpenapiEnum, values[0], value)
+			}
+		}
+		enum = append(enum, value)
+	}
+	return enum, nil
+}
+
+// IsStatusField reports whether the api.http_code annotation of a response field
+// marks the field as carrying the HTTP status of the response.
+func IsStatusField(values []string) bool {
+	return len(values) > 0 && values[0] == "true"
+}
+
+// ProfileActive reports whether a node carrying the openapi.only_if values is
+// generated for the active profiles. Each value may hold comma-separated
+// conditions, the node is kept when any of them is active or when it has none.
+func ProfileActive(conditions, profiles []string) bool {
+	conditional := false
+	for _, value := range conditions {
+		for _, condition := range strings.Split(value, ",") {
+			condition = strings.TrimSpace(condition)
+			if condition == "" {
+				continue
+			}
+			conditional = true
+			for _, profile := range profiles {
+				if condition == profile {
+					return true
+				}
+			}
+		}
+	}
+	return !conditional
+}
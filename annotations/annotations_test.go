@@ -0,0 +1,291 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package annotations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/thrift_reflection"
+)
+
+// function returns a function carrying the annotations, given as key and value pairs.
+func function(pairs ...string) *parser.Function {
+	f := &parser.Function{Name: "Call"}
+	for i := 0; i < len(pairs); i += 2 {
+		f.Annotations = append(f.Annotations, &parser.Annotation{Key: pairs[i], Values: []string{pairs[i+1]}})
+	}
+	return f
+}
+
+// field returns a field named id carrying the annotations, given as key and value pairs.
+func field(pairs ...string) *thrift_reflection.FieldDescriptor {
+	desc := &thrift_reflection.FieldDescriptor{Name: "id", Annotations: make(map[string][]string)}
+	for i := 0; i < len(pairs); i += 2 {
+		desc.Annotations[pairs[i]] = append(desc.Annotations[pairs[i]], pairs[i+1])
+	}
+	return desc
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		f    *parser.Function
+		want []Route
+	}{
+		{"none", function("api.version", "1"), nil},
+		{"get", function(ApiGet, "/users"), []Route{{"GET", "/users"}}},
+		{"case-insensitive key", function("API.Post", "/users"), []Route{{"POST", "/users"}}},
+		{
+			"declaration order", function(ApiPost, "/users", ApiGet, "/users", ApiAny, "/any"),
+			[]Route{{"POST", "/users"}, {"GET", "/users"}, {"ANY", "/any"}},
+		},
+		{"repeated method", function(ApiGet, "/a", "api.GET", "/b"), []Route{{"GET", "/a"}}},
+		{"empty path", function(ApiGet, ""), []Route{{"GET", ""}}},
+		{"without value", &parser.Function{Annotations: parser.Annotations{{Key: ApiGet}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Routes(tt.f); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		f          *parser.Function
+		verb, path string
+		ok         bool
+	}{
+		{"none", function(), "", "", false},
+		{"first route", function(ApiDelete, "/users/:id", ApiGet, "/users/:id"), "DELETE", "/users/:id", true},
+		{"head", function(ApiHEAD, "/ping"), "HEAD", "/ping", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verb, path, ok := HTTPMethod(tt.f)
+			if verb != tt.verb || path != tt.path || ok != tt.ok {
+				t.Errorf("got %s %s %v, want %s %s %v", verb, path, ok, tt.verb, tt.path, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBindings(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *thrift_reflection.FieldDescriptor
+		want  []Binding
+	}{
+		{"none", field(), nil},
+		{"query", field(ApiQuery, "user_id"), []Binding{{ApiQuery, InQuery, "user_id"}}},
+		{"empty parameter", field(ApiQuery, ""), nil},
+		{"empty body", field(ApiBody, ""), []Binding{{ApiBody, InBody, "id"}}},
+		{"empty raw body", field(ApiRawBody, ""), []Binding{{ApiRawBody, InRawBody, "id"}}},
+		{
+			"parameters first", field(ApiBody, "user", ApiHeader, "X-User", ApiPath, "user"),
+			[]Binding{{ApiPath, InPath, "user"}, {ApiHeader, InHeader, "X-User"}, {ApiBody, InBody, "user"}},
+		},
+		{
+			"body and form", field(ApiForm, "name", ApiBody, "name"),
+			[]Binding{{ApiBody, InBody, "name"}, {ApiForm, InForm, "name"}},
+		},
+		{"cookie", field(ApiCookie, "session"), []Binding{{ApiCookie, InCookie, "session"}}},
+		{"other annotations", field("api.vd", "$>0", "go.tag", `json:"id"`), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Bindings(tt.field); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsParameter(t *testing.T) {
+	for _, in := range []string{InQuery, InPath, InCookie, InHeader} {
+		if !(Binding{In: in}).IsParameter() {
+			t.Errorf("%s is not a parameter", in)
+		}
+	}
+	for _, in := range []string{InBody, InForm, InRawBody} {
+		if (Binding{In: in}).IsParameter() {
+			t.Errorf("%s is a parameter", in)
+		}
+	}
+}
+
+func TestPropertyName(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *thrift_reflection.FieldDescriptor
+		want  string
+	}{
+		{"none", field(), "id"},
+		{"query only", field(ApiQuery, "user_id"), "id"},
+		{"body", field(ApiBody, "user_id"), "user_id"},
+		{"empty body", field(ApiBody, ""), "id"},
+		{"header", field(ApiHeader, "X-Id"), "X-Id"},
+		{"last annotation", field(ApiHeader, "X-Id", ApiBody, "body_id", ApiForm, "form_id"), "form_id"},
+		{"raw body", field(ApiBody, "body_id", ApiRawBody, "raw"), "raw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PropertyName(tt.field); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileActive(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+		profiles   []string
+		want       bool
+	}{
+		{"unconditional", nil, nil, true},
+		{"empty condition", []string{" , "}, nil, true},
+		{"inactive", []string{"enterprise"}, nil, false},
+		{"active", []string{"enterprise"}, []string{"enterprise"}, true},
+		{"any of", []string{"enterprise, beta"}, []string{"beta"}, true},
+		{"several annotations", []string{"enterprise", "beta"}, []string{"beta"}, true},
+		{"none of", []string{"enterprise,beta"}, []string{"alpha"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProfileActive(tt.conditions, tt.profiles); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceptionStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    int
+		wantErr bool
+	}{
+		{"none", nil, DefaultExceptionStatus, false},
+		{"empty value", []string{""}, DefaultExceptionStatus, false},
+		{"not found", []string{"404"}, 404, false},
+		{"first value", []string{"409", "403"}, 409, false},
+		{"server error", []string{"503"}, 503, false},
+		{"success status", []string{"200"}, DefaultExceptionStatus, true},
+		{"out of range", []string{"600"}, DefaultExceptionStatus, true},
+		{"not a number", []string{"conflict"}, DefaultExceptionStatus, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExceptionStatus(tt.values)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("got %d, %v, want %d and error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsStatusField(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   bool
+	}{
+		{"none", nil, false},
+		{"empty value", []string{""}, false},
+		{"true", []string{"true"}, true},
+		{"first value", []string{"true", "false"}, true},
+		{"false", []string{"false"}, false},
+		{"status code", []string{"404"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStatusField(tt.values); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    bool
+		wantErr bool
+	}{
+		{"none", nil, false, false},
+		{"empty value", []string{""}, false, false},
+		{"true", []string{"true"}, true, false},
+		{"false", []string{"false"}, false, false},
+		{"first value", []string{"true", "false"}, true, false},
+		{"capitalized", []string{"True"}, false, true},
+		{"yes", []string{"yes"}, false, true},
+	}
+	for _, parse := range []struct {
+		name  string
+		parse func([]string) (bool, error)
+	}{
+		{OpenapiIgnore, IsIgnored},
+		{OpenapiSkip, IsSkipped},
+	} {
+		for _, tt := range tests {
+			t.Run(parse.name+" "+tt.name, func(t *testing.T) {
+				got, err := parse.parse(tt.values)
+				if got != tt.want || (err != nil) != tt.wantErr {
+					t.Errorf("got %v, %v, want %v and error %v", got, err, tt.want, tt.wantErr)
+				}
+				if err != nil && !strings.Contains(err.Error(), parse.name) {
+					t.Errorf("got error %q, want it to name %s", err, parse.name)
+				}
+			})
+		}
+	}
+}
+
+func TestAudience(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		fallback string
+		want     string
+		wantErr  bool
+	}{
+		{"none", nil, AudiencePublic, AudiencePublic, false},
+		{"inherited", nil, AudienceInternal, AudienceInternal, false},
+		{"public", []string{"public"}, AudienceInternal, AudiencePublic, false},
+		{"internal", []string{"internal"}, AudiencePublic, AudienceInternal, false},
+		{"all", []string{"all"}, AudiencePublic, AudiencePublic, true},
+		{"unknown", []string{"partner"}, AudienceInternal, AudienceInternal, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Audience(tt.values, tt.fallback)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("got %s, %v, want %s and error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
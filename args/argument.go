@@ -17,15 +17,116 @@
 package args
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
 )
 
 type Arguments struct {
-	OutputDir string
-	HertzAddr string
-	KitexAddr string
+	OutputDir      string
+	HertzAddr      string
+	KitexAddr      string
+	ServiceName    string
+	Resolver       string
+	ResolverImport string
+	ResolverExpr   string
+
+	ServerCert       string
+	ServerKey        string
+	ClientCA         string
+	ClientCert       string
+	ClientKey        string
+	ClientServerName string
+
+	Auth      string
+	AuthProxy bool
+
+	StripStatusField bool
+	StreamThreshold  int
+	MaxResponseBytes int
+	StripHeaders     []string
+	ForwardHeaders   []string
+	Debug            bool
+	DebugBodyLimit   int
+	Metrics          bool
+	MetricsPort      int
+	RequestID        bool
+	Tracing          string
+	ConfirmMutations bool
+	ValidateRequests bool
+	ReadinessProbe   bool
+	RateLimit        int
+	RateBurst        int
+	InvokeAPI        bool
+
+	GatewayExtensionKey string
+
+	Strict          bool
+	Lint            bool
+	ContinueOnError bool
+	AutoOperations  bool
+	DefaultMapping  bool
+	WrapMultiArgs   bool
+
+	Profiles   []string
+	Audience   string
+	SunsetDate string
+
+	IncludeServices []string
+	ExcludeServices []string
+
+	PublishURL      string
+	PublishTokenEnv string
+	PublishRequired bool
+
+	OperationIDPrefix string
+	SchemaNamespace   bool
+	NamingStrategy    string
+	StandardFormats   bool
+	BaseURLPath       string
+	Order             string
+	VersionInPath     bool
+	ApiVersion        string
+
+	SpecMode string
+	UI       string
+	UIDist   string
+	UISpec   string
+
+	CodeSamples    []string
+	CodeSamplesDir string
+
+	Langs            []string
+	MarkUntranslated bool
+
+	MarkdownDescriptions bool
+	MaxDescriptionLength int
+
+	ContactName    string
+	ContactEmail   string
+	ContactURL     string
+	LicenseName    string
+	LicenseURL     string
+	TermsOfService string
+
+	MaxOperationsPerDoc int
+
+	Format         string
+	OpenapiVersion string
+
+	ContractHashes bool
+	Minify         bool
+	ReuseBodies    bool
+	KeepUnused     bool
+
+	NoServer  bool
+	NoOpenapi bool
+
+	Watch  bool
+	Stdout bool
+
+	NoTimestamp bool
 }
 
 func (a *Arguments) Unpack(args []string) error {
@@ -33,5 +134,20 @@ func (a *Arguments) Unpack(args []string) error {
 	if err != nil {
 		return fmt.Errorf("unpack argument failed: %s", err)
 	}
+	if a.NoServer && a.NoOpenapi {
+		return errors.New("NoServer and NoOpenapi can not be used together")
+	}
+	if a.Stdout && (a.NoOpenapi || a.Watch) {
+		return errors.New("Stdout can not be used with NoOpenapi or Watch")
+	}
 	return nil
 }
+
+// ServiceSelected reports whether the service passes the IncludeServices and
+// ExcludeServices filters.
+func (a *Arguments) ServiceSelected(name string) bool {
+	if len(a.IncludeServices) > 0 && !utils.Contains(a.IncludeServices, name) {
+		return false
+	}
+	return !utils.Contains(a.ExcludeServices, name)
+}
@@ -4,12 +4,18 @@ package main
 //import (
 //	"bytes"
 //	"context"
+//	"crypto/rand"
+//	"crypto/sha256"
 //	_ "embed"
+//	"encoding/hex"
 //	"encoding/json"
 //	"errors"
+//	"io"
 //	"net/http"
 //	"os"
 //	"path/filepath"
+//	"sort"
+//	"strconv"
 //	"strings"
 //
 //	"github.com/cloudwego/hertz/pkg/app"
@@ -21,17 +27,32 @@ package main
 //	"github.com/hertz-contrib/cors"
 //	"github.com/hertz-contrib/swagger"
 //	swaggerFiles "github.com/swaggo/files"
+//	"gopkg.in/yaml.v3"
 //)
 //
 ////go:embed openapi.yaml
 //var openapiYAML []byte
 //
+//// spec is a rendering of the OpenAPI document served with an ETag.
+//type spec struct {
+//	content     []byte
+//	contentType string
+//	etag        string
+//}
+//
+//// specs holds the YAML and the JSON renderings of the same document.
+//type specs struct {
+//	yaml *spec
+//	json *spec
+//}
+//
 //func main() {
-//	h := server.Default(server.WithHostPorts("127.0.0.1:8080"))
+//	h := server.Default(server.WithHostPorts("127.0.0.1:8080"), server.WithStreamBody(true))
 //
 //	h.Use(cors.Default())
 //
 //	cli := initializeGenericClient()
+//	setupHealthRoutes(h, cli)
 //	setupSwaggerRoutes(h)
 //	setupProxyRoutes(h, cli)
 //
@@ -98,25 +119,92 @@ package main
 //	return cli
 //}
 //
+//// setupHealthRoutes serves the probes: /healthz answers once the server is up,
+//// /readyz once the generic client is created.
+//func setupHealthRoutes(h *server.Hertz, cli genericclient.Client) {
+//	h.GET("/healthz", func(c context.Context, ctx *app.RequestContext) {
+//		ctx.JSON(http.StatusOK, map[string]interface{}{
+//			"status": "ok",
+//		})
+//	})
+//
+//	h.GET("/readyz", func(c context.Context, ctx *app.RequestContext) {
+//		if cli == nil {
+//			ctx.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+//				"status": "unavailable",
+//				"error":  "generic client not created",
+//			})
+//			return
+//		}
+//		ctx.JSON(http.StatusOK, map[string]interface{}{
+//			"status": "ok",
+//		})
+//	})
+//}
+//
 //func setupSwaggerRoutes(h *server.Hertz) {
+//	embedded, err := newSpecs(openapiYAML)
+//	if err != nil {
+//		hlog.Fatal("Failed to convert the spec to JSON:", err)
+//	}
+//
 //	h.GET("swagger/*any", swagger.WrapHandler(swaggerFiles.Handler, swagger.URL("/openapi.yaml")))
 //
 //	h.GET("/openapi.yaml", func(c context.Context, ctx *app.RequestContext) {
-//		ctx.Header("Content-Type", "application/x-yaml")
-//		ctx.Write(openapiYAML)
+//		serveSpec(ctx, embedded.yaml)
+//	})
+//
+//	h.GET("/openapi.json", func(c context.Context, ctx *app.RequestContext) {
+//		serveSpec(ctx, embedded.json)
 //	})
 //}
 //
+//// newSpecs converts the YAML document to JSON once, so that neither rendering is
+//// converted again when it is served.
+//func newSpecs(content []byte) (*specs, error) {
+//	var doc interface{}
+//	if err := yaml.Unmarshal(content, &doc); err != nil {
+//		return nil, err
+//	}
+//	jsonContent, err := json.Marshal(doc)
+//	if err != nil {
+//		return nil, err
+//	}
+//	return &specs{
+//		yaml: newSpec(content, "application/x-yaml"),
+//		json: newSpec(jsonContent, "application/json"),
+//	}, nil
+//}
+//
+//func newSpec(content []byte, contentType string) *spec {
+//	sum := sha256.Sum256(content)
+//	return &spec{
+//		content:     content,
+//		contentType: contentType,
+//		etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+//	}
+//}
+//
+//func serveSpec(ctx *app.RequestContext, s *spec) {
+//	ctx.Header("ETag", s.etag)
+//	if string(ctx.Request.Header.Peek("If-None-Match")) == s.etag {
+//		ctx.Status(http.StatusNotModified)
+//		return
+//	}
+//	ctx.Data(http.StatusOK, s.contentType, s.content)
+//}
+//
 //func setupProxyRoutes(h *server.Hertz, cli genericclient.Client) {
-//	h.Any("/*ServiceMethod", func(c context.Context, ctx *app.RequestContext) {
-//		serviceMethod := ctx.Param("ServiceMethod")
+//	proxy := func(c context.Context, ctx *app.RequestContext) {
+//		serviceMethod := strings.TrimPrefix(string(ctx.Path()), "/")
 //		if serviceMethod == "" {
 //			handleError(ctx, "ServiceMethod not provided", http.StatusBadRequest)
 //			return
 //		}
 //
 //		queryString := formatQueryParams(ctx)
-//		bodyBytes := ctx.Request.Body()
+//		// The content type is forwarded as is, so that multipart bodies keep
+//		// their boundary.
 //		contentType := string(ctx.Request.Header.ContentType())
 //
 //		url := "http://127.0.0.1:8080/" + serviceMethod
@@ -124,20 +212,226 @@ package main
 //			url += "?" + queryString
 //		}
 //
-//		req, err := http.NewRequest(string(ctx.Request.Method()), url, bytes.NewBuffer(bodyBytes))
+//		req, cleanup, err := newProxyRequest(ctx, url)
 //		if err != nil {
 //			handleError(ctx, err.Error(), http.StatusInternalServerError)
 //			return
 //		}
+//		defer cleanup()
 //
+//		connection := connectionHeaders(string(ctx.Request.Header.Peek("Connection")))
 //		ctx.Request.Header.VisitAll(func(key, value []byte) {
-//			req.Header.Set(string(key), string(value))
+//			if forwardRequestHeader(string(key), connection) {
+//				req.Header.Set(string(key), string(value))
+//			}
 //		})
 //
 //		req.Header.Set("Content-Type", contentType)
 //
-//		handleProxyRequest(ctx, cli, req)
+//		handleProxyRequest(c, ctx, cli, req)
+//	}
+//
+//	debug := debugMiddleware()
+//
+//	h.Any("/*ServiceMethod", debug, proxy)
+//}
+//
+//// debugBodyLimit is the size at which the bodies logged in debug mode are truncated.
+//const debugBodyLimit = 1024
+//
+//// debugEnabled turns on the debug logging of the proxied requests, the Debug
+//// generation option can be overridden by the PROXY_DEBUG environment variable.
+//var debugEnabled = debugFromEnv(false)
+//
+//// requestIDKey is the key of the request ID in the request context.
+//const requestIDKey = "request_id"
+//
+//// redactedHeaders are the headers whose value is never logged.
+//var redactedHeaders = map[string]bool{
+//	"authorization":       true,
+//	"proxy-authorization": true,
+//	"cookie":              true,
+//	"set-cookie":          true,
+//	"x-api-key":           true,
+//}
+//
+//func debugFromEnv(generated bool) bool {
+//	value, ok := os.LookupEnv("PROXY_DEBUG")
+//	if !ok {
+//		return generated
+//	}
+//	enabled, err := strconv.ParseBool(value)
+//	if err != nil {
+//		hlog.Warnf("Invalid PROXY_DEBUG '%s', using %t: %s", value, generated, err)
+//		return generated
+//	}
+//	return enabled
+//}
+//
+//// debugMiddleware tags the proxied requests with a request ID, returned in the
+//// X-Request-Id header, and logs them when debug logging is enabled.
+//func debugMiddleware() app.HandlerFunc {
+//	return func(c context.Context, ctx *app.RequestContext) {
+//		if !debugEnabled {
+//			ctx.Next(c)
+//			return
+//		}
+//		requestID := newRequestID()
+//		ctx.Set(requestIDKey, requestID)
+//		ctx.Header("X-Request-Id", requestID)
+//
+//		// Streamed bodies are not logged, reading them would load them in memory.
+//		body := "<streamed>"
+//		if length := ctx.Request.Header.ContentLength(); length >= 0 && length <= streamThreshold {
+//			body = truncateBody(ctx.Request.Body())
+//		}
+//		debugf(ctx, "stage=inbound method=%s path=%s query=%q headers=%q body=%s",
+//			ctx.Method(), ctx.Path(), ctx.QueryArgs().String(), redactHeaders(ctx), body)
+//		ctx.Next(c)
+//	}
+//}
+//
+//func debugf(ctx *app.RequestContext, format string, args ...interface{}) {
+//	if !debugEnabled {
+//		return
+//	}
+//	hlog.Infof("request_id=%s "+format, append([]interface{}{ctx.GetString(requestIDKey)}, args...)...)
+//}
+//
+//func newRequestID() string {
+//	var id [16]byte
+//	if _, err := rand.Read(id[:]); err != nil {
+//		return "unknown"
+//	}
+//	return hex.EncodeToString(id[:])
+//}
+//
+//// redactHeaders returns the request headers with the values of the sensitive
+//// headers replaced.
+//func redactHeaders(ctx *app.RequestContext) []string {
+//	var headers []string
+//	ctx.Request.Header.VisitAll(func(key, value []byte) {
+//		if redactedHeaders[strings.ToLower(string(key))] {
+//			value = []byte("<redacted>")
+//		}
+//		headers = append(headers, string(key)+": "+string(value))
 //	})
+//	return headers
+//}
+//
+//func debugJSON(value interface{}) string {
+//	content, err := json.Marshal(value)
+//	if err != nil {
+//		return "<" + err.Error() + ">"
+//	}
+//	return truncateBody(content)
+//}
+//
+//func truncateBody(body []byte) string {
+//	if len(body) > debugBodyLimit {
+//		return string(body[:debugBodyLimit]) + "...(truncated)"
+//	}
+//	return string(body)
+//}
+//
+//// streamThreshold is the size above which a request body, e.g. a file upload, is
+//// streamed to a temporary file instead of being held in memory.
+//const streamThreshold = 4194304
+//
+//// newProxyRequest returns the request forwarding the body of ctx to url. The body
+//// stays readable through GetBody, the generic call reading it before the form is
+//// parsed. The returned cleanup removes the temporary file of a streamed body.
+//func newProxyRequest(ctx *app.RequestContext, url string) (*http.Request, func(), error) {
+//	method := string(ctx.Request.Method())
+//	if length := ctx.Request.Header.ContentLength(); length >= 0 && length <= streamThreshold {
+//		req, err := http.NewRequest(method, url, bytes.NewReader(ctx.Request.Body()))
+//		return req, func() {}, err
+//	}
+//
+//	f, err := os.CreateTemp("", "swagger-proxy-*")
+//	if err != nil {
+//		return nil, nil, err
+//	}
+//	var opened []io.Closer
+//	cleanup := func() {
+//		for _, c := range opened {
+//			c.Close()
+//		}
+//		f.Close()
+//		os.Remove(f.Name())
+//	}
+//	size, err := io.Copy(f, ctx.RequestBodyStream())
+//	if err != nil {
+//		cleanup()
+//		return nil, nil, err
+//	}
+//	open := func() (io.ReadCloser, error) {
+//		body, err := os.Open(f.Name())
+//		if err == nil {
+//			opened = append(opened, body)
+//		}
+//		return body, err
+//	}
+//	body, err := open()
+//	if err != nil {
+//		cleanup()
+//		return nil, nil, err
+//	}
+//	req, err := http.NewRequest(method, url, body)
+//	if err != nil {
+//		cleanup()
+//		return nil, nil, err
+//	}
+//	req.ContentLength = size
+//	req.GetBody = open
+//	return req, cleanup, nil
+//}
+//
+//// hopHeaders are the hop-by-hop headers, which are not forwarded, along with Host and
+//// Content-Length, set again for the forwarded body.
+//var hopHeaders = map[string]bool{
+//	"connection":          true,
+//	"keep-alive":          true,
+//	"proxy-authenticate":  true,
+//	"proxy-authorization": true,
+//	"te":                  true,
+//	"trailer":             true,
+//	"transfer-encoding":   true,
+//	"upgrade":             true,
+//	"host":                true,
+//	"content-length":      true,
+//}
+//
+//// strippedHeaders are the headers removed in both directions.
+//var strippedHeaders = map[string]bool{}
+//
+//// connectionHeaders returns the headers named by a Connection header, which are
+//// hop-by-hop too.
+//func connectionHeaders(connection string) []string {
+//	var headers []string
+//	for _, header := range strings.Split(connection, ",") {
+//		if header = strings.TrimSpace(header); header != "" {
+//			headers = append(headers, strings.ToLower(header))
+//		}
+//	}
+//	return headers
+//}
+//
+//func forwardResponseHeader(key string, connection []string) bool {
+//	key = strings.ToLower(key)
+//	if hopHeaders[key] || strippedHeaders[key] {
+//		return false
+//	}
+//	for _, header := range connection {
+//		if header == key {
+//			return false
+//		}
+//	}
+//	return true
+//}
+//
+//func forwardRequestHeader(key string, connection []string) bool {
+//	return forwardResponseHeader(key, connection)
 //}
 //
 //func formatQueryParams(ctx *app.RequestContext) string {
@@ -148,16 +442,23 @@ package main
 //	return strings.Join(newQueryParams, "&")
 //}
 //
-//func handleProxyRequest(ctx *app.RequestContext, cli genericclient.Client, req *http.Request) {
+//// handleProxyRequest calls the Kitex service within the context of the request, which
+//// carries its trace.
+//func handleProxyRequest(c context.Context, ctx *app.RequestContext, cli genericclient.Client, req *http.Request) {
 //	customReq, err := generic.FromHTTPRequest(req)
 //	if err != nil {
 //		handleError(ctx, "Failed to create generic request", http.StatusInternalServerError)
 //		return
 //	}
+//	if debugEnabled {
+//		debugf(ctx, "stage=generic_request method=%s path=%s query=%q body=%s",
+//			customReq.Method, customReq.Path, customReq.Query.Encode(), debugJSON(customReq.Body))
+//	}
 //
-//	resp, err := cli.GenericCall(context.Background(), "", customReq)
+//	resp, err := cli.GenericCall(c, "", customReq)
 //	if err != nil {
-//		handleError(ctx, "GenericCall error: "+err.Error(), http.StatusInternalServerError)
+//		debugf(ctx, "stage=upstream_error error=%q", err.Error())
+//		handleTransportError(ctx, "GenericCall error: "+err.Error())
 //		return
 //	}
 //
@@ -171,28 +472,173 @@ package main
 //		handleError(ctx, "Invalid response format", http.StatusInternalServerError)
 //		return
 //	}
+//	if debugEnabled {
+//		debugf(ctx, "stage=upstream_response status=%d body=%s", realResp.StatusCode, debugJSON(realResp.Body))
+//	}
+//
+//	if body, ok := rawBody(ctx, realResp); ok {
+//		sendRawResponse(ctx, realResp, body)
+//		return
+//	}
 //
 //	sendResponse(ctx, realResp)
 //}
 //
-//func sendResponse(ctx *app.RequestContext, realResp *generic.HTTPResponse) {
+//// rawBody returns the bytes of a response which is not JSON, a documented download
+//// or a response of another content type, to be written as they are.
+//func rawBody(ctx *app.RequestContext, realResp *generic.HTTPResponse) ([]byte, bool) {
+//	var value interface{}
+//	documented := false
+//	if !documented {
+//		if isJSON(responseContentType(realResp)) {
+//			return nil, false
+//		}
+//		if len(realResp.Body) == 1 {
+//			for _, v := range realResp.Body {
+//				value = v
+//			}
+//		}
+//	}
+//	if len(realResp.RawBody) > 0 {
+//		return realResp.RawBody, true
+//	}
+//	switch v := value.(type) {
+//	case []byte:
+//		return v, true
+//	case string:
+//		return []byte(v), true
+//	}
+//	return nil, false
+//}
+//
+//// sendRawResponse writes the body as is, along with the headers of the service such
+//// as Content-Disposition. The generic client already holds the whole body, it is
+//// handed to the response without another copy.
+//func sendRawResponse(ctx *app.RequestContext, realResp *generic.HTTPResponse, body []byte) {
 //	if realResp.StatusCode == 0 {
 //		realResp.StatusCode = http.StatusOK
 //	}
 //
+//	connection := connectionHeaders(realResp.Header.Get("Connection"))
 //	for key, values := range realResp.Header {
+//		if !forwardResponseHeader(key, connection) {
+//			continue
+//		}
 //		for _, value := range values {
-//			ctx.Response.Header.Set(key, value)
+//			ctx.Response.Header.Add(key, value)
 //		}
 //	}
 //
-//	respBody, err := json.Marshal(realResp.Body)
-//	if err != nil {
+//	contentType := responseContentType(realResp)
+//	if isJSON(contentType) {
+//		contentType = "application/octet-stream"
+//	}
+//	ctx.SetStatusCode(int(realResp.StatusCode))
+//	ctx.SetContentType(contentType)
+//	ctx.Response.SetBodyRaw(body)
+//}
+//
+//func responseContentType(realResp *generic.HTTPResponse) string {
+//	if contentType := realResp.Header.Get("Content-Type"); contentType != "" {
+//		return contentType
+//	}
+//	return string(realResp.ContentType)
+//}
+//
+//// isJSON reports whether a response of the content type is encoded as JSON, which
+//// is the case of responses without content type.
+//func isJSON(contentType string) bool {
+//	return contentType == "" || strings.Contains(contentType, "json")
+//}
+//
+//// sendResponse writes the JSON of the body decoded by the generic client. The JSON
+//// is encoded straight into the response rather than into a buffer copied afterwards.
+//func sendResponse(ctx *app.RequestContext, realResp *generic.HTTPResponse) {
+//	if realResp.StatusCode == 0 {
+//		realResp.StatusCode = http.StatusOK
+//	}
+//
+//	var w io.Writer = ctx.Response.BodyWriter()
+//	if err := writeJSON(w, realResp.Body); err != nil {
+//		ctx.Response.ResetBody()
 //		handleError(ctx, "Failed to marshal response body", http.StatusInternalServerError)
 //		return
 //	}
 //
-//	ctx.Data(int(realResp.StatusCode), string(realResp.ContentType), respBody)
+//	connection := connectionHeaders(realResp.Header.Get("Connection"))
+//	for key, values := range realResp.Header {
+//		if !forwardResponseHeader(key, connection) {
+//			continue
+//		}
+//		for _, value := range values {
+//			ctx.Response.Header.Set(key, value)
+//		}
+//	}
+//
+//	ctx.SetStatusCode(int(realResp.StatusCode))
+//	ctx.SetContentType(string(realResp.ContentType))
+//}
+//
+//// writeJSON writes the JSON of the value, the objects and the arrays element by
+//// element so that only the encoding of one element is held in memory at a time. The
+//// keys of the objects are sorted as json.Marshal does.
+//func writeJSON(w io.Writer, value interface{}) error {
+//	switch v := value.(type) {
+//	case map[string]interface{}:
+//		if v == nil {
+//			break
+//		}
+//		keys := make([]string, 0, len(v))
+//		for key := range v {
+//			keys = append(keys, key)
+//		}
+//		sort.Strings(keys)
+//		if _, err := io.WriteString(w, "{"); err != nil {
+//			return err
+//		}
+//		for i, key := range keys {
+//			name, err := json.Marshal(key)
+//			if err != nil {
+//				return err
+//			}
+//			if i > 0 {
+//				name = append([]byte{','}, name...)
+//			}
+//			if _, err := w.Write(append(name, ':')); err != nil {
+//				return err
+//			}
+//			if err := writeJSON(w, v[key]); err != nil {
+//				return err
+//			}
+//		}
+//		_, err := io.WriteString(w, "}")
+//		return err
+//	case []interface{}:
+//		if v == nil {
+//			break
+//		}
+//		if _, err := io.WriteString(w, "["); err != nil {
+//			return err
+//		}
+//		for i, element := range v {
+//			if i > 0 {
+//				if _, err := io.WriteString(w, ","); err != nil {
+//					return err
+//				}
+//			}
+//			if err := writeJSON(w, element); err != nil {
+//				return err
+//			}
+//		}
+//		_, err := io.WriteString(w, "]")
+//		return err
+//	}
+//	data, err := json.Marshal(value)
+//	if err != nil {
+//		return err
+//	}
+//	_, err = w.Write(data)
+//	return err
 //}
 //
 //func handleError(ctx *app.RequestContext, errMsg string, statusCode int) {
@@ -201,3 +647,13 @@ package main
 //		"error": errMsg,
 //	})
 //}
+//
+//// handleTransportError answers a failed call to the Kitex service, the type field
+//// tells it apart from the exceptions declared by the IDL.
+//func handleTransportError(ctx *app.RequestContext, errMsg string) {
+//	hlog.Errorf("Error: %s", errMsg)
+//	ctx.JSON(http.StatusBadGateway, map[string]interface{}{
+//		"error": errMsg,
+//		"type":  "transport",
+//	})
+//}
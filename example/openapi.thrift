@@ -2,6 +2,7 @@ namespace go openapi
 
 struct _ServiceOptions {
       1:required Document document
+      2:required Parameter parameter
 }
 
 struct _StructOptions {
@@ -15,6 +16,7 @@ struct _MethodOptions {
 struct _FieldOptions {
       1:required Parameter parameter
       2:required Schema property
+      3:required SchemaOrReference schema
 }
 
 struct AdditionalPropertiesItem {
@@ -460,7 +462,8 @@ struct Schema {
   33: DefaultType default,
   34: string description,
   35: string format,
-  36: list<NamedAny> specification_extension
+  36: list<NamedAny> specification_extension,
+  37: list<Example> examples
 }
 
 struct SchemaOrReference {
@@ -543,4 +546,4 @@ struct Xml {
   4: bool attribute,
   5: bool wrapped,
   6: list<NamedAny> specification_extension
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,359 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	codeSamplesExtension = "x-codeSamples"
+	defaultProxyAddr     = "127.0.0.1:8080"
+	// maxSampleDepth bounds the example synthesized for recursive schemas.
+	maxSampleDepth = 8
+)
+
+// codeSampleLanguages maps the CodeSamples values to the language and the label of the samples.
+var codeSampleLanguages = map[string][2]string{
+	"go":   {"Go", "Go"},
+	"curl": {"Shell", "curl"},
+	"js":   {"JavaScript", "JavaScript"},
+}
+
+// codeSampleTemplates are the default templates, a <lang>.tmpl file in
+// CodeSamplesDir overrides the template of that language.
+var codeSampleTemplates = map[string]string{
+	"curl": `curl -X {{.Method}} {{shellQuote .URL}}
+{{- range .Headers}} \
+  -H {{shellQuote (printf "%s: %s" .Name .Value)}}
+{{- end}}
+{{- if .Body}} \
+  -d {{shellQuote .Body}}
+{{- end}}
+`,
+	"go": `package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+{{- if .Body}}
+	"strings"
+{{- end}}
+)
+
+func main() {
+{{- if .Body}}
+	body := strings.NewReader({{printf "%q" .Body}})
+	req, err := http.NewRequest({{printf "%q" .Method}}, {{printf "%q" .URL}}, body)
+{{- else}}
+	req, err := http.NewRequest({{printf "%q" .Method}}, {{printf "%q" .URL}}, nil)
+{{- end}}
+	if err != nil {
+		panic(err)
+	}
+{{- range .Headers}}
+	req.Header.Set({{printf "%q" .Name}}, {{printf "%q" .Value}})
+{{- end}}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(resp.Status, string(data))
+}
+`,
+	"js": `const response = await fetch({{printf "%q" .URL}}, {
+  method: {{printf "%q" .Method}},
+{{- if .Headers}}
+  headers: {
+{{- range .Headers}}
+    {{printf "%q" .Name}}: {{printf "%q" .Value}},
+{{- end}}
+  },
+{{- end}}
+{{- if .Body}}
+  body: JSON.stringify({{.Body}}),
+{{- end}}
+});
+console.log(response.status, await response.text());
+`,
+}
+
+// codeSampleFuncs are the functions of the code sample templates.
+var codeSampleFuncs = template.FuncMap{
+	"shellQuote": shellQuote,
+}
+
+// shellQuote quotes s as a single argument of a POSIX shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// codeSample is the data the code sample templates are executed with.
+type codeSample struct {
+	Method  string
+	URL     string
+	Headers []codeSampleHeader
+	// Body is the JSON example of the request body, empty when the operation has none.
+	Body string
+}
+
+type codeSampleHeader struct {
+	Name  string
+	Value string
+}
+
+// addCodeSamplesToDocument emits the x-codeSamples extension on every operation.
+func (g *OpenAPIGenerator) addCodeSamplesToDocument(d *openapi.Document) error {
+	templates, err := g.codeSampleTemplates()
+	if err != nil {
+		return err
+	}
+
+	// The samples target the closest server, or the generated proxy.
+	defaultURL := "http://" + defaultProxyAddr
+	if g.arguments.HertzAddr != "" {
+		defaultURL = "http://" + g.arguments.HertzAddr
+	}
+	if len(d.Servers) > 0 {
+		defaultURL = d.Servers[0].URL
+	}
+
+	for _, path := range d.Paths.Path {
+		pathURL := defaultURL
+		if len(path.Value.Servers) > 0 {
+			pathURL = path.Value.Servers[0].URL
+		}
+		for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"} {
+			op := operationOf(path.Value, method)
+			if op == nil {
+				continue
+			}
+			baseURL := pathURL
+			if len(op.Servers) > 0 {
+				baseURL = op.Servers[0].URL
+			}
+			sample := g.buildCodeSample(d, baseURL, path.Name, method, op)
+
+			var samples []map[string]string
+			for _, lang := range g.arguments.CodeSamples {
+				var buf bytes.Buffer
+				if err = templates[lang].Execute(&buf, sample); err != nil {
+					return fmt.Errorf("error executing %s code sample of '%s': %s", lang, op.OperationID, err)
+				}
+				samples = append(samples, map[string]string{
+					"lang":   codeSampleLanguages[lang][0],
+					"label":  codeSampleLanguages[lang][1],
+					"source": buf.String(),
+				})
+			}
+			extension, err := newNamedAny(codeSamplesExtension, samples)
+			if err != nil {
+				return err
+			}
+			op.SpecificationExtension = append(op.SpecificationExtension, extension)
+		}
+	}
+	return nil
+}
+
+func (g *OpenAPIGenerator) codeSampleTemplates() (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	for _, lang := range g.arguments.CodeSamples {
+		if _, ok := codeSampleLanguages[lang]; !ok {
+			return nil, fmt.Errorf("unsupported code sample language '%s', use 'go', 'curl' or 'js'", lang)
+		}
+		text := codeSampleTemplates[lang]
+		if g.arguments.CodeSamplesDir != "" {
+			content, err := ioutil.ReadFile(filepath.Join(g.arguments.CodeSamplesDir, lang+".tmpl"))
+			if err == nil {
+				text = string(content)
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		tmpl, err := template.New(lang).Funcs(codeSampleFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s code sample template: %s", lang, err)
+		}
+		templates[lang] = tmpl
+	}
+	return templates, nil
+}
+
+func (g *OpenAPIGenerator) buildCodeSample(d *openapi.Document, baseURL, path, method string, op *openapi.Operation) *codeSample {
+	query := url.Values{}
+	var headers, cookies []string
+	sample := &codeSample{Method: method}
+	for _, param := range op.Parameters {
+		if param.Parameter == nil {
+			continue
+		}
+		value := sampleParameter(param.Parameter)
+		switch param.Parameter.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Parameter.Name+"}", url.PathEscape(value))
+		case "query":
+			query.Add(param.Parameter.Name, value)
+		case "header":
+			headers = append(headers, param.Parameter.Name, value)
+		case "cookie":
+			cookies = append(cookies, param.Parameter.Name+"="+value)
+		}
+	}
+
+	sample.URL = strings.TrimSuffix(baseURL, "/") + path
+	if len(query) > 0 {
+		sample.URL += "?" + query.Encode()
+	}
+	for i := 0; i < len(headers); i += 2 {
+		sample.Headers = append(sample.Headers, codeSampleHeader{Name: headers[i], Value: headers[i+1]})
+	}
+	if len(cookies) > 0 {
+		sample.Headers = append(sample.Headers, codeSampleHeader{Name: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+
+	if body := op.RequestBody; body != nil && body.RequestBody != nil && body.RequestBody.Content != nil {
+		for _, content := range body.RequestBody.Content.AdditionalProperties {
+			if content.Name != "application/json" || content.Value == nil {
+				continue
+			}
+			var buf bytes.Buffer
+			writeSampleJSON(&buf, d, content.Value.Schema, 0)
+			sample.Body = buf.String()
+			sample.Headers = append(sample.Headers, codeSampleHeader{Name: "Content-Type", Value: "application/json"})
+		}
+	}
+	return sample
+}
+
+func sampleParameter(param *openapi.Parameter) string {
+	if param.Schema == nil || param.Schema.Schema == nil {
+		return "string"
+	}
+	if example := sampleExample(param.Schema.Schema); example != nil {
+		if s, ok := example.(string); ok {
+			return s
+		}
+		return fmt.Sprint(example)
+	}
+	switch param.Schema.Schema.Type {
+	case "integer", "number":
+		return "0"
+	case "boolean":
+		return "false"
+	}
+	return "string"
+}
+
+// sampleExample returns the example of the schema, or else its first enum value.
+func sampleExample(schema *openapi.Schema) interface{} {
+	value := schema.Example
+	if (value == nil || value.Yaml == "") && len(schema.Enum) > 0 {
+		value = schema.Enum[0]
+	}
+	if value == nil || value.Yaml == "" {
+		return nil
+	}
+	var example interface{}
+	if err := yaml.Unmarshal([]byte(value.Yaml), &example); err != nil {
+		return nil
+	}
+	return example
+}
+
+// writeSampleJSON writes an example value of the schema, properties keep their declaration order.
+func writeSampleJSON(buf *bytes.Buffer, d *openapi.Document, schemaOrRef *openapi.SchemaOrReference, depth int) {
+	schema := resolveSchema(d, schemaOrRef)
+	if schema == nil || depth > maxSampleDepth {
+		buf.WriteString("null")
+		return
+	}
+	if example := sampleExample(schema); example != nil {
+		if content, err := json.Marshal(example); err == nil {
+			buf.Write(content)
+			return
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		buf.WriteString("{")
+		if schema.Properties != nil {
+			for i, property := range schema.Properties.AdditionalProperties {
+				if i > 0 {
+					buf.WriteString(",")
+				}
+				name, _ := json.Marshal(property.Name)
+				buf.Write(name)
+				buf.WriteString(":")
+				writeSampleJSON(buf, d, property.Value, depth+1)
+			}
+		}
+		buf.WriteString("}")
+	case "array":
+		buf.WriteString("[")
+		if schema.Items != nil && len(schema.Items.SchemaOrReference) > 0 {
+			writeSampleJSON(buf, d, schema.Items.SchemaOrReference[0], depth+1)
+		}
+		buf.WriteString("]")
+	case "integer", "number":
+		buf.WriteString("0")
+	case "boolean":
+		buf.WriteString("false")
+	default:
+		buf.WriteString(`"string"`)
+	}
+}
+
+// resolveSchema follows a reference to a component schema.
+func resolveSchema(d *openapi.Document, schemaOrRef *openapi.SchemaOrReference) *openapi.Schema {
+	if schemaOrRef == nil {
+		return nil
+	}
+	if schemaOrRef.Schema != nil {
+		return schemaOrRef.Schema
+	}
+	if schemaOrRef.Reference == nil || d.Components == nil || d.Components.Schemas == nil {
+		return nil
+	}
+	name := strings.TrimPrefix(schemaOrRef.Reference.Xref, "#/components/schemas/")
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		if schema.Name == name {
+			return resolveSchema(d, schema.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"flag"
+	goparser "go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+const codeSamplesIDL = `
+struct UpdateUserReq {
+    1: string id (api.path = "id")
+    2: bool notify (api.query = "notify")
+    3: string token (api.header = "X-Token")
+    4: string name (api.body = "name")
+    5: i32 age (api.body = "age")
+}
+
+service UserService {
+    UpdateUserReq UpdateUser(1: UpdateUserReq req) (api.put = "/users/:id")
+}
+`
+
+// codeSamples returns the sources of the code samples of PUT /users/{id} by label.
+func codeSamples(t *testing.T, content string) map[string]string {
+	t.Helper()
+	samples := make(map[string]string)
+	list, _ := lookup(t, content, "paths", "/users/{id}", "put", codeSamplesExtension).([]interface{})
+	for _, sample := range list {
+		sample := sample.(map[string]interface{})
+		samples[sample["label"].(string)] = sample["source"].(string)
+	}
+	return samples
+}
+
+func TestCodeSamples(t *testing.T) {
+	idl := writeMain(t, codeSamplesIDL)
+	content := generateYAML(t, idl, &args.Arguments{CodeSamples: []string{"go", "curl", "js"}})
+	samples := codeSamples(t, content)
+	for lang, label := range map[string]string{"go": "Go", "curl": "curl", "js": "JavaScript"} {
+		source, ok := samples[label]
+		if !ok {
+			t.Errorf("missing %s sample", label)
+			continue
+		}
+		golden := filepath.Join("testdata", "code_samples", lang+".golden")
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(source), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if source != string(want) {
+			t.Errorf("%s sample differs from %s:\n%s", lang, golden, source)
+		}
+	}
+	if _, err := goparser.ParseFile(token.NewFileSet(), "main.go", samples["Go"], 0); err != nil {
+		t.Errorf("the Go sample does not parse: %s", err)
+	}
+
+	again := generateYAML(t, idl, &args.Arguments{CodeSamples: []string{"go", "curl", "js"}})
+	if !reflect.DeepEqual(codeSamples(t, again), samples) {
+		t.Error("the code samples are not deterministic")
+	}
+}
+
+// quotedSamplesIDL declares examples holding single quotes.
+const quotedSamplesIDL = `
+struct UpdateUserReq {
+    1: string id (api.path = "id")
+    2: string owner (api.query = "owner", openapi.enum = "O'Brien")
+    3: string token (api.header = "X-Token", openapi.enum = "it's")
+    4: string name (api.body = "name", openapi.enum = "O'Brien")
+}
+
+service UserService {
+    UpdateUserReq UpdateUser(1: UpdateUserReq req) (api.put = "/users/:id")
+}
+`
+
+func TestCurlQuoting(t *testing.T) {
+	content := generateYAML(t, writeMain(t, quotedSamplesIDL), &args.Arguments{CodeSamples: []string{"curl"}})
+	source := codeSamples(t, content)["curl"]
+	golden := filepath.Join("testdata", "code_samples", "curl_quote.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != string(want) {
+		t.Errorf("curl sample differs from %s:\n%s", golden, source)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for s, want := range map[string]string{
+		"":        `''`,
+		"plain":   `'plain'`,
+		"it's":    `'it'\''s'`,
+		"''":      `''\'''\'''`,
+		`a $b \c`: `'a $b \c'`,
+	} {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestCodeSamplesDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "curl.tmpl"), []byte("http {{.Method}} {{.URL}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content := generateYAML(t, writeMain(t, codeSamplesIDL), &args.Arguments{CodeSamples: []string{"curl", "js"}, CodeSamplesDir: dir})
+	samples := codeSamples(t, content)
+	if got, want := samples["curl"], "http PUT http://127.0.0.1:8080/users/"; !strings.HasPrefix(got, want) {
+		t.Errorf("got curl sample %q, want the overriding template", got)
+	}
+	if !strings.HasPrefix(samples["JavaScript"], "const response = await fetch(") {
+		t.Errorf("got js sample %q, want the default template", samples["JavaScript"])
+	}
+
+	err := buildError(t, writeMain(t, codeSamplesIDL), &args.Arguments{CodeSamples: []string{"python"}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported code sample language 'python'") {
+		t.Errorf("got error %v, want an unsupported language error", err)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+const (
+	requestBodyRefPrefix = "#/components/requestBodies/"
+	responseRefPrefix    = "#/components/responses/"
+)
+
+// componentNameReplacer matches the characters not allowed in component names.
+var componentNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// reusedComponent is a request body or a response shared by several operations.
+type reusedComponent struct {
+	name  string
+	value interface{}
+	uses  []func(ref string)
+}
+
+// componentIndex groups identical objects under names derived from the schemas
+// they carry.
+type componentIndex struct {
+	components []*reusedComponent
+	taken      map[string]bool
+}
+
+func newComponentIndex(taken []string) *componentIndex {
+	index := &componentIndex{taken: make(map[string]bool)}
+	for _, name := range taken {
+		index.taken[name] = true
+	}
+	return index
+}
+
+// add records a use of value, replaced by a reference when reuse is called.
+func (x *componentIndex) add(base string, value interface{}, use func(ref string)) {
+	for _, c := range x.components {
+		if reflect.DeepEqual(c.value, value) {
+			c.uses = append(c.uses, use)
+			return
+		}
+	}
+	x.components = append(x.components, &reusedComponent{name: base, value: value, uses: []func(string){use}})
+}
+
+// reuse names the objects used more than once, distinct objects with the same
+// schemas are suffixed with an index from 2, and references them with prefix.
+func (x *componentIndex) reuse(prefix string) []*reusedComponent {
+	var reused []*reusedComponent
+	for _, c := range x.components {
+		if len(c.uses) < 2 {
+			continue
+		}
+		name := c.name
+		for i := 2; x.taken[name]; i++ {
+			name = c.name + strconv.Itoa(i)
+		}
+		x.taken[name] = true
+		c.name = name
+		for _, use := range c.uses {
+			use(prefix + name)
+		}
+		reused = append(reused, c)
+	}
+	return reused
+}
+
+// reuseComponents moves the request bodies and the responses used by several
+// operations to components.requestBodies and components.responses, the operations
+// reference them instead. They are named after the schemas of their content, with
+// the media types other than JSON, e.g. CreateItemRequest or Item_form-data.
+func reuseComponents(d *openapi.Document) {
+	if d.Components == nil {
+		d.Components = &openapi.Components{}
+	}
+	var bodyNames, responseNames []string
+	if d.Components.RequestBodies != nil {
+		for _, body := range d.Components.RequestBodies.AdditionalProperties {
+			bodyNames = append(bodyNames, body.Name)
+		}
+	}
+	if d.Components.Responses != nil {
+		for _, response := range d.Components.Responses.AdditionalProperties {
+			responseNames = append(responseNames, response.Name)
+		}
+	}
+	bodies, responses := newComponentIndex(bodyNames), newComponentIndex(responseNames)
+
+	for _, item := range d.Paths.Path {
+		for _, op := range operationsOf(item.Value) {
+			if body := op.RequestBody; body != nil && body.RequestBody != nil {
+				if base := contentComponentName(body.RequestBody.Content); base != "" {
+					bodies.add(base, body.RequestBody, func(ref string) {
+						body.RequestBody, body.Reference = nil, &openapi.Reference{Xref: ref}
+					})
+				}
+			}
+			if op.Responses == nil {
+				continue
+			}
+			for _, named := range op.Responses.ResponseOrReference {
+				response := named.Value
+				if response == nil || response.Response == nil {
+					continue
+				}
+				if base := contentComponentName(response.Response.Content); base != "" {
+					responses.add(base, response.Response, func(ref string) {
+						response.Response, response.Reference = nil, &openapi.Reference{Xref: ref}
+					})
+				}
+			}
+		}
+	}
+
+	for _, c := range bodies.reuse(requestBodyRefPrefix) {
+		if d.Components.RequestBodies == nil {
+			d.Components.RequestBodies = &openapi.RequestBodiesOrReferences{}
+		}
+		d.Components.RequestBodies.AdditionalProperties = append(d.Components.RequestBodies.AdditionalProperties,
+			&openapi.NamedRequestBodyOrReference{
+				Name:  c.name,
+				Value: &openapi.RequestBodyOrReference{RequestBody: c.value.(*openapi.RequestBody)},
+			})
+	}
+	for _, c := range responses.reuse(responseRefPrefix) {
+		if d.Components.Responses == nil {
+			d.Components.Responses = &openapi.ResponsesOrReferences{}
+		}
+		d.Components.Responses.AdditionalProperties = append(d.Components.Responses.AdditionalProperties,
+			&openapi.NamedResponseOrReference{
+				Name:  c.name,
+				Value: &openapi.ResponseOrReference{Response: c.value.(*openapi.Response)},
+			})
+	}
+}
+
+// contentComponentName returns the name of a request body or a response with the
+// given content, empty when a media type has no schema reference.
+func contentComponentName(content *openapi.MediaTypes) string {
+	if content == nil || len(content.AdditionalProperties) == 0 {
+		return ""
+	}
+	var name string
+	var media []string
+	for _, mediaType := range content.AdditionalProperties {
+		if mediaType.Value == nil || mediaType.Value.Schema == nil || mediaType.Value.Schema.Reference == nil {
+			return ""
+		}
+		schema := strings.TrimPrefix(mediaType.Value.Schema.Reference.Xref, schemaRefPrefix)
+		if name == "" {
+			name = schema
+		} else if name != schema {
+			return ""
+		}
+		if mediaType.Name != "application/json" {
+			subtype := mediaType.Name[strings.LastIndex(mediaType.Name, "/")+1:]
+			if i := strings.IndexByte(subtype, ';'); i >= 0 {
+				subtype = subtype[:i]
+			}
+			media = append(media, componentNameReplacer.ReplaceAllString(strings.TrimSpace(subtype), "_"))
+		}
+	}
+	if len(media) > 0 {
+		name += "_" + strings.Join(media, "_")
+	}
+	return componentNameReplacer.ReplaceAllString(name, "_")
+}
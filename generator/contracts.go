@@ -0,0 +1,266 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/thriftgo/plugin"
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+	"gopkg.in/yaml.v3"
+)
+
+const contractHashExtension = "x-contract-hash"
+
+// contractIgnoredKeys are the keys which do not change the shape of a contract.
+var contractIgnoredKeys = map[string]bool{
+	"description":  true,
+	"summary":      true,
+	"title":        true,
+	"example":      true,
+	"examples":     true,
+	"externalDocs": true,
+	"tags":         true,
+	"servers":      true,
+	"operationId":  true,
+	"deprecated":   true,
+}
+
+// contracts is the index written to contracts.json.
+type contracts struct {
+	// Operations are keyed by method and path, e.g. "GET /items/{id}".
+	Operations map[string]contract `json:"operations"`
+	Schemas    map[string]string   `json:"schemas"`
+}
+
+type contract struct {
+	OperationID string `json:"operationId"`
+	Hash        string `json:"hash"`
+}
+
+// addContractHashes sets the x-contract-hash of the operations and the component
+// schemas of d and returns the contracts.json index. The hash of an operation
+// covers its verb, parameters, request body and responses with the schemas they
+// reference, the hash of a schema the schemas it references.
+func addContractHashes(d *openapi.Document, outputDir string) (*plugin.Generated, error) {
+	index := contracts{
+		Operations: make(map[string]contract),
+		Schemas:    make(map[string]string),
+	}
+
+	schemas := make(map[string]interface{})
+	if d.Components != nil && d.Components.Schemas != nil {
+		for _, schema := range d.Components.Schemas.AdditionalProperties {
+			value, err := normalizeContract(schema.Value.ToRawInfo())
+			if err != nil {
+				return nil, err
+			}
+			schemas[schema.Name] = value
+		}
+		for _, schema := range d.Components.Schemas.AdditionalProperties {
+			hash, err := contractHash(resolveContractRefs(schemas[schema.Name], schemas, []string{schema.Name}))
+			if err != nil {
+				return nil, err
+			}
+			index.Schemas[schema.Name] = hash
+			if schema.Value.Schema != nil {
+				if err := setContractHash(&schema.Value.Schema.SpecificationExtension, hash); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, item := range d.Paths.Path {
+		for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"} {
+			op := operationOf(item.Value, method)
+			if op == nil {
+				continue
+			}
+			value, err := normalizeContract(op.ToRawInfo())
+			if err != nil {
+				return nil, err
+			}
+			hash, err := contractHash([]interface{}{method, resolveContractRefs(value, schemas, nil)})
+			if err != nil {
+				return nil, err
+			}
+			index.Operations[method+" "+item.Name] = contract{OperationID: op.OperationID, Hash: hash}
+			if err := setContractHash(&op.SpecificationExtension, hash); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	bytes, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error converting contracts to json: %s", err)
+	}
+	filePath := filepath.Join(filepath.Clean(outputDir), "contracts.json")
+	return &plugin.Generated{
+		Content: string(bytes) + "\n",
+		Name:    &filePath,
+	}, nil
+}
+
+func setContractHash(extensions *[]*openapi.NamedAny, hash string) error {
+	extension, err := newNamedAny(contractHashExtension, hash)
+	if err != nil {
+		return err
+	}
+	*extensions = append(*extensions, extension)
+	return nil
+}
+
+// normalizeContract decodes a node, leaving out the keys which do not change the
+// shape of a contract and the specification extensions.
+func normalizeContract(node *yaml.Node) (interface{}, error) {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, fmt.Errorf("error decoding contract: %s", err)
+	}
+	return stripContract(value, ""), nil
+}
+
+// stripContract leaves out the ignored keys of a value found under the key parent.
+// The names of the properties, responses and other name maps are kept even when
+// they look like ignored keys, and the enums and defaults are data copied as is.
+func stripContract(value interface{}, parent string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		stripped := make(map[string]interface{}, len(v))
+		if minifyNameMaps[parent] {
+			for name, child := range v {
+				stripped[name] = stripContract(child, "entry of "+parent)
+			}
+			return stripped
+		}
+		for key, child := range v {
+			if contractIgnoredKeys[key] || strings.HasPrefix(key, "x-") {
+				continue
+			}
+			if minifyDataKeys[key] {
+				stripped[key] = plainValue(child)
+				continue
+			}
+			stripped[key] = stripContract(child, key)
+		}
+		return stripped
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			converted[fmt.Sprint(key)] = child
+		}
+		return stripContract(converted, parent)
+	case []interface{}:
+		stripped := make([]interface{}, len(v))
+		for i, child := range v {
+			stripped[i] = stripContract(child, "[]"+parent)
+		}
+		return stripped
+	}
+	return value
+}
+
+// resolveContractRefs replaces the references to component schemas with the schemas,
+// a schema referencing itself through the schemas on the path keeps its reference.
+func resolveContractRefs(value interface{}, schemas map[string]interface{}, path []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, schemaRefPrefix) {
+			name := strings.TrimPrefix(ref, schemaRefPrefix)
+			schema, ok := schemas[name]
+			if !ok {
+				return v
+			}
+			for _, visited := range path {
+				if visited == name {
+					return v
+				}
+			}
+			return resolveContractRefs(schema, schemas, append(path[:len(path):len(path)], name))
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			resolved[key] = resolveContractRefs(child, schemas, path)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, child := range v {
+			resolved[i] = resolveContractRefs(child, schemas, path)
+		}
+		return resolved
+	}
+	return value
+}
+
+// contractHash returns the SHA-256 of the JSON encoding of value, with the items of
+// every list sorted so that only the content counts. Maps are encoded with sorted keys.
+func contractHash(value interface{}) (string, error) {
+	sorted, err := sortContract(value)
+	if err != nil {
+		return "", err
+	}
+	bytes, err := json.Marshal(sorted)
+	if err != nil {
+		return "", fmt.Errorf("error hashing contract: %s", err)
+	}
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func sortContract(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		sorted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			s, err := sortContract(child)
+			if err != nil {
+				return nil, err
+			}
+			sorted[key] = s
+		}
+		return sorted, nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, child := range v {
+			s, err := sortContract(child)
+			if err != nil {
+				return nil, err
+			}
+			bytes, err := json.Marshal(s)
+			if err != nil {
+				return nil, fmt.Errorf("error hashing contract: %s", err)
+			}
+			items[i] = string(bytes)
+		}
+		sort.Strings(items)
+		sorted := make([]interface{}, len(items))
+		for i, item := range items {
+			sorted[i] = json.RawMessage(item)
+		}
+		return sorted, nil
+	}
+	return value, nil
+}
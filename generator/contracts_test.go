@@ -0,0 +1,168 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"gopkg.in/yaml.v3"
+)
+
+// hashOf returns the contract hash of the YAML content.
+func hashOf(t *testing.T, content string) string {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatal(err)
+	}
+	value, err := normalizeContract(&node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := contractHash(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestContractHash(t *testing.T) {
+	const base = `
+type: object
+required: [id, name]
+properties:
+  id: {type: integer, format: int64}
+  name: {type: string}
+`
+	tests := []struct {
+		name    string
+		content string
+		same    bool
+	}{
+		{"identical", base, true},
+		{"description", base + "description: a user\n", true},
+		{"example", base + "example: {id: 1}\n", true},
+		{"extension", base + "x-go-type: User\n", true},
+		{"key order", "properties:\n  name: {type: string}\n  id: {format: int64, type: integer}\nrequired: [id, name]\ntype: object\n", true},
+		{"list order", "type: object\nrequired: [name, id]\nproperties:\n  id: {type: integer, format: int64}\n  name: {type: string}\n", true},
+		{"nested description", "type: object\nrequired: [id, name]\nproperties:\n  id: {type: integer, format: int64, description: id}\n  name: {type: string}\n", true},
+		{"type", "type: object\nrequired: [id, name]\nproperties:\n  id: {type: string}\n  name: {type: string}\n", false},
+		{"format", "type: object\nrequired: [id, name]\nproperties:\n  id: {type: integer, format: int32}\n  name: {type: string}\n", false},
+		{"property name", "type: object\nrequired: [id, name]\nproperties:\n  id: {type: integer, format: int64}\n  title: {type: string}\n", false},
+		{"property named description", base + "  description: {type: string}\n", false},
+		{"required", "type: object\nrequired: [id]\nproperties:\n  id: {type: integer, format: int64}\n  name: {type: string}\n", false},
+	}
+	want := hashOf(t, base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hashOf(t, tt.content); (got == want) != tt.same {
+				t.Errorf("got hash %s, want it the same as %s: %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestResolveContractRefs(t *testing.T) {
+	schemas := map[string]interface{}{
+		"Item": map[string]interface{}{"type": "string"},
+		"Node": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"next": map[string]interface{}{"$ref": schemaRefPrefix + "Node"}},
+		},
+	}
+	got := resolveContractRefs([]interface{}{
+		map[string]interface{}{"$ref": schemaRefPrefix + "Item"},
+		map[string]interface{}{"$ref": schemaRefPrefix + "Node"},
+		map[string]interface{}{"$ref": schemaRefPrefix + "Missing"},
+	}, schemas, nil)
+	bytes, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"type":"string"},{"properties":{"next":{"$ref":"#/components/schemas/Node"}},"type":"object"},{"$ref":"#/components/schemas/Missing"}]`
+	if string(bytes) != want {
+		t.Errorf("got %s, want %s", bytes, want)
+	}
+}
+
+// contractIDL declares GET /users/:id, the declarations of User are formatted in.
+const contractIDL = `
+struct Req {
+    1: i64 id (api.path = "id")
+}
+
+// User of the service.
+struct User {
+    %s
+}
+
+service UserService {
+    User GetUser(1: Req req) (api.get = "/users/:id")
+}
+`
+
+func TestContractHashes(t *testing.T) {
+	generate := func(user string) (string, contracts) {
+		idl := writeMain(t, fmt.Sprintf(contractIDL, user))
+		_, generated := generateFiles(t, idl, &args.Arguments{ContractHashes: true})
+		var index contracts
+		if err := json.Unmarshal([]byte(generatedFile(t, generated, "contracts.json")), &index); err != nil {
+			t.Fatal(err)
+		}
+		return generatedFile(t, generated, "openapi.yaml"), index
+	}
+
+	content, index := generate(`1: string name (api.body = "name")`)
+	op, ok := index.Operations["GET /users/{id}"]
+	if !ok || op.OperationID != "UserService_GetUser" || len(op.Hash) != 64 {
+		t.Fatalf("got operations %v", index.Operations)
+	}
+	if got := lookup(t, content, "paths", "/users/{id}", "get", contractHashExtension); got != op.Hash {
+		t.Errorf("got operation hash %v, want %s", got, op.Hash)
+	}
+	if got := lookup(t, content, "components", "schemas", "UserBody", contractHashExtension); got == nil || got != index.Schemas["UserBody"] {
+		t.Errorf("got schema hash %v, want %s", got, index.Schemas["UserBody"])
+	}
+
+	// the comment only changes the description
+	_, described := generate("// The name.\n    1: string name (api.body = \"name\")")
+	if described.Operations["GET /users/{id}"] != op || described.Schemas["UserBody"] != index.Schemas["UserBody"] {
+		t.Errorf("got hashes changed by a description")
+	}
+	_, typed := generate(`1: i32 name (api.body = "name")`)
+	if typed.Operations["GET /users/{id}"].Hash == op.Hash || typed.Schemas["UserBody"] == index.Schemas["UserBody"] {
+		t.Errorf("got hashes unchanged by a type")
+	}
+
+	// the fields named like the ignored keys are part of the contract
+	_, summarized := generate("1: string name (api.body = \"name\")\n    2: string description (api.body = \"description\")")
+	_, retyped := generate("1: string name (api.body = \"name\")\n    2: i32 description (api.body = \"description\")")
+	if summarized.Operations["GET /users/{id}"].Hash == op.Hash || retyped.Schemas["UserBody"] == summarized.Schemas["UserBody"] ||
+		retyped.Operations["GET /users/{id}"].Hash == summarized.Operations["GET /users/{id}"].Hash {
+		t.Errorf("got hashes unchanged by the field named description")
+	}
+
+	_, generated := generateFiles(t, writeMain(t, fmt.Sprintf(contractIDL, "")), &args.Arguments{})
+	for _, file := range generated {
+		if file.GetName() == "contracts.json" {
+			t.Error("got contracts.json without ContractHashes")
+		}
+	}
+}
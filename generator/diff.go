@@ -0,0 +1,411 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+)
+
+// BreakingChangeKind is the kind of a change of a document which breaks its clients.
+type BreakingChangeKind string
+
+const (
+	RemovedPath              BreakingChangeKind = "removed-path"
+	RemovedOperation         BreakingChangeKind = "removed-operation"
+	RemovedRequiredParameter BreakingChangeKind = "removed-required-parameter"
+	AddedRequiredParameter   BreakingChangeKind = "added-required-parameter"
+	AddedRequiredBody        BreakingChangeKind = "added-required-body"
+	AddedRequiredBodyField   BreakingChangeKind = "added-required-body-field"
+	RemovedResponse          BreakingChangeKind = "removed-response"
+	ChangedResponseSchema    BreakingChangeKind = "changed-response-schema"
+)
+
+var diffMethods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// BreakingChange is a change of a document which breaks its clients.
+type BreakingChange struct {
+	Kind BreakingChangeKind
+	// Path and Method locate the change, Method is empty for a removed path.
+	Path   string
+	Method string
+	// Detail tells what changed, e.g. the parameter or the property.
+	Detail string
+}
+
+func (c BreakingChange) String() string {
+	location := c.Path
+	if c.Method != "" {
+		location = c.Method + " " + c.Path
+	}
+	if c.Detail == "" {
+		return fmt.Sprintf("%s: %s", c.Kind, location)
+	}
+	return fmt.Sprintf("%s: %s: %s", c.Kind, location, c.Detail)
+}
+
+// CompareDocuments returns the changes from oldDoc to newDoc which break the clients
+// of oldDoc, in the order of its paths and operations: removed paths and
+// operations, removed or added required parameters, request bodies added or made
+// required, properties required by the request bodies, nested ones included,
+// removed responses and response schemas whose properties are removed or whose
+// types change. References to the components are resolved, additions which clients
+// can ignore are not reported.
+func CompareDocuments(oldDoc, newDoc *openapi.Document) []BreakingChange {
+	var changes []BreakingChange
+	if oldDoc == nil || oldDoc.Paths == nil {
+		return nil
+	}
+	for _, oldItem := range oldDoc.Paths.Path {
+		newItem := pathItemOf(newDoc, oldItem.Name)
+		if newItem == nil {
+			changes = append(changes, BreakingChange{Kind: RemovedPath, Path: oldItem.Name})
+			continue
+		}
+		for _, method := range diffMethods {
+			oldOp := operationOf(oldItem.Value, method)
+			if oldOp == nil {
+				continue
+			}
+			newOp := operationOf(newItem, method)
+			if newOp == nil {
+				changes = append(changes, BreakingChange{Kind: RemovedOperation, Path: oldItem.Name, Method: method})
+				continue
+			}
+			c := &operationComparison{old: oldDoc, new: newDoc, path: oldItem.Name, method: method}
+			c.compareParameters(
+				append(append([]*openapi.ParameterOrReference{}, oldItem.Value.Parameters...), oldOp.Parameters...),
+				append(append([]*openapi.ParameterOrReference{}, newItem.Parameters...), newOp.Parameters...))
+			c.compareRequestBodies(oldOp.RequestBody, newOp.RequestBody)
+			c.compareResponses(oldOp.Responses, newOp.Responses)
+			changes = append(changes, c.changes...)
+		}
+	}
+	return changes
+}
+
+// operationComparison collects the breaking changes of an operation.
+type operationComparison struct {
+	old, new     *openapi.Document
+	path, method string
+	changes      []BreakingChange
+}
+
+func (c *operationComparison) report(kind BreakingChangeKind, format string, a ...interface{}) {
+	c.changes = append(c.changes, BreakingChange{Kind: kind, Path: c.path, Method: c.method, Detail: fmt.Sprintf(format, a...)})
+}
+
+func (c *operationComparison) compareParameters(oldParameters, newParameters []*openapi.ParameterOrReference) {
+	find := func(d *openapi.Document, parameters []*openapi.ParameterOrReference, in, name string) *openapi.Parameter {
+		for _, parameter := range parameters {
+			if p := resolveParameter(d, parameter); p != nil && p.In == in && p.Name == name {
+				return p
+			}
+		}
+		return nil
+	}
+	for _, parameter := range oldParameters {
+		if p := resolveParameter(c.old, parameter); p != nil && p.Required && find(c.new, newParameters, p.In, p.Name) == nil {
+			c.report(RemovedRequiredParameter, "%s parameter '%s'", p.In, p.Name)
+		}
+	}
+	for _, parameter := range newParameters {
+		p := resolveParameter(c.new, parameter)
+		if p == nil || !p.Required {
+			continue
+		}
+		if previous := find(c.old, oldParameters, p.In, p.Name); previous == nil || !previous.Required {
+			c.report(AddedRequiredParameter, "%s parameter '%s'", p.In, p.Name)
+		}
+	}
+}
+
+func (c *operationComparison) compareRequestBodies(oldBody, newBody *openapi.RequestBodyOrReference) {
+	oldRequestBody, newRequestBody := resolveRequestBody(c.old, oldBody), resolveRequestBody(c.new, newBody)
+	if newRequestBody == nil {
+		return
+	}
+	if oldRequestBody == nil {
+		// The clients send no body, which only breaks them when one is expected.
+		if newRequestBody.Required || c.requiresFields(newRequestBody) {
+			c.report(AddedRequiredBody, "request body is added")
+		}
+		return
+	}
+	if newRequestBody.Required && !oldRequestBody.Required {
+		c.report(AddedRequiredBody, "request body is required")
+	}
+	for _, media := range mediaTypesOf(newRequestBody.Content) {
+		oldMedia := mediaTypeOf(oldRequestBody.Content, media.Name)
+		if oldMedia == nil || media.Value == nil {
+			continue
+		}
+		at := fmt.Sprintf("request body (%s)", media.Name)
+		c.compareRequiredFields(at, "", oldMedia.Schema, media.Value.Schema, make(map[string]bool))
+	}
+}
+
+// requiresFields reports whether a schema of the request body requires properties.
+func (c *operationComparison) requiresFields(body *openapi.RequestBody) bool {
+	for _, media := range mediaTypesOf(body.Content) {
+		if media.Value == nil {
+			continue
+		}
+		if schema := resolveSchema(c.new, media.Value.Schema); schema != nil && len(schema.Required) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compareRequiredFields reports the properties the new request schema requires and
+// the old one does not, through the properties and the items of arrays found in
+// both, field being the path of the schema in the body. The properties the clients
+// do not send yet are reported themselves when they are required, their own
+// properties are not. The pairs of references already compared are skipped.
+func (c *operationComparison) compareRequiredFields(at, field string, oldSchema, newSchema *openapi.SchemaOrReference, compared map[string]bool) {
+	if oldSchema != nil && oldSchema.Reference != nil && newSchema != nil && newSchema.Reference != nil {
+		pair := oldSchema.Reference.Xref + " " + newSchema.Reference.Xref
+		if compared[pair] {
+			return
+		}
+		compared[pair] = true
+	}
+	oldValue, newValue := resolveSchema(c.old, oldSchema), resolveSchema(c.new, newSchema)
+	if oldValue == nil || newValue == nil {
+		return
+	}
+	prefix := ""
+	if field != "" {
+		prefix = field + "."
+	}
+	for _, name := range newValue.Required {
+		if !utils.Contains(oldValue.Required, name) {
+			c.report(AddedRequiredBodyField, "%s field '%s'", at, prefix+name)
+		}
+	}
+	if newValue.Properties != nil {
+		for _, property := range newValue.Properties.AdditionalProperties {
+			if oldProperty := propertyOf(oldValue, property.Name); oldProperty != nil {
+				c.compareRequiredFields(at, prefix+property.Name, oldProperty, property.Value, compared)
+			}
+		}
+	}
+	if oldValue.Items != nil && len(oldValue.Items.SchemaOrReference) > 0 &&
+		newValue.Items != nil && len(newValue.Items.SchemaOrReference) > 0 {
+		c.compareRequiredFields(at, field+"[]", oldValue.Items.SchemaOrReference[0], newValue.Items.SchemaOrReference[0], compared)
+	}
+}
+
+func (c *operationComparison) compareResponses(oldResponses, newResponses *openapi.Responses) {
+	if oldResponses == nil || newResponses == nil {
+		return
+	}
+	compare := func(status string, oldResponse, newResponse *openapi.ResponseOrReference) {
+		oldValue, newValue := resolveResponse(c.old, oldResponse), resolveResponse(c.new, newResponse)
+		if oldValue == nil || newValue == nil {
+			return
+		}
+		for _, media := range mediaTypesOf(oldValue.Content) {
+			at := fmt.Sprintf("response %s (%s)", status, media.Name)
+			newMedia := mediaTypeOf(newValue.Content, media.Name)
+			if newMedia == nil {
+				c.report(ChangedResponseSchema, "%s is removed", at)
+				continue
+			}
+			if media.Value != nil {
+				c.compareSchemas(at, "", media.Value.Schema, newMedia.Schema, make(map[string]bool))
+			}
+		}
+	}
+	if oldResponses.Default != nil && newResponses.Default == nil {
+		c.report(RemovedResponse, "response default is removed")
+	}
+	compare("default", oldResponses.Default, newResponses.Default)
+	for _, response := range oldResponses.ResponseOrReference {
+		found := false
+		for _, newResponse := range newResponses.ResponseOrReference {
+			if newResponse.Name == response.Name {
+				found = true
+				compare(response.Name, response.Value, newResponse.Value)
+			}
+		}
+		if !found {
+			c.report(RemovedResponse, "response %s is removed", response.Name)
+		}
+	}
+}
+
+// compareSchemas reports the properties of the old schema removed from the new one
+// and the changes of type, through the properties and the items of arrays, field
+// being the path of the schema in the body, e.g. items[].name. The pairs of
+// references already compared are skipped, which stops recursive schemas.
+func (c *operationComparison) compareSchemas(at, field string, oldSchema, newSchema *openapi.SchemaOrReference, compared map[string]bool) {
+	if oldSchema != nil && oldSchema.Reference != nil && newSchema != nil && newSchema.Reference != nil {
+		pair := oldSchema.Reference.Xref + " " + newSchema.Reference.Xref
+		if compared[pair] {
+			return
+		}
+		compared[pair] = true
+	}
+	oldValue, newValue := resolveSchema(c.old, oldSchema), resolveSchema(c.new, newSchema)
+	if oldValue == nil || newValue == nil {
+		return
+	}
+	if oldValue.Type != newValue.Type || oldValue.Format != newValue.Format {
+		if field == "" {
+			c.report(ChangedResponseSchema, "%s type %s changed to %s", at, schemaType(oldValue), schemaType(newValue))
+		} else {
+			c.report(ChangedResponseSchema, "%s field '%s' type %s changed to %s", at, field, schemaType(oldValue), schemaType(newValue))
+		}
+		return
+	}
+	if oldValue.Properties != nil {
+		for _, property := range oldValue.Properties.AdditionalProperties {
+			name := property.Name
+			if field != "" {
+				name = field + "." + name
+			}
+			newProperty := propertyOf(newValue, property.Name)
+			if newProperty == nil {
+				c.report(ChangedResponseSchema, "%s field '%s' is removed", at, name)
+				continue
+			}
+			c.compareSchemas(at, name, property.Value, newProperty, compared)
+		}
+	}
+	if oldValue.Items != nil && len(oldValue.Items.SchemaOrReference) > 0 &&
+		newValue.Items != nil && len(newValue.Items.SchemaOrReference) > 0 {
+		c.compareSchemas(at, field+"[]", oldValue.Items.SchemaOrReference[0], newValue.Items.SchemaOrReference[0], compared)
+	}
+}
+
+func schemaType(schema *openapi.Schema) string {
+	if schema.Format == "" {
+		return "'" + schema.Type + "'"
+	}
+	return "'" + schema.Type + "/" + schema.Format + "'"
+}
+
+func pathItemOf(d *openapi.Document, path string) *openapi.PathItem {
+	if d == nil || d.Paths == nil {
+		return nil
+	}
+	for _, item := range d.Paths.Path {
+		if item.Name == path {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func mediaTypesOf(content *openapi.MediaTypes) []*openapi.NamedMediaType {
+	if content == nil {
+		return nil
+	}
+	return content.AdditionalProperties
+}
+
+func mediaTypeOf(content *openapi.MediaTypes, name string) *openapi.MediaType {
+	for _, media := range mediaTypesOf(content) {
+		if media.Name == name {
+			return media.Value
+		}
+	}
+	return nil
+}
+
+func propertyOf(schema *openapi.Schema, name string) *openapi.SchemaOrReference {
+	if schema.Properties == nil {
+		return nil
+	}
+	for _, property := range schema.Properties.AdditionalProperties {
+		if property.Name == name {
+			return property.Value
+		}
+	}
+	return nil
+}
+
+// componentName returns the name of the component referenced by ref under prefix,
+// empty when ref is not such a reference.
+func componentName(ref *openapi.Reference, prefix string) string {
+	if ref == nil || !strings.HasPrefix(ref.Xref, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(ref.Xref, prefix)
+}
+
+func resolveParameter(d *openapi.Document, parameter *openapi.ParameterOrReference) *openapi.Parameter {
+	if parameter == nil {
+		return nil
+	}
+	if parameter.Parameter != nil {
+		return parameter.Parameter
+	}
+	name := componentName(parameter.Reference, "#/components/parameters/")
+	if name == "" || d.Components == nil || d.Components.Parameters == nil {
+		return nil
+	}
+	for _, component := range d.Components.Parameters.AdditionalProperties {
+		if component.Name == name && component.Value != nil {
+			return component.Value.Parameter
+		}
+	}
+	return nil
+}
+
+func resolveRequestBody(d *openapi.Document, body *openapi.RequestBodyOrReference) *openapi.RequestBody {
+	if body == nil {
+		return nil
+	}
+	if body.RequestBody != nil {
+		return body.RequestBody
+	}
+	name := componentName(body.Reference, requestBodyRefPrefix)
+	if name == "" || d.Components == nil || d.Components.RequestBodies == nil {
+		return nil
+	}
+	for _, component := range d.Components.RequestBodies.AdditionalProperties {
+		if component.Name == name && component.Value != nil {
+			return component.Value.RequestBody
+		}
+	}
+	return nil
+}
+
+func resolveResponse(d *openapi.Document, response *openapi.ResponseOrReference) *openapi.Response {
+	if response == nil {
+		return nil
+	}
+	if response.Response != nil {
+		return response.Response
+	}
+	name := componentName(response.Reference, responseRefPrefix)
+	if name == "" || d.Components == nil || d.Components.Responses == nil {
+		return nil
+	}
+	for _, component := range d.Components.Responses.AdditionalProperties {
+		if component.Name == name && component.Value != nil {
+			return component.Value.Response
+		}
+	}
+	return nil
+}
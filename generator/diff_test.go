@@ -0,0 +1,288 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+const oldItemsIDL = `
+struct Item {
+    1: i64 id
+    2: string name
+}
+
+struct ListReq {
+    1: string tenant (api.query = "tenant", openapi.parameter = '{required: true}')
+    2: string q (api.query = "q")
+}
+
+struct ListResp {
+    1: list<Item> items (api.body = "items")
+    2: i32 total (api.body = "total")
+}
+
+struct CreateReq {
+    1: string name (api.body = "name")
+} (openapi.schema = '{required: ["name"]}')
+
+struct CreateResp {
+    1: i64 id (api.body = "id")
+}
+
+service ItemService {
+    ListResp ListItems(1: ListReq req) (api.get = "/items")
+    CreateResp CreateItem(1: CreateReq req) (api.post = "/items")
+    ListResp DeleteItems(1: ListReq req) (api.delete = "/items")
+    ListResp GetOld(1: ListReq req) (api.get = "/old")
+}
+`
+
+// newItemsIDL breaks the clients of oldItemsIDL: the tenant parameter and the name
+// of the items are removed, q and owner are required and the types of id and total
+// change.
+const newItemsIDL = `
+struct Item {
+    1: string id
+}
+
+struct ListReq {
+    2: string q (api.query = "q", openapi.parameter = '{required: true}')
+}
+
+struct ListResp {
+    1: list<Item> items (api.body = "items")
+    2: i64 total (api.body = "total")
+}
+
+struct CreateReq {
+    1: string name (api.body = "name")
+    2: string owner (api.body = "owner")
+} (openapi.schema = '{required: ["name", "owner"]}')
+
+struct CreateResp {
+    1: i64 id (api.body = "id")
+}
+
+service ItemService {
+    ListResp ListItems(1: ListReq req) (api.get = "/items")
+    CreateResp CreateItem(1: CreateReq req) (api.post = "/items")
+}
+`
+
+func TestCompareDocuments(t *testing.T) {
+	oldIDL, newIDL := writeMain(t, oldItemsIDL), writeMain(t, newItemsIDL)
+	// extendedIDL only adds a property to the items and a service to oldItemsIDL.
+	extended := strings.Replace(oldItemsIDL, "    2: string name\n", "    2: string name\n    3: string color\n", 1)
+	extendedIDL := writeMain(t, extended+`
+service ColorService {
+    ListResp ListColors(1: ListReq req) (api.get = "/colors")
+}
+`)
+	want := []string{
+		"removed-required-parameter: GET /items: query parameter 'tenant'",
+		"added-required-parameter: GET /items: query parameter 'q'",
+		"changed-response-schema: GET /items: response 200 (application/json) field 'items[].id' type 'integer/int64' changed to 'string'",
+		"changed-response-schema: GET /items: response 200 (application/json) field 'items[].name' is removed",
+		"changed-response-schema: GET /items: response 200 (application/json) field 'total' type 'integer/int32' changed to 'integer/int64'",
+		"added-required-body-field: POST /items: request body (application/json) field 'owner'",
+		"removed-operation: DELETE /items",
+		"removed-path: /old",
+	}
+	// The bodies referenced from the components are resolved.
+	for _, reuseBodies := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ReuseBodies=%v", reuseBodies), func(t *testing.T) {
+			arguments := &args.Arguments{ReuseBodies: reuseBodies}
+			_, oldDoc := buildDocument(t, oldIDL, arguments)
+			_, newDoc := buildDocument(t, newIDL, arguments)
+			var got []string
+			for _, change := range CompareDocuments(oldDoc, newDoc) {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got changes\n%q\nwant\n%q", got, want)
+			}
+
+			if changes := CompareDocuments(oldDoc, oldDoc); len(changes) != 0 {
+				t.Errorf("got changes %v comparing a document with itself", changes)
+			}
+			_, extendedDoc := buildDocument(t, extendedIDL, arguments)
+			if changes := CompareDocuments(oldDoc, extendedDoc); len(changes) != 0 {
+				t.Errorf("got changes %v for additions, which do not break the clients", changes)
+			}
+		})
+	}
+}
+
+func TestCompareRequestsAndResponses(t *testing.T) {
+	const resp = `
+struct Resp {
+    1: i64 id (api.body = "id")
+}
+`
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "added body",
+			old: resp + `
+struct Req {
+    1: string q (api.query = "q")
+}
+
+service ItemService {
+    Resp CreateItem(1: Req req) (api.post = "/items")
+}
+`,
+			new: resp + `
+struct Req {
+    1: string q (api.query = "q")
+    2: string name (api.body = "name")
+} (openapi.schema = '{required: ["name"]}')
+
+service ItemService {
+    Resp CreateItem(1: Req req) (api.post = "/items")
+}
+`,
+			want: []string{"added-required-body: POST /items: request body is added"},
+		},
+		{
+			name: "added optional body",
+			old: resp + `
+struct Req {
+    1: string q (api.query = "q")
+}
+
+service ItemService {
+    Resp CreateItem(1: Req req) (api.post = "/items")
+}
+`,
+			new: resp + `
+struct Req {
+    1: string q (api.query = "q")
+    2: string name (api.body = "name")
+}
+
+service ItemService {
+    Resp CreateItem(1: Req req) (api.post = "/items")
+}
+`,
+		},
+		{
+			name: "nested required field",
+			old: resp + `
+struct Owner {
+    1: string name
+}
+
+struct Req {
+    1: Owner owner (api.body = "owner")
+}
+
+service ItemService {
+    Resp CreateItem(1: Req req) (api.post = "/items")
+}
+`,
+			new: resp + `
+struct Owner {
+    1: string name
+    2: string email
+} (openapi.schema = '{required: ["email"]}')
+
+struct Req {
+    1: Owner owner (api.body = "owner")
+}
+
+service ItemService {
+    Resp CreateItem(1: Req req) (api.post = "/items")
+}
+`,
+			want: []string{"added-required-body-field: POST /items: request body (application/json) field 'owner.email'"},
+		},
+		{
+			name: "removed response",
+			old: resp + `
+struct Req {
+    1: string q (api.query = "q")
+}
+
+exception NotFound {
+    1: string message
+} (api.http_code = "404")
+
+service ItemService {
+    Resp GetItem(1: Req req) throws (1: NotFound notFound) (api.get = "/items")
+}
+`,
+			new: resp + `
+struct Req {
+    1: string q (api.query = "q")
+}
+
+service ItemService {
+    Resp GetItem(1: Req req) (api.get = "/items")
+}
+`,
+			want: []string{"removed-response: GET /items: response 404 is removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, oldDoc := buildDocument(t, writeMain(t, tt.old), &args.Arguments{})
+			_, newDoc := buildDocument(t, writeMain(t, tt.new), &args.Arguments{})
+			var got []string
+			for _, change := range CompareDocuments(oldDoc, newDoc) {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got changes\n%q\nwant\n%q", got, tt.want)
+			}
+			// The reverse changes relax the requests or add responses.
+			if changes := CompareDocuments(newDoc, oldDoc); len(changes) != 0 {
+				t.Errorf("got changes %v reverting the change", changes)
+			}
+		})
+	}
+}
+
+func TestCompareRequiredBody(t *testing.T) {
+	document := func(required bool) *openapi.Document {
+		body := &openapi.RequestBody{Required: required, Content: &openapi.MediaTypes{}}
+		return &openapi.Document{Paths: &openapi.Paths{Path: []*openapi.NamedPathItem{{
+			Name:  "/items",
+			Value: &openapi.PathItem{Post: &openapi.Operation{RequestBody: &openapi.RequestBodyOrReference{RequestBody: body}}},
+		}}}}
+	}
+	var got []string
+	for _, change := range CompareDocuments(document(false), document(true)) {
+		got = append(got, change.String())
+	}
+	if want := []string{"added-required-body: POST /items: request body is required"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %q, want %q", got, want)
+	}
+	if changes := CompareDocuments(document(true), document(false)); len(changes) != 0 {
+		t.Errorf("got changes %v for an optional body", changes)
+	}
+}
@@ -0,0 +1,101 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"gopkg.in/yaml.v3"
+)
+
+const fuzzIDL = `
+namespace go fuzz
+
+include "openapi.thrift"
+
+struct Req {
+    1: string name (api.query = "name")
+    2: i64 id (api.path = "id")
+    3: list<string> tags (api.body = "tags")
+}
+
+struct Resp {
+    1: string body (api.body = "body")
+}
+
+service FuzzService {
+    Resp Call(1: Req req) (api.post = "/items/:id")
+}
+`
+
+// FuzzBuildDocument builds documents whose comments, route path and struct option
+// are arbitrary, the seeds are in testdata/fuzz/FuzzBuildDocument. Any input must
+// build a document, at worst with warnings, whose files are valid YAML or JSON. As
+// thriftgo keeps the descriptors of every registered AST, long fuzzing sessions slow
+// down over time.
+func FuzzBuildDocument(f *testing.F) {
+	f.Add("Req is a request", "/items/:id", `{title: "Req"}`)
+
+	annotations, err := ioutil.ReadFile(filepath.Join("..", "example", "openapi.thrift"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	files := map[string]string{"main.thrift": fuzzIDL, "openapi.thrift": string(annotations)}
+
+	f.Fuzz(func(t *testing.T, comment, path, option string) {
+		ast, err := parser.ParseBatchString("main.thrift", files, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := semantic.ResolveSymbols(ast); err != nil {
+			t.Fatal(err)
+		}
+		req, function := ast.Structs[0], ast.Services[0].Functions[0]
+		req.ReservedComments = comment
+		req.Fields[0].ReservedComments = comment
+		function.ReservedComments = comment
+		function.Annotations[0].Values = []string{path}
+		req.Annotations = append(req.Annotations, &parser.Annotation{Key: "openapi.schema", Values: []string{option}})
+
+		generated, err := NewOpenAPIGenerator(ast).BuildDocument(&args.Arguments{})
+		if err != nil {
+			t.Fatalf("build: %s", err)
+		}
+		for _, file := range generated {
+			var document interface{}
+			switch {
+			case strings.HasSuffix(file.GetName(), ".yaml"):
+				err = yaml.Unmarshal([]byte(file.Content), &document)
+			case strings.HasSuffix(file.GetName(), ".json"):
+				err = json.Unmarshal([]byte(file.Content), &document)
+			}
+			if err != nil {
+				t.Errorf("%s is invalid: %s", file.GetName(), err)
+			}
+		}
+	})
+}
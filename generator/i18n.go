@@ -0,0 +1,104 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/thriftgo/plugin"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+)
+
+const untranslatedExtension = "x-untranslated"
+
+// langTagPattern matches a comment line tagged with its language, e.g. "[zh] 用户".
+var langTagPattern = regexp.MustCompile(`^\[([A-Za-z]{2,3}(?:-[A-Za-z0-9]+)*)\]\s?(.*)$`)
+
+// localizeComment returns the lines of a comment in the language of the document.
+// Lines tagged with one of the Langs belong to that language, the other lines to the
+// default language, the first of the Langs. A comment without lines in the language
+// of the document falls back to the default language.
+func (g *OpenAPIGenerator) localizeComment(comment string) string {
+	if g.lang == "" || comment == "" {
+		return comment
+	}
+	langs := g.arguments.Langs
+	tagged := make(map[string][]string)
+	var untagged []string
+	for _, line := range strings.Split(comment, "\n") {
+		if match := langTagPattern.FindStringSubmatch(line); match != nil && utils.Contains(langs, match[1]) {
+			tagged[match[1]] = append(tagged[match[1]], match[2])
+			continue
+		}
+		untagged = append(untagged, line)
+	}
+	if lines, ok := tagged[g.lang]; ok {
+		return strings.Join(lines, "\n")
+	}
+	if g.lang != langs[0] {
+		if lines, ok := tagged[langs[0]]; ok {
+			untagged = lines
+		}
+	}
+	fallback := strings.Join(untagged, "\n")
+	if g.lang != langs[0] && strings.TrimSpace(fallback) != "" {
+		g.untranslated = utils.AppendUnique(g.untranslated, fallback)
+	}
+	return fallback
+}
+
+// localizedDocuments builds the document once per language of Langs, e.g.
+// openapi.zh.yaml, the UI lists them as the parts of the document. Only the
+// descriptions differ between the languages.
+func (g *OpenAPIGenerator) localizedDocuments(arguments *args.Arguments) ([]*plugin.Generated, error) {
+	localized := *arguments
+	localized.ContractHashes = false
+	localized.MaxOperationsPerDoc = 0
+
+	var ret []*plugin.Generated
+	for _, lang := range arguments.Langs {
+		lg := newOpenAPIGenerator(g.ast, g.fileDesc)
+		lg.naming = g.naming
+		lg.routes = g.routes
+		lg.lang = lang
+		lg.localized = true
+		generated, err := lg.BuildDocument(&localized)
+		if err != nil {
+			return nil, err
+		}
+		file := "openapi." + lang + ".yaml"
+		filePath := filepath.Join(filepath.Clean(arguments.OutputDir), file)
+		ret = append(ret, &plugin.Generated{
+			Content: generated[0].Content,
+			Name:    &filePath,
+		})
+		g.specParts = append(g.specParts, SpecPart{File: file, Name: lang})
+	}
+	return ret, nil
+}
+
+// untranslatedDescriptions returns the descriptions of the document which fell back
+// to the default language, listed in x-untranslated with MarkUntranslated.
+func (g *OpenAPIGenerator) untranslatedDescriptions() []string {
+	untranslated := append([]string{}, g.untranslated...)
+	sort.Strings(untranslated)
+	return untranslated
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"gopkg.in/yaml.v3"
+)
+
+// localizedIDL carries comments in English and Chinese, the comment of Req.id is
+// only in English.
+const localizedIDL = `
+// A user.
+// [zh] 用户
+struct User {
+    // The name.
+    // [zh] 名字
+    1: string name (api.body = "name")
+}
+
+struct Req {
+    // The id.
+    1: i64 id (api.path = "id")
+}
+
+// Manages the users.
+// [zh] 管理用户
+service UserService {
+    User GetUser(1: Req req) (api.get = "/users/:id")
+}
+`
+
+// withoutDescriptions decodes the document, leaving out the comment header, the
+// descriptions and the x-untranslated extension, which are all that may differ
+// between the languages.
+func withoutDescriptions(t *testing.T, content string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(content), &value); err != nil {
+		t.Fatal(err)
+	}
+	var strip func(value interface{}) interface{}
+	strip = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			stripped := make(map[string]interface{})
+			for key, child := range v {
+				if key != "description" && key != untranslatedExtension {
+					stripped[key] = strip(child)
+				}
+			}
+			return stripped
+		case []interface{}:
+			stripped := make([]interface{}, len(v))
+			for i, child := range v {
+				stripped[i] = strip(child)
+			}
+			return stripped
+		}
+		return value
+	}
+	return strip(value)
+}
+
+func TestLocalizedDocuments(t *testing.T) {
+	idl := writeMain(t, localizedIDL)
+	g, generated := generateFiles(t, idl, &args.Arguments{Langs: []string{"en", "zh"}, MarkUntranslated: true})
+	content := generatedFile(t, generated, "openapi.yaml")
+	en := generatedFile(t, generated, "openapi.en.yaml")
+	zh := generatedFile(t, generated, "openapi.zh.yaml")
+
+	tests := []struct {
+		path   []interface{}
+		en, zh string
+	}{
+		{[]interface{}{"info", "description"}, "Manages the users.", "管理用户"},
+		{[]interface{}{"paths", "/users/{id}", "get", "responses", "200", "description"}, "A user.", "用户"},
+		{[]interface{}{"components", "schemas", "UserBody", "properties", "name", "description"}, "The name.", "名字"},
+		{[]interface{}{"paths", "/users/{id}", "get", "parameters", 0, "description"}, "The id.", "The id."},
+	}
+	for _, tt := range tests {
+		if got := lookup(t, en, tt.path...); got != tt.en {
+			t.Errorf("en %v: got %v, want %s", tt.path, got, tt.en)
+		}
+		if got := lookup(t, zh, tt.path...); got != tt.zh {
+			t.Errorf("zh %v: got %v, want %s", tt.path, got, tt.zh)
+		}
+	}
+
+	// only the descriptions differ between the languages
+	if !reflect.DeepEqual(withoutDescriptions(t, en), withoutDescriptions(t, zh)) {
+		t.Error("got documents of different structures")
+	}
+	if !reflect.DeepEqual(withoutDescriptions(t, content), withoutDescriptions(t, en)) {
+		t.Error("got openapi.yaml of a different structure")
+	}
+
+	if got := lookup(t, zh, "info", untranslatedExtension); !reflect.DeepEqual(got, []interface{}{"The id."}) {
+		t.Errorf("got zh %s %v, want the id", untranslatedExtension, got)
+	}
+	if got := lookup(t, en, "info", untranslatedExtension); got != nil {
+		t.Errorf("got en %s %v", untranslatedExtension, got)
+	}
+
+	var parts []string
+	for _, part := range g.SpecParts() {
+		parts = append(parts, part.File+" "+part.Name)
+	}
+	if want := []string{"openapi.en.yaml en", "openapi.zh.yaml zh"}; !reflect.DeepEqual(parts, want) {
+		t.Errorf("got parts %v, want %v", parts, want)
+	}
+
+	if err := buildError(t, idl, &args.Arguments{MarkUntranslated: true}); err == nil || !strings.Contains(err.Error(), "requires Langs") {
+		t.Errorf("got error %v, want MarkUntranslated to require Langs", err)
+	}
+}
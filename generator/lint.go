@@ -0,0 +1,87 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/thrift_reflection"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/annotations"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+)
+
+// Lint rules, a function ignores a rule by listing it in openapi.lint_ignore.
+const (
+	lintVerbMismatch = "verb-mismatch"
+)
+
+// lintMaxDeleteBodyFields is the number of body fields above which a DELETE
+// operation is reported as looking like an update.
+const lintMaxDeleteBodyFields = 3
+
+// lintIgnored reports whether the function ignores the lint rule.
+func lintIgnored(f *parser.Function, rule string) bool {
+	for _, value := range utils.GetAnnotation(f.Annotations, annotations.OpenapiLintIgnore) {
+		for _, ignored := range strings.Split(value, ",") {
+			if strings.TrimSpace(ignored) == rule {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lintVerb reports the routes whose verb does not match what the function looks
+// like it does, guessed from its argument and its name.
+func (g *OpenAPIGenerator) lintVerb(s *parser.Service, f *parser.Function, method string, inputDesc *thrift_reflection.StructDescriptor) {
+	if !g.arguments.Lint || lintIgnored(f, lintVerbMismatch) {
+		return
+	}
+	name := s.GetName() + "." + f.GetName()
+	report := func(problem, suggestion string) {
+		g.warn("%s: %s (%s) %s, %s, or set %s = \"%s\"",
+			lintVerbMismatch, name, method, problem, suggestion, annotations.OpenapiLintIgnore, lintVerbMismatch)
+	}
+
+	bodyFields := 0
+	if inputDesc != nil {
+		for _, field := range inputDesc.GetFields() {
+			for _, binding := range annotations.Bindings(field) {
+				if binding.In == annotations.InBody || binding.In == annotations.InForm || binding.In == annotations.InRawBody {
+					bodyFields++
+					break
+				}
+			}
+		}
+	}
+
+	switch method {
+	case "GET", "HEAD":
+		if bodyFields > 0 {
+			report("takes a body, which is not documented for this verb", "use api.post or bind the fields with api.query")
+		}
+	case "DELETE":
+		if bodyFields > lintMaxDeleteBodyFields {
+			report("takes a body of many fields, which looks like an update", "use api.put or api.patch")
+		}
+	case "POST":
+		if strings.HasPrefix(f.GetName(), "Get") || strings.HasPrefix(f.GetName(), "List") {
+			report("is named like a read", "use api.get")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+const lintIDL = `
+struct Query {
+    1: string name (api.query = "name")
+}
+
+struct Body {
+    1: string name (api.body = "name")
+}
+
+struct Form {
+    1: string name (api.form = "name")
+}
+
+struct Update {
+    1: string id (api.path = "id")
+    2: string a (api.body = "a")
+    3: string b (api.body = "b")
+    4: string c (api.body = "c")
+    5: string d (api.body = "d")
+}
+
+service UserService {
+    Query Search(1: Body req) (api.get = "/search")
+    Query Ping(1: Form req) (api.head = "/ping")
+    Query Remove(1: Update req) (api.delete = "/users/:id")
+    Query GetUser(1: Body req) (api.post = "/users/get")
+    Query ListUsers(1: Body req) (api.post = "/users/list")
+    Query Find(1: Query req) (api.get = "/find")
+    Query Drop(1: Body req) (api.delete = "/drop")
+    Query Create(1: Body req) (api.post = "/users")
+    Query GetIgnored(1: Body req) (api.post = "/ignored", openapi.lint_ignore = "other, verb-mismatch")
+}
+`
+
+// lintFindings returns the functions reported by the verb lint.
+func lintFindings(warnings []string) []string {
+	var findings []string
+	for _, warning := range warnings {
+		if strings.HasPrefix(warning, lintVerbMismatch+": ") {
+			findings = append(findings, strings.Fields(warning)[1])
+		}
+	}
+	sort.Strings(findings)
+	return findings
+}
+
+func TestLintVerb(t *testing.T) {
+	idl := writeMain(t, lintIDL)
+	g, _ := buildDocument(t, idl, &args.Arguments{Lint: true})
+	want := []string{"UserService.GetUser", "UserService.ListUsers", "UserService.Ping", "UserService.Remove", "UserService.Search"}
+	if got := lintFindings(g.Warnings()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got findings %v, want %v", got, want)
+	}
+	for _, warning := range g.Warnings() {
+		if strings.HasPrefix(warning, "verb-mismatch: UserService.Search ") &&
+			warning != `verb-mismatch: UserService.Search (GET) takes a body, which is not documented for this verb, use api.post or bind the fields with api.query, or set openapi.lint_ignore = "verb-mismatch"` {
+			t.Errorf("got finding %q", warning)
+		}
+	}
+
+	g, _ = buildDocument(t, idl, &args.Arguments{})
+	if got := lintFindings(g.Warnings()); len(got) != 0 {
+		t.Errorf("got findings %v without Lint", got)
+	}
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MinifiedName is the name of the minified document generated with Minify.
+const MinifiedName = "openapi.min.json"
+
+// minifyStrippedKeys are the keys holding documentation only.
+var minifyStrippedKeys = map[string]bool{
+	"description":  true,
+	"summary":      true,
+	"title":        true,
+	"example":      true,
+	"examples":     true,
+	"externalDocs": true,
+}
+
+// minifyNameMaps are the keys whose value maps names, e.g. the names of the
+// properties, to objects. The names are kept even when they look like stripped keys.
+var minifyNameMaps = map[string]bool{
+	"paths":           true,
+	"properties":      true,
+	"schemas":         true,
+	"responses":       true,
+	"content":         true,
+	"headers":         true,
+	"parameters":      true,
+	"requestBodies":   true,
+	"securitySchemes": true,
+	"variables":       true,
+	"links":           true,
+	"callbacks":       true,
+	"encoding":        true,
+	"scopes":          true,
+	"mapping":         true,
+	"[]security":      true,
+}
+
+// minifyDataKeys are the keys whose value is data of the API, copied as is.
+var minifyDataKeys = map[string]bool{
+	"enum":    true,
+	"default": true,
+}
+
+// Minify returns the smallest functionally equivalent rendering of an OpenAPI
+// document, given as YAML or JSON, as compact JSON. Descriptions, examples and
+// specification extensions are left out and the component schemas referenced once
+// are inlined, the types and the required properties are kept.
+func Minify(content []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("error decoding document: %s", err)
+	}
+	minified, ok := minifyValue(doc, "").(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("error decoding document: not an object")
+	}
+	inlineSingleUseSchemas(minified)
+	bytes, err := json.Marshal(minified)
+	if err != nil {
+		return nil, fmt.Errorf("error converting to json: %s", err)
+	}
+	return bytes, nil
+}
+
+// minifyValue strips the documentation from a value found under the key parent.
+func minifyValue(value interface{}, parent string) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			converted[fmt.Sprint(key)] = child
+		}
+		return minifyValue(converted, parent)
+	case map[string]interface{}:
+		minified := make(map[string]interface{}, len(v))
+		if minifyNameMaps[parent] {
+			for name, child := range v {
+				minified[name] = minifyValue(child, "entry of "+parent)
+			}
+			return minified
+		}
+		for key, child := range v {
+			// info.title is required.
+			if minifyStrippedKeys[key] && !(key == "title" && parent == "info") || strings.HasPrefix(key, "x-") {
+				continue
+			}
+			if minifyDataKeys[key] {
+				minified[key] = plainValue(child)
+				continue
+			}
+			minified[key] = minifyValue(child, key)
+		}
+		// The description of a response is required.
+		if _, ref := minified["$ref"]; parent == "entry of responses" && !ref {
+			minified["description"] = ""
+		}
+		return minified
+	case []interface{}:
+		minified := make([]interface{}, len(v))
+		for i, child := range v {
+			minified[i] = minifyValue(child, "[]"+parent)
+		}
+		return minified
+	}
+	return value
+}
+
+// plainValue converts the maps of a decoded value to maps with string keys, so that
+// the value can be encoded to JSON.
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			converted[fmt.Sprint(key)] = plainValue(child)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			converted[key] = plainValue(child)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, child := range v {
+			converted[i] = plainValue(child)
+		}
+		return converted
+	}
+	return value
+}
+
+// inlineSingleUseSchemas replaces the references to the component schemas which are
+// referenced once with the schemas, and removes them from the components. A schema
+// referencing itself, directly or through other schemas, stays a component.
+func inlineSingleUseSchemas(doc map[string]interface{}) {
+	components, _ := doc["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+	if len(schemas) == 0 {
+		return
+	}
+
+	uses := make(map[string]int)
+	countSchemaRefs(doc, uses)
+	candidates := make(map[string]bool)
+	for name := range schemas {
+		if uses[name] == 1 {
+			candidates[name] = true
+		}
+	}
+
+	inlined := make(map[string]bool)
+	var inline func(value interface{}, path []string) interface{}
+	inline = func(value interface{}, path []string) interface{} {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, schemaRefPrefix) {
+				name := strings.TrimPrefix(ref, schemaRefPrefix)
+				if !candidates[name] {
+					return v
+				}
+				for _, visited := range path {
+					if visited == name {
+						return v
+					}
+				}
+				inlined[name] = true
+				return inline(schemas[name], append(path[:len(path):len(path)], name))
+			}
+			for key, child := range v {
+				v[key] = inline(child, path)
+			}
+		case []interface{}:
+			for i, child := range v {
+				v[i] = inline(child, path)
+			}
+		}
+		return value
+	}
+
+	for key, child := range doc {
+		if key != "components" {
+			doc[key] = inline(child, nil)
+		}
+	}
+	for key, child := range components {
+		if key != "schemas" {
+			components[key] = inline(child, nil)
+		}
+	}
+	for name, schema := range schemas {
+		if !candidates[name] {
+			schemas[name] = inline(schema, []string{name})
+		}
+	}
+	for name := range inlined {
+		delete(schemas, name)
+	}
+	if len(schemas) == 0 {
+		delete(components, "schemas")
+	}
+}
+
+func countSchemaRefs(value interface{}, uses map[string]int) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, schemaRefPrefix) {
+			uses[strings.TrimPrefix(ref, schemaRefPrefix)]++
+		}
+		for _, child := range v {
+			countSchemaRefs(child, uses)
+		}
+	case []interface{}:
+		for _, child := range v {
+			countSchemaRefs(child, uses)
+		}
+	}
+}
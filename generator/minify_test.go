@@ -0,0 +1,194 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+// minifyIDL declares a struct referenced once, which is inlined, and one referenced
+// twice, which stays a component.
+const minifyIDL = `
+// An address.
+struct Address {
+    1: string city (api.body = "city")
+}(openapi.schema = '{required: ["city"]}')
+
+enum Role {
+    ADMIN = 1
+    GUEST = 2
+}
+
+// A user.
+struct User {
+    // The name.
+    1: string name (api.body = "name", openapi.property = '{example: "Ann"}')
+    2: i32 age (api.body = "age")
+    3: list<Address> addresses (api.body = "addresses")
+    4: map<string, i64> scores (api.body = "scores")
+    5: Role role (api.body = "role")
+    6: Tag tag (api.body = "tag")
+}(openapi.schema = '{required: ["name"]}')
+
+struct Tag {
+    1: string name (api.body = "name")
+}
+
+struct Tags {
+    1: list<Tag> tags (api.body = "tags")
+}
+
+service UserService {
+    Tags CreateUser(1: User req) (api.post = "/users")
+}
+`
+
+// validate reports whether value is valid against the schema, resolving the
+// references with the schemas of the document. Only the keywords emitted by the
+// generator are checked.
+func validate(schema interface{}, value interface{}, schemas map[string]interface{}) error {
+	s, _ := schema.(map[string]interface{})
+	if ref, ok := s["$ref"].(string); ok {
+		return validate(schemas[strings.TrimPrefix(ref, schemaRefPrefix)], value, schemas)
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || fmt.Sprint(allowed) == fmt.Sprint(value)
+		}
+		if !found {
+			return fmt.Errorf("%v is not one of %v", value, enum)
+		}
+	}
+	switch s["type"] {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an array", value)
+		}
+		for _, item := range items {
+			if err := validate(s["items"], item, schemas); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an object", value)
+		}
+		required, _ := s["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s is required", name)
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		for name, property := range object {
+			propertySchema, ok := properties[name]
+			if !ok {
+				propertySchema = s["additionalProperties"]
+			}
+			if propertySchema == nil {
+				continue
+			}
+			if err := validate(propertySchema, property, schemas); err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// requestSchema returns the schema of the JSON request body of POST /users and the
+// component schemas of the document.
+func requestSchema(t *testing.T, content string) (interface{}, map[string]interface{}) {
+	t.Helper()
+	schemas, _ := lookup(t, content, "components", "schemas").(map[string]interface{})
+	return lookup(t, content, "paths", "/users", "post", "requestBody", "content", "application/json", "schema"), schemas
+}
+
+func TestMinify(t *testing.T) {
+	idl := writeMain(t, minifyIDL)
+	_, generated := generateFiles(t, idl, &args.Arguments{Minify: true})
+	full := generatedFile(t, generated, "openapi.yaml")
+	minified := generatedFile(t, generated, MinifiedName)
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(minified), &value); err != nil {
+		t.Fatalf("minified document is not JSON: %s", err)
+	}
+	if strings.Contains(minified, "\n") || strings.Contains(minified, "A user") ||
+		strings.Contains(minified, `"example"`) || strings.Contains(minified, `"x-`) {
+		t.Errorf("got minified document %s", minified)
+	}
+	if len(minified) >= len(full) {
+		t.Errorf("got minified document of %d bytes, the full one has %d", len(minified), len(full))
+	}
+	// Address is referenced once by the components, Tag twice
+	schemas, _ := lookup(t, minified, "components", "schemas").(map[string]interface{})
+	if _, ok := schemas["Address"]; ok {
+		t.Error("got Address component, want it inlined")
+	}
+	if _, ok := schemas["Tag"]; !ok {
+		t.Error("got no Tag component")
+	}
+
+	payloads := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"name": "Ann"}`, true},
+		{`{"name": "Ann", "age": 3, "addresses": [{"city": "Paris"}], "scores": {"math": 3}, "role": 1, "tag": {"name": "a"}}`, true},
+		{`{"age": 3}`, false},
+		{`{"name": 3}`, false},
+		{`{"name": "Ann", "age": "3"}`, false},
+		{`{"name": "Ann", "addresses": [{}]}`, false},
+		{`{"name": "Ann", "scores": {"math": "a"}}`, false},
+		{`{"name": "Ann", "role": 3}`, false},
+		{`{"name": "Ann", "tag": {"name": 1}}`, false},
+	}
+	fullSchema, fullSchemas := requestSchema(t, full)
+	minifiedSchema, minifiedSchemas := requestSchema(t, minified)
+	for _, payload := range payloads {
+		var body interface{}
+		if err := json.Unmarshal([]byte(payload.body), &body); err != nil {
+			t.Fatal(err)
+		}
+		fullErr := validate(fullSchema, body, fullSchemas)
+		minifiedErr := validate(minifiedSchema, body, minifiedSchemas)
+		if (fullErr == nil) != payload.valid || (minifiedErr == nil) != payload.valid {
+			t.Errorf("%s: got full %v and minified %v, want valid %v", payload.body, fullErr, minifiedErr, payload.valid)
+		}
+	}
+
+	if _, err := Minify([]byte("- a list")); err == nil {
+		t.Error("minified a document which is not an object")
+	}
+}
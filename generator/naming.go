@@ -0,0 +1,146 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy builds the names of the document from the names of the IDL. The
+// property and parameter names are the ones the service reads and writes, the
+// built-in strategies keep them as they are.
+type NamingStrategy interface {
+	// OperationID returns the operationId of the function of a service, before
+	// OperationIDPrefix is added.
+	OperationID(service, function string) string
+	// SchemaName returns the component name of a struct from its name, which may be
+	// prefixed with the name of its file. The names of the body schemas, e.g.
+	// ReqBody, are built from the name it returned, so it should be idempotent.
+	SchemaName(name string) string
+	// PropertyName returns the name of a schema property from the name of its field.
+	PropertyName(name string) string
+	// ParameterName returns the name of a parameter or a header from the name of its
+	// binding.
+	ParameterName(name string) string
+}
+
+// Built-in naming strategies, selected with the NamingStrategy argument.
+const (
+	// NamingDefault names operations Service_Function and keeps the IDL names.
+	NamingDefault = "default"
+	// NamingLowerCamel names operations serviceFunction.
+	NamingLowerCamel = "lowerCamel"
+	// NamingStrictGateway names operations like lowerCamel and removes from schema
+	// names everything but letters, digits and dots, as required by some gateways.
+	NamingStrictGateway = "strict-gateway"
+)
+
+// NewNamingStrategy returns the built-in strategy of the given name.
+func NewNamingStrategy(name string) (NamingStrategy, error) {
+	switch name {
+	case "", NamingDefault:
+		return defaultNaming{}, nil
+	case NamingLowerCamel:
+		return lowerCamelNaming{}, nil
+	case NamingStrictGateway:
+		return strictGatewayNaming{}, nil
+	}
+	return nil, fmt.Errorf("unsupported NamingStrategy '%s', use '%s', '%s' or '%s'",
+		name, NamingDefault, NamingLowerCamel, NamingStrictGateway)
+}
+
+type defaultNaming struct{}
+
+func (defaultNaming) OperationID(service, function string) string {
+	return service + "_" + function
+}
+
+func (defaultNaming) SchemaName(name string) string {
+	return name
+}
+
+func (defaultNaming) PropertyName(name string) string {
+	return name
+}
+
+func (defaultNaming) ParameterName(name string) string {
+	return name
+}
+
+type lowerCamelNaming struct {
+	defaultNaming
+}
+
+func (lowerCamelNaming) OperationID(service, function string) string {
+	return lowerCamel(service + "_" + function)
+}
+
+type strictGatewayNaming struct {
+	defaultNaming
+}
+
+// OperationID returns an operationId matching ^[a-z][a-zA-Z0-9]*$.
+func (strictGatewayNaming) OperationID(service, function string) string {
+	id := lowerCamel(service + "_" + function)
+	if id == "" || !unicode.IsLower(rune(id[0])) {
+		id = "op" + upperFirst(id)
+	}
+	return id
+}
+
+func (strictGatewayNaming) SchemaName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, ".") {
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		for _, word := range words(part) {
+			b.WriteString(upperFirst(word))
+		}
+	}
+	return b.String()
+}
+
+// lowerCamel joins the words of name, the first one in lower case and the others
+// capitalized.
+func lowerCamel(name string) string {
+	var b strings.Builder
+	for i, word := range words(name) {
+		if i == 0 {
+			b.WriteString(strings.ToLower(word[:1]) + word[1:])
+		} else {
+			b.WriteString(upperFirst(word))
+		}
+	}
+	return b.String()
+}
+
+// words splits name on everything but ASCII letters and digits.
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	tests := []struct {
+		strategy    string
+		operationID string
+		schemaName  string
+	}{
+		{NamingDefault, "user_service_Get_user", "base.User_Info"},
+		{NamingLowerCamel, "userServiceGetUser", "base.User_Info"},
+		{NamingStrictGateway, "userServiceGetUser", "Base.UserInfo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			naming, err := NewNamingStrategy(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := naming.OperationID("user_service", "Get_user"); got != tt.operationID {
+				t.Errorf("got operationId %q, want %q", got, tt.operationID)
+			}
+			if got := naming.SchemaName("base.User_Info"); got != tt.schemaName {
+				t.Errorf("got schema name %q, want %q", got, tt.schemaName)
+			}
+			if got := naming.PropertyName("user_name"); got != "user_name" {
+				t.Errorf("got property name %q, want it unchanged", got)
+			}
+			if got := naming.ParameterName("X-Token"); got != "X-Token" {
+				t.Errorf("got parameter name %q, want it unchanged", got)
+			}
+		})
+	}
+
+	strict, _ := NewNamingStrategy(NamingStrictGateway)
+	if got := strict.OperationID("_9", "get"); got != "op9Get" {
+		t.Errorf("got operationId %q, want one starting with a lower case letter", got)
+	}
+	if _, err := NewNamingStrategy("snake"); err == nil || !strings.Contains(err.Error(), "unsupported NamingStrategy 'snake'") {
+		t.Errorf("got error %v, want an unsupported NamingStrategy error", err)
+	}
+}
+
+func TestDefaultNaming(t *testing.T) {
+	idl := filepath.Join("..", "example", "hello.thrift")
+	want := generateYAML(t, idl, &args.Arguments{NoTimestamp: true})
+	if got := generateYAML(t, idl, &args.Arguments{NoTimestamp: true, NamingStrategy: NamingDefault}); got != want {
+		t.Error("the default strategy changes the document")
+	}
+}
+
+// upperNaming upper-cases every name, so that a name not built by the strategy stands out.
+type upperNaming struct{}
+
+func (upperNaming) OperationID(service, function string) string {
+	return strings.ToUpper(service + "_" + function)
+}
+
+func (upperNaming) SchemaName(name string) string    { return strings.ToUpper(name) }
+func (upperNaming) PropertyName(name string) string  { return strings.ToUpper(name) }
+func (upperNaming) ParameterName(name string) string { return strings.ToUpper(name) }
+
+func TestSetNamingStrategy(t *testing.T) {
+	idl := writeMain(t, `
+struct Item {
+    1: string name
+}
+
+struct Req {
+    1: string id (api.path = "id")
+    2: string token (api.header = "X-Token")
+    3: Item item (api.body = "item")
+}
+
+service ItemService {
+    Req Put(1: Req req) (api.put = "/items/:id")
+}
+`)
+	g := NewOpenAPIGenerator(parseIDL(t, idl))
+	g.SetNamingStrategy(upperNaming{})
+	if _, err := g.BuildDocument(&args.Arguments{}); err != nil {
+		t.Fatal(err)
+	}
+	d := g.Document()
+
+	op := d.Paths.Path[0].Value.Put
+	if op.OperationID != "ITEMSERVICE_PUT" {
+		t.Errorf("got operationId %s", op.OperationID)
+	}
+	var parameters []string
+	for _, p := range op.Parameters {
+		parameters = append(parameters, p.Parameter.Name)
+	}
+	if want := []string{"ID", "X-TOKEN"}; !reflect.DeepEqual(parameters, want) {
+		t.Errorf("got parameters %v, want %v", parameters, want)
+	}
+	if got := schemaNames(d); !reflect.DeepEqual(got, []string{"ITEM", "REQBODY"}) {
+		t.Errorf("got schemas %v", got)
+	}
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		if schema.Name != "ITEM" {
+			continue
+		}
+		if name := schema.Value.Schema.Properties.AdditionalProperties[0].Name; name != "NAME" {
+			t.Errorf("got property %s, want NAME", name)
+		}
+	}
+}
@@ -34,55 +34,245 @@
 package generator
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/cloudwego/hertz/cmd/hz/util/logs"
 	"github.com/cloudwego/thriftgo/parser"
 	"github.com/cloudwego/thriftgo/plugin"
 	"github.com/cloudwego/thriftgo/thrift_reflection"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/annotations"
 	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
 	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
 	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	infoURL = "https://github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger"
+
+	defaultGatewayExtensionKey = "x-amazon-apigateway-integration"
+
+	// maxCommentLength caps the comments read as descriptions, longer ones are truncated.
+	maxCommentLength = 64 << 10
+)
+
+// Placements of the path of api.baseurl and api.base_domain, selected with the
+// BaseURLPath argument.
+const (
+	// BaseURLPathServer keeps the path in the URL of the server of the operation.
+	BaseURLPathServer = "server"
+	// BaseURLPathOperation prefixes the path of the operation with it.
+	BaseURLPathOperation = "operation"
+)
+
+// Orders of the tags, the paths and the schemas of the document, selected with the
+// Order argument.
+const (
+	// OrderAlphabetical sorts them by name.
+	OrderAlphabetical = "alphabetical"
+	// OrderDeclaration keeps the order of the services, the functions and the
+	// structs in the IDL.
+	OrderDeclaration = "declaration"
 )
 
+// Formats of the document, selected with the Format argument. openapi.yaml is always
+// generated, the generated server serves it.
+const (
+	// FormatYAML generates openapi.yaml only.
+	FormatYAML = "yaml"
+	// FormatJSON also generates the document as openapi.json.
+	FormatJSON = "json"
+	// FormatPostman also generates the document as a Postman collection.
+	FormatPostman = "postman"
+)
+
+// JSONName is the name of the document generated with the json Format.
+const JSONName = "openapi.json"
+
+// Versions of the specification the document declares, selected with the
+// OpenapiVersion argument.
+const (
+	// OpenapiVersion30 is the default version.
+	OpenapiVersion30 = "3.0.3"
+	// OpenapiVersion31 adds the examples of the schemas.
+	OpenapiVersion31 = "3.1.0"
+)
+
+// StdoutName is the name of the document generated with Stdout. The standard output
+// of the plugin carries its response, so thriftgo writes the document to its own.
+const StdoutName = "/dev/stdout"
+
 type OpenAPIGenerator struct {
-	fileDesc          *thrift_reflection.FileDescriptor
-	ast               *parser.Thrift
-	generatedSchemas  []string
-	requiredSchemas   []string
-	commentPattern    *regexp.Regexp
-	linterRulePattern *regexp.Regexp
+	fileDesc           *thrift_reflection.FileDescriptor
+	ast                *parser.Thrift
+	arguments          *args.Arguments
+	generatedSchemas   []string
+	requiredSchemas    []string
+	requiredStructs    map[string]*thrift_reflection.StructDescriptor
+	schemaNames        map[string]string
+	schemaOwners       map[string]*thrift_reflection.StructDescriptor
+	operationIDs       *operationIDRegistry
+	versionPrefix      string
+	sunsetDate         time.Time
+	reportedEnums      map[*thrift_reflection.FieldDescriptor]bool
+	reportedExamples   map[*thrift_reflection.StructDescriptor]bool
+	naming             NamingStrategy
+	routes             *RouteModel
+	strictErrors       []string
+	warnings           []string
+	generationErrors   []string
+	specParts          []SpecPart
+	document           *openapi.Document
+	serverVariables    map[string]*openapi.ServerVariable
+	commentPattern     *regexp.Regexp
+	linterRulePattern  *regexp.Regexp
+	variablePattern    *regexp.Regexp
+	placeholderPattern *regexp.Regexp
+	pathParamPattern   *regexp.Regexp
+	// lang is the language of the descriptions with Langs, localized tells the
+	// documents of the other languages apart from the main document.
+	lang         string
+	localized    bool
+	untranslated []string
 }
 
 // NewOpenAPIGenerator creates a new generator for a protoc plugin invocation.
 func NewOpenAPIGenerator(ast *parser.Thrift) *OpenAPIGenerator {
 	_, fileDesc := thrift_reflection.RegisterAST(ast)
+	return newOpenAPIGenerator(ast, fileDesc)
+}
+
+func newOpenAPIGenerator(ast *parser.Thrift, fileDesc *thrift_reflection.FileDescriptor) *OpenAPIGenerator {
 	return &OpenAPIGenerator{
-		fileDesc:          fileDesc,
-		ast:               ast,
-		generatedSchemas:  make([]string, 0),
-		commentPattern:    regexp.MustCompile(`//\s*(.*)|/\*([\s\S]*?)\*/`),
-		linterRulePattern: regexp.MustCompile(`\(-- .* --\)`),
+		fileDesc:           fileDesc,
+		ast:                ast,
+		generatedSchemas:   make([]string, 0),
+		requiredStructs:    make(map[string]*thrift_reflection.StructDescriptor),
+		schemaNames:        make(map[string]string),
+		schemaOwners:       make(map[string]*thrift_reflection.StructDescriptor),
+		operationIDs:       newOperationIDRegistry(),
+		reportedEnums:      make(map[*thrift_reflection.FieldDescriptor]bool),
+		reportedExamples:   make(map[*thrift_reflection.StructDescriptor]bool),
+		serverVariables:    make(map[string]*openapi.ServerVariable),
+		commentPattern:     regexp.MustCompile(`//(.*)|/\*([\s\S]*?)\*/`),
+		linterRulePattern:  regexp.MustCompile(`\(-- .* --\)`),
+		variablePattern:    regexp.MustCompile(`\{(\w+)\}`),
+		placeholderPattern: regexp.MustCompile(`\$\{(\w*)\}`),
+		pathParamPattern:   regexp.MustCompile(`[:*](\w+)`),
 	}
 }
 
-func (g *OpenAPIGenerator) BuildDocument(arguments *args.Arguments) []*plugin.Generated {
+// SetRouteModel makes the generator document the routes of the model instead of
+// building one from the IDL.
+func (g *OpenAPIGenerator) SetRouteModel(routes *RouteModel) {
+	g.routes = routes
+}
+
+// SetNamingStrategy makes the generator build names with naming instead of the
+// strategy selected by the NamingStrategy argument.
+func (g *OpenAPIGenerator) SetNamingStrategy(naming NamingStrategy) {
+	g.naming = naming
+}
+
+func (g *OpenAPIGenerator) BuildDocument(arguments *args.Arguments) ([]*plugin.Generated, error) {
+	g.arguments = arguments
+	if g.naming == nil {
+		naming, err := NewNamingStrategy(arguments.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+		g.naming = naming
+	}
+	if g.routes == nil {
+		g.routes = NewRouteModel(g.ast, arguments)
+	}
+	if key := arguments.GatewayExtensionKey; key != "" && !strings.HasPrefix(key, "x-") {
+		return nil, fmt.Errorf("GatewayExtensionKey '%s' must start with 'x-'", key)
+	}
+	switch arguments.BaseURLPath {
+	case "", BaseURLPathServer, BaseURLPathOperation:
+	default:
+		return nil, fmt.Errorf("unsupported BaseURLPath '%s', use '%s' or '%s'", arguments.BaseURLPath, BaseURLPathServer, BaseURLPathOperation)
+	}
+	switch arguments.Order {
+	case "", OrderAlphabetical, OrderDeclaration:
+	default:
+		return nil, fmt.Errorf("unsupported Order '%s', use '%s' or '%s'", arguments.Order, OrderAlphabetical, OrderDeclaration)
+	}
+	switch arguments.Format {
+	case "", FormatYAML, FormatJSON, FormatPostman:
+	default:
+		return nil, fmt.Errorf("unsupported Format '%s', use '%s', '%s' or '%s'", arguments.Format, FormatYAML, FormatJSON, FormatPostman)
+	}
+	switch arguments.OpenapiVersion {
+	case "", OpenapiVersion30, OpenapiVersion31:
+	default:
+		return nil, fmt.Errorf("unsupported OpenapiVersion '%s', use '%s' or '%s'", arguments.OpenapiVersion, OpenapiVersion30, OpenapiVersion31)
+	}
+	switch arguments.Audience {
+	case "", annotations.AudiencePublic, annotations.AudienceInternal, annotations.AudienceAll:
+	default:
+		return nil, fmt.Errorf("unsupported Audience '%s', use '%s', '%s' or '%s'", arguments.Audience,
+			annotations.AudiencePublic, annotations.AudienceInternal, annotations.AudienceAll)
+	}
+	// The sunsets are compared with the day of the generation, SunsetDate makes the
+	// document reproducible.
+	g.sunsetDate = time.Now().UTC().Truncate(24 * time.Hour)
+	if arguments.SunsetDate != "" {
+		date, err := time.Parse("2006-01-02", arguments.SunsetDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SunsetDate '%s', use a date such as 2025-12-31", arguments.SunsetDate)
+		}
+		g.sunsetDate = date
+	}
+	if arguments.ApiVersion != "" && !arguments.VersionInPath {
+		return nil, errors.New("ApiVersion requires VersionInPath")
+	}
+	if arguments.MaxDescriptionLength < 0 {
+		return nil, fmt.Errorf("MaxDescriptionLength must be positive, got %d", arguments.MaxDescriptionLength)
+	}
+	if arguments.MaxOperationsPerDoc < 0 {
+		return nil, fmt.Errorf("MaxOperationsPerDoc must be positive, got %d", arguments.MaxOperationsPerDoc)
+	}
+	if len(arguments.Langs) > 0 {
+		if arguments.MaxOperationsPerDoc > 0 {
+			return nil, errors.New("Langs can not be used with MaxOperationsPerDoc")
+		}
+		for i, lang := range arguments.Langs {
+			if !langTagPattern.MatchString("["+lang+"]") || utils.Contains(arguments.Langs[:i], lang) {
+				return nil, fmt.Errorf("invalid or duplicate language '%s' in Langs", lang)
+			}
+		}
+		if g.lang == "" {
+			g.lang = arguments.Langs[0]
+		}
+	} else if arguments.MarkUntranslated {
+		return nil, errors.New("MarkUntranslated requires Langs")
+	}
+
 	d := &openapi.Document{}
 
-	version := "3.0.3"
+	version := OpenapiVersion30
+	if arguments.OpenapiVersion != "" {
+		version = arguments.OpenapiVersion
+	}
 	d.Openapi = version
 	d.Info = &openapi.Info{
-		Title:       "API generated by thrift-gen-rpc-swagger",
-		Description: "API description",
-		Version:     "1.0.0",
+		Title:   "API generated by thrift-gen-rpc-swagger",
+		Version: "1.0.0",
 	}
 	d.Paths = &openapi.Paths{}
 	d.Components = &openapi.Components{
@@ -94,25 +284,56 @@ func (g *OpenAPIGenerator) BuildDocument(arguments *args.Arguments) []*plugin.Ge
 	var extDocument *openapi.Document
 	err := g.getDocumentOption(&extDocument)
 	if err != nil {
-		logs.Errorf("Error getting document option: %s", err)
-		return nil
+		return nil, fmt.Errorf("error getting document option: %s", err)
 	}
 	if extDocument != nil {
 		err := utils.MergeStructs(d, extDocument)
 		if err != nil {
-			logs.Errorf("Error merging document option: %s", err)
-			return nil
+			return nil, fmt.Errorf("error merging document option: %s", err)
+		}
+	}
+	g.addInfoToDocument(d)
+	g.addSecuritySchemesToDocument(d)
+
+	if len(arguments.Profiles) > 0 {
+		profiles, err := newNamedAny("x-profiles", arguments.Profiles)
+		if err != nil {
+			return nil, err
+		}
+		d.Info.SpecificationExtension = append(d.Info.SpecificationExtension, profiles)
+	}
+
+	if arguments.VersionInPath {
+		version := d.Info.Version
+		if arguments.ApiVersion != "" {
+			version = arguments.ApiVersion
+		}
+		major, err := majorVersion(version)
+		if err != nil {
+			return nil, err
 		}
+		g.versionPrefix = "/v" + strconv.Itoa(major)
 	}
 
-	g.addPathsToDocument(d, g.ast.Services)
+	g.checkAnnotations()
+	err = g.addPathsToDocument(d, g.ast.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	g.checkOperationIDs(d)
+	g.checkSecurityRequirements(d)
+	if countOperations(d) == 0 && len(g.ast.Services) > 0 && !g.routes.HasRoutes() && !arguments.AutoOperations && !arguments.DefaultMapping {
+		return nil, g.noOperationsError()
+	}
 
 	for len(g.requiredSchemas) > 0 {
 		count := len(g.requiredSchemas)
-		g.addSchemasForStructsToDocument(d, g.ast.GetStructLikes())
+		for _, schemaName := range g.requiredSchemas[:count] {
+			g.addSchemaForStructToDocument(d, schemaName, g.requiredStructs[schemaName])
+		}
 		g.requiredSchemas = g.requiredSchemas[count:len(g.requiredSchemas)]
 	}
-
 	// If there is only 1 service, then use it's title for the
 	// document, if the document is missing it.
 	if len(d.Tags) == 1 {
@@ -124,333 +345,1531 @@ func (g *OpenAPIGenerator) BuildDocument(arguments *args.Arguments) []*plugin.Ge
 		}
 		d.Tags[0].Description = ""
 	}
+	// The placeholder is only set once neither the annotations nor the service
+	// describe the API.
+	if d.Info.Description == "" {
+		d.Info.Description = "API description"
+	}
 
-	var allServers []string
+	var allServers []*openapi.Server
 
 	// If paths methods has servers, but they're all the same, then move servers to path level
 	for _, path := range d.Paths.Path {
-		var servers []string
-		// Only 1 server will ever be set, per method, by the generator
-		if path.Value.Get != nil && len(path.Value.Get.Servers) == 1 {
-			servers = utils.AppendUnique(servers, path.Value.Get.Servers[0].URL)
-			allServers = utils.AppendUnique(allServers, path.Value.Get.Servers[0].URL)
-		}
-		if path.Value.Post != nil && len(path.Value.Post.Servers) == 1 {
-			servers = utils.AppendUnique(servers, path.Value.Post.Servers[0].URL)
-			allServers = utils.AppendUnique(allServers, path.Value.Post.Servers[0].URL)
-		}
-		if path.Value.Put != nil && len(path.Value.Put.Servers) == 1 {
-			servers = utils.AppendUnique(servers, path.Value.Put.Servers[0].URL)
-			allServers = utils.AppendUnique(allServers, path.Value.Put.Servers[0].URL)
-		}
-		if path.Value.Delete != nil && len(path.Value.Delete.Servers) == 1 {
-			servers = utils.AppendUnique(servers, path.Value.Delete.Servers[0].URL)
-			allServers = utils.AppendUnique(allServers, path.Value.Delete.Servers[0].URL)
-		}
-		if path.Value.Patch != nil && len(path.Value.Patch.Servers) == 1 {
-			servers = utils.AppendUnique(servers, path.Value.Patch.Servers[0].URL)
-			allServers = utils.AppendUnique(allServers, path.Value.Patch.Servers[0].URL)
+		var servers []*openapi.Server
+		ops := operationsOf(path.Value)
+		for _, op := range ops {
+			// Only 1 server is set per method from api.baseurl, the methods declaring
+			// several servers with openapi.server keep them.
+			if len(op.Servers) == 1 {
+				servers = appendUniqueServer(servers, op.Servers[0])
+				allServers = appendUniqueServer(allServers, op.Servers[0])
+			}
 		}
 
 		if len(servers) == 1 {
-			path.Value.Servers = []*openapi.Server{{URL: servers[0]}}
-
-			if path.Value.Get != nil {
-				path.Value.Get.Servers = nil
-			}
-			if path.Value.Post != nil {
-				path.Value.Post.Servers = nil
-			}
-			if path.Value.Put != nil {
-				path.Value.Put.Servers = nil
-			}
-			if path.Value.Delete != nil {
-				path.Value.Delete.Servers = nil
-			}
-			if path.Value.Patch != nil {
-				path.Value.Patch.Servers = nil
+			path.Value.Servers = servers
+			for _, op := range ops {
+				op.Servers = nil
 			}
 		}
 	}
 
-	// Set all servers on API level
-	if len(allServers) > 0 {
-		d.Servers = []*openapi.Server{}
-		for _, server := range allServers {
-			d.Servers = append(d.Servers, &openapi.Server{URL: server})
-		}
+	// Set all servers on API level, after the ones of openapi.document, sorted so that
+	// the result does not depend on the order in which the paths were declared.
+	sort.SliceStable(allServers, func(i, j int) bool {
+		return allServers[i].URL < allServers[j].URL
+	})
+	for _, server := range allServers {
+		d.Servers = appendUniqueServer(d.Servers, server)
 	}
 
 	// If there is only 1 server, we can safely remove all path level servers
-	if len(allServers) == 1 {
+	if len(allServers) == 1 && len(d.Servers) == 1 {
 		for _, path := range d.Paths.Path {
 			path.Value.Servers = nil
 		}
 	}
 
-	{
-		pairs := d.Tags
-		sort.Slice(pairs, func(i, j int) bool {
-			return pairs[i].Name < pairs[j].Name
-		})
-		d.Tags = pairs
+	g.addServerVariablesToDocument(d)
+
+	if len(arguments.CodeSamples) > 0 {
+		err = g.addCodeSamplesToDocument(d)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	{
-		pairs := d.Paths.Path
-		sort.Slice(pairs, func(i, j int) bool {
-			return pairs[i].Name < pairs[j].Name
-		})
-		d.Paths.Path = pairs
+	if arguments.ConfirmMutations {
+		addConfirmParameters(d)
 	}
 
-	{
-		pairs := d.Components.Schemas.AdditionalProperties
-		sort.Slice(pairs, func(i, j int) bool {
-			return pairs[i].Name < pairs[j].Name
-		})
-		d.Components.Schemas.AdditionalProperties = pairs
+	g.addPathParametersToDocument(d)
+
+	if !arguments.KeepUnused {
+		g.pruneUnusedSchemas(d)
+	}
+
+	// The tags and the paths are added in declaration order, the schemas in the
+	// order they are referenced.
+	if arguments.Order == OrderDeclaration {
+		g.sortSchemasByDeclaration(d)
+	} else {
+		{
+			pairs := d.Tags
+			sort.Slice(pairs, func(i, j int) bool {
+				return pairs[i].Name < pairs[j].Name
+			})
+			d.Tags = pairs
+		}
+
+		{
+			pairs := d.Paths.Path
+			sort.Slice(pairs, func(i, j int) bool {
+				return pairs[i].Name < pairs[j].Name
+			})
+			d.Paths.Path = pairs
+		}
+
+		{
+			pairs := d.Components.Schemas.AdditionalProperties
+			sort.Slice(pairs, func(i, j int) bool {
+				return pairs[i].Name < pairs[j].Name
+			})
+			d.Components.Schemas.AdditionalProperties = pairs
+		}
+	}
+
+	if arguments.MarkUntranslated && len(g.untranslated) > 0 {
+		extension, err := newNamedAny(untranslatedExtension, g.untranslatedDescriptions())
+		if err != nil {
+			return nil, err
+		}
+		d.Info.SpecificationExtension = append(d.Info.SpecificationExtension, extension)
 	}
 
-	bytes, err := d.YAMLValue("Generated with thrift-gen-rpc-swagger\n" + infoURL)
+	// Every check has run, the warnings of strict mode fail the generation.
+	if len(g.strictErrors) > 0 {
+		return nil, errors.New(strings.Join(g.strictErrors, "; "))
+	}
+
+	var contractIndex *plugin.Generated
+	if arguments.ContractHashes {
+		contractIndex, err = addContractHashes(d, arguments.OutputDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// The contracts are hashed on the inline bodies, which resolve their schemas.
+	if arguments.ReuseBodies {
+		reuseComponents(d)
+	}
+
+	g.document = d
+
+	header := g.documentHeader()
+	bytes, err := d.YAMLValue(header)
 	if err != nil {
-		fmt.Printf("Error converting to yaml: %s\n", err)
-		return nil
+		return nil, fmt.Errorf("error converting to yaml: %s", err)
+	}
+	var exported []byte
+	exportedName := ""
+	if !g.localized {
+		switch arguments.Format {
+		case FormatJSON:
+			exported, err = DocumentJSON(bytes)
+			exportedName = JSONName
+		case FormatPostman:
+			exported, err = ConvertToPostman(d)
+			exportedName = PostmanName
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
+
 	filePath := filepath.Clean(arguments.OutputDir)
 	filePath = filepath.Join(filePath, "openapi.yaml")
+	content := bytes
+	// Stdout prints the document in the selected format.
+	if arguments.Stdout {
+		filePath = StdoutName
+		if exportedName != "" {
+			content, exportedName = exported, ""
+		}
+	}
 	var ret []*plugin.Generated
 	ret = append(ret, &plugin.Generated{
-		Content: string(bytes),
+		Content: string(content),
 		Name:    &filePath,
 	})
+	if exportedName != "" {
+		exportedPath := filepath.Join(filepath.Clean(arguments.OutputDir), exportedName)
+		ret = append(ret, &plugin.Generated{
+			Content: string(exported),
+			Name:    &exportedPath,
+		})
+	}
 
-	return ret
-}
+	if contractIndex != nil {
+		ret = append(ret, contractIndex)
+	}
 
-func (g *OpenAPIGenerator) getDocumentOption(obj interface{}) error {
-	serviceOrStruct, name := g.getDocumentAnnotationInWhichServiceOrStruct()
-	if serviceOrStruct == "service" {
-		serviceDesc := g.fileDesc.GetServiceDescriptor(name)
-		err := utils.ParseServiceOption(serviceDesc, OpenapiDocument, obj)
+	if arguments.Minify && !arguments.Stdout && !g.localized {
+		minified, err := Minify(bytes)
 		if err != nil {
-			return err
+			return nil, err
 		}
-	} else if serviceOrStruct == "struct" {
-		structDesc := g.fileDesc.GetStructDescriptor(name)
-		err := utils.ParseStructOption(structDesc, OpenapiDocument, obj)
+		minifiedPath := filepath.Join(filepath.Clean(arguments.OutputDir), MinifiedName)
+		ret = append(ret, &plugin.Generated{
+			Content: string(minified),
+			Name:    &minifiedPath,
+		})
+	}
+
+	if len(arguments.Langs) > 0 && !g.localized && !arguments.Stdout {
+		localized, err := g.localizedDocuments(arguments)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		ret = append(ret, localized...)
 	}
-	return nil
-}
 
-func (g *OpenAPIGenerator) addPathsToDocument(d *openapi.Document, services []*parser.Service) {
-	for _, s := range services {
-		annotationsCount := 0
-		for _, f := range s.Functions {
-			comment := g.filterCommentString(f.ReservedComments)
-			operationID := s.GetName() + "_" + f.GetName()
-			rs := utils.GetAnnotations(f.Annotations, HttpMethodAnnotations)
-			if len(rs) == 0 {
-				continue
+	// The complete document is still generated for the tools, only the UI loads
+	// the parts.
+	if max := arguments.MaxOperationsPerDoc; max > 0 && !arguments.Stdout && countOperations(d) > max {
+		var docs []*openapi.Document
+		docs, g.specParts = splitDocument(d, max)
+		for i, doc := range docs {
+			bytes, err := doc.YAMLValue(header)
+			if err != nil {
+				return nil, fmt.Errorf("error converting to yaml: %s", err)
 			}
+			partPath := filepath.Join(filepath.Clean(arguments.OutputDir), g.specParts[i].File)
+			ret = append(ret, &plugin.Generated{
+				Content: string(bytes),
+				Name:    &partPath,
+			})
+		}
+	}
 
-			var inputDesc *thrift_reflection.StructDescriptor
-			if len(f.Arguments) >= 1 {
-				if len(f.Arguments) > 1 {
-					logs.Warnf("function '%s' has more than one argument, but only the first can be used in hertz now", f.GetName())
-				}
-				inputDesc = g.fileDesc.GetStructDescriptor(f.GetArguments()[0].GetType().GetName())
-			}
-			outputDesc := g.fileDesc.GetStructDescriptor(f.GetFunctionType().GetName())
-			for methodName, path := range rs {
-				if methodName != "" {
-					annotationsCount++
-					var host string
-					hostOrNil := utils.GetAnnotation(f.Annotations, ApiBaseURL)
+	return ret, nil
+}
 
-					if len(hostOrNil) != 0 {
-						host = utils.GetAnnotation(f.Annotations, ApiBaseURL)[0]
-					}
+// documentHeader returns the comment written at the top of the generated documents,
+// the generation time is left out with NoTimestamp to make the output reproducible.
+func (g *OpenAPIGenerator) documentHeader() string {
+	header := "Generated with thrift-gen-rpc-swagger " + Version + "\n" + infoURL + "\n" + "IDL: " + g.ast.Filename
+	if !g.arguments.NoTimestamp {
+		header += "\nGenerated at: " + time.Now().UTC().Format(time.RFC3339)
+	}
+	return header
+}
 
-					if host == "" {
-						hostOrNil = utils.GetAnnotation(s.Annotations, ApiBaseDomain)
-						if len(hostOrNil) != 0 {
-							host = utils.GetAnnotation(s.Annotations, ApiBaseDomain)[0]
-						}
-					}
+// SpecParts returns the parts the document was split into by BuildDocument, it is
+// empty when the operations do not exceed MaxOperationsPerDoc.
+func (g *OpenAPIGenerator) SpecParts() []SpecPart {
+	return g.specParts
+}
 
-					op, path2 := g.buildOperation(d, methodName, comment, operationID, s.GetName(), path[0], host, inputDesc, outputDesc)
-					methodDesc := g.fileDesc.GetMethodDescriptor(s.GetName(), f.GetName())
-					newOp := &openapi.Operation{}
-					err := utils.ParseMethodOption(methodDesc, OpenapiOperation, &newOp)
-					if err != nil {
-						logs.Errorf("Error parsing method option: %s", err)
-					}
-					err = utils.MergeStructs(op, newOp)
-					if err != nil {
-						logs.Errorf("Error merging method option: %s", err)
-					}
-					g.addOperationToDocument(d, op, path2, methodName)
-				}
-			}
+// Document returns the document built by BuildDocument, e.g. to compare it with the
+// one of another revision of the IDL with CompareDocuments.
+func (g *OpenAPIGenerator) Document() *openapi.Document {
+	return g.document
+}
+
+// pruneUnusedSchemas removes the schemas referenced neither by the paths nor by the
+// other components, e.g. the structs of fields which are not documented.
+func (g *OpenAPIGenerator) pruneUnusedSchemas(d *openapi.Document) {
+	schemas := d.Components.Schemas
+	d.Components.Schemas = nil
+	used := reachableSchemas(d, schemas.AdditionalProperties)
+	d.Components.Schemas = schemas
+
+	var kept []*openapi.NamedSchemaOrReference
+	var pruned []string
+	for _, schema := range schemas.AdditionalProperties {
+		if used[schema.Name] {
+			kept = append(kept, schema)
+		} else {
+			pruned = append(pruned, schema.Name)
 		}
-		if annotationsCount > 0 {
-			comment := g.filterCommentString(s.ReservedComments)
-			d.Tags = append(d.Tags, &openapi.Tag{Name: s.GetName(), Description: comment})
+	}
+	if len(pruned) > 0 {
+		sort.Strings(pruned)
+		// The removal is a notice, which does not fail the Strict builds.
+		if !g.localized {
+			g.warnings = append(g.warnings, "unreferenced schemas are removed, set KeepUnused to keep them: "+strings.Join(pruned, ", "))
 		}
+		schemas.AdditionalProperties = kept
 	}
 }
 
-func (g *OpenAPIGenerator) buildOperation(
-	d *openapi.Document,
-	methodName string,
-	description string,
-	operationID string,
-	tagName string,
-	path string,
-	host string,
-	inputDesc *thrift_reflection.StructDescriptor,
-	outputDesc *thrift_reflection.StructDescriptor,
-) (*openapi.Operation, string) {
-	// Parameters array to hold all parameter objects
-	var parameters []*openapi.ParameterOrReference
-
-	for _, v := range inputDesc.GetFields() {
-		var paramName, paramIn, paramDesc string
-		var fieldSchema *openapi.SchemaOrReference
-		required := false
-
-		extOrNil := v.Annotations[ApiQuery]
-		if len(extOrNil) > 0 {
-			if ext := v.Annotations[ApiQuery][0]; ext != "" {
-				paramIn = "query"
-				paramName = ext
-				paramDesc = g.filterCommentString(v.Comments)
-				fieldSchema = g.schemaOrReferenceForField(v.Type)
-				extPropertyOrNil := v.Annotations[OpenapiProperty]
-				if len(extPropertyOrNil) > 0 {
-					newFieldSchema := &openapi.Schema{}
-					err := utils.ParseFieldOption(v, OpenapiProperty, &newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error parsing field option: %s", err)
-					}
-					err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error merging field option: %s", err)
-					}
-				}
-			}
+// sortSchemasByDeclaration orders the schemas of the structs, unions and exceptions
+// of the IDL as they are declared, then the other schemas, e.g. of the included
+// IDLs, in the order they are first referenced.
+func (g *OpenAPIGenerator) sortSchemasByDeclaration(d *openapi.Document) {
+	declared := make(map[string]int)
+	for _, structs := range [][]*parser.StructLike{g.ast.Structs, g.ast.Unions, g.ast.Exceptions} {
+		for _, st := range structs {
+			declared[st.Name] = len(declared)
 		}
-		extOrNil = v.Annotations[ApiPath]
-		if len(extOrNil) > 0 {
-			if ext := v.Annotations[ApiPath][0]; ext != "" {
-				paramIn = "path"
-				paramName = ext
-				paramDesc = g.filterCommentString(v.Comments)
-				fieldSchema = g.schemaOrReferenceForField(v.Type)
-				extPropertyOrNil := v.Annotations[OpenapiProperty]
-				if len(extPropertyOrNil) > 0 {
-					newFieldSchema := &openapi.Schema{}
-					err := utils.ParseFieldOption(v, OpenapiProperty, &newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error parsing field option: %s", err)
-					}
-					err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error merging field option: %s", err)
-					}
-				}
-				required = true
-			}
-		}
-		extOrNil = v.Annotations[ApiCookie]
-		if len(extOrNil) > 0 {
-			if ext := v.Annotations[ApiCookie][0]; ext != "" {
-				paramIn = "cookie"
-				paramName = ext
-				paramDesc = g.filterCommentString(v.Comments)
-				fieldSchema = g.schemaOrReferenceForField(v.Type)
-				extPropertyOrNil := v.Annotations[OpenapiProperty]
-				if len(extPropertyOrNil) > 0 {
-					newFieldSchema := &openapi.Schema{}
-					err := utils.ParseFieldOption(v, OpenapiProperty, &newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error parsing field option: %s", err)
-					}
-					err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error merging field option: %s", err)
-					}
-				}
+	}
+	position := func(schema *openapi.NamedSchemaOrReference) int {
+		if owner, ok := g.schemaOwners[schema.Name]; ok && owner.GetFilepath() == g.ast.Filename {
+			if i, ok := declared[owner.GetName()]; ok {
+				return i
 			}
 		}
-		extOrNil = v.Annotations[ApiHeader]
-		if len(extOrNil) > 0 {
-			if ext := v.Annotations[ApiHeader][0]; ext != "" {
-				paramIn = "header"
-				paramName = ext
-				paramDesc = g.filterCommentString(v.Comments)
-				fieldSchema = g.schemaOrReferenceForField(v.Type)
-				extPropertyOrNil := v.Annotations[OpenapiProperty]
-				if len(extPropertyOrNil) > 0 {
-					newFieldSchema := &openapi.Schema{}
-					err := utils.ParseFieldOption(v, OpenapiProperty, &newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error parsing field option: %s", err)
-					}
-					err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
-					if err != nil {
-						logs.Errorf("Error merging field option: %s", err)
-					}
-				}
-			}
+		return len(declared)
+	}
+	pairs := d.Components.Schemas.AdditionalProperties
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return position(pairs[i]) < position(pairs[j])
+	})
+}
+
+// appendUniqueServer appends server unless an identical server, variables included,
+// is already listed.
+func appendUniqueServer(servers []*openapi.Server, server *openapi.Server) []*openapi.Server {
+	for _, s := range servers {
+		if s.URL == server.URL && s.Description == server.Description && reflect.DeepEqual(s.Variables, server.Variables) {
+			return servers
 		}
+	}
+	return append(servers, server)
+}
 
-		parameter := &openapi.Parameter{
-			Name:        paramName,
-			In:          paramIn,
-			Description: paramDesc,
-			Required:    required,
-			Schema:      fieldSchema,
+// majorVersion returns the major number of a version such as 2, v2 or 2.1.0.
+func majorVersion(version string) (int, error) {
+	major := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexByte(major, '.'); i >= 0 {
+		major = major[:i]
+	}
+	n, err := strconv.ParseUint(major, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version '%s' for VersionInPath, expected a major number such as 2 or 2.1.0", version)
+	}
+	return int(n), nil
+}
+
+func countOperations(d *openapi.Document) int {
+	count := 0
+	for _, item := range d.Paths.Path {
+		count += len(operationsOf(item.Value))
+	}
+	return count
+}
+
+// noOperationsError reports an IDL whose functions declare no route, listing the
+// functions scanned, rather than generating a document without operations.
+func (g *OpenAPIGenerator) noOperationsError() error {
+	var scanned []string
+	for _, s := range g.ast.Services {
+		var functions []string
+		for _, f := range s.Functions {
+			functions = append(functions, f.GetName())
 		}
+		scanned = append(scanned, s.GetName()+" ("+strings.Join(functions, ", ")+")")
+	}
+	return fmt.Errorf("no operation generated, no function of the services %s declares an HTTP method annotation such as %s or %s: "+
+		"annotate them or set AutoOperations to route every function to POST /Service/Method",
+		strings.Join(scanned, ", "), annotations.ApiGet, annotations.ApiPost)
+}
 
-		var extParameter *openapi.Parameter
-		err := utils.ParseFieldOption(v, OpenapiParameter, &extParameter)
+func (g *OpenAPIGenerator) getDocumentOption(obj interface{}) error {
+	serviceOrStruct, name := g.getDocumentAnnotationInWhichServiceOrStruct()
+	if serviceOrStruct == "service" {
+		serviceDesc := g.fileDesc.GetServiceDescriptor(name)
+		err := utils.ParseServiceOption(serviceDesc, annotations.OpenapiDocument, obj)
 		if err != nil {
-			logs.Errorf("Error parsing field option: %s", err)
+			return err
 		}
-		err = utils.MergeStructs(parameter, extParameter)
+	} else if serviceOrStruct == "struct" {
+		structDesc := g.fileDesc.GetStructDescriptor(name)
+		err := utils.ParseStructOption(structDesc, annotations.OpenapiDocument, obj)
 		if err != nil {
-			logs.Errorf("Error merging field option: %s", err)
+			return err
 		}
+	}
+	return nil
+}
 
-		// Append the parameter to the parameters array if it was set
-		if paramName != "" && paramIn != "" {
-			parameters = append(parameters, &openapi.ParameterOrReference{
-				Parameter: parameter,
-			})
+func (g *OpenAPIGenerator) addPathsToDocument(d *openapi.Document, services []*parser.Service) error {
+	for _, name := range append(append([]string{}, g.arguments.IncludeServices...), g.arguments.ExcludeServices...) {
+		if !containsService(services, name) {
+			g.warn("service '%s' of IncludeServices or ExcludeServices is not declared", name)
 		}
 	}
 
-	var RequestBody *openapi.RequestBodyOrReference
-	if methodName != "GET" && methodName != "HEAD" && methodName != "DELETE" {
-		bodySchema := g.getSchemaByOption(inputDesc, ApiBody)
-		formSchema := g.getSchemaByOption(inputDesc, ApiForm)
-		rawBodySchema := g.getSchemaByOption(inputDesc, ApiRawBody)
+	usages := newStructUsages()
+	for _, s := range services {
+		if ignored, _ := annotations.IsIgnored(utils.GetAnnotation(s.Annotations, annotations.OpenapiIgnore)); ignored || !g.arguments.ServiceSelected(s.GetName()) {
+			continue
+		}
+		if !g.arguments.ContinueOnError || g.arguments.Strict {
+			if err := g.addServiceToDocument(d, s, usages); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := g.addServiceIsolated(d, s, usages); err != nil {
+			g.generationErrors = append(g.generationErrors, fmt.Sprintf("service '%s' omitted: %s", s.GetName(), err))
+		}
+	}
+	if len(g.generationErrors) > 0 {
+		extension, err := newNamedAny("x-generation-errors", g.generationErrors)
+		if err != nil {
+			return err
+		}
+		d.SpecificationExtension = append(d.SpecificationExtension, extension)
+	}
+	g.checkBindingUsages(usages)
+	g.checkBindingConflicts(usages)
+	return nil
+}
 
-		var additionalProperties []*openapi.NamedMediaType
-		if len(bodySchema.Properties.AdditionalProperties) > 0 {
-			additionalProperties = append(additionalProperties, &openapi.NamedMediaType{
-				Name: "application/json",
-				Value: &openapi.MediaType{
-					Schema: &openapi.SchemaOrReference{
-						Schema: bodySchema,
-					},
-				},
-			})
+func containsService(services []*parser.Service, name string) bool {
+	for _, s := range services {
+		if s.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addServiceToDocument adds the operations of the routed functions of the service.
+func (g *OpenAPIGenerator) addServiceToDocument(d *openapi.Document, s *parser.Service, usages *structUsages) error {
+	err := g.collectServerVariables(s)
+	if err != nil {
+		g.warn("error parsing server variables of service '%s': %s", s.GetName(), err)
+	}
+
+	basePath, err := annotations.BasePath(utils.GetAnnotation(s.Annotations, annotations.OpenapiBasePath))
+	if err != nil {
+		g.warn("service '%s': %s", s.GetName(), err)
+	}
+
+	serviceParameters := g.serviceParameters(s)
+	serviceServers := g.serversOption(s.GetName(), utils.GetAnnotation(s.Annotations, annotations.OpenapiServer))
+	serviceAudience, err := annotations.Audience(utils.GetAnnotation(s.Annotations, annotations.OpenapiAudience), annotations.AudiencePublic)
+	if err != nil {
+		g.warn("service '%s': %s", s.GetName(), err)
+	}
+
+	annotationsCount := 0
+	for _, f := range s.Functions {
+		binding := g.routes.Function(f)
+		if !binding.Enabled || binding.Ignored {
+			continue
+		}
+		audience, err := annotations.Audience(utils.GetAnnotation(f.Annotations, annotations.OpenapiAudience), serviceAudience)
+		if err != nil {
+			g.warn("%s.%s: %s", s.GetName(), f.GetName(), err)
+		}
+		if !annotations.AudienceSelected(audience, g.arguments.Audience) {
+			continue
+		}
+		for _, route := range binding.InvalidRoutes {
+			g.warn("%s.%s: route %s %.64q is skipped: %s", s.GetName(), f.GetName(), route.Method, route.Path, route.Err)
+		}
+		comment := g.filterCommentString(f.ReservedComments)
+		operationID := g.naming.OperationID(s.GetName(), f.GetName())
+		if len(binding.Routes) == 0 {
+			continue
+		}
+
+		var inputDesc *thrift_reflection.StructDescriptor
+		var argumentsSchema string
+		if len(f.Arguments) > 1 && g.arguments.WrapMultiArgs {
+			argumentsSchema = g.addArgumentsSchema(d, s, f)
+		} else if len(f.Arguments) >= 1 {
+			if len(f.Arguments) > 1 {
+				g.warn("function '%s' has more than one argument, but only the first can be used in hertz now, set WrapMultiArgs to document them all", f.GetName())
+			}
+			inputDesc = g.fileDesc.GetStructDescriptor(f.GetArguments()[0].GetType().GetName())
+		}
+		outputDesc := g.fileDesc.GetStructDescriptor(f.GetFunctionType().GetName())
+		usages.add(inputDesc, usedAsRequest)
+		usages.add(outputDesc, usedAsResponse)
+		servers := g.serversOption(s.GetName()+"."+f.GetName(), utils.GetAnnotation(f.Annotations, annotations.OpenapiServer))
+		functionExtensions := g.extensionsOption(s.GetName()+"."+f.GetName(), utils.GetAnnotation(f.Annotations, annotations.OpenapiExtension))
+		sunset, sunsetExtension := g.sunsetOption(s, f)
+		if sunsetExtension != nil {
+			functionExtensions = setExtensions([]*openapi.NamedAny{sunsetExtension}, functionExtensions)
+		}
+		if len(servers) == 0 {
+			servers = serviceServers
+		}
+		for _, route := range binding.Routes {
+			methodName, path, host := route.Method, g.versionPrefix+basePath+route.Path, ""
+			if binding.Host != "" {
+				server, prefix, err := annotations.ParseBaseURL(binding.Host)
+				if err != nil {
+					g.warn("%s.%s: %s", s.GetName(), f.GetName(), err)
+				} else if g.arguments.BaseURLPath == BaseURLPathOperation {
+					host, path = server, prefix+path
+				} else {
+					host = server + prefix
+				}
+			}
+			annotationsCount++
+
+			g.lintVerb(s, f, methodName, inputDesc)
+			op, path2 := g.buildOperation(d, methodName, comment, operationID, s.GetName(), path, host, inputDesc, outputDesc)
+			if outputDesc == nil && !f.Void {
+				g.warn("%s.%s: the result '%s' is not a struct, the response is not documented",
+					s.GetName(), f.GetName(), f.GetFunctionType().GetName())
+				op.Responses = undocumentedResponses()
+			}
+			if len(servers) > 0 {
+				op.Servers = servers
+			}
+			if f.Oneway {
+				op.Responses = acceptedResponses()
+			}
+			if binding.Default {
+				g.setDefaultBodies(op, inputDesc, outputDesc)
+			}
+			if argumentsSchema != "" {
+				op.RequestBody = &openapi.RequestBodyOrReference{
+					RequestBody: &openapi.RequestBody{
+						Required: true,
+						Content:  jsonContent("#/components/schemas/" + argumentsSchema),
+					},
+				}
+			}
+			g.applyResponseOption(op, s, f, outputDesc)
+			methodDesc := g.fileDesc.GetMethodDescriptor(s.GetName(), f.GetName())
+			g.addExceptionResponses(op, methodDesc)
+			g.addStatusResponses(op, outputDesc)
+			newOp := &openapi.Operation{}
+			err := utils.ParseMethodOption(methodDesc, annotations.OpenapiOperation, &newOp)
+			if err != nil {
+				g.warn("service '%s': %s", s.GetName(), err)
+			}
+			err = utils.MergeStructs(op, newOp)
+			if err != nil {
+				logs.Errorf("Error merging method option: %s", err)
+			}
+			op.SpecificationExtension = setExtensions(op.SpecificationExtension, functionExtensions)
+			if !sunset.IsZero() && !sunset.After(g.sunsetDate) {
+				op.Deprecated = true
+			}
+			op.OperationID = g.arguments.OperationIDPrefix + op.OperationID
+			op.Parameters = withServiceParameters(serviceParameters, op.Parameters)
+			source := fmt.Sprintf("%s.%s (%s %s)", s.GetName(), f.GetName(), methodName, path)
+			id, previous := g.operationIDs.register(op.OperationID, methodName, source)
+			if previous != "" {
+				g.warn("operationId '%s' of %s is already used by %s, it is renamed to '%s'",
+					op.OperationID, source, previous, id)
+				op.OperationID = id
+			}
+			g.checkPathParameters(op, s, f, methodName, path2, annotations.WildcardName(path))
+			err = g.addGatewayIntegration(op, s, f, methodName, path2)
+			if err != nil {
+				return err
+			}
+			g.addOperationToDocument(d, op, path2, methodName)
+		}
+	}
+	if annotationsCount > 0 {
+		comment := g.filterCommentString(s.ReservedComments)
+		d.Tags = append(d.Tags, &openapi.Tag{Name: s.GetName(), Description: comment})
+	}
+	return nil
+}
+
+// serviceParameters returns the parameters declared by the openapi.parameter
+// annotations of the service, e.g. a tenant header, one per annotation. A parameter
+// without schema is a string.
+func (g *OpenAPIGenerator) serviceParameters(s *parser.Service) []*openapi.Parameter {
+	serviceDesc := g.fileDesc.GetServiceDescriptor(s.GetName())
+	if serviceDesc == nil {
+		return nil
+	}
+	var parameters []*openapi.Parameter
+	for _, value := range serviceDesc.Annotations[annotations.OpenapiParameter] {
+		// The option parser reads a single value, each one is parsed on its own.
+		single := *serviceDesc
+		single.Annotations = map[string][]string{annotations.OpenapiParameter: {value}}
+		var parameter *openapi.Parameter
+		err := utils.ParseServiceOption(&single, annotations.OpenapiParameter, &parameter)
+		if err == nil && (parameter == nil || parameter.Name == "" || parameter.In == "") {
+			err = fmt.Errorf("service '%s': %s %.64q has no name or location", s.GetName(), annotations.OpenapiParameter, value)
+		}
+		if err != nil {
+			g.warn("%s", err)
+			continue
+		}
+		if parameter.Schema == nil {
+			parameter.Schema = &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "string"}}
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// withServiceParameters returns the parameters of an operation preceded by the
+// parameters of its service, a parameter of the operation with the same name and
+// location replacing the one of the service.
+func withServiceParameters(common []*openapi.Parameter, parameters []*openapi.ParameterOrReference) []*openapi.ParameterOrReference {
+	var merged []*openapi.ParameterOrReference
+	for _, param := range common {
+		overridden := false
+		for _, p := range parameters {
+			if p.Parameter != nil && p.Parameter.In == param.In && (p.Parameter.Name == param.Name ||
+				param.In == "header" && strings.EqualFold(p.Parameter.Name, param.Name)) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			parameter := *param
+			merged = append(merged, &openapi.ParameterOrReference{Parameter: &parameter})
+		}
+	}
+	return append(merged, parameters...)
+}
+
+// addServiceIsolated adds the service like addServiceToDocument, but leaves the
+// document as it was when the service fails or panics, so that ContinueOnError can
+// omit the service and generate the others.
+func (g *OpenAPIGenerator) addServiceIsolated(d *openapi.Document, s *parser.Service, usages *structUsages) (err error) {
+	scratch := *d
+	scratch.Paths = &openapi.Paths{}
+	scratch.Tags = append([]*openapi.Tag{}, d.Tags...)
+	state := g.saveServiceState(d, usages)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			g.restoreServiceState(d, usages, state)
+			return
+		}
+		d.Tags = scratch.Tags
+		for _, item := range scratch.Paths.Path {
+			for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"} {
+				if op := operationOf(item.Value, method); op != nil {
+					g.addOperationToDocument(d, op, item.Name, method)
+				}
+			}
+		}
+	}()
+	return g.addServiceToDocument(&scratch, s, usages)
+}
+
+// serviceState is the state of the generator and of the document changed by adding
+// a service. The issues already reported are kept, along with the maps telling which
+// ones were reported.
+type serviceState struct {
+	schemas         int
+	generated       int
+	required        int
+	operationIDs    int
+	requiredStructs map[string]*thrift_reflection.StructDescriptor
+	schemaNames     map[string]string
+	schemaOwners    map[string]*thrift_reflection.StructDescriptor
+	serverVariables map[string]*openapi.ServerVariable
+	structs         int
+	usage           map[*thrift_reflection.StructDescriptor]structUsage
+}
+
+func (g *OpenAPIGenerator) saveServiceState(d *openapi.Document, usages *structUsages) *serviceState {
+	state := &serviceState{
+		schemas:         len(d.Components.Schemas.AdditionalProperties),
+		generated:       len(g.generatedSchemas),
+		required:        len(g.requiredSchemas),
+		operationIDs:    len(g.operationIDs.ids),
+		requiredStructs: make(map[string]*thrift_reflection.StructDescriptor, len(g.requiredStructs)),
+		schemaNames:     make(map[string]string, len(g.schemaNames)),
+		schemaOwners:    make(map[string]*thrift_reflection.StructDescriptor, len(g.schemaOwners)),
+		serverVariables: make(map[string]*openapi.ServerVariable, len(g.serverVariables)),
+		structs:         len(usages.structs),
+		usage:           make(map[*thrift_reflection.StructDescriptor]structUsage, len(usages.usage)),
+	}
+	for k, v := range g.requiredStructs {
+		state.requiredStructs[k] = v
+	}
+	for k, v := range g.schemaNames {
+		state.schemaNames[k] = v
+	}
+	for k, v := range g.schemaOwners {
+		state.schemaOwners[k] = v
+	}
+	for k, v := range g.serverVariables {
+		state.serverVariables[k] = v
+	}
+	for k, v := range usages.usage {
+		state.usage[k] = v
+	}
+	return state
+}
+
+func (g *OpenAPIGenerator) restoreServiceState(d *openapi.Document, usages *structUsages, state *serviceState) {
+	d.Components.Schemas.AdditionalProperties = d.Components.Schemas.AdditionalProperties[:state.schemas]
+	g.generatedSchemas = g.generatedSchemas[:state.generated]
+	g.requiredSchemas = g.requiredSchemas[:state.required]
+	g.operationIDs.truncate(state.operationIDs)
+	g.requiredStructs = state.requiredStructs
+	g.schemaNames = state.schemaNames
+	g.schemaOwners = state.schemaOwners
+	g.serverVariables = state.serverVariables
+	usages.structs = usages.structs[:state.structs]
+	usages.usage = state.usage
+}
+
+// Warnings returns the issues reported by the plugin response rather than logged,
+// so that the tool driving thriftgo shows them in one place: the warnings of the
+// generation, then the services omitted with ContinueOnError.
+func (g *OpenAPIGenerator) Warnings() []string {
+	return append(append([]string{}, g.warnings...), g.generationErrors...)
+}
+
+// structUsage tells whether the routed functions take a struct as argument, return
+// it, or both.
+type structUsage int
+
+const (
+	usedAsRequest structUsage = 1 << iota
+	usedAsResponse
+)
+
+// structUsages records the usage of the structs in the order they are met.
+type structUsages struct {
+	structs []*thrift_reflection.StructDescriptor
+	usage   map[*thrift_reflection.StructDescriptor]structUsage
+}
+
+func newStructUsages() *structUsages {
+	return &structUsages{usage: make(map[*thrift_reflection.StructDescriptor]structUsage)}
+}
+
+func (u *structUsages) add(desc *thrift_reflection.StructDescriptor, usage structUsage) {
+	if desc == nil {
+		return
+	}
+	if _, ok := u.usage[desc]; !ok {
+		u.structs = append(u.structs, desc)
+	}
+	u.usage[desc] |= usage
+}
+
+// responseOnlyHeaders are headers only meaningful in a response.
+var responseOnlyHeaders = []string{
+	"set-cookie",
+	"location",
+	"www-authenticate",
+	"retry-after",
+	"etag",
+	"last-modified",
+	"content-disposition",
+}
+
+// checkBindingUsages warns about binding annotations which do not apply to the way
+// a struct is used: request locations on a struct only returned, and response
+// statuses and headers on a struct only taken as argument. Structs used both ways
+// are not checked.
+func (g *OpenAPIGenerator) checkBindingUsages(usages *structUsages) {
+	for _, desc := range usages.structs {
+		switch usages.usage[desc] {
+		case usedAsResponse:
+			for _, field := range desc.GetFields() {
+				for _, binding := range annotations.Bindings(field) {
+					switch binding.In {
+					case annotations.InQuery, annotations.InPath, annotations.InCookie, annotations.InForm:
+						g.warn("%s.%s: %s is ignored, the struct is only used as a response", desc.GetName(), field.GetName(), binding.Annotation)
+					}
+				}
+			}
+		case usedAsRequest:
+			for _, field := range desc.GetFields() {
+				if annotations.IsStatusField(field.Annotations[annotations.ApiHttpCode]) {
+					g.warn("%s.%s: %s is ignored, the struct is only used as a request", desc.GetName(), field.GetName(), annotations.ApiHttpCode)
+				}
+				for _, binding := range annotations.Bindings(field) {
+					if binding.In == annotations.InHeader && utils.Contains(responseOnlyHeaders, strings.ToLower(binding.Name)) {
+						g.warn("%s.%s: %s '%s' is a response header, but the struct is only used as a request",
+							desc.GetName(), field.GetName(), binding.Annotation, binding.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// warn reports an issue of the IDL which does not prevent generation, returned by
+// Warnings. In strict mode the issue is an error instead and BuildDocument fails
+// once the document is built.
+func (g *OpenAPIGenerator) warn(format string, a ...interface{}) {
+	// The documents of the other languages repeat the issues of the main document.
+	if g.localized {
+		return
+	}
+	if g.arguments.Strict {
+		g.strictErrors = append(g.strictErrors, fmt.Sprintf(format, a...))
+		return
+	}
+	g.warnings = append(g.warnings, fmt.Sprintf(format, a...))
+}
+
+// checkAnnotations reports the api and openapi annotations of the services, the
+// functions, the structs and their fields that are not known, e.g. a misspelled
+// api.querry which would leave its field out of the documentation, with the closest
+// known annotation, and the invalid values of the boolean annotations.
+func (g *OpenAPIGenerator) checkAnnotations() {
+	flags := map[string]func([]string) (bool, error){
+		annotations.OpenapiIgnore: annotations.IsIgnored,
+		annotations.OpenapiSkip:   annotations.IsSkipped,
+	}
+	check := func(owner string, annos parser.Annotations) {
+		for _, anno := range annos {
+			if annotations.Known(anno.Key) {
+				if parse, ok := flags[strings.ToLower(anno.Key)]; ok {
+					if _, err := parse(anno.Values); err != nil {
+						g.warn("%s: %s", owner, err)
+					}
+				}
+				continue
+			}
+			if suggestion := annotations.Suggest(anno.Key); suggestion != "" {
+				g.warn("%s: unknown annotation '%s', did you mean '%s'?", owner, anno.Key, suggestion)
+			} else {
+				g.warn("%s: unknown annotation '%s'", owner, anno.Key)
+			}
+		}
+	}
+	checkFields := func(owner string, fields []*parser.Field) {
+		for _, field := range fields {
+			check(owner+" '"+field.Name+"'", field.Annotations)
+		}
+	}
+
+	for _, s := range g.ast.Services {
+		check("service '"+s.Name+"'", s.Annotations)
+		for _, f := range s.Functions {
+			check("method '"+s.Name+"."+f.Name+"'", f.Annotations)
+			checkFields("method '"+s.Name+"."+f.Name+"' argument", f.Arguments)
+		}
+	}
+	for _, structs := range [][]*parser.StructLike{g.ast.Structs, g.ast.Unions, g.ast.Exceptions} {
+		for _, st := range structs {
+			check("struct '"+st.Name+"'", st.Annotations)
+			checkFields("struct '"+st.Name+"' field", st.Fields)
+		}
+	}
+}
+
+// checkOperationIDs reports the operationIds shared by several operations, it fails
+// in strict mode and warns otherwise. The generated operations are renamed as they
+// are added, so this only catches ids set elsewhere, e.g. by the document option.
+func (g *OpenAPIGenerator) checkOperationIDs(d *openapi.Document) {
+	var ids []string
+	operations := make(map[string][]string)
+	for _, path := range d.Paths.Path {
+		for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"} {
+			op := operationOf(path.Value, method)
+			if op == nil || op.OperationID == "" {
+				continue
+			}
+			if _, ok := operations[op.OperationID]; !ok {
+				ids = append(ids, op.OperationID)
+			}
+			operations[op.OperationID] = append(operations[op.OperationID], method+" "+path.Name)
+		}
+	}
+
+	for _, id := range ids {
+		if len(operations[id]) < 2 {
+			continue
+		}
+		g.warn("operationId '%s' is used by %s, set OperationIDPrefix or override it with %s",
+			id, strings.Join(operations[id], ", "), annotations.OpenapiOperation)
+	}
+}
+
+// checkBindingConflicts warns about the fields of the requests whose binding
+// annotations cannot coexist, e.g. api.query and api.path copied on the same field.
+// The parameter is the one of ParameterBinding, a field also bound to the body is a
+// property of it too.
+func (g *OpenAPIGenerator) checkBindingConflicts(usages *structUsages) {
+	for _, desc := range usages.structs {
+		if usages.usage[desc]&usedAsRequest == 0 {
+			continue
+		}
+		for _, field := range desc.GetFields() {
+			bindings := annotations.Bindings(field)
+			conflicting := annotations.ConflictingBindings(bindings)
+			if len(conflicting) == 0 {
+				continue
+			}
+			if binding, ok := annotations.ParameterBinding(bindings); ok {
+				g.warn("%s.%s: conflicting %s, %s is used for the parameter", desc.GetName(), field.GetName(), strings.Join(conflicting, ", "), binding.Annotation)
+			} else {
+				g.warn("%s.%s: conflicting %s", desc.GetName(), field.GetName(), strings.Join(conflicting, ", "))
+			}
+		}
+	}
+}
+
+// checkSecurityRequirements reports the security requirements of the document and
+// of the operations naming a scheme absent from components.securitySchemes. An
+// operation requirement, set with openapi.operation, replaces the ones of the
+// document, and an empty one, e.g. {security: []}, leaves the operation public.
+func (g *OpenAPIGenerator) checkSecurityRequirements(d *openapi.Document) {
+	declared := func(name string) bool {
+		return d.Components.SecuritySchemes != nil && indexOfSecurityScheme(d.Components.SecuritySchemes, name) >= 0
+	}
+	check := func(owner string, requirements []*openapi.SecurityRequirement) {
+		for _, requirement := range requirements {
+			if requirement == nil {
+				continue
+			}
+			for _, scheme := range requirement.AdditionalProperties {
+				if !declared(scheme.Name) {
+					g.warn("%s: security requirement '%s' names no declared security scheme", owner, scheme.Name)
+				}
+			}
+		}
+	}
+
+	check("document", d.Security)
+	for _, path := range d.Paths.Path {
+		for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"} {
+			if op := operationOf(path.Value, method); op != nil {
+				check(method+" "+path.Name, op.Security)
+			}
+		}
+	}
+}
+
+// checkPathParameters cross-checks the placeholders of the path template with the
+// path parameters of the operation. A placeholder without parameter, e.g. when the
+// field is bound with api.query by mistake, is added as a required string parameter,
+// and a path parameter absent from the template is left out. Both fail in strict
+// mode and are warnings otherwise. The parameter of the catch-all wildcard, which
+// matches the rest of the path including slashes, is marked with x-wildcard.
+func (g *OpenAPIGenerator) checkPathParameters(op *openapi.Operation, s *parser.Service, f *parser.Function, methodName, path, wildcard string) {
+	placeholders := make(map[string]bool)
+	for _, match := range g.variablePattern.FindAllStringSubmatch(path, -1) {
+		name := match[1]
+		placeholders[name] = true
+		found := false
+		for _, param := range op.Parameters {
+			if param.Parameter != nil && param.Parameter.In == "path" && param.Parameter.Name == name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		g.warn("%s.%s: path parameter '%s' of '%s %s' has no field annotated with %s, it is documented as a string",
+			s.GetName(), f.GetName(), name, methodName, path, annotations.ApiPath)
+		op.Parameters = append(op.Parameters, &openapi.ParameterOrReference{
+			Parameter: &openapi.Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "string"}},
+			},
+		})
+	}
+
+	parameters := op.Parameters[:0]
+	for _, param := range op.Parameters {
+		if param.Parameter != nil && param.Parameter.In == "path" && !placeholders[param.Parameter.Name] {
+			g.warn("%s.%s: path parameter '%s' is not in the path '%s %s', it is left out",
+				s.GetName(), f.GetName(), param.Parameter.Name, methodName, path)
+			continue
+		}
+		if wildcard != "" && param.Parameter != nil && param.Parameter.In == "path" && param.Parameter.Name == g.naming.ParameterName(wildcard) {
+			param.Parameter.SpecificationExtension = append(param.Parameter.SpecificationExtension, &openapi.NamedAny{
+				Name:  "x-wildcard",
+				Value: &openapi.Any{Yaml: "true"},
+			})
+		}
+		parameters = append(parameters, param)
+	}
+	op.Parameters = parameters
+}
+
+// addGatewayIntegration expands the gateway integration template of the function,
+// falling back to the one of its service, and emits it as an extension of the operation.
+func (g *OpenAPIGenerator) addGatewayIntegration(op *openapi.Operation, s *parser.Service, f *parser.Function, methodName, path string) error {
+	tmpl := utils.GetAnnotation(f.Annotations, annotations.OpenapiGatewayIntegration)
+	if len(tmpl) == 0 {
+		tmpl = utils.GetAnnotation(s.Annotations, annotations.OpenapiGatewayIntegration)
+	}
+	if len(tmpl) == 0 || tmpl[0] == "" {
+		return nil
+	}
+
+	values := map[string]string{
+		"method":      methodName,
+		"path":        path,
+		"service":     s.GetName(),
+		"function":    f.GetName(),
+		"operationId": op.OperationID,
+	}
+	var expandErr error
+	expanded := g.placeholderPattern.ReplaceAllStringFunc(tmpl[0], func(placeholder string) string {
+		value, ok := values[placeholder[2:len(placeholder)-1]]
+		if !ok {
+			if expandErr == nil {
+				expandErr = fmt.Errorf("unknown placeholder '%s'", placeholder)
+			}
+			return placeholder
+		}
+		// Escape the value so that it can be placed inside a JSON string.
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	})
+	if expandErr == nil && strings.Contains(expanded, "${") {
+		expandErr = errors.New("unterminated placeholder")
+	}
+	if expandErr != nil {
+		return fmt.Errorf("error expanding gateway integration of function '%s.%s': %s", s.GetName(), f.GetName(), expandErr)
+	}
+
+	var integration interface{}
+	if err := json.Unmarshal([]byte(expanded), &integration); err != nil {
+		return fmt.Errorf("invalid gateway integration of function '%s.%s': %s", s.GetName(), f.GetName(), err)
+	}
+
+	key := g.arguments.GatewayExtensionKey
+	if key == "" {
+		key = defaultGatewayExtensionKey
+	}
+	extension, err := newNamedAny(key, integration)
+	if err != nil {
+		return err
+	}
+	op.SpecificationExtension = append(op.SpecificationExtension, extension)
+	return nil
+}
+
+// addInfoToDocument sets the fields of the openapi.info annotation of the first
+// service declaring it and the openapi.extension extensions of the services on the
+// info of the document, then fills the contact, the license and the terms of
+// service left empty from the arguments.
+func (g *OpenAPIGenerator) addInfoToDocument(d *openapi.Document) {
+	var option *infoOption
+	for _, s := range g.ast.Services {
+		values := utils.GetAnnotation(s.Annotations, annotations.OpenapiInfo)
+		if len(values) == 0 {
+			continue
+		}
+		if option != nil {
+			g.warn("%s of service '%s' is ignored, it is already declared by an earlier service", annotations.OpenapiInfo, s.GetName())
+			continue
+		}
+		if err := utils.UnmarshalAnnotation(values, &option); err != nil {
+			g.warn("error parsing %s of service '%s': %s", annotations.OpenapiInfo, s.GetName(), err)
+			option = nil
+			continue
+		}
+		if option == nil {
+			option = &infoOption{}
+		}
+	}
+
+	info := d.Info
+	for _, s := range g.ast.Services {
+		info.SpecificationExtension = setExtensions(info.SpecificationExtension,
+			g.extensionsOption("service '"+s.GetName()+"'", utils.GetAnnotation(s.Annotations, annotations.OpenapiExtension)))
+	}
+	if option != nil {
+		setString(&info.Title, option.Title)
+		setString(&info.Description, option.Description)
+		setString(&info.Version, option.Version)
+		setString(&info.TermsOfService, option.TermsOfService)
+		if c := option.Contact; c != nil {
+			if info.Contact == nil {
+				info.Contact = &openapi.Contact{}
+			}
+			setString(&info.Contact.Name, c.Name)
+			setString(&info.Contact.Email, c.Email)
+			setString(&info.Contact.URL, c.URL)
+		}
+		if l := option.License; l != nil {
+			if info.License == nil {
+				info.License = &openapi.License{}
+			}
+			setString(&info.License.Name, l.Name)
+			setString(&info.License.URL, l.URL)
+		}
+	}
+
+	arguments := g.arguments
+	fillString(&info.TermsOfService, arguments.TermsOfService)
+	if arguments.ContactName != "" || arguments.ContactEmail != "" || arguments.ContactURL != "" {
+		if info.Contact == nil {
+			info.Contact = &openapi.Contact{}
+		}
+		fillString(&info.Contact.Name, arguments.ContactName)
+		fillString(&info.Contact.Email, arguments.ContactEmail)
+		fillString(&info.Contact.URL, arguments.ContactURL)
+	}
+	if arguments.LicenseName != "" || arguments.LicenseURL != "" {
+		if info.License == nil {
+			info.License = &openapi.License{}
+		}
+		fillString(&info.License.Name, arguments.LicenseName)
+		fillString(&info.License.URL, arguments.LicenseURL)
+	}
+}
+
+// addSecuritySchemesToDocument adds the security schemes declared by the
+// openapi.security_scheme annotations of the services and of the structs, one per
+// annotation, to the components. The first declaration of a name wins.
+func (g *OpenAPIGenerator) addSecuritySchemesToDocument(d *openapi.Document) {
+	type declaration struct {
+		owner  string
+		values []string
+	}
+	var declarations []declaration
+	for _, s := range g.ast.Services {
+		declarations = append(declarations, declaration{"service '" + s.GetName() + "'", utils.GetAnnotation(s.Annotations, annotations.OpenapiSecurityScheme)})
+	}
+	for _, st := range g.ast.Structs {
+		declarations = append(declarations, declaration{"struct '" + st.GetName() + "'", utils.GetAnnotation(st.Annotations, annotations.OpenapiSecurityScheme)})
+	}
+
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = &openapi.SecuritySchemesOrReferences{}
+	}
+	schemes := d.Components.SecuritySchemes
+	for _, declaration := range declarations {
+		for _, value := range declaration.values {
+			var option *securitySchemeOption
+			err := utils.UnmarshalAnnotation([]string{value}, &option)
+			if err == nil {
+				err = option.validate()
+			}
+			if err != nil {
+				g.warn("%s: invalid %s annotation: %s", declaration.owner, annotations.OpenapiSecurityScheme, err)
+				continue
+			}
+			if indexOfSecurityScheme(schemes, option.Name) >= 0 {
+				g.warn("%s: security scheme '%s' is already declared", declaration.owner, option.Name)
+				continue
+			}
+			schemes.AdditionalProperties = append(schemes.AdditionalProperties, &openapi.NamedSecuritySchemeOrReference{
+				Name:  option.Name,
+				Value: &openapi.SecuritySchemeOrReference{SecurityScheme: option.Scheme.securityScheme()},
+			})
+		}
+	}
+	if len(schemes.AdditionalProperties) == 0 {
+		d.Components.SecuritySchemes = nil
+	}
+}
+
+func indexOfSecurityScheme(schemes *openapi.SecuritySchemesOrReferences, name string) int {
+	for i, scheme := range schemes.AdditionalProperties {
+		if scheme.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// extensionsOption returns the vendor extensions declared by an openapi.extension
+// annotation, a JSON object whose keys start with x-, e.g. {"x-rate-limit": 100},
+// sorted by key.
+func (g *OpenAPIGenerator) extensionsOption(owner string, values []string) []*openapi.NamedAny {
+	var option map[string]interface{}
+	if err := utils.UnmarshalAnnotation(values, &option); err != nil {
+		g.warn("error parsing %s of %s: %s", annotations.OpenapiExtension, owner, err)
+		return nil
+	}
+	names := make([]string, 0, len(option))
+	for name := range option {
+		if !strings.HasPrefix(name, "x-") {
+			g.warn("%s of %s: '%s' is skipped, extensions must start with 'x-'", annotations.OpenapiExtension, owner, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var extensions []*openapi.NamedAny
+	for _, name := range names {
+		extension, err := newNamedAny(name, option[name])
+		if err != nil {
+			g.warn("%s of %s: %s", annotations.OpenapiExtension, owner, err)
+			continue
+		}
+		extensions = append(extensions, extension)
+	}
+	return extensions
+}
+
+// setExtensions sets the extensions on the list, replacing the ones with the same
+// name.
+func setExtensions(list []*openapi.NamedAny, extensions []*openapi.NamedAny) []*openapi.NamedAny {
+	for _, extension := range extensions {
+		replaced := false
+		for i, existing := range list {
+			if existing.Name == extension.Name {
+				list[i], replaced = extension, true
+				break
+			}
+		}
+		if !replaced {
+			list = append(list, extension)
+		}
+	}
+	return list
+}
+
+// sunsetOption returns the retirement date of the openapi.sunset annotation of the
+// function and its x-sunset extension, written as declared, or the zero time and nil
+// when there is none or it is invalid.
+func (g *OpenAPIGenerator) sunsetOption(s *parser.Service, f *parser.Function) (time.Time, *openapi.NamedAny) {
+	values := utils.GetAnnotation(f.Annotations, annotations.OpenapiSunset)
+	sunset, err := annotations.Sunset(values)
+	if err != nil {
+		g.warn("%s.%s: %s", s.GetName(), f.GetName(), err)
+		return time.Time{}, nil
+	}
+	if sunset.IsZero() {
+		return sunset, nil
+	}
+	extension, err := newNamedAny("x-sunset", values[0])
+	if err != nil {
+		g.warn("%s.%s: %s", s.GetName(), f.GetName(), err)
+		return time.Time{}, nil
+	}
+	return sunset, extension
+}
+
+// setString sets dst to value unless value is empty.
+func setString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// fillString sets dst to value when dst is empty.
+func fillString(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// newNamedAny wraps value into a named extension serialized as YAML.
+func newNamedAny(name string, value interface{}) (*openapi.NamedAny, error) {
+	bytes, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("error converting extension '%s' to yaml: %s", name, err)
+	}
+	return &openapi.NamedAny{
+		Name:  name,
+		Value: &openapi.Any{Yaml: string(bytes)},
+	}, nil
+}
+
+// collectServerVariables records the server variables declared on a service,
+// the first declaration of a variable wins.
+func (g *OpenAPIGenerator) collectServerVariables(s *parser.Service) error {
+	var variables map[string]*serverVariableOption
+	err := utils.UnmarshalAnnotation(utils.GetAnnotation(s.Annotations, annotations.OpenapiServerVariables), &variables)
+	if err != nil {
+		return err
+	}
+	for name, v := range variables {
+		if _, ok := g.serverVariables[name]; ok || v == nil {
+			continue
+		}
+		g.serverVariables[name] = v.variable()
+	}
+	return nil
+}
+
+// serversOption returns the servers declared by the openapi.server annotations of a
+// service or a function, one per annotation, in declaration order.
+func (g *OpenAPIGenerator) serversOption(owner string, values []string) []*openapi.Server {
+	var servers []*openapi.Server
+	for _, value := range values {
+		var option *serverOption
+		err := utils.UnmarshalAnnotation([]string{value}, &option)
+		if err == nil && (option == nil || option.URL == "") {
+			err = errors.New("the server has no url")
+		}
+		if err != nil {
+			g.warn("%s: invalid %s annotation: %s", owner, annotations.OpenapiServer, err)
+			continue
+		}
+		server := &openapi.Server{URL: option.URL, Description: option.Description}
+		names := make([]string, 0, len(option.Variables))
+		for name, v := range option.Variables {
+			if v != nil {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var variables []*openapi.NamedServerVariable
+		for _, name := range names {
+			v := option.Variables[name]
+			if len(v.Enum) > 0 && !utils.Contains(v.Enum, v.Default) {
+				g.warn("%s: default '%s' of server variable '%s' is not one of its enum values", owner, v.Default, name)
+			}
+			variables = append(variables, &openapi.NamedServerVariable{Name: name, Value: v.variable()})
+		}
+		if len(variables) > 0 {
+			server.Variables = &openapi.ServerVariables{AdditionalProperties: variables}
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+// addServerVariablesToDocument sets the variables of every server whose URL
+// references a declared server variable.
+func (g *OpenAPIGenerator) addServerVariablesToDocument(d *openapi.Document) {
+	if len(g.serverVariables) == 0 {
+		return
+	}
+	servers := d.Servers
+	for _, path := range d.Paths.Path {
+		servers = append(servers, path.Value.Servers...)
+		for _, op := range operationsOf(path.Value) {
+			servers = append(servers, op.Servers...)
+		}
+	}
+
+	// The variables declared by the server itself, e.g. by openapi.server, are kept,
+	// the missing ones are taken from openapi.server_variables.
+	for _, server := range servers {
+		var variables []*openapi.NamedServerVariable
+		var names []string
+		if server.Variables != nil {
+			variables = server.Variables.AdditionalProperties
+			for _, variable := range variables {
+				names = append(names, variable.Name)
+			}
+		}
+		for _, match := range g.variablePattern.FindAllStringSubmatch(server.URL, -1) {
+			name := match[1]
+			if utils.Contains(names, name) {
+				continue
+			}
+			variable, ok := g.serverVariables[name]
+			if !ok {
+				g.warn("server '%s' references undeclared variable '%s'", server.URL, name)
+				continue
+			}
+			names = append(names, name)
+			variables = append(variables, &openapi.NamedServerVariable{Name: name, Value: variable})
+		}
+		if len(variables) > 0 {
+			server.Variables = &openapi.ServerVariables{AdditionalProperties: variables}
+		}
+	}
+}
+
+// addPathParametersToDocument moves the parameters shared by every operation of a
+// path item to the path item itself.
+func (g *OpenAPIGenerator) addPathParametersToDocument(d *openapi.Document) {
+	for _, path := range d.Paths.Path {
+		ops := operationsOf(path.Value)
+		if len(ops) < 2 {
+			continue
+		}
+		// Parameters must be identical in every operation, otherwise hoisting
+		// one of them would drop the details of the others.
+		var shared []*openapi.ParameterOrReference
+		for _, param := range ops[0].Parameters {
+			if param.Parameter == nil {
+				continue
+			}
+			common := true
+			for _, op := range ops[1:] {
+				if indexOfParameter(op.Parameters, param) < 0 {
+					common = false
+					break
+				}
+			}
+			if common {
+				shared = append(shared, param)
+			}
+		}
+		if len(shared) == 0 {
+			continue
+		}
+
+		for _, op := range ops {
+			var params []*openapi.ParameterOrReference
+			for _, param := range op.Parameters {
+				if indexOfParameter(shared, param) < 0 {
+					params = append(params, param)
+				}
+			}
+			op.Parameters = params
+		}
+		path.Value.Parameters = append(path.Value.Parameters, shared...)
+	}
+}
+
+func indexOfParameter(params []*openapi.ParameterOrReference, param *openapi.ParameterOrReference) int {
+	for i, p := range params {
+		if reflect.DeepEqual(p, param) {
+			return i
+		}
+	}
+	return -1
+}
+
+// addConfirmParameters documents the X-Confirm header required by the generated
+// server with ConfirmMutations on the operations with a mutating verb.
+func addConfirmParameters(d *openapi.Document) {
+	for _, item := range d.Paths.Path {
+		for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+			op := operationOf(item.Value, method)
+			if op == nil {
+				continue
+			}
+			op.Parameters = append(op.Parameters, &openapi.ParameterOrReference{
+				Parameter: &openapi.Parameter{
+					Name:        "X-Confirm",
+					In:          "header",
+					Description: "Confirms the operation, which modifies data",
+					Required:    true,
+					Schema: &openapi.SchemaOrReference{
+						Schema: &openapi.Schema{
+							Type: "string",
+							Enum: []*openapi.Any{{Yaml: `"yes"`}},
+						},
+					},
+				},
+			})
+		}
+	}
+}
+
+// operationOf returns the operation of a path item for the HTTP method.
+func operationOf(item *openapi.PathItem, method string) *openapi.Operation {
+	switch method {
+	case "GET":
+		return item.Get
+	case "PUT":
+		return item.Put
+	case "POST":
+		return item.Post
+	case "DELETE":
+		return item.Delete
+	case "OPTIONS":
+		return item.Options
+	case "HEAD":
+		return item.Head
+	case "PATCH":
+		return item.Patch
+	case "TRACE":
+		return item.Trace
+	}
+	return nil
+}
+
+// operationsOf returns the operations set on a path item.
+func operationsOf(item *openapi.PathItem) []*openapi.Operation {
+	var ops []*openapi.Operation
+	for _, op := range []*openapi.Operation{
+		item.Get, item.Put, item.Post, item.Delete,
+		item.Options, item.Head, item.Patch, item.Trace,
+	} {
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func (g *OpenAPIGenerator) buildOperation(
+	d *openapi.Document,
+	methodName string,
+	description string,
+	operationID string,
+	tagName string,
+	path string,
+	host string,
+	inputDesc *thrift_reflection.StructDescriptor,
+	outputDesc *thrift_reflection.StructDescriptor,
+) (*openapi.Operation, string) {
+	// Parameters array to hold all parameter objects
+	var parameters []*openapi.ParameterOrReference
+
+	for _, v := range inputDesc.GetFields() {
+		var paramName, paramIn, paramDesc string
+		var fieldSchema *openapi.SchemaOrReference
+		required := false
+
+		if binding, ok := annotations.ParameterBinding(annotations.Bindings(v)); ok {
+			paramIn = binding.In
+			paramName = g.naming.ParameterName(binding.Name)
+			paramDesc = g.filterCommentString(v.Comments)
+			var replaced bool
+			fieldSchema, replaced = g.fieldSchema(inputDesc, v)
+			extPropertyOrNil := v.Annotations[annotations.OpenapiProperty]
+			if len(extPropertyOrNil) > 0 && !replaced {
+				newFieldSchema := &openapi.Schema{}
+				err := utils.ParseFieldOption(v, annotations.OpenapiProperty, &newFieldSchema)
+				if err != nil {
+					g.warn("struct '%s': %s", inputDesc.GetName(), err)
+				}
+				err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
+				if err != nil {
+					logs.Errorf("Error merging field option: %s", err)
+				}
+			}
+			required = binding.In == annotations.InPath
+		}
+
+		parameter := &openapi.Parameter{
+			Name:        paramName,
+			In:          paramIn,
+			Description: paramDesc,
+			Required:    required,
+			Schema:      fieldSchema,
+		}
+
+		var extParameter *openapi.Parameter
+		err := utils.ParseFieldOption(v, annotations.OpenapiParameter, &extParameter)
+		if err != nil {
+			g.warn("struct '%s': %s", inputDesc.GetName(), err)
+		}
+		err = utils.MergeStructs(parameter, extParameter)
+		if err != nil {
+			logs.Errorf("Error merging field option: %s", err)
+		}
+
+		// Append the parameter to the parameters array if it was set
+		if paramName != "" && paramIn != "" {
+			parameters = append(parameters, &openapi.ParameterOrReference{
+				Parameter: parameter,
+			})
+		}
+	}
+
+	var RequestBody *openapi.RequestBodyOrReference
+	if methodName != "GET" && methodName != "HEAD" && methodName != "DELETE" {
+		var bodySchema *openapi.SchemaOrReference
+		if field := g.inlineBodyField(inputDesc); field != nil {
+			bodySchema = g.inlineBodySchema(field)
+		} else if schema := g.getSchemaByOption(inputDesc, annotations.ApiBody); len(schema.Properties.AdditionalProperties) > 0 {
+			bodySchema = &openapi.SchemaOrReference{Schema: schema}
+		}
+		formSchema := g.getSchemaByOption(inputDesc, annotations.ApiForm)
+		rawBodySchema := g.getSchemaByOption(inputDesc, annotations.ApiRawBody)
+
+		var additionalProperties []*openapi.NamedMediaType
+		if bodySchema != nil {
+			additionalProperties = append(additionalProperties, &openapi.NamedMediaType{
+				Name: "application/json",
+				Value: &openapi.MediaType{
+					Schema: bodySchema,
+				},
+			})
 		}
 
 		if len(formSchema.Properties.AdditionalProperties) > 0 {
@@ -497,27 +1916,28 @@ func (g *OpenAPIGenerator) buildOperation(
 
 	}
 
-	name, header, content := g.getResponseForStruct(d, outputDesc)
-	desc := g.filterCommentString(outputDesc.Comments)
-
-	if desc == "" {
-		desc = "Successful response"
-	}
+	var responses *openapi.Responses
+	if outputDesc == nil {
+		// A void function answers without a body.
+		responses = noContentResponses()
+	} else if name, header, content := g.getResponseForStruct(d, outputDesc); len(header.AdditionalProperties) != 0 || len(content.AdditionalProperties) != 0 {
+		desc := g.filterCommentString(outputDesc.Comments)
+		if desc == "" {
+			desc = "Successful response"
+		}
 
-	var headerOrEmpty *openapi.HeadersOrReferences
+		var headerOrEmpty *openapi.HeadersOrReferences
 
-	if len(header.AdditionalProperties) != 0 {
-		headerOrEmpty = header
-	}
+		if len(header.AdditionalProperties) != 0 {
+			headerOrEmpty = header
+		}
 
-	var contentOrEmpty *openapi.MediaTypes
+		var contentOrEmpty *openapi.MediaTypes
 
-	if len(content.AdditionalProperties) != 0 {
-		contentOrEmpty = content
-	}
+		if len(content.AdditionalProperties) != 0 {
+			contentOrEmpty = content
+		}
 
-	var responses *openapi.Responses
-	if headerOrEmpty != nil || contentOrEmpty != nil {
 		responses = &openapi.Responses{
 			ResponseOrReference: []*openapi.NamedResponseOrReference{
 				{
@@ -534,8 +1954,11 @@ func (g *OpenAPIGenerator) buildOperation(
 		}
 	}
 
-	re := regexp.MustCompile(`:(\w+)`)
-	path = re.ReplaceAllString(path, `{$1}`)
+	// Both the named parameters, :id, and the catch-all wildcards, *filepath, become
+	// path parameters.
+	path = g.pathParamPattern.ReplaceAllStringFunc(path, func(param string) string {
+		return "{" + g.naming.ParameterName(param[1:]) + "}"
+	})
 
 	op := &openapi.Operation{
 		Tags:        []string{tagName},
@@ -559,14 +1982,14 @@ func (g *OpenAPIGenerator) buildOperation(
 func (g *OpenAPIGenerator) getDocumentAnnotationInWhichServiceOrStruct() (string, string) {
 	var ret string
 	for _, s := range g.ast.Services {
-		v := s.Annotations.Get(OpenapiDocument)
+		v := s.Annotations.Get(annotations.OpenapiDocument)
 		if len(v) > 0 {
 			ret = s.GetName()
 			return "service", ret
 		}
 	}
 	for _, s := range g.ast.Structs {
-		v := s.Annotations.Get(OpenapiDocument)
+		v := s.Annotations.Get(annotations.OpenapiDocument)
 		if len(v) > 0 {
 			ret = s.GetName()
 			return "struct", ret
@@ -579,11 +2002,11 @@ func (g *OpenAPIGenerator) getResponseForStruct(d *openapi.Document, desc *thrif
 	headers := &openapi.HeadersOrReferences{AdditionalProperties: []*openapi.NamedHeaderOrReference{}}
 
 	for _, field := range desc.Fields {
-		if len(field.Annotations[ApiHeader]) < 1 {
-			continue
-		}
-		if ext := field.Annotations[ApiHeader][0]; ext != "" {
-			headerName := ext
+		for _, binding := range annotations.Bindings(field) {
+			if binding.In != annotations.InHeader {
+				continue
+			}
+			headerName := g.naming.ParameterName(binding.Name)
 			header := &openapi.Header{
 				Description: g.filterCommentString(field.Comments),
 				Schema:      g.schemaOrReferenceForField(field.Type),
@@ -598,16 +2021,29 @@ func (g *OpenAPIGenerator) getResponseForStruct(d *openapi.Document, desc *thrif
 	}
 
 	// Get api.body and api.raw_body option schema
-	bodySchema := g.getSchemaByOption(desc, ApiBody)
-	rawBodySchema := g.getSchemaByOption(desc, ApiRawBody)
+	bodySchema := g.getSchemaByOption(desc, annotations.ApiBody)
+	rawBodySchema := g.getSchemaByOption(desc, annotations.ApiRawBody)
 	var additionalProperties []*openapi.NamedMediaType
 
-	if len(bodySchema.Properties.AdditionalProperties) > 0 {
+	if field := statusField(desc); field != nil && g.arguments.StripStatusField {
+		removeProperty(bodySchema, g.naming.PropertyName(statusFieldName(field)))
+	}
+
+	if field := g.inlineBodyField(desc); field != nil {
+		if schema := g.inlineBodySchema(field); schema != nil {
+			additionalProperties = append(additionalProperties, &openapi.NamedMediaType{
+				Name: "application/json",
+				Value: &openapi.MediaType{
+					Schema: schema,
+				},
+			})
+		}
+	} else if len(bodySchema.Properties.AdditionalProperties) > 0 {
 		refSchema := &openapi.NamedSchemaOrReference{
-			Name:  desc.GetName() + "Body",
+			Name:  g.naming.SchemaName(g.schemaNameForMessage(desc) + "Body"),
 			Value: &openapi.SchemaOrReference{Schema: bodySchema},
 		}
-		ref := "#/components/schemas/" + desc.GetName() + "Body"
+		ref := "#/components/schemas/" + refSchema.Name
 		g.addSchemaToDocument(d, refSchema)
 		additionalProperties = append(additionalProperties, &openapi.NamedMediaType{
 			Name: "application/json",
@@ -619,12 +2055,25 @@ func (g *OpenAPIGenerator) getResponseForStruct(d *openapi.Document, desc *thrif
 		})
 	}
 
-	if len(rawBodySchema.Properties.AdditionalProperties) > 0 {
+	if field := binaryRawBodyField(desc); field != nil {
+		additionalProperties = append(additionalProperties, &openapi.NamedMediaType{
+			Name: "application/octet-stream",
+			Value: &openapi.MediaType{
+				Schema: &openapi.SchemaOrReference{
+					Schema: &openapi.Schema{
+						Type:        "string",
+						Format:      "binary",
+						Description: g.filterCommentString(field.Comments),
+					},
+				},
+			},
+		})
+	} else if len(rawBodySchema.Properties.AdditionalProperties) > 0 {
 		refSchema := &openapi.NamedSchemaOrReference{
-			Name:  desc.GetName() + "RawBody",
+			Name:  g.naming.SchemaName(g.schemaNameForMessage(desc) + "RawBody"),
 			Value: &openapi.SchemaOrReference{Schema: rawBodySchema},
 		}
-		ref := "#/components/schemas/" + desc.GetName() + "RawBody"
+		ref := "#/components/schemas/" + refSchema.Name
 		g.addSchemaToDocument(d, refSchema)
 		additionalProperties = append(additionalProperties, &openapi.NamedMediaType{
 			Name: "text/plain",
@@ -643,6 +2092,251 @@ func (g *OpenAPIGenerator) getResponseForStruct(d *openapi.Document, desc *thrif
 	return "200", headers, content
 }
 
+// addExceptionResponses documents the exceptions thrown by the method as error
+// responses keyed by the api.http_code of the exception. The generated server
+// answers an exception with its fields, exceptions sharing a status are documented
+// with oneOf.
+func (g *OpenAPIGenerator) addExceptionResponses(op *openapi.Operation, methodDesc *thrift_reflection.MethodDescriptor) {
+	if methodDesc == nil {
+		return
+	}
+	var statuses []int
+	schemas := make(map[int][]*openapi.SchemaOrReference)
+	descriptions := make(map[int][]string)
+	for _, field := range methodDesc.GetThrowExceptions() {
+		exception, err := field.GetType().GetExceptionDescriptor()
+		if err != nil || exception == nil {
+			logs.Errorf("Error getting exception descriptor of '%s': %v", field.GetName(), err)
+			continue
+		}
+		status, err := annotations.ExceptionStatus(exception.Annotations[annotations.ApiHttpCode])
+		if err != nil {
+			g.warn("%s: %s", exception.GetName(), err)
+		}
+		if _, ok := schemas[status]; !ok {
+			statuses = append(statuses, status)
+		}
+		ref := g.schemaReferenceForMessage(&thrift_reflection.StructDescriptor{
+			Filepath:    exception.Filepath,
+			Name:        exception.Name,
+			Fields:      exception.Fields,
+			Annotations: exception.Annotations,
+			Comments:    exception.Comments,
+		})
+		schemas[status] = append(schemas[status], &openapi.SchemaOrReference{
+			Reference: &openapi.Reference{Xref: ref},
+		})
+		description := g.filterCommentString(exception.Comments)
+		if description == "" {
+			description = exception.GetName()
+		}
+		descriptions[status] = append(descriptions[status], description)
+	}
+	if len(statuses) == 0 {
+		return
+	}
+
+	sort.Ints(statuses)
+	if op.Responses == nil {
+		op.Responses = &openapi.Responses{}
+	}
+	for _, status := range statuses {
+		schema := schemas[status][0]
+		if len(schemas[status]) > 1 {
+			schema = &openapi.SchemaOrReference{
+				Schema: &openapi.Schema{OneOf: schemas[status]},
+			}
+		}
+		op.Responses.ResponseOrReference = append(op.Responses.ResponseOrReference, &openapi.NamedResponseOrReference{
+			Name: strconv.Itoa(status),
+			Value: &openapi.ResponseOrReference{
+				Response: &openapi.Response{
+					Description: strings.Join(descriptions[status], "\n"),
+					Content: &openapi.MediaTypes{
+						AdditionalProperties: []*openapi.NamedMediaType{{
+							Name:  "application/json",
+							Value: &openapi.MediaType{Schema: schema},
+						}},
+					},
+				},
+			},
+		})
+	}
+}
+
+// binaryRawBodyField returns the api.raw_body field of a response struct when it is
+// its only one and is binary, the response is then a download of its bytes.
+func binaryRawBodyField(desc *thrift_reflection.StructDescriptor) *thrift_reflection.FieldDescriptor {
+	var raw []*thrift_reflection.FieldDescriptor
+	for _, field := range desc.GetFields() {
+		for _, binding := range annotations.Bindings(field) {
+			if binding.In == annotations.InRawBody {
+				raw = append(raw, field)
+			}
+		}
+	}
+	if len(raw) != 1 || raw[0].GetType().GetName() != "binary" {
+		return nil
+	}
+	return raw[0]
+}
+
+// statusField returns the field of a response struct annotated with api.http_code
+// = "true", the generated server answers with the status it carries.
+func statusField(desc *thrift_reflection.StructDescriptor) *thrift_reflection.FieldDescriptor {
+	if desc == nil {
+		return nil
+	}
+	for _, field := range desc.GetFields() {
+		if annotations.IsStatusField(field.Annotations[annotations.ApiHttpCode]) {
+			return field
+		}
+	}
+	return nil
+}
+
+// statusFieldName returns the name of the status field in the response body.
+func statusFieldName(field *thrift_reflection.FieldDescriptor) string {
+	for _, binding := range annotations.Bindings(field) {
+		if binding.In == annotations.InBody {
+			return binding.Name
+		}
+	}
+	return field.GetName()
+}
+
+func removeProperty(schema *openapi.Schema, name string) {
+	properties := schema.Properties.AdditionalProperties[:0]
+	for _, property := range schema.Properties.AdditionalProperties {
+		if property.Name != name {
+			properties = append(properties, property)
+		}
+	}
+	schema.Properties.AdditionalProperties = properties
+	required := schema.Required[:0]
+	for _, r := range schema.Required {
+		if r != name {
+			required = append(required, r)
+		}
+	}
+	schema.Required = required
+}
+
+// addStatusResponses documents the statuses a response may be answered with through
+// its status field, along with the content of the successful response. The values of
+// an enum field are documented one by one, any other field gives a default response.
+// Statuses already documented, e.g. by an exception, are left as they are.
+func (g *OpenAPIGenerator) addStatusResponses(op *openapi.Operation, desc *thrift_reflection.StructDescriptor) {
+	field := statusField(desc)
+	if field == nil {
+		return
+	}
+
+	descriptions := make(map[int]string)
+	var statuses []int
+	if field.GetType().IsEnum() {
+		enum, err := field.GetType().GetEnumDescriptor()
+		if err != nil || enum == nil {
+			logs.Errorf("Error getting enum descriptor of '%s': %v", field.GetName(), err)
+		} else {
+			for _, value := range enum.GetValues() {
+				if value.Value < 100 || value.Value > 599 {
+					g.warn("%s.%s: %s value %s is not an HTTP status", desc.GetName(), field.GetName(), enum.GetName(), value.Name)
+					continue
+				}
+				status := int(value.Value)
+				if _, ok := descriptions[status]; !ok {
+					statuses = append(statuses, status)
+				}
+				descriptions[status] = g.filterCommentString(value.Comments)
+			}
+		}
+	}
+	sort.Ints(statuses)
+
+	if op.Responses == nil {
+		op.Responses = &openapi.Responses{}
+	}
+	var success *openapi.Response
+	documented := make(map[string]bool)
+	for _, response := range op.Responses.ResponseOrReference {
+		documented[response.Name] = true
+		if response.Name == "200" && response.Value != nil {
+			success = response.Value.Response
+		}
+	}
+	names := []string{"default"}
+	if len(statuses) > 0 {
+		names = names[:0]
+		for _, status := range statuses {
+			names = append(names, strconv.Itoa(status))
+		}
+	}
+	for i, name := range names {
+		if documented[name] {
+			continue
+		}
+		description := fmt.Sprintf("Response with the status set by %s", field.GetName())
+		if len(statuses) > 0 && descriptions[statuses[i]] != "" {
+			description = descriptions[statuses[i]]
+		}
+		response := &openapi.Response{Description: description}
+		if success != nil {
+			response.Headers, response.Content = success.Headers, success.Content
+		}
+		op.Responses.ResponseOrReference = append(op.Responses.ResponseOrReference, &openapi.NamedResponseOrReference{
+			Name:  name,
+			Value: &openapi.ResponseOrReference{Response: response},
+		})
+	}
+}
+
+// inlineBodyField returns the api.body field annotated with openapi.body_inline, the
+// body is then documented as the schema of the field itself, e.g. a struct, a map or
+// a list, instead of an object holding the field. The annotation is ignored unless
+// the field is the only api.body field of the struct.
+func (g *OpenAPIGenerator) inlineBodyField(desc *thrift_reflection.StructDescriptor) *thrift_reflection.FieldDescriptor {
+	var inline *thrift_reflection.FieldDescriptor
+	bodies := 0
+	for _, field := range desc.GetFields() {
+		for _, binding := range annotations.Bindings(field) {
+			if binding.In != annotations.InBody {
+				continue
+			}
+			bodies++
+			if utils.Contains(field.Annotations[annotations.OpenapiBodyInline], "true") {
+				inline = field
+			}
+		}
+	}
+	if inline != nil && bodies > 1 {
+		g.warn("%s.%s: %s is ignored, the field is not the only %s field",
+			desc.GetName(), inline.GetName(), annotations.OpenapiBodyInline, annotations.ApiBody)
+		return nil
+	}
+	return inline
+}
+
+// inlineBodySchema returns the schema of an inline body field, supplemented with its
+// openapi.property annotation like the properties of a wrapped body.
+func (g *OpenAPIGenerator) inlineBodySchema(field *thrift_reflection.FieldDescriptor) *openapi.SchemaOrReference {
+	fieldSchema := g.schemaOrReferenceForField(field.Type)
+	if fieldSchema == nil || !fieldSchema.IsSetSchema() {
+		return fieldSchema
+	}
+	fieldSchema.Schema.Description = fieldDescription(g.filterCommentString(field.Comments), fieldSchema.Schema.Description)
+	newFieldSchema := &openapi.Schema{}
+	err := utils.ParseFieldOption(field, annotations.OpenapiProperty, &newFieldSchema)
+	if err != nil {
+		g.warn("%s", err)
+	}
+	err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
+	if err != nil {
+		logs.Errorf("Error merging field option: %s", err)
+	}
+	return fieldSchema
+}
+
 func (g *OpenAPIGenerator) getSchemaByOption(inputDesc *thrift_reflection.StructDescriptor, option string) *openapi.Schema {
 	definitionProperties := &openapi.Properties{
 		AdditionalProperties: make([]*openapi.NamedSchemaOrReference, 0),
@@ -650,9 +2344,9 @@ func (g *OpenAPIGenerator) getSchemaByOption(inputDesc *thrift_reflection.Struct
 
 	var allRequired []string
 	var extSchema *openapi.Schema
-	err := utils.ParseStructOption(inputDesc, OpenapiSchema, &extSchema)
+	err := utils.ParseStructOption(inputDesc, annotations.OpenapiSchema, &extSchema)
 	if err != nil {
-		logs.Errorf("Error parsing struct option: %s", err)
+		g.warn("%s", err)
 	}
 	if extSchema != nil {
 		if extSchema.Required != nil {
@@ -660,31 +2354,33 @@ func (g *OpenAPIGenerator) getSchemaByOption(inputDesc *thrift_reflection.Struct
 		}
 	}
 
+	// The properties follow the declaration order of the fields, like the ones of
+	// the component schemas.
 	var required []string
 	for _, field := range inputDesc.GetFields() {
-		if field.Annotations[option] != nil {
-			extName := field.GetName()
-			if field.Annotations[option] != nil && field.Annotations[option][0] != "" {
-				extName = field.Annotations[option][0]
+		for _, binding := range annotations.Bindings(field) {
+			if binding.Annotation != option {
+				continue
 			}
+			extName := g.naming.PropertyName(binding.Name)
 
-			if utils.Contains(allRequired, extName) {
+			if utils.Contains(allRequired, binding.Name) {
 				required = append(required, extName)
 			}
 
 			// Get the field description from the comments.
 			description := g.filterCommentString(field.Comments)
-			fieldSchema := g.schemaOrReferenceForField(field.Type)
+			fieldSchema, replaced := g.fieldSchema(inputDesc, field)
 			if fieldSchema == nil {
 				continue
 			}
 
-			if fieldSchema.IsSetSchema() {
-				fieldSchema.Schema.Description = description
+			if fieldSchema.IsSetSchema() && !replaced {
+				fieldSchema.Schema.Description = fieldDescription(description, fieldSchema.Schema.Description)
 				newFieldSchema := &openapi.Schema{}
-				err := utils.ParseFieldOption(field, OpenapiProperty, &newFieldSchema)
+				err := utils.ParseFieldOption(field, annotations.OpenapiProperty, &newFieldSchema)
 				if err != nil {
-					logs.Errorf("Error parsing field option: %s", err)
+					g.warn("struct '%s': %s", inputDesc.GetName(), err)
 				}
 				err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
 				if err != nil {
@@ -692,180 +2388,504 @@ func (g *OpenAPIGenerator) getSchemaByOption(inputDesc *thrift_reflection.Struct
 				}
 			}
 
-			definitionProperties.AdditionalProperties = append(
-				definitionProperties.AdditionalProperties,
-				&openapi.NamedSchemaOrReference{
-					Name:  extName,
-					Value: fieldSchema,
+			definitionProperties.AdditionalProperties = append(
+				definitionProperties.AdditionalProperties,
+				&openapi.NamedSchemaOrReference{
+					Name:  extName,
+					Value: fieldSchema,
+				},
+			)
+		}
+	}
+
+	schema := &openapi.Schema{
+		Type:       "object",
+		Properties: definitionProperties,
+	}
+
+	if extSchema != nil {
+		err := utils.MergeStructs(schema, extSchema)
+		if err != nil {
+			logs.Errorf("Error merging struct option: %s", err)
+		}
+	}
+
+	schema.Required = required
+	return schema
+}
+
+// filterCommentString removes linter rules from comments.
+func (g *OpenAPIGenerator) filterCommentString(str string) string {
+	str = g.sanitizeComment(str)
+	markdown := g.arguments.MarkdownDescriptions
+	var comments []string
+	matches := g.commentPattern.FindAllStringSubmatch(str, -1)
+
+	for _, match := range matches {
+		var comment string
+		if match[2] == "" {
+			// One-line comment, the extra slashes of /// are dropped. An empty one
+			// separates paragraphs, in Markdown the indentation after the first
+			// space and the repeated empty ones are kept.
+			comment = strings.TrimLeft(match[1], "/")
+			if markdown {
+				comment = strings.TrimRight(strings.TrimPrefix(comment, " "), " \t\r")
+			} else {
+				comment = strings.TrimSpace(comment)
+			}
+			if comment == "" && (len(comments) == 0 || comments[len(comments)-1] == "" && !markdown) {
+				continue
+			}
+		} else {
+			// Multiline comment
+			comment = blockComment(match[2], markdown)
+			if comment == "" {
+				continue
+			}
+		}
+		comments = append(comments, comment)
+	}
+	for len(comments) > 0 && comments[len(comments)-1] == "" {
+		comments = comments[:len(comments)-1]
+	}
+
+	return truncateDescription(g.localizeComment(strings.Join(comments, "\n")), g.arguments.MaxDescriptionLength)
+}
+
+// truncateDescription cuts a description longer than max runes at the last space
+// before the limit, or at the limit in a word longer than it, and appends "...".
+func truncateDescription(description string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(description) <= max {
+		return description
+	}
+	runes := []rune(description)[:max+1]
+	end := max
+	for i := max; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			end = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:end]), unicode.IsSpace) + "..."
+}
+
+// blockComment returns the text of a /* */ comment. The leading asterisk of a line
+// and the space after it are removed and the rest of its indentation is kept, e.g.
+// in code examples, the blank lines around the text are dropped. The lines without
+// asterisk are unindented, in markdown only by their common indentation.
+func blockComment(body string, markdown bool) string {
+	lines := strings.Split(body, "\n")
+	var plain []int
+	indent := -1
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case strings.HasPrefix(trimmed, "*"):
+			line = strings.TrimPrefix(trimmed[1:], " ")
+		case !markdown || trimmed == "":
+			line = trimmed
+		default:
+			plain = append(plain, i)
+			if n := len(line) - len(trimmed); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+		lines[i] = line
+	}
+	for _, i := range plain {
+		lines[i] = lines[i][indent:]
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sanitizeComment makes a comment safe to emit as a description: it is truncated to
+// maxCommentLength, invalid UTF-8 is replaced and the control characters other than
+// tabs and line feeds are removed.
+func (g *OpenAPIGenerator) sanitizeComment(str string) string {
+	if len(str) > maxCommentLength {
+		g.warn("comment of %d bytes is truncated to %d bytes", len(str), maxCommentLength)
+		end := maxCommentLength
+		for end > 0 && !utf8.RuneStart(str[end]) {
+			end--
+		}
+		str = str[:end]
+	}
+	if !utf8.ValidString(str) {
+		g.warn("comment %.64q is not valid UTF-8, the invalid bytes are replaced", str)
+		str = strings.ToValidUTF8(str, string(utf8.RuneError))
+	}
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, str)
+}
+
+// addSchemaForStructToDocument adds the schema of a struct to the components,
+// structs excluded by the active profiles are left out.
+func (g *OpenAPIGenerator) addSchemaForStructToDocument(d *openapi.Document, schemaName string, structDesc *thrift_reflection.StructDescriptor) {
+	if structDesc == nil || utils.Contains(g.generatedSchemas, schemaName) {
+		return
+	}
+	if !annotations.ProfileActive(structDesc.Annotations[annotations.OpenapiOnlyIf], g.arguments.Profiles) {
+		return
+	}
+
+	// Get the description from the comments.
+	messageDescription := g.filterCommentString(structDesc.Comments)
+
+	// Build an array holding the fields of the message. The properties follow the
+	// declaration order of the fields, not their IDs, whatever the Order: merging
+	// openapi.schema updates them in place and appends the properties it adds.
+	definitionProperties := &openapi.Properties{
+		AdditionalProperties: make([]*openapi.NamedSchemaOrReference, 0),
+	}
+
+	for _, field := range structDesc.Fields {
+		// Get the field description from the comments.
+		description := g.filterCommentString(field.Comments)
+		fieldSchema, replaced := g.fieldSchema(structDesc, field)
+		if fieldSchema == nil {
+			continue
+		}
+
+		if fieldSchema.IsSetSchema() && !replaced {
+			fieldSchema.Schema.Description = fieldDescription(description, fieldSchema.Schema.Description)
+			newFieldSchema := &openapi.Schema{}
+			err := utils.ParseFieldOption(field, annotations.OpenapiProperty, &newFieldSchema)
+			if err != nil {
+				g.warn("struct '%s': %s", structDesc.GetName(), err)
+			}
+			err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
+			if err != nil {
+				logs.Errorf("Error merging field option: %s", err)
+			}
+		}
+
+		extName := g.naming.PropertyName(annotations.PropertyName(field))
+
+		definitionProperties.AdditionalProperties = append(
+			definitionProperties.AdditionalProperties,
+			&openapi.NamedSchemaOrReference{
+				Name:  extName,
+				Value: fieldSchema,
+			},
+		)
+	}
+
+	schema := &openapi.Schema{
+		Type:        "object",
+		Description: messageDescription,
+		Properties:  definitionProperties,
+	}
+
+	var extSchema *openapi.Schema
+	err := utils.ParseStructOption(structDesc, annotations.OpenapiSchema, &extSchema)
+	if err != nil {
+		g.warn("%s", err)
+	}
+	if extSchema != nil {
+		err = utils.MergeStructs(schema, extSchema)
+		if err != nil {
+			logs.Errorf("Error merging struct option: %s", err)
+		}
+	}
+	// The examples of the schemas are new in OAS 3.1, the documents of the earlier
+	// versions only add them to the media types of the responses.
+	if strings.HasPrefix(d.Openapi, "3.1") {
+		for _, example := range g.structExamples(structDesc) {
+			if example != nil {
+				schema.Examples = append(schema.Examples, example.example())
+			}
+		}
+	}
+	schema.SpecificationExtension = setExtensions(schema.SpecificationExtension,
+		g.extensionsOption("struct '"+structDesc.GetName()+"'", structDesc.Annotations[annotations.OpenapiExtension]))
+
+	// Add the schema to the components.schema list.
+	g.addSchemaToDocument(d, &openapi.NamedSchemaOrReference{
+		Name: schemaName,
+		Value: &openapi.SchemaOrReference{
+			Schema: schema,
+		},
+	})
+}
+
+// addArgumentsSchema adds the schema Service_Function_Request wrapping the arguments
+// of a function taking several of them, each one being a property named after the
+// argument, and returns its name.
+func (g *OpenAPIGenerator) addArgumentsSchema(d *openapi.Document, s *parser.Service, f *parser.Function) string {
+	schemaName := g.naming.SchemaName(s.GetName() + "_" + f.GetName() + "_Request")
+	if owner, ok := g.schemaOwners[schemaName]; ok {
+		g.warn("%s.%s: the arguments schema '%s' has the name of struct '%s'",
+			s.GetName(), f.GetName(), schemaName, owner.GetName())
+	}
+
+	properties := &openapi.Properties{}
+	for _, arg := range g.fileDesc.GetMethodDescriptor(s.GetName(), f.GetName()).GetArgs() {
+		fieldSchema := g.schemaOrReferenceForField(arg.Type)
+		if fieldSchema == nil {
+			continue
+		}
+		if fieldSchema.IsSetSchema() {
+			fieldSchema.Schema.Description = fieldDescription(g.filterCommentString(arg.Comments), fieldSchema.Schema.Description)
+		}
+		properties.AdditionalProperties = append(properties.AdditionalProperties, &openapi.NamedSchemaOrReference{
+			Name:  g.naming.PropertyName(arg.GetName()),
+			Value: fieldSchema,
+		})
+	}
+
+	g.addSchemaToDocument(d, &openapi.NamedSchemaOrReference{
+		Name: schemaName,
+		Value: &openapi.SchemaOrReference{
+			Schema: &openapi.Schema{
+				Type:        "object",
+				Description: "Arguments of " + s.GetName() + "." + f.GetName(),
+				Properties:  properties,
+			},
+		},
+	})
+	return schemaName
+}
+
+// fieldSchema returns the schema of a field, the one of its openapi.schema annotation
+// when it has one, which replaces the derived schema and is reported as replaced.
+func (g *OpenAPIGenerator) fieldSchema(owner *thrift_reflection.StructDescriptor, field *thrift_reflection.FieldDescriptor) (*openapi.SchemaOrReference, bool) {
+	if len(field.Annotations[annotations.OpenapiSchema]) > 0 {
+		var schema *openapi.SchemaOrReference
+		err := utils.ParseFieldOption(field, annotations.OpenapiSchema, &schema)
+		if err == nil && (schema == nil || schema.Schema == nil && schema.Reference == nil) {
+			err = fmt.Errorf("field '%s': %s has neither schema nor reference", field.GetName(), annotations.OpenapiSchema)
+		}
+		if err == nil {
+			return schema, true
+		}
+		g.warn("struct '%s': %s", owner.GetName(), err)
+	}
+	fieldSchema := g.schemaOrReferenceForField(field.Type)
+	if fieldSchema != nil {
+		g.addFieldEnum(owner, field, fieldSchema)
+	}
+	return fieldSchema, false
+}
+
+// addFieldEnum restricts the string schema of a field to the values of its
+// openapi.enum annotation. An invalid annotation is reported once for the field.
+func (g *OpenAPIGenerator) addFieldEnum(owner *thrift_reflection.StructDescriptor, field *thrift_reflection.FieldDescriptor, schema *openapi.SchemaOrReference) {
+	enum, err := annotations.EnumValues(field.Annotations[annotations.OpenapiEnum])
+	if err == nil && len(enum) > 0 && (!schema.IsSetSchema() || schema.Schema.Type != "string") {
+		err = fmt.Errorf("%s only applies to string fields", annotations.OpenapiEnum)
+	}
+	if err != nil {
+		if !g.reportedEnums[field] {
+			g.reportedEnums[field] = true
+			g.warn("%s.%s: %s", owner.GetName(), field.GetName(), err)
+		}
+		return
+	}
+	for _, value := range enum {
+		quoted, _ := json.Marshal(value)
+		schema.Schema.Enum = append(schema.Schema.Enum, &openapi.Any{Yaml: string(quoted)})
+	}
+}
+
+// addSchemaToDocument adds the schema to the document if required
+func (g *OpenAPIGenerator) addSchemaToDocument(d *openapi.Document, schema *openapi.NamedSchemaOrReference) {
+	if utils.Contains(g.generatedSchemas, schema.Name) {
+		return
+	}
+	g.generatedSchemas = append(g.generatedSchemas, schema.Name)
+	d.Components.Schemas.AdditionalProperties = append(d.Components.Schemas.AdditionalProperties, schema)
+}
+
+// setDefaultBodies documents the whole argument and result of a function routed by
+// DefaultMapping or AutoOperations as the JSON bodies of the request and of the response.
+func (g *OpenAPIGenerator) setDefaultBodies(op *openapi.Operation, inputDesc, outputDesc *thrift_reflection.StructDescriptor) {
+	if inputDesc != nil {
+		op.RequestBody = &openapi.RequestBodyOrReference{
+			RequestBody: &openapi.RequestBody{
+				Description: g.filterCommentString(inputDesc.Comments),
+				Required:    true,
+				Content:     jsonContent(g.schemaReferenceForMessage(inputDesc)),
+			},
+		}
+	}
+	if outputDesc != nil {
+		description := g.filterCommentString(outputDesc.Comments)
+		if description == "" {
+			description = "Successful response"
+		}
+		op.Responses = &openapi.Responses{
+			ResponseOrReference: []*openapi.NamedResponseOrReference{
+				{
+					Name: "200",
+					Value: &openapi.ResponseOrReference{
+						Response: &openapi.Response{
+							Description: description,
+							Content:     jsonContent(g.schemaReferenceForMessage(outputDesc)),
+						},
+					},
 				},
-			)
+			},
 		}
 	}
+}
 
-	schema := &openapi.Schema{
-		Type:       "object",
-		Properties: definitionProperties,
+// applyResponseOption merges the openapi.response annotation of the function into its
+// successful response: the description replaces the one of the result struct or the
+// default one, and the examples are added to every media type of the response after
+// the openapi.examples of the result struct, named example1, example2... The headers
+// and content of the response are kept.
+func (g *OpenAPIGenerator) applyResponseOption(op *openapi.Operation, s *parser.Service, f *parser.Function, outputDesc *thrift_reflection.StructDescriptor) {
+	var structExamples []*exampleOption
+	if outputDesc != nil {
+		structExamples = g.structExamples(outputDesc)
+	}
+	var option responseOption
+	err := utils.UnmarshalAnnotation(utils.GetAnnotation(f.Annotations, annotations.OpenapiResponse), &option)
+	if err != nil {
+		g.warn("%s.%s: invalid %s: %s", s.GetName(), f.GetName(), annotations.OpenapiResponse, err)
+		return
 	}
 
-	if extSchema != nil {
-		err := utils.MergeStructs(schema, extSchema)
-		if err != nil {
-			logs.Errorf("Error merging struct option: %s", err)
-		}
+	response := successResponse(op)
+	if response == nil {
+		return
+	}
+	if option.Description != "" {
+		response.Description = option.Description
 	}
 
-	schema.Required = required
-	return schema
+	examples := &openapi.ExamplesOrReferences{}
+	for i, example := range structExamples {
+		if example != nil {
+			setExample(examples, fmt.Sprintf("example%d", i+1), example.example())
+		}
+	}
+	names := make([]string, 0, len(option.Examples))
+	for name := range option.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if option.Examples[name] != nil {
+			setExample(examples, name, option.Examples[name].example())
+		}
+	}
+	if len(examples.AdditionalProperties) == 0 {
+		return
+	}
+	if response.Content == nil || len(response.Content.AdditionalProperties) == 0 {
+		g.warn("%s.%s: the response has no content, its examples are left out", s.GetName(), f.GetName())
+		return
+	}
+	for _, mediaType := range response.Content.AdditionalProperties {
+		if mediaType.Value != nil {
+			mediaType.Value.Examples = examples
+		}
+	}
 }
 
-func (g *OpenAPIGenerator) getStructLikeByName(name string) *parser.StructLike {
-	for _, s := range g.ast.GetStructLikes() {
-		if s.GetName() == name {
-			return s
+// successResponse returns the first 2XX response of the operation.
+func successResponse(op *openapi.Operation) *openapi.Response {
+	if op.Responses == nil {
+		return nil
+	}
+	for _, response := range op.Responses.ResponseOrReference {
+		if strings.HasPrefix(response.Name, "2") && response.Value != nil && response.Value.Response != nil {
+			return response.Value.Response
 		}
 	}
 	return nil
 }
 
-// filterCommentString removes linter rules from comments.
-func (g *OpenAPIGenerator) filterCommentString(str string) string {
-	var comments []string
-	matches := g.commentPattern.FindAllStringSubmatch(str, -1)
-
-	for _, match := range matches {
-		var comment string
-		if match[1] != "" {
-			// One-line comment
-			comment = strings.TrimSpace(match[1])
-		} else if match[2] != "" {
-			// Multiline comment
-			multiLineComment := match[2]
-			lines := strings.Split(multiLineComment, "\n")
-			for i, line := range lines {
-				lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
-			}
-			comment = strings.Join(lines, "\n")
+// setExample adds the named example, replacing the one of the same name.
+func setExample(examples *openapi.ExamplesOrReferences, name string, example *openapi.Example) {
+	value := &openapi.ExampleOrReference{Example: example}
+	for _, named := range examples.AdditionalProperties {
+		if named.Name == name {
+			named.Value = value
+			return
 		}
-		comments = append(comments, comment)
 	}
-
-	return strings.Join(comments, "\n")
+	examples.AdditionalProperties = append(examples.AdditionalProperties, &openapi.NamedExampleOrReference{Name: name, Value: value})
 }
 
-func (g *OpenAPIGenerator) addSchemasForStructsToDocument(d *openapi.Document, structs []*parser.StructLike) {
-	// Handle nested structs
-	for _, s := range structs {
-		var sls []*parser.StructLike
-		for _, f := range s.GetFields() {
-			if f.GetType().GetCategory().IsStruct() {
-				sls = append(sls, g.getStructLikeByName(f.GetType().GetName()))
-			}
-		}
-		g.addSchemasForStructsToDocument(d, sls)
-
-		schemaName := s.GetName()
-		// Only generate this if we need it and haven't already generated it.
-		if !utils.Contains(g.requiredSchemas, schemaName) ||
-			utils.Contains(g.generatedSchemas, schemaName) {
-			continue
-		}
-
-		structDesc := g.fileDesc.GetStructDescriptor(s.GetName())
-
-		// Get the description from the comments.
-		messageDescription := g.filterCommentString(structDesc.Comments)
-
-		// Build an array holding the fields of the message.
-		definitionProperties := &openapi.Properties{
-			AdditionalProperties: make([]*openapi.NamedSchemaOrReference, 0),
-		}
-
-		for _, field := range structDesc.Fields {
-			// Get the field description from the comments.
-			description := g.filterCommentString(field.Comments)
-			fieldSchema := g.schemaOrReferenceForField(field.Type)
-			if fieldSchema == nil {
-				continue
-			}
+// noContentResponses returns the 204 response of a void function.
+func noContentResponses() *openapi.Responses {
+	return emptyResponses("204", "No Content")
+}
 
-			if fieldSchema.IsSetSchema() {
-				fieldSchema.Schema.Description = description
-				newFieldSchema := &openapi.Schema{}
-				err := utils.ParseFieldOption(field, OpenapiProperty, &newFieldSchema)
-				if err != nil {
-					logs.Errorf("Error parsing field option: %s", err)
-				}
-				err = utils.MergeStructs(fieldSchema.Schema, newFieldSchema)
-				if err != nil {
-					logs.Errorf("Error merging field option: %s", err)
-				}
-			}
+// acceptedResponses returns the 202 response of a oneway function, whose client
+// does not wait for the call to complete.
+func acceptedResponses() *openapi.Responses {
+	return emptyResponses("202", "Request accepted")
+}
 
-			extName := field.GetName()
-			options := []string{ApiHeader, ApiBody, ApiForm, ApiRawBody}
-			for _, option := range options {
-				if field.Annotations[option] != nil && field.Annotations[option][0] != "" {
-					extName = field.Annotations[option][0]
-				}
-			}
+// undocumentedResponses returns the default response of a function whose result is
+// not a struct, an operation must declare at least one response.
+func undocumentedResponses() *openapi.Responses {
+	return emptyResponses("default", "Response not documented, the result is not a struct")
+}
 
-			definitionProperties.AdditionalProperties = append(
-				definitionProperties.AdditionalProperties,
-				&openapi.NamedSchemaOrReference{
-					Name:  extName,
-					Value: fieldSchema,
+func emptyResponses(code, description string) *openapi.Responses {
+	return &openapi.Responses{
+		ResponseOrReference: []*openapi.NamedResponseOrReference{
+			{
+				Name: code,
+				Value: &openapi.ResponseOrReference{
+					Response: &openapi.Response{Description: description},
 				},
-			)
-		}
-
-		schema := &openapi.Schema{
-			Type:        "object",
-			Description: messageDescription,
-			Properties:  definitionProperties,
-		}
-
-		var extSchema *openapi.Schema
-		err := utils.ParseStructOption(structDesc, OpenapiSchema, &extSchema)
-		if err != nil {
-			logs.Errorf("Error parsing struct option: %s", err)
-		}
-		if extSchema != nil {
-			err = utils.MergeStructs(schema, extSchema)
-			if err != nil {
-				logs.Errorf("Error merging struct option: %s", err)
-			}
-		}
-
-		// Add the schema to the components.schema list.
-		g.addSchemaToDocument(d, &openapi.NamedSchemaOrReference{
-			Name: schemaName,
-			Value: &openapi.SchemaOrReference{
-				Schema: schema,
 			},
-		})
+		},
 	}
 }
 
-// addSchemaToDocument adds the schema to the document if required
-func (g *OpenAPIGenerator) addSchemaToDocument(d *openapi.Document, schema *openapi.NamedSchemaOrReference) {
-	if utils.Contains(g.generatedSchemas, schema.Name) {
-		return
+func jsonContent(ref string) *openapi.MediaTypes {
+	return &openapi.MediaTypes{
+		AdditionalProperties: []*openapi.NamedMediaType{
+			{
+				Name: "application/json",
+				Value: &openapi.MediaType{
+					Schema: &openapi.SchemaOrReference{
+						Reference: &openapi.Reference{Xref: ref},
+					},
+				},
+			},
+		},
 	}
-	g.generatedSchemas = append(g.generatedSchemas, schema.Name)
-	d.Components.Schemas.AdditionalProperties = append(d.Components.Schemas.AdditionalProperties, schema)
 }
 
+// addOperationToDocument sets the operation on the path item. When the method of the
+// path is already taken the operation is reported, failing in strict mode, and moved to
+// the first free path with a numeric suffix so that both operations are kept.
 func (g *OpenAPIGenerator) addOperationToDocument(d *openapi.Document, op *openapi.Operation, path, methodName string) {
-	var selectedPathItem *openapi.NamedPathItem
-	for _, namedPathItem := range d.Paths.Path {
-		if namedPathItem.Name == path {
-			selectedPathItem = namedPathItem
+	selectedPath := path
+	for i := 1; ; i++ {
+		namedPathItem := findPathItem(d, selectedPath)
+		if namedPathItem == nil || operationOf(namedPathItem.Value, methodName) == nil {
 			break
 		}
+		if i == 1 {
+			g.warn("operation '%s' conflicts with '%s' on '%s %s'",
+				op.OperationID, operationOf(namedPathItem.Value, methodName).OperationID, methodName, path)
+		}
+		selectedPath = fmt.Sprintf("%s_%d", path, i)
 	}
+
+	selectedPathItem := findPathItem(d, selectedPath)
 	// If we get here, we need to create a path item.
 	if selectedPathItem == nil {
-		selectedPathItem = &openapi.NamedPathItem{Name: path, Value: &openapi.PathItem{}}
+		selectedPathItem = &openapi.NamedPathItem{Name: selectedPath, Value: &openapi.PathItem{}}
 		d.Paths.Path = append(d.Paths.Path, selectedPathItem)
 	}
 	// Set the operation on the specified method.
@@ -887,166 +2907,448 @@ func (g *OpenAPIGenerator) addOperationToDocument(d *openapi.Document, op *opena
 	}
 }
 
+func findPathItem(d *openapi.Document, path string) *openapi.NamedPathItem {
+	for _, namedPathItem := range d.Paths.Path {
+		if namedPathItem.Name == path {
+			return namedPathItem
+		}
+	}
+	return nil
+}
+
 func (g *OpenAPIGenerator) schemaReferenceForMessage(message *thrift_reflection.StructDescriptor) string {
-	schemaName := message.GetName()
+	schemaName := g.schemaNameForMessage(message)
 	if !utils.Contains(g.requiredSchemas, schemaName) {
 		g.requiredSchemas = append(g.requiredSchemas, schemaName)
+		g.requiredStructs[schemaName] = message
 	}
 	return "#/components/schemas/" + schemaName
 }
 
-func (g *OpenAPIGenerator) schemaOrReferenceForField(fieldType *thrift_reflection.TypeDescriptor) *openapi.SchemaOrReference {
-	var kindSchema *openapi.SchemaOrReference
-	if fieldType.IsStruct() {
-		structDesc, err := fieldType.GetStructDescriptor()
-		if err != nil {
-			logs.Errorf("Error getting struct descriptor: %s", err)
-			return nil
-		}
-		ref := g.schemaReferenceForMessage(structDesc)
+// schemaNameForMessage returns the component name of a struct. Structs of different
+// IDL files sharing a name reuse it when they are identical, otherwise the later one
+// is prefixed with its file name, or generation fails in strict mode.
+func (g *OpenAPIGenerator) schemaNameForMessage(message *thrift_reflection.StructDescriptor) string {
+	key := message.GetFilepath() + "#" + message.GetName()
+	if schemaName, ok := g.schemaNames[key]; ok {
+		return schemaName
+	}
 
-		kindSchema = &openapi.SchemaOrReference{
-			Reference: &openapi.Reference{Xref: ref},
+	schemaName := message.GetName()
+	if g.arguments.SchemaNamespace {
+		schemaName = fileScope(message.GetFilepath()) + "_" + schemaName
+	}
+	schemaName = g.naming.SchemaName(schemaName)
+	if owner, ok := g.schemaOwners[schemaName]; ok {
+		if owner.GetName() == message.GetName() && sameStruct(owner, message) {
+			g.schemaNames[key] = schemaName
+			return schemaName
+		}
+		if owner.GetName() != message.GetName() {
+			g.warn("structs '%s' and '%s' are both named '%s' by the naming strategy",
+				owner.GetName(), message.GetName(), schemaName)
+		} else {
+			g.warn("schema '%s' is declared differently in '%s' and '%s', set SchemaNamespace to tell them apart",
+				schemaName, owner.GetFilepath(), message.GetFilepath())
+		}
+		base := fileScope(message.GetFilepath()) + "_" + message.GetName()
+		schemaName = g.naming.SchemaName(base)
+		for i := 1; g.schemaOwners[schemaName] != nil; i++ {
+			schemaName = g.naming.SchemaName(fmt.Sprintf("%s_%d", base, i))
 		}
 	}
+	g.schemaNames[key] = schemaName
+	g.schemaOwners[schemaName] = message
+	return schemaName
+}
 
-	if fieldType.GetName() == "string" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type: "string",
-			},
-		}
+// sameStruct compares the serialized declarations of two structs, the files they
+// are declared in are left out so that only the types of their fields tell them apart.
+func sameStruct(a, b *thrift_reflection.StructDescriptor) bool {
+	x, errX := json.Marshal([]interface{}{declaredFields(a), a.Annotations, a.Comments})
+	y, errY := json.Marshal([]interface{}{declaredFields(b), b.Annotations, b.Comments})
+	return errX == nil && errY == nil && string(x) == string(y)
+}
+
+// declaredFields copies the fields of the struct without the file they are declared in.
+func declaredFields(s *thrift_reflection.StructDescriptor) []*thrift_reflection.FieldDescriptor {
+	fields := make([]*thrift_reflection.FieldDescriptor, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		field := *f
+		field.Filepath = ""
+		field.Type = declaredType(f.Type)
+		fields = append(fields, &field)
 	}
+	return fields
+}
 
-	if fieldType.GetName() == "binary" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type:   "string",
-				Format: "binary",
-			},
-		}
+// declaredType clears the file of base types and containers, which do not depend on
+// it, the file of a named type is kept as it tells types of the same name apart.
+func declaredType(t *thrift_reflection.TypeDescriptor) *thrift_reflection.TypeDescriptor {
+	if t == nil || !(t.IsBasic() || t.IsContainer()) {
+		return t
 	}
+	typ := *t
+	typ.Filepath = ""
+	typ.KeyType = declaredType(t.KeyType)
+	typ.ValueType = declaredType(t.ValueType)
+	return &typ
+}
 
-	if fieldType.GetName() == "bool" {
-		kindSchema = &openapi.SchemaOrReference{
+// fileScope returns the name of an IDL file without directory and extension.
+func fileScope(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// smallIntegerSchema returns the schema of an integer type narrower than int32. OAS
+// only registers the int32 and int64 formats, with StandardFormats the type is an
+// int32 bounded by its range, the original format being kept in x-format.
+func (g *OpenAPIGenerator) smallIntegerSchema(format string, min, max float64) *openapi.SchemaOrReference {
+	if !g.arguments.StandardFormats {
+		return &openapi.SchemaOrReference{
 			Schema: &openapi.Schema{
-				Type: "boolean",
+				Type:   "integer",
+				Format: format,
 			},
 		}
 	}
+	return &openapi.SchemaOrReference{
+		Schema: &openapi.Schema{
+			Type:    "integer",
+			Format:  "int32",
+			Minimum: min,
+			Maximum: max,
+			SpecificationExtension: []*openapi.NamedAny{{
+				Name:  "x-format",
+				Value: &openapi.Any{Yaml: format},
+			}},
+		},
+	}
+}
 
-	if fieldType.GetName() == "byte" {
-		kindSchema = &openapi.SchemaOrReference{
+// schemaOrReferenceForField returns the schema of a type. Containers recurse into
+// their element types, so list<map<string, list<Item>>> nests three schemas around a
+// reference to Item, and every struct met on the way is registered as a required
+// schema. Typedefs are replaced by the type they alias and enums are written as
+// their i32 values. A container of an unsupported type is left out like the type
+// itself, which was already reported.
+func (g *OpenAPIGenerator) schemaOrReferenceForField(fieldType *thrift_reflection.TypeDescriptor) *openapi.SchemaOrReference {
+	if fieldType == nil {
+		return nil
+	}
+	switch {
+	case fieldType.IsMap():
+		return g.mapSchema(fieldType)
+	case fieldType.IsList():
+		itemSchema := g.schemaOrReferenceForField(fieldType.GetValueType())
+		if itemSchema == nil {
+			return nil
+		}
+		return &openapi.SchemaOrReference{
 			Schema: &openapi.Schema{
-				Type:   "string",
-				Format: "byte",
+				Type: "array",
+				Items: &openapi.ItemsItem{
+					SchemaOrReference: []*openapi.SchemaOrReference{itemSchema},
+				},
+				UniqueItems: fieldType.GetName() == "set",
 			},
 		}
+	case fieldType.IsTypedef():
+		typedef, err := fieldType.GetTypedefDescriptor()
+		if err != nil || typedef == nil {
+			logs.Errorf("Error getting typedef descriptor of '%s': %v", fieldType.GetName(), err)
+			return nil
+		}
+		return g.schemaOrReferenceForField(typedef.GetType())
+	case fieldType.IsEnum():
+		return g.enumSchema(fieldType)
+	case fieldType.IsStruct():
+		structDesc, err := fieldType.GetStructDescriptor()
+		if err != nil {
+			logs.Errorf("Error getting struct descriptor: %s", err)
+			return nil
+		}
+		return &openapi.SchemaOrReference{
+			Reference: &openapi.Reference{Xref: g.schemaReferenceForMessage(structDesc)},
+		}
 	}
 
-	if fieldType.GetName() == "double" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type:   "number",
-				Format: "double",
-			},
-		}
+	switch fieldType.GetName() {
+	case "string":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "string"}}
+	// The generic calls carry binary fields as base64 strings in JSON, the octet-stream
+	// body of a binary api.raw_body is the only string/binary schema.
+	case "binary":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "string", Format: "byte"}}
+	case "bool":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "boolean"}}
+	case "double":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "number", Format: "double"}}
+	case "float":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "number", Format: "float"}}
+	// byte is the former name of i8, a signed 8-bit integer.
+	case "i8", "byte":
+		return g.smallIntegerSchema("int8", math.MinInt8, math.MaxInt8)
+	case "i16":
+		return g.smallIntegerSchema("int16", math.MinInt16, math.MaxInt16)
+	case "i32":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "integer", Format: "int32"}}
+	case "i64":
+		return &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "integer", Format: "int64"}}
 	}
 
-	if fieldType.GetName() == "i8" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type:   "integer",
-				Format: "int8",
+	g.warn("type '%s' is not supported", fieldType.GetName())
+	return nil
+}
+
+// mapSchema returns the schema of a map, an object whose additional properties are
+// the values.
+func (g *OpenAPIGenerator) mapSchema(fieldType *thrift_reflection.TypeDescriptor) *openapi.SchemaOrReference {
+	valueSchema := g.schemaOrReferenceForField(fieldType.GetValueType())
+	if valueSchema == nil {
+		return nil
+	}
+	schema := &openapi.SchemaOrReference{
+		Schema: &openapi.Schema{
+			Type: "object",
+			AdditionalProperties: &openapi.AdditionalPropertiesItem{
+				SchemaOrReference: valueSchema,
 			},
+		},
+	}
+	// The keys of a JSON object are strings, the generic calls write the other
+	// keys as strings, e.g. "42" for an i32. Their Thrift type is kept in
+	// x-thrift-key-type and noted in the description.
+	if keyType := fieldType.GetKeyType(); keyType.GetName() != "string" {
+		if mapKeyRepresentable(keyType) {
+			g.warn("map<%s, %s> keys are coerced to strings", keyType.GetName(), fieldType.GetValueType().GetName())
+		} else {
+			g.warn("map<%s, %s> keys can not be written as JSON object keys", keyType.GetName(), fieldType.GetValueType().GetName())
 		}
+		schema.Schema.Description = fmt.Sprintf("The keys are %s values written as strings.", keyType.GetName())
+		schema.Schema.SpecificationExtension = append(schema.Schema.SpecificationExtension, &openapi.NamedAny{
+			Name:  "x-thrift-key-type",
+			Value: &openapi.Any{Yaml: keyType.GetName()},
+		})
 	}
+	return schema
+}
 
-	if fieldType.GetName() == "i16" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type:   "integer",
-				Format: "int16",
-			},
-		}
+// enumSchema returns the schema of an enum, the generic calls write its values as
+// i32 numbers.
+func (g *OpenAPIGenerator) enumSchema(fieldType *thrift_reflection.TypeDescriptor) *openapi.SchemaOrReference {
+	schema := &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "integer", Format: "int32"}}
+	enum, err := fieldType.GetEnumDescriptor()
+	if err != nil || enum == nil {
+		logs.Errorf("Error getting enum descriptor of '%s': %v", fieldType.GetName(), err)
+		return schema
+	}
+	for _, value := range enum.GetValues() {
+		schema.Schema.Enum = append(schema.Schema.Enum, &openapi.Any{Yaml: strconv.FormatInt(value.Value, 10)})
 	}
+	return schema
+}
 
-	if fieldType.GetName() == "i32" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type:   "integer",
-				Format: "int32",
-			},
-		}
+// mapKeyRepresentable reports whether the keys of a map can be written as the strings
+// of JSON object keys, which excludes structs, binaries and containers.
+func mapKeyRepresentable(keyType *thrift_reflection.TypeDescriptor) bool {
+	return !keyType.IsStruct() && !keyType.IsUnion() && !keyType.IsException() && !keyType.IsContainer() &&
+		keyType.GetName() != "binary"
+}
+
+// fieldDescription returns the description of the schema of a field, its comment
+// followed by the note the schema already carries, e.g. on the keys of a map.
+func fieldDescription(comment, note string) string {
+	if note == "" {
+		return comment
 	}
+	if comment == "" {
+		return note
+	}
+	return comment + "\n\n" + note
+}
 
-	if fieldType.GetName() == "i64" {
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type:   "integer",
-				Format: "int64",
-			},
+// exampleOption is an example of the openapi.response and openapi.examples annotations.
+type exampleOption struct {
+	Summary       string          `json:"summary"`
+	Description   string          `json:"description"`
+	Value         json.RawMessage `json:"value"`
+	ExternalValue string          `json:"externalValue"`
+}
+
+func (e *exampleOption) example() *openapi.Example {
+	example := &openapi.Example{
+		Summary:       e.Summary,
+		Description:   e.Description,
+		ExternalValue: e.ExternalValue,
+	}
+	var value bytes.Buffer
+	if len(e.Value) > 0 && json.Compact(&value, e.Value) == nil {
+		example.Value = &openapi.Any{Yaml: value.String()}
+	}
+	return example
+}
+
+// structExamples returns the openapi.examples of a struct. An invalid annotation is
+// reported once for the struct.
+func (g *OpenAPIGenerator) structExamples(desc *thrift_reflection.StructDescriptor) []*exampleOption {
+	var examples []*exampleOption
+	err := utils.UnmarshalAnnotation(desc.Annotations[annotations.OpenapiExamples], &examples)
+	if err != nil {
+		if !g.reportedExamples[desc] {
+			g.reportedExamples[desc] = true
+			g.warn("struct '%s': invalid %s: %s", desc.GetName(), annotations.OpenapiExamples, err)
 		}
+		return nil
 	}
+	return examples
+}
 
-	if fieldType.IsMap() {
-		kindSchema = g.schemaOrReferenceForField(fieldType.GetValueType())
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type: "object",
-				AdditionalProperties: &openapi.AdditionalPropertiesItem{
-					SchemaOrReference: kindSchema,
-				},
-			},
+// responseOption is the JSON payload of the openapi.response annotation of a function.
+type responseOption struct {
+	Description string                    `json:"description"`
+	Examples    map[string]*exampleOption `json:"examples"`
+}
+
+// serverVariableOption is the JSON payload of a single openapi.server_variables entry.
+type serverVariableOption struct {
+	Default     string   `json:"default"`
+	Enum        []string `json:"enum"`
+	Description string   `json:"description"`
+}
+
+func (v *serverVariableOption) variable() *openapi.ServerVariable {
+	variable := &openapi.ServerVariable{
+		Enum:        v.Enum,
+		Description: v.Description,
+	}
+	variable.Set_Default(v.Default)
+	return variable
+}
+
+// infoOption is the JSON payload of the openapi.info annotation.
+type infoOption struct {
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	Version        string `json:"version"`
+	TermsOfService string `json:"termsOfService"`
+	Contact        *struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		URL   string `json:"url"`
+	} `json:"contact"`
+	License *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"license"`
+}
+
+// securitySchemeOption is the JSON payload of the openapi.security_scheme annotation,
+// e.g. {"name": "bearerAuth", "securityScheme": {"type": "http", "scheme": "bearer"}}.
+type securitySchemeOption struct {
+	Name   string               `json:"name"`
+	Scheme *securitySchemeValue `json:"securityScheme"`
+}
+
+type securitySchemeValue struct {
+	Type             string                     `json:"type"`
+	Description      string                     `json:"description"`
+	Name             string                     `json:"name"`
+	In               string                     `json:"in"`
+	Scheme           string                     `json:"scheme"`
+	BearerFormat     string                     `json:"bearerFormat"`
+	Flows            map[string]*oauthFlowValue `json:"flows"`
+	OpenIDConnectURL string                     `json:"openIdConnectUrl"`
+}
+
+type oauthFlowValue struct {
+	AuthorizationURL string            `json:"authorizationUrl"`
+	TokenURL         string            `json:"tokenUrl"`
+	RefreshURL       string            `json:"refreshUrl"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+// validate reports the fields required by the type of the scheme which are missing.
+func (o *securitySchemeOption) validate() error {
+	if o == nil || o.Name == "" || o.Scheme == nil {
+		return errors.New("the security scheme has no name or securityScheme")
+	}
+	scheme := o.Scheme
+	switch scheme.Type {
+	case "apiKey":
+		if scheme.Name == "" || scheme.In != "query" && scheme.In != "header" && scheme.In != "cookie" {
+			return errors.New("an apiKey scheme requires a name and in 'query', 'header' or 'cookie'")
+		}
+	case "http":
+		if scheme.Scheme == "" {
+			return errors.New("an http scheme requires a scheme, e.g. 'bearer'")
+		}
+	case "oauth2":
+		if len(scheme.Flows) == 0 {
+			return errors.New("an oauth2 scheme requires flows")
+		}
+		for name := range scheme.Flows {
+			switch name {
+			case "implicit", "password", "clientCredentials", "authorizationCode":
+			default:
+				return fmt.Errorf("unknown oauth2 flow '%s'", name)
+			}
+		}
+	case "openIdConnect":
+		if scheme.OpenIDConnectURL == "" {
+			return errors.New("an openIdConnect scheme requires an openIdConnectUrl")
 		}
+	default:
+		return fmt.Errorf("unsupported type '%s', use 'apiKey', 'http', 'oauth2' or 'openIdConnect'", scheme.Type)
 	}
+	return nil
+}
 
-	if fieldType.IsList() {
-		kindSchema = g.schemaOrReferenceForField(fieldType.GetValueType())
-		kindSchema = &openapi.SchemaOrReference{
-			Schema: &openapi.Schema{
-				Type: "array",
-				Items: &openapi.ItemsItem{
-					SchemaOrReference: []*openapi.SchemaOrReference{kindSchema},
-				},
-			},
+func (v *securitySchemeValue) securityScheme() *openapi.SecurityScheme {
+	scheme := &openapi.SecurityScheme{
+		Description:      v.Description,
+		Name:             v.Name,
+		Scheme:           v.Scheme,
+		BearerFormat:     v.BearerFormat,
+		OpenIDConnectURL: v.OpenIDConnectURL,
+	}
+	scheme.Set_Type(v.Type)
+	scheme.Set_In(v.In)
+	if len(v.Flows) > 0 {
+		scheme.Flows = &openapi.OauthFlows{
+			Implicit:          v.Flows["implicit"].oauthFlow(),
+			Password:          v.Flows["password"].oauthFlow(),
+			ClientCredentials: v.Flows["clientCredentials"].oauthFlow(),
+			AuthorizationCode: v.Flows["authorizationCode"].oauthFlow(),
 		}
 	}
-	return kindSchema
+	return scheme
 }
 
-const (
-	ApiGet           = "api.get"
-	ApiPost          = "api.post"
-	ApiPut           = "api.put"
-	ApiPatch         = "api.patch"
-	ApiDelete        = "api.delete"
-	ApiOptions       = "api.options"
-	ApiHEAD          = "api.head"
-	ApiAny           = "api.any"
-	ApiQuery         = "api.query"
-	ApiForm          = "api.form"
-	ApiPath          = "api.path"
-	ApiHeader        = "api.header"
-	ApiCookie        = "api.cookie"
-	ApiBody          = "api.body"
-	ApiRawBody       = "api.raw_body"
-	ApiBaseDomain    = "api.base_domain"
-	ApiBaseURL       = "api.baseurl"
-	OpenapiOperation = "openapi.operation"
-	OpenapiProperty  = "openapi.property"
-	OpenapiSchema    = "openapi.schema"
-	OpenapiParameter = "openapi.parameter"
-	OpenapiDocument  = "openapi.document"
-)
+func (v *oauthFlowValue) oauthFlow() *openapi.OauthFlow {
+	if v == nil {
+		return nil
+	}
+	flow := &openapi.OauthFlow{
+		AuthorizationURL: v.AuthorizationURL,
+		TokenURL:         v.TokenURL,
+		RefreshURL:       v.RefreshURL,
+		Scopes:           &openapi.Strings{},
+	}
+	names := make([]string, 0, len(v.Scopes))
+	for name := range v.Scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flow.Scopes.AdditionalProperties = append(flow.Scopes.AdditionalProperties, &openapi.NamedString{Name: name, Value: v.Scopes[name]})
+	}
+	return flow
+}
 
-var HttpMethodAnnotations = map[string]string{
-	ApiGet:     "GET",
-	ApiPost:    "POST",
-	ApiPut:     "PUT",
-	ApiPatch:   "PATCH",
-	ApiDelete:  "DELETE",
-	ApiOptions: "OPTIONS",
-	ApiHEAD:    "HEAD",
-	ApiAny:     "ANY",
+// serverOption is the JSON payload of the openapi.server annotation.
+type serverOption struct {
+	URL         string                           `json:"url"`
+	Description string                           `json:"description"`
+	Variables   map[string]*serverVariableOption `json:"variables"`
 }
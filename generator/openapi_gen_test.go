@@ -0,0 +1,2467 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/plugin"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/cloudwego/thriftgo/thrift_reflection"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// writeIDLs writes the IDL files, keyed by name, to a temporary directory next to
+// the openapi.thrift of the example and returns the path of main.thrift.
+func writeIDLs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	annotations, err := ioutil.ReadFile(filepath.Join("..", "example", "openapi.thrift"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "openapi.thrift"), annotations, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "main.thrift")
+}
+
+// writeMain writes main.thrift, including openapi.thrift, with the declarations and
+// returns its path.
+func writeMain(t *testing.T, declarations string) string {
+	t.Helper()
+	return writeIDLs(t, map[string]string{"main.thrift": "namespace go test\n\ninclude \"openapi.thrift\"\n" + declarations})
+}
+
+// buildDocument parses the IDL and builds its document with the arguments.
+func buildDocument(t *testing.T, idl string, arguments *args.Arguments) (*OpenAPIGenerator, *openapi.Document) {
+	t.Helper()
+	g, _ := generateFiles(t, idl, arguments)
+	return g, g.Document()
+}
+
+// parseIDL parses the IDL, looking up includes in its directory.
+func parseIDL(t *testing.T, idl string) *parser.Thrift {
+	t.Helper()
+	ast, err := parser.ParseFile(idl, []string{filepath.Dir(idl)}, true)
+	if err != nil {
+		t.Fatalf("parse %s: %s", idl, err)
+	}
+	if err := semantic.ResolveSymbols(ast); err != nil {
+		t.Fatalf("resolve %s: %s", idl, err)
+	}
+	return ast
+}
+
+// generateFiles parses the IDL and generates its files with the arguments.
+func generateFiles(t *testing.T, idl string, arguments *args.Arguments) (*OpenAPIGenerator, []*plugin.Generated) {
+	t.Helper()
+	g := NewOpenAPIGenerator(parseIDL(t, idl))
+	generated, err := g.BuildDocument(arguments)
+	if err != nil {
+		t.Fatalf("build %s: %s", idl, err)
+	}
+	return g, generated
+}
+
+// generateYAML parses the IDL and returns its openapi.yaml with the arguments.
+func generateYAML(t *testing.T, idl string, arguments *args.Arguments) string {
+	t.Helper()
+	_, generated := generateFiles(t, idl, arguments)
+	return generatedFile(t, generated, "openapi.yaml")
+}
+
+// generatedFile returns the content of the generated file of the base name.
+func generatedFile(t *testing.T, generated []*plugin.Generated, name string) string {
+	t.Helper()
+	for _, file := range generated {
+		if filepath.Base(file.GetName()) == name {
+			return file.Content
+		}
+	}
+	t.Fatalf("%s is not generated", name)
+	return ""
+}
+
+// lookup decodes the YAML or JSON content and returns the value at the path of keys
+// and indexes, e.g. "paths", "/users", "get", "parameters", 0, nil when it is absent.
+func lookup(t *testing.T, content string, path ...interface{}) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(content), &value); err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	for _, key := range path {
+		switch key := key.(type) {
+		case string:
+			object, _ := value.(map[string]interface{})
+			value = object[key]
+		case int:
+			array, _ := value.([]interface{})
+			if key >= len(array) {
+				return nil
+			}
+			value = array[key]
+		}
+	}
+	return value
+}
+
+// componentSchema returns the component schema of the name.
+func componentSchema(t *testing.T, d *openapi.Document, name string) *openapi.Schema {
+	t.Helper()
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		if schema.Name == name {
+			return schema.Value.Schema
+		}
+	}
+	t.Fatalf("no component schema '%s'", name)
+	return nil
+}
+
+// schemaShape renders a schema compactly: [T] is an array, set[T] an array of unique
+// items, {T} an object of additional properties and ref(Name) a reference.
+func schemaShape(s *openapi.SchemaOrReference) string {
+	if s == nil {
+		return "nil"
+	}
+	if s.Reference != nil {
+		return "ref(" + strings.TrimPrefix(s.Reference.Xref, "#/components/schemas/") + ")"
+	}
+	switch s.Schema.Type {
+	case "array":
+		if s.Schema.UniqueItems {
+			return "set[" + schemaShape(s.Schema.Items.SchemaOrReference[0]) + "]"
+		}
+		return "[" + schemaShape(s.Schema.Items.SchemaOrReference[0]) + "]"
+	case "object":
+		if s.Schema.AdditionalProperties != nil {
+			return "{" + schemaShape(s.Schema.AdditionalProperties.SchemaOrReference) + "}"
+		}
+	}
+	shape := s.Schema.Type
+	if s.Schema.Format != "" {
+		shape += "/" + s.Schema.Format
+	}
+	if len(s.Schema.Enum) > 0 {
+		var values []string
+		for _, value := range s.Schema.Enum {
+			values = append(values, value.Yaml)
+		}
+		shape += "(" + strings.Join(values, ",") + ")"
+	}
+	return shape
+}
+
+// propertyShapes returns the shapes of the properties of a component schema, keyed
+// by name, and their names in order.
+func propertyShapes(t *testing.T, d *openapi.Document, name string) (map[string]string, []string) {
+	t.Helper()
+	shapes := make(map[string]string)
+	var names []string
+	for _, property := range componentSchema(t, d, name).Properties.AdditionalProperties {
+		shapes[property.Name] = schemaShape(property.Value)
+		names = append(names, property.Name)
+	}
+	return shapes, names
+}
+
+const containersIDL = `
+namespace go test
+
+include "openapi.thrift"
+
+struct Item {
+    1: string name
+}
+
+struct Containers {
+    1: list<string> names
+    2: set<i64> ids
+    3: map<string, i32> counts
+    4: map<i32, string> labels
+    5: list<Item> items
+    6: set<Item> unique_items
+    7: map<string, Item> by_name
+    8: list<binary> blobs
+    9: set<byte> flags
+}
+
+struct ContainersReq {
+    1: Containers containers (api.body = "containers")
+}
+
+service ContainerService {
+    ContainersReq Get(1: ContainersReq req) (api.post = "/containers")
+}
+`
+
+func TestContainerSchemas(t *testing.T) {
+	idl := writeIDLs(t, map[string]string{"main.thrift": containersIDL})
+	_, d := buildDocument(t, idl, &args.Arguments{})
+	shapes, _ := propertyShapes(t, d, "Containers")
+
+	tests := []struct {
+		property string
+		want     string
+	}{
+		{"names", "[string]"},
+		{"ids", "set[integer/int64]"},
+		{"counts", "{integer/int32}"},
+		{"labels", "{string}"},
+		{"items", "[ref(Item)]"},
+		{"unique_items", "set[ref(Item)]"},
+		{"by_name", "{ref(Item)}"},
+		{"blobs", "[string/byte]"},
+		{"flags", "set[integer/int8]"},
+	}
+	for _, tt := range tests {
+		if got := shapes[tt.property]; got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.property, got, tt.want)
+		}
+	}
+}
+
+func TestMapKeyType(t *testing.T) {
+	idl := writeIDLs(t, map[string]string{"main.thrift": containersIDL})
+	_, d := buildDocument(t, idl, &args.Arguments{})
+	for _, property := range componentSchema(t, d, "Containers").Properties.AdditionalProperties {
+		schema := property.Value.Schema
+		switch property.Name {
+		case "counts":
+			if schema.Description != "" || len(schema.SpecificationExtension) != 0 {
+				t.Errorf("counts: string keys are noted: %q", schema.Description)
+			}
+		case "labels":
+			if !strings.Contains(schema.Description, "i32") {
+				t.Errorf("labels: the i32 keys are not noted: %q", schema.Description)
+			}
+			if len(schema.SpecificationExtension) != 1 || schema.SpecificationExtension[0].Name != "x-thrift-key-type" ||
+				schema.SpecificationExtension[0].Value.Yaml != "i32" {
+				t.Errorf("labels: missing x-thrift-key-type: %v", schema.SpecificationExtension)
+			}
+		}
+	}
+}
+
+func TestStrictExample(t *testing.T) {
+	g, _ := buildDocument(t, filepath.Join("..", "example", "hello.thrift"), &args.Arguments{Strict: true})
+	if warnings := g.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+}
+
+func TestStrictWithoutOpenapiInclude(t *testing.T) {
+	idl := writeIDLs(t, map[string]string{"main.thrift": `
+namespace go test
+
+struct Req {
+    1: string name (api.query = "name")
+    2: Item item (api.body = "item")
+}
+
+struct Item {
+    1: i64 id
+}
+
+service Service {
+    Req Post(1: Req req) (api.post = "/items")
+}
+`})
+	g, _ := buildDocument(t, idl, &args.Arguments{Strict: true})
+	if warnings := g.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+}
+
+func TestReuseBodies(t *testing.T) {
+	idl := writeIDLs(t, map[string]string{"main.thrift": `
+namespace go test
+
+include "openapi.thrift"
+
+struct Req {
+    1: string name (api.query = "name")
+}
+
+struct Resp {
+    1: string body (api.body = "body")
+}
+
+service ItemService {
+    Resp GetItem(1: Req req) (api.get = "/item")
+    Resp ListItems(1: Req req) (api.get = "/items")
+}
+`})
+	tests := []struct {
+		reuse bool
+		want  string
+	}{
+		{false, ""},
+		{true, "#/components/responses/RespBody"},
+	}
+	for _, tt := range tests {
+		_, d := buildDocument(t, idl, &args.Arguments{ReuseBodies: tt.reuse})
+		for _, path := range d.Paths.Path {
+			var ref string
+			if response := path.Value.Get.Responses.ResponseOrReference[0].Value; response.Reference != nil {
+				ref = response.Reference.Xref
+			}
+			if ref != tt.want {
+				t.Errorf("ReuseBodies %v: %s responds with '%s', want '%s'", tt.reuse, path.Name, ref, tt.want)
+			}
+		}
+	}
+}
+
+func TestReproducibleOutput(t *testing.T) {
+	idl := filepath.Join("..", "example", "hello.thrift")
+	arguments := &args.Arguments{NoTimestamp: true, Format: "json", Minify: true, ReuseBodies: true}
+	_, first := generateFiles(t, idl, arguments)
+	for i := 1; i < 10; i++ {
+		_, generated := generateFiles(t, idl, arguments)
+		if len(generated) != len(first) {
+			t.Fatalf("run %d: got %d files, want %d", i, len(generated), len(first))
+		}
+		for j, file := range generated {
+			if file.GetName() != first[j].GetName() || file.Content != first[j].Content {
+				t.Fatalf("run %d: %s differs from the first run", i, file.GetName())
+			}
+		}
+	}
+}
+
+func TestInfoDescription(t *testing.T) {
+	idl := `
+namespace go test
+
+include "openapi.thrift"
+
+struct Req {
+    1: string name (api.query = "name")
+}
+
+struct Resp {
+    1: string body (api.body = "body")
+}
+
+%s
+service HelloService {
+    Resp Hello(1: Req req) (api.get = "/hello")
+}%s
+`
+	tests := []struct {
+		name     string
+		comment  string
+		document string
+		want     string
+	}{
+		{"service comment", "// HelloService says hello", "", "HelloService says hello"},
+		{
+			"service comment with document info", "// HelloService says hello",
+			`(openapi.document = '{info: {title: "Hello", version: "2.0.0"}}')`, "HelloService says hello",
+		},
+		{
+			"document description", "// HelloService says hello",
+			`(openapi.document = '{info: {description: "From the document"}}')`, "From the document",
+		},
+		{"placeholder", "", "", "API description"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			main := fmt.Sprintf(idl, tt.comment, tt.document)
+			_, d := buildDocument(t, writeIDLs(t, map[string]string{"main.thrift": main}), &args.Arguments{})
+			if d.Info.Description != tt.want {
+				t.Errorf("got description %q, want %q", d.Info.Description, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleInfoDescription(t *testing.T) {
+	_, d := buildDocument(t, filepath.Join("..", "example", "hello.thrift"), &args.Arguments{})
+	if want := "HelloService1描述"; d.Info.Description != want {
+		t.Errorf("got description %q, want %q", d.Info.Description, want)
+	}
+}
+
+func TestNestedAndTypedefSchemas(t *testing.T) {
+	idl := writeIDLs(t, map[string]string{
+		"types.thrift": `
+namespace go types
+
+enum Color {
+    RED = 1
+    GREEN = 2
+}
+
+typedef string Name
+typedef list<Color> Colors
+
+struct Tag {
+    1: string label
+}
+
+typedef Tag Label
+`,
+		"main.thrift": `
+namespace go test
+
+include "openapi.thrift"
+include "types.thrift"
+
+enum Status {
+    ACTIVE = 0
+    BLOCKED = 3
+}
+
+typedef i64 ID
+typedef ID UserID
+typedef UserID OwnerID
+typedef map<string, Status> StatusMap
+typedef list<StatusMap> StatusMaps
+
+struct Nested {
+    1: list<map<string, Status>> statuses
+    2: UserID user
+    3: OwnerID owner
+    4: StatusMaps status_maps
+    5: types.Name name
+    6: types.Colors colors
+    7: map<string, list<types.Color>> palette
+    8: types.Label label
+    9: set<list<i32>> matrix
+}
+
+struct NestedReq {
+    1: Nested nested (api.body = "nested")
+}
+
+service NestedService {
+    NestedReq Get(1: NestedReq req) (api.post = "/nested")
+}
+`,
+	})
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	if warnings := g.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+	shapes, _ := propertyShapes(t, d, "Nested")
+
+	tests := []struct {
+		property string
+		want     string
+	}{
+		{"statuses", "[{integer/int32(0,3)}]"},
+		{"user", "integer/int64"},
+		{"owner", "integer/int64"},
+		{"status_maps", "[{integer/int32(0,3)}]"},
+		{"name", "string"},
+		{"colors", "[integer/int32(1,2)]"},
+		{"palette", "{[integer/int32(1,2)]}"},
+		{"label", "ref(Tag)"},
+		{"matrix", "set[[integer/int32]]"},
+	}
+	for _, tt := range tests {
+		if got := shapes[tt.property]; got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.property, got, tt.want)
+		}
+	}
+	componentSchema(t, d, "Tag")
+}
+
+func TestPropertyDeclarationOrder(t *testing.T) {
+	idl := writeIDLs(t, map[string]string{"main.thrift": `
+namespace go test
+
+include "openapi.thrift"
+
+struct Ordered {
+    7: string zeta
+    2: i64 alpha
+    9: bool mid
+    1: string beta
+    5: list<string> last
+}
+
+struct OrderedReq {
+    1: Ordered ordered (api.body = "ordered")
+}
+
+service OrderService {
+    OrderedReq Get(1: OrderedReq req) (api.post = "/ordered")
+}
+`})
+	_, d := buildDocument(t, idl, &args.Arguments{})
+	_, names := propertyShapes(t, d, "Ordered")
+	if got, want := strings.Join(names, ","), "zeta,alpha,mid,beta,last"; got != want {
+		t.Errorf("got properties %s, want the declaration order %s", got, want)
+	}
+}
+
+func TestServerVariables(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service EnvService {
+    Req Get(1: Req req) (api.get = "/env")
+} (
+    api.base_domain = "https://{env}.example.com",
+    openapi.server_variables = '{"env": {"default": "prod", "enum": ["prod", "staging"], "description": "Environment"}}'
+)
+`)
+	content := generateYAML(t, idl, &args.Arguments{})
+	// The only server of the operations is moved to the document.
+	server := []interface{}{"servers", 0}
+	if url := lookup(t, content, append(server, "url")...); url != "https://{env}.example.com" {
+		t.Fatalf("got server %v, want https://{env}.example.com", url)
+	}
+	want := map[string]interface{}{
+		"default":     "prod",
+		"enum":        []interface{}{"prod", "staging"},
+		"description": "Environment",
+	}
+	if got := lookup(t, content, append(server, "variables", "env")...); !reflect.DeepEqual(got, want) {
+		t.Errorf("got variable %v, want %v", got, want)
+	}
+}
+
+const gatewayIDL = `
+struct Req {
+    1: i64 id (api.path = "id")
+}
+
+service PetService {
+    Req GetPet(1: Req req) (api.get = "/pets/:id")
+    Req DeletePet(1: Req req) (
+        api.delete = "/pets/:id",
+        openapi.gateway_integration = '{"type": "mock", "function": "${function}"}'
+    )
+} (
+    openapi.gateway_integration = '{"type": "http_proxy", "httpMethod": "${method}", "uri": "https://backend${path}", "id": "${operationId}", "service": "${service}"}'
+)
+`
+
+func TestGatewayIntegration(t *testing.T) {
+	tests := []struct {
+		key       string
+		extension string
+	}{
+		{"", "x-amazon-apigateway-integration"},
+		{"x-google-backend", "x-google-backend"},
+	}
+	for _, tt := range tests {
+		content := generateYAML(t, writeMain(t, gatewayIDL), &args.Arguments{GatewayExtensionKey: tt.key})
+		path := []interface{}{"paths", "/pets/{id}"}
+		want := map[string]interface{}{
+			"type":       "http_proxy",
+			"httpMethod": "GET",
+			"uri":        "https://backend/pets/{id}",
+			"id":         "PetService_GetPet",
+			"service":    "PetService",
+		}
+		if got := lookup(t, content, append(path, "get", tt.extension)...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got GET integration %v, want %v", tt.extension, got, want)
+		}
+		want = map[string]interface{}{"type": "mock", "function": "DeletePet"}
+		if got := lookup(t, content, append(path, "delete", tt.extension)...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got DELETE integration %v, want the one of the method %v", tt.extension, got, want)
+		}
+	}
+}
+
+// buildError parses the IDL and returns the error building its document with the
+// arguments.
+func buildError(t *testing.T, idl string, arguments *args.Arguments) error {
+	t.Helper()
+	_, err := NewOpenAPIGenerator(parseIDL(t, idl)).BuildDocument(arguments)
+	return err
+}
+
+func TestGatewayIntegrationErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		arguments *args.Arguments
+	}{
+		{"unknown placeholder", `{"uri": "${host}"}`, &args.Arguments{}},
+		{"unterminated placeholder", `{"uri": "${path"}`, &args.Arguments{}},
+		{"invalid JSON", `{"uri": "${path}"`, &args.Arguments{}},
+		{"extension key", `{"uri": "${path}"}`, &args.Arguments{GatewayExtensionKey: "google-backend"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idl := writeMain(t, `
+struct Req {
+    1: i64 id (api.query = "id")
+}
+
+service PetService {
+    Req GetPet(1: Req req) (api.get = "/pets", openapi.gateway_integration = '`+tt.template+`')
+}
+`)
+			if err := buildError(t, idl, tt.arguments); err == nil {
+				t.Error("built without error")
+			}
+		})
+	}
+}
+
+func TestPathLevelParameters(t *testing.T) {
+	idl := writeMain(t, `
+struct GetReq {
+    1: i64 id (api.path = "id")
+    2: string fields (api.query = "fields")
+}
+
+struct DeleteReq {
+    1: i64 id (api.path = "id")
+    2: bool force (api.query = "force")
+}
+
+service UserService {
+    GetReq GetUser(1: GetReq req) (api.get = "/users/:id")
+    DeleteReq DeleteUser(1: DeleteReq req) (api.delete = "/users/:id")
+}
+`)
+	_, d := buildDocument(t, idl, &args.Arguments{})
+	item := d.Paths.Path[0].Value
+	if len(item.Parameters) != 1 || item.Parameters[0].Parameter.Name != "id" || item.Parameters[0].Parameter.In != "path" {
+		t.Fatalf("got path item parameters %+v, want the path parameter id", item.Parameters)
+	}
+	for _, op := range []*openapi.Operation{item.Get, item.Delete} {
+		if len(op.Parameters) != 1 || op.Parameters[0].Parameter.In != "query" {
+			t.Errorf("%s: got parameters %+v, want its query parameter only", op.OperationID, op.Parameters)
+		}
+	}
+}
+
+func TestPathParameterWarnings(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: i64 id (api.path = "id")
+    2: string version (api.path = "version")
+}
+
+service FileService {
+    Req GetFile(1: Req req) (api.get = "/files/:id/:name")
+}
+`)
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	warnings := strings.Join(g.Warnings(), "\n")
+	for _, want := range []string{"path parameter 'name'", "path parameter 'version'"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("got warnings %q, want one about %s", warnings, want)
+		}
+	}
+	var names []string
+	for _, param := range d.Paths.Path[0].Value.Get.Parameters {
+		names = append(names, param.Parameter.Name)
+	}
+	if got := strings.Join(names, ","); got != "id,name" {
+		t.Errorf("got parameters %s, want id,name", got)
+	}
+}
+
+const profilesIDL = `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+struct License {
+    1: string key
+} (openapi.only_if = "enterprise")
+
+struct LicenseReq {
+    1: License license (api.body = "license")
+}
+
+service ProductService {
+    Req Get(1: Req req) (api.get = "/product")
+    LicenseReq Activate(1: LicenseReq req) (api.post = "/license", openapi.only_if = "enterprise,beta")
+}
+`
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		profiles []string
+		paths    string
+	}{
+		{nil, "/product"},
+		{[]string{"beta"}, "/license,/product"},
+		{[]string{"enterprise"}, "/license,/product"},
+	}
+	for _, tt := range tests {
+		_, d := buildDocument(t, writeMain(t, profilesIDL), &args.Arguments{Profiles: tt.profiles})
+		var paths []string
+		for _, path := range d.Paths.Path {
+			paths = append(paths, path.Name)
+		}
+		if got := strings.Join(paths, ","); got != tt.paths {
+			t.Errorf("profiles %v: got paths %s, want %s", tt.profiles, got, tt.paths)
+		}
+	}
+
+	content := generateYAML(t, writeMain(t, profilesIDL), &args.Arguments{Profiles: []string{"beta"}})
+	if got := lookup(t, content, "info", "x-profiles"); !reflect.DeepEqual(got, []interface{}{"beta"}) {
+		t.Errorf("got x-profiles %v, want [beta]", got)
+	}
+	if got := lookup(t, content, "components", "schemas", "License"); got != nil {
+		t.Errorf("the schema of License, only for enterprise, is documented: %v", got)
+	}
+}
+
+const twoServicesIDL = `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service UserService {
+    Req Get(1: Req req) (api.get = "/users"%s)
+}
+
+service OrderService {
+    Req Get(1: Req req) (api.get = "/orders"%s)
+}
+`
+
+// operationIDs returns the operationIds of the document by path.
+func operationIDs(d *openapi.Document) map[string]string {
+	ids := make(map[string]string)
+	for _, path := range d.Paths.Path {
+		for _, op := range operationsOf(path.Value) {
+			ids[path.Name] = op.OperationID
+		}
+	}
+	return ids
+}
+
+func TestOperationIDs(t *testing.T) {
+	idl := writeMain(t, fmt.Sprintf(twoServicesIDL, "", ""))
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	want := map[string]string{"/users": "UserService_Get", "/orders": "OrderService_Get"}
+	if got := operationIDs(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("got operationIds %v, want %v", got, want)
+	}
+	if warnings := g.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+
+	_, d = buildDocument(t, idl, &args.Arguments{OperationIDPrefix: "billing_"})
+	want = map[string]string{"/users": "billing_UserService_Get", "/orders": "billing_OrderService_Get"}
+	if got := operationIDs(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("got prefixed operationIds %v, want %v", got, want)
+	}
+}
+
+func TestDuplicateOperationIDs(t *testing.T) {
+	override := `, openapi.operation = '{operation_id: "Get"}'`
+	idl := writeMain(t, fmt.Sprintf(twoServicesIDL, override, override))
+	g, _ := buildDocument(t, idl, &args.Arguments{})
+	if warnings := strings.Join(g.Warnings(), "\n"); !strings.Contains(warnings, "operationId 'Get'") {
+		t.Errorf("got warnings %q, want one about operationId 'Get'", warnings)
+	}
+}
+
+const stableIDL = `
+struct User {
+    1: string id (api.path = "id")
+    2: string name (api.body = "name")
+    3: list<string> tags (api.body = "tags")
+}
+
+struct Order {
+    1: string id (api.path = "id")
+    2: i64 amount (api.body = "amount")
+}
+
+service StableService {
+    User GetUser(1: User req) (api.get = "/users/:id")
+    User UpdateUser(1: User req) (api.put = "/users/:id")
+    Order GetOrder(1: Order req) (api.get = "/orders/:id")
+    %s
+}
+`
+
+// isSubsequence reports whether every line of old appears in lines in the same order.
+func isSubsequence(old, lines []string) bool {
+	i := 0
+	for _, line := range lines {
+		if i < len(old) && old[i] == line {
+			i++
+		}
+	}
+	return i == len(old)
+}
+
+// specLines splits the document into lines, leaving out the header comment.
+func specLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestStableDiff(t *testing.T) {
+	idl := writeMain(t, fmt.Sprintf(stableIDL, ""))
+	base := generateYAML(t, idl, &args.Arguments{})
+	added := `Order CancelOrder(1: Cancel req) (api.post = "/orders/:id/cancel")`
+	content := `namespace go test
+
+include "openapi.thrift"
+
+struct Cancel {
+    1: string id (api.path = "id")
+    2: string reason (api.body = "reason")
+}
+` + fmt.Sprintf(stableIDL, added)
+	if err := ioutil.WriteFile(idl, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next := generateYAML(t, idl, &args.Arguments{})
+
+	baseLines := specLines(base)
+	nextLines := specLines(next)
+	if !isSubsequence(baseLines, nextLines) {
+		t.Fatalf("adding a method changed existing lines:\n%s\n---\n%s", base, next)
+	}
+	// the inserted lines must belong to the new operation and its schema
+	var inserted []string
+	i := 0
+	for _, line := range nextLines {
+		if i < len(baseLines) && baseLines[i] == line {
+			i++
+			continue
+		}
+		inserted = append(inserted, line)
+	}
+	diff := strings.Join(inserted, "\n")
+	for _, want := range []string{"/orders/{id}/cancel:", "StableService_CancelOrder", "reason:"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("inserted lines miss %q:\n%s", want, diff)
+		}
+	}
+	for _, unrelated := range []string{"/users/{id}:", "User:", "Order:"} {
+		if strings.Contains(diff, unrelated) {
+			t.Errorf("inserted lines touch %q:\n%s", unrelated, diff)
+		}
+	}
+}
+
+const duplicateRouteIDL = `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service UserService {
+    Req GetUser(1: Req req) (api.get = "/users")
+    Req ListUsers(1: Req req) (api.get = "/users")
+}
+`
+
+func TestDuplicateRoute(t *testing.T) {
+	idl := writeMain(t, duplicateRouteIDL)
+	g, d := buildDocument(t, idl, &args.Arguments{})
+
+	want := map[string]string{"/users": "UserService_GetUser", "/users_1": "UserService_ListUsers"}
+	if got := operationIDs(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("got operationIds %v, want %v", got, want)
+	}
+	warnings := g.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'UserService_ListUsers' conflicts with 'UserService_GetUser'") {
+		t.Errorf("got warnings %q, want one about the conflict", warnings)
+	}
+
+	err := buildError(t, idl, &args.Arguments{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "conflicts with") {
+		t.Errorf("got error %v, want the conflict in strict mode", err)
+	}
+}
+
+// schemaCollisionIDLs declares User differently in a.thrift and b.thrift, and Same
+// identically in b.thrift and main.thrift.
+var schemaCollisionIDLs = map[string]string{
+	"a.thrift": "namespace go a\n\nstruct User {\n    1: string name\n}\n",
+	"b.thrift": "namespace go b\n\nstruct User {\n    1: i64 id\n}\n\nstruct Same {\n    1: string x\n}\n",
+	"main.thrift": `namespace go test
+
+include "openapi.thrift"
+include "a.thrift"
+include "b.thrift"
+
+struct Same {
+    1: string x
+}
+
+struct Req {
+    1: a.User a (api.body = "a")
+    2: b.User b (api.body = "b")
+    3: b.Same s (api.body = "s")
+    4: Same s2 (api.body = "s2")
+}
+
+service UserService {
+    Req Get(1: Req req) (api.post = "/users")
+}
+`,
+}
+
+// schemaNames returns the component schema names of the document.
+func schemaNames(d *openapi.Document) []string {
+	var names []string
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		names = append(names, schema.Name)
+	}
+	return names
+}
+
+func TestSchemaCollisions(t *testing.T) {
+	idl := writeIDLs(t, schemaCollisionIDLs)
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	want := []string{"ReqBody", "Same", "User", "b_User"}
+	if got := schemaNames(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("got schemas %v, want %v", got, want)
+	}
+	warnings := g.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "schema 'User' is declared differently") {
+		t.Errorf("got warnings %q, want one about User", warnings)
+	}
+
+	err := buildError(t, idl, &args.Arguments{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "schema 'User' is declared differently") {
+		t.Errorf("got error %v, want the collision in strict mode", err)
+	}
+
+	g, d = buildDocument(t, idl, &args.Arguments{SchemaNamespace: true})
+	want = []string{"a_User", "b_Same", "b_User", "main_ReqBody", "main_Same"}
+	if got := schemaNames(d); !reflect.DeepEqual(got, want) {
+		t.Errorf("got namespaced schemas %v, want %v", got, want)
+	}
+	if warnings := g.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q with SchemaNamespace", warnings)
+	}
+}
+
+const inlineBodyIDL = `
+struct Item {
+    1: string name
+}
+
+struct MapReq {
+    1: map<string, Item> items (api.body = "items", openapi.body_inline = "true")
+}
+
+struct ListReq {
+    1: list<Item> items (api.body = "items", openapi.body_inline = "true")
+}
+
+struct ListResp {
+    1: list<Item> items (api.body = "items", openapi.body_inline = "true")
+}
+
+struct TwoBodies {
+    1: list<Item> items (api.body = "items", openapi.body_inline = "true")
+    2: string name (api.body = "name")
+}
+
+service ItemService {
+    ListResp PutMap(1: MapReq req) (api.put = "/items/map")
+    ListResp PutList(1: ListReq req) (api.put = "/items/list")
+    ListResp PutTwo(1: TwoBodies req) (api.put = "/items/two")
+}
+`
+
+func TestInlineBody(t *testing.T) {
+	g, generated := generateFiles(t, writeMain(t, inlineBodyIDL), &args.Arguments{})
+	content := generatedFile(t, generated, "openapi.yaml")
+	body := func(path string) interface{} {
+		return lookup(t, content, "paths", path, "put", "requestBody", "content", "application/json", "schema")
+	}
+
+	mapBody, _ := body("/items/map").(map[string]interface{})
+	if mapBody["type"] != "object" || lookup(t, content, "paths", "/items/map", "put", "requestBody", "content",
+		"application/json", "schema", "additionalProperties", "$ref") != "#/components/schemas/Item" {
+		t.Errorf("got map body %v, want an object of Item", mapBody)
+	}
+	listBody, _ := body("/items/list").(map[string]interface{})
+	if listBody["type"] != "array" || lookup(t, content, "paths", "/items/list", "put", "requestBody", "content",
+		"application/json", "schema", "items", "$ref") != "#/components/schemas/Item" {
+		t.Errorf("got list body %v, want an array of Item", listBody)
+	}
+	if ref := lookup(t, content, "paths", "/items/list", "put", "responses", "200", "content",
+		"application/json", "schema", "items", "$ref"); ref != "#/components/schemas/Item" {
+		t.Errorf("got response items %v, want an array of Item", ref)
+	}
+	// The element struct is a required schema.
+	if lookup(t, content, "components", "schemas", "Item", "properties", "name") == nil {
+		t.Error("the Item schema is missing")
+	}
+
+	// The annotation is ignored with other body fields.
+	if properties := lookup(t, content, "paths", "/items/two", "put", "requestBody", "content",
+		"application/json", "schema", "properties"); properties == nil {
+		t.Error("the body with two fields is not wrapped in an object")
+	}
+	warnings := strings.Join(g.Warnings(), "\n")
+	if !strings.Contains(warnings, "TwoBodies.items: openapi.body_inline is ignored") {
+		t.Errorf("got warnings %q, want one about TwoBodies.items", warnings)
+	}
+}
+
+const exceptionsIDL = `
+struct Req {
+    1: string id (api.path = "id")
+}
+
+// NotFound is thrown for unknown ids.
+exception NotFound {
+    1: string message
+} (api.http_code = "404")
+
+exception Gone {
+    1: string reason
+} (api.http_code = "404")
+
+exception Conflict {
+    1: string message
+    2: i64 version
+} (api.http_code = "409")
+
+exception Internal {
+    1: string message
+}
+
+exception Invalid {
+    1: string message
+} (api.http_code = "200")
+
+service UserService {
+    Req Get(1: Req req) throws (1: NotFound notFound, 2: Conflict conflict, 3: Internal internal) (api.get = "/users/:id")
+    Req Delete(1: Req req) throws (1: NotFound notFound, 2: Gone gone, 3: Invalid invalid) (api.delete = "/users/:id")
+}
+`
+
+func TestExceptionResponses(t *testing.T) {
+	g, generated := generateFiles(t, writeMain(t, exceptionsIDL), &args.Arguments{})
+	content := generatedFile(t, generated, "openapi.yaml")
+
+	responses, _ := lookup(t, content, "paths", "/users/{id}", "get", "responses").(map[string]interface{})
+	for status, ref := range map[string]string{"404": "NotFound", "409": "Conflict", "500": "Internal"} {
+		if got := lookup(t, content, "paths", "/users/{id}", "get", "responses", status, "content",
+			"application/json", "schema", "$ref"); got != "#/components/schemas/"+ref {
+			t.Errorf("got %s response %v, want %s", status, got, ref)
+		}
+	}
+	if len(responses) != 3 {
+		t.Errorf("got responses %v, want one per status", responses)
+	}
+	if got := lookup(t, content, "paths", "/users/{id}", "get", "responses", "404", "description"); got != "NotFound is thrown for unknown ids." {
+		t.Errorf("got 404 description %v", got)
+	}
+
+	// Exceptions sharing a status are documented with oneOf.
+	oneOf, _ := lookup(t, content, "paths", "/users/{id}", "delete", "responses", "404", "content",
+		"application/json", "schema", "oneOf").([]interface{})
+	if len(oneOf) != 2 {
+		t.Errorf("got 404 schemas %v, want NotFound and Gone", oneOf)
+	}
+	if lookup(t, content, "paths", "/users/{id}", "delete", "responses", "500") == nil {
+		t.Error("the exception with an invalid status is not answered with 500")
+	}
+	if warnings := strings.Join(g.Warnings(), "\n"); !strings.Contains(warnings, "Invalid: invalid api.http_code '200'") {
+		t.Errorf("got warnings %q, want one about Invalid", warnings)
+	}
+}
+
+const statusFieldIDL = `
+struct Req {
+    1: string id (api.path = "id")
+}
+
+enum Status {
+    // Found
+    OK = 200
+    // Unknown user
+    NOT_FOUND = 404
+    UNKNOWN = 7
+}
+
+struct UserResp {
+    1: Status code (api.http_code = "true", api.body = "code")
+    2: string name (api.body = "name")
+}
+
+struct OrderResp {
+    1: i32 code (api.http_code = "true", api.body = "code")
+    2: string name (api.body = "name")
+}
+
+service UserService {
+    UserResp GetUser(1: Req req) (api.get = "/users/:id")
+    OrderResp GetOrder(1: Req req) (api.get = "/orders/:id")
+}
+`
+
+func TestStatusField(t *testing.T) {
+	g, generated := generateFiles(t, writeMain(t, statusFieldIDL), &args.Arguments{})
+	content := generatedFile(t, generated, "openapi.yaml")
+
+	// The successful response is already documented.
+	for status, description := range map[string]string{"200": "Successful response", "404": "Unknown user"} {
+		if got := lookup(t, content, "paths", "/users/{id}", "get", "responses", status, "description"); got != description {
+			t.Errorf("got %s description %v, want %q", status, got, description)
+		}
+	}
+	if got := lookup(t, content, "paths", "/users/{id}", "get", "responses", "404", "content",
+		"application/json", "schema", "$ref"); got == nil {
+		t.Error("the 404 response has no content")
+	}
+	if got := lookup(t, content, "paths", "/orders/{id}", "get", "responses", "default", "description"); got != "Response with the status set by code" {
+		t.Errorf("got default response %v", got)
+	}
+	if warnings := strings.Join(g.Warnings(), "\n"); !strings.Contains(warnings, "UserResp.code: Status value UNKNOWN is not an HTTP status") {
+		t.Errorf("got warnings %q, want one about UNKNOWN", warnings)
+	}
+
+	// The status field stays in the body unless it is stripped.
+	if lookup(t, content, "components", "schemas", "UserRespBody", "properties", "code") == nil {
+		t.Error("the status field is not documented in the body")
+	}
+	content = generateYAML(t, writeMain(t, statusFieldIDL), &args.Arguments{StripStatusField: true})
+	if lookup(t, content, "components", "schemas", "UserRespBody", "properties", "code") != nil {
+		t.Error("the stripped status field is documented in the body")
+	}
+}
+
+const bindingUsagesIDL = `
+struct Req {
+    1: string id (api.path = "id")
+    2: string location (api.header = "Location")
+    3: i32 code (api.http_code = "true")
+}
+
+struct Resp {
+    1: string name (api.query = "name")
+    2: string token (api.cookie = "token")
+    3: string etag (api.header = "ETag")
+}
+
+struct Both {
+    1: string name (api.query = "name")
+    2: string location (api.header = "Location")
+}
+
+service UserService {
+    Resp Get(1: Req req) (api.get = "/users/:id")
+    Both Echo(1: Both req) (api.post = "/echo")
+}
+`
+
+func TestBindingUsages(t *testing.T) {
+	idl := writeMain(t, bindingUsagesIDL)
+	g, _ := buildDocument(t, idl, &args.Arguments{})
+	want := []string{
+		"Req.location: api.header 'Location' is a response header, but the struct is only used as a request",
+		"Req.code: api.http_code is ignored, the struct is only used as a request",
+		"Resp.name: api.query is ignored, the struct is only used as a response",
+		"Resp.token: api.cookie is ignored, the struct is only used as a response",
+	}
+	warnings := strings.Join(g.Warnings(), "\n")
+	for _, w := range want {
+		if !strings.Contains(warnings, w) {
+			t.Errorf("missing warning %q in %q", w, warnings)
+		}
+	}
+	for _, exempt := range []string{"Both.", "Resp.etag"} {
+		if strings.Contains(warnings, exempt) {
+			t.Errorf("got a warning about %s in %q", exempt, warnings)
+		}
+	}
+
+	err := buildError(t, idl, &args.Arguments{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "the struct is only used as a response") {
+		t.Errorf("got error %v, want the binding usages in strict mode", err)
+	}
+}
+
+const downloadIDL = `
+struct DownloadReq {
+    1: string name (api.query = "name")
+}
+
+struct DownloadResp {
+    1: binary data (api.raw_body = "data")
+}
+
+service FileService {
+    DownloadResp Download(1: DownloadReq req) (api.get = "/download")
+}
+`
+
+func TestDownloadResponse(t *testing.T) {
+	content := generateYAML(t, writeMain(t, downloadIDL), &args.Arguments{})
+	schema := lookup(t, content, "paths", "/download", "get", "responses", "200", "content", "application/octet-stream", "schema")
+	want := map[string]interface{}{"type": "string", "format": "binary"}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("got download schema %v, want %v", schema, want)
+	}
+	if lookup(t, content, "paths", "/download", "get", "responses", "200", "content", "application/json") != nil {
+		t.Error("the download is documented as JSON")
+	}
+}
+
+func TestDocumentHeader(t *testing.T) {
+	idl := filepath.Join("..", "example", "hello.thrift")
+	content := generateYAML(t, idl, &args.Arguments{})
+	header := []string{
+		"# Generated with thrift-gen-rpc-swagger " + Version,
+		"# " + infoURL,
+		"# IDL: " + idl,
+		"# Generated at: ",
+	}
+	lines := strings.Split(content, "\n")
+	for i, want := range header {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("got header line %q, want %q", lines[i], want)
+		}
+	}
+
+	first := generateYAML(t, idl, &args.Arguments{NoTimestamp: true})
+	if strings.Contains(first, "Generated at") {
+		t.Error("the generation time is written with NoTimestamp")
+	}
+	if !strings.HasPrefix(first, strings.Join(header[:3], "\n")+"\n") {
+		t.Errorf("got header %q with NoTimestamp", strings.Join(strings.Split(first, "\n")[:3], "\n"))
+	}
+	if second := generateYAML(t, idl, &args.Arguments{NoTimestamp: true}); second != first {
+		t.Error("consecutive runs with NoTimestamp differ")
+	}
+}
+
+func TestFloatSchema(t *testing.T) {
+	// thriftgo does not parse float, other compilers declare it as a base type.
+	g := &OpenAPIGenerator{arguments: &args.Arguments{}}
+	for _, test := range []struct {
+		name   string
+		format string
+	}{
+		{"float", "float"},
+		{"double", "double"},
+	} {
+		schema := g.schemaOrReferenceForField(&thrift_reflection.TypeDescriptor{Name: test.name})
+		if schema == nil || schema.Schema == nil {
+			t.Fatalf("%s: got no schema", test.name)
+		}
+		if schema.Schema.Type != "number" || schema.Schema.Format != test.format {
+			t.Errorf("%s: got %s %s, want number %s", test.name, schema.Schema.Type, schema.Schema.Format, test.format)
+		}
+	}
+	if len(g.Warnings()) != 0 {
+		t.Errorf("got warnings %q", g.Warnings())
+	}
+
+	list := &thrift_reflection.TypeDescriptor{Name: "list", ValueType: &thrift_reflection.TypeDescriptor{Name: "float"}}
+	schema := g.schemaOrReferenceForField(list)
+	if schema == nil || schema.Schema.Items.SchemaOrReference[0].Schema.Format != "float" {
+		t.Errorf("got list schema %v, want float items", schema)
+	}
+}
+
+// continueOnErrorIDL declares a service whose gateway integration is invalid next to
+// a healthy one.
+const continueOnErrorIDL = `
+struct PetReq {
+    1: i64 id (api.query = "id")
+}
+
+struct Pet {
+    1: string name (api.body = "name")
+}
+
+struct Order {
+    1: i64 id (api.body = "id")
+}
+
+service BrokenService {
+    Order CreateOrder(1: Order req) (api.post = "/orders", openapi.gateway_integration = '{"uri": "${host}"}')
+}
+
+service PetService {
+    Pet GetPet(1: PetReq req) (api.get = "/pets")
+}
+`
+
+func TestContinueOnError(t *testing.T) {
+	idl := writeMain(t, continueOnErrorIDL)
+	if err := buildError(t, idl, &args.Arguments{}); err == nil {
+		t.Error("built without error, want the broken service to abort the generation")
+	}
+	if err := buildError(t, idl, &args.Arguments{ContinueOnError: true, Strict: true}); err == nil {
+		t.Error("built without error in strict mode")
+	}
+
+	g, generated := generateFiles(t, idl, &args.Arguments{ContinueOnError: true})
+	content := generatedFile(t, generated, "openapi.yaml")
+	if lookup(t, content, "paths", "/pets", "get") == nil {
+		t.Error("got no GET /pets, want the healthy service generated")
+	}
+	if lookup(t, content, "paths", "/orders") != nil {
+		t.Error("got /orders, want the broken service omitted")
+	}
+	if names := schemaNames(g.Document()); !reflect.DeepEqual(names, []string{"PetBody"}) {
+		t.Errorf("got schemas %v, want the schemas of the broken service dropped", names)
+	}
+	errors, _ := lookup(t, content, "x-generation-errors").([]interface{})
+	if len(errors) != 1 || !strings.HasPrefix(errors[0].(string), "service 'BrokenService' omitted: ") {
+		t.Errorf("got x-generation-errors %v", errors)
+	}
+	if warnings := g.Warnings(); len(warnings) != 1 || warnings[0] != errors[0] {
+		t.Errorf("got warnings %q, want the omitted service", warnings)
+	}
+}
+
+// omittedServiceIDLs declares a service which names the schema of another file and
+// uses a struct only as a response before failing, next to a healthy service.
+var omittedServiceIDLs = map[string]string{
+	"other.thrift": "namespace go other\n\nstruct User {\n    1: i64 id\n}\n",
+	"main.thrift": `namespace go test
+
+include "openapi.thrift"
+include "other.thrift"
+
+struct User {
+    1: string name
+}
+
+struct PetReq {
+    1: i64 id (api.query = "id")
+}
+
+struct OtherPet {
+    1: other.User owner (api.body = "owner")
+}
+
+struct Pet {
+    1: User owner (api.body = "owner")
+}
+
+struct Status {
+    1: i64 code (api.query = "code")
+}
+
+struct Order {
+    1: i64 id (api.body = "id")
+}
+
+service BrokenService {
+    OtherPet GetOtherPet(1: PetReq req) (api.get = "/other")
+    Status GetStatus(1: PetReq req) (api.get = "/status")
+    Order CreateOrder(1: Order req) (api.post = "/orders", openapi.gateway_integration = '{"uri": "${host}"}')
+}
+
+service PetService {
+    Pet GetPet(1: PetReq req) (api.get = "/pets")
+}
+`,
+}
+
+func TestContinueOnErrorRollback(t *testing.T) {
+	idl := writeIDLs(t, omittedServiceIDLs)
+	g, d := buildDocument(t, idl, &args.Arguments{ContinueOnError: true})
+	// The schema names and the struct usages of the omitted service are dropped: User
+	// is not renamed after other.User, Status is not reported as a response.
+	names := schemaNames(d)
+	if !utils.Contains(names, "User") || utils.Contains(names, "main_User") {
+		t.Errorf("got schemas %v, want User named as without the omitted service", names)
+	}
+	warnings := g.Warnings()
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "service 'BrokenService' omitted: ") {
+		t.Errorf("got warnings %q, want only the omitted service", warnings)
+	}
+}
+
+// smallIntegersIDL declares the integer types narrower than int32.
+const smallIntegersIDL = `
+struct Req {
+    1: i8 a (api.body = "a")
+    2: byte b (api.body = "b")
+    3: i16 c (api.body = "c")
+    4: i32 d (api.body = "d")
+}
+
+service IntService {
+    Req Echo(1: Req req) (api.post = "/echo")
+}
+`
+
+func TestStandardFormats(t *testing.T) {
+	idl := writeMain(t, smallIntegersIDL)
+	tests := []struct {
+		name      string
+		arguments *args.Arguments
+		want      map[string]map[string]interface{}
+	}{
+		{
+			"thrift formats", &args.Arguments{},
+			map[string]map[string]interface{}{
+				"a": {"type": "integer", "format": "int8"},
+				"b": {"type": "integer", "format": "int8"},
+				"c": {"type": "integer", "format": "int16"},
+				"d": {"type": "integer", "format": "int32"},
+			},
+		},
+		{
+			"standard formats", &args.Arguments{StandardFormats: true},
+			map[string]map[string]interface{}{
+				"a": {"type": "integer", "format": "int32", "minimum": -128.0, "maximum": 127.0, "x-format": "int8"},
+				"b": {"type": "integer", "format": "int32", "minimum": -128.0, "maximum": 127.0, "x-format": "int8"},
+				"c": {"type": "integer", "format": "int32", "minimum": -32768.0, "maximum": 32767.0, "x-format": "int16"},
+				"d": {"type": "integer", "format": "int32"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := generateYAML(t, idl, tt.arguments)
+			for name, want := range tt.want {
+				got, _ := lookup(t, content, "components", "schemas", "ReqBody", "properties", name).(map[string]interface{})
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmParameters(t *testing.T) {
+	// confirmParameter returns the X-Confirm parameter of the operation.
+	confirmParameter := func(content, path, method string) map[string]interface{} {
+		parameters, _ := lookup(t, content, "paths", path, method, "parameters").([]interface{})
+		for _, parameter := range parameters {
+			parameter, _ := parameter.(map[string]interface{})
+			if parameter["name"] == "X-Confirm" {
+				return parameter
+			}
+		}
+		return nil
+	}
+
+	content := generateYAML(t, helloIDL, &args.Arguments{ConfirmMutations: true})
+	parameter := confirmParameter(content, "/body", "post")
+	if parameter == nil || parameter["in"] != "header" || parameter["required"] != true {
+		t.Fatalf("got X-Confirm %v on POST /body, want a required header", parameter)
+	}
+	schema, _ := parameter["schema"].(map[string]interface{})
+	if !reflect.DeepEqual(schema["enum"], []interface{}{"yes"}) {
+		t.Errorf("got X-Confirm values %v, want yes", schema["enum"])
+	}
+	if confirmParameter(content, "/hello1", "get") != nil {
+		t.Error("got GET /hello1 with X-Confirm")
+	}
+
+	content = generateYAML(t, helloIDL, &args.Arguments{})
+	if confirmParameter(content, "/body", "post") != nil {
+		t.Error("got X-Confirm without ConfirmMutations")
+	}
+}
+
+// mapKeysIDL declares maps whose keys are not strings.
+const mapKeysIDL = `
+struct Item {
+    1: i64 id
+}
+
+struct Req {
+    1: map<i32, string> labels (api.body = "labels")
+    2: map<bool, Item> flags (api.body = "flags")
+    3: map<string, i64> counts (api.body = "counts")
+    %s
+}
+
+service MapService {
+    Item Update(1: Req req) (api.post = "/maps")
+}
+`
+
+func TestMapKeys(t *testing.T) {
+	idl := writeMain(t, fmt.Sprintf(mapKeysIDL, ""))
+	g, generated := generateFiles(t, idl, &args.Arguments{})
+	content := generatedFile(t, generated, "openapi.yaml")
+	for _, test := range []struct {
+		property, keyType string
+		value             interface{}
+	}{
+		{"labels", "i32", map[string]interface{}{"type": "string"}},
+		{"flags", "bool", map[string]interface{}{"$ref": "#/components/schemas/Item"}},
+		{"counts", "", map[string]interface{}{"type": "integer", "format": "int64"}},
+	} {
+		schema, _ := lookup(t, content, "paths", "/maps", "post", "requestBody", "content", "application/json", "schema",
+			"properties", test.property).(map[string]interface{})
+		if schema["type"] != "object" || !reflect.DeepEqual(schema["additionalProperties"], test.value) {
+			t.Errorf("%s: got schema %v", test.property, schema)
+		}
+		if test.keyType == "" {
+			if _, ok := schema["x-thrift-key-type"]; ok {
+				t.Errorf("%s: got x-thrift-key-type on string keys", test.property)
+			}
+			continue
+		}
+		if schema["x-thrift-key-type"] != test.keyType {
+			t.Errorf("%s: got x-thrift-key-type %v, want %s", test.property, schema["x-thrift-key-type"], test.keyType)
+		}
+	}
+	want := []string{"map<i32, string> keys are coerced to strings", "map<bool, Item> keys are coerced to strings"}
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+	if err := buildError(t, idl, &args.Arguments{Strict: true}); err == nil || !strings.Contains(err.Error(), "map<i32, string> keys are coerced to strings") {
+		t.Errorf("got error %v in strict mode, want the coerced keys", err)
+	}
+
+	// struct keys can not be written as JSON object keys
+	idl = writeMain(t, fmt.Sprintf(mapKeysIDL, `4: map<Item, string> items (api.body = "items")`))
+	g, _ = buildDocument(t, idl, &args.Arguments{})
+	if warnings := g.Warnings(); len(warnings) != 3 || !strings.Contains(warnings[2], "map<Item, string> keys can not be written as JSON object keys") {
+		t.Errorf("got warnings %q, want the struct keys", warnings)
+	}
+	if err := buildError(t, idl, &args.Arguments{Strict: true}); err == nil || !strings.Contains(err.Error(), "map<Item, string> keys can not be written") {
+		t.Errorf("got error %v in strict mode, want the struct keys", err)
+	}
+}
+
+// nestedContainersIDL declares containers of containers.
+const nestedContainersIDL = `
+struct Item {
+    1: string name
+}
+
+struct Nested {
+    1: list<map<string, string>> rows
+    2: map<string, list<i32>> groups
+    3: list<list<Item>> pages
+    4: map<string, map<string, Item>> index
+    5: list<map<string, list<Item>>> deep
+    6: map<string, set<i64>> tags
+}
+
+struct NestedReq {
+    1: Nested nested (api.body = "nested")
+}
+
+service NestedService {
+    NestedReq Echo(1: NestedReq req) (api.post = "/nested")
+}
+`
+
+func TestNestedContainerSchemas(t *testing.T) {
+	_, d := buildDocument(t, writeMain(t, nestedContainersIDL), &args.Arguments{})
+	shapes, _ := propertyShapes(t, d, "Nested")
+	tests := []struct {
+		property string
+		want     string
+	}{
+		{"rows", "[{string}]"},
+		{"groups", "{[integer/int32]}"},
+		{"pages", "[[ref(Item)]]"},
+		{"index", "{{ref(Item)}}"},
+		{"deep", "[{[ref(Item)]}]"},
+		{"tags", "{set[integer/int64]}"},
+	}
+	for _, tt := range tests {
+		if got := shapes[tt.property]; got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.property, got, tt.want)
+		}
+	}
+	// the struct met inside the containers is registered once
+	if names := schemaNames(d); !reflect.DeepEqual(names, []string{"Item", "Nested", "NestedReqBody"}) {
+		t.Errorf("got schemas %v", names)
+	}
+}
+
+// ignoreIDL declares an ignored service and an ignored function, the value of the
+// openapi.ignore of DebugService is formatted in.
+const ignoreIDL = `
+struct Req {
+    1: i64 id (api.query = "id")
+}
+
+service UserService {
+    Req GetUser(1: Req req) (api.get = "/users")
+    Req ResetUsers(1: Req req) (api.post = "/users/reset", openapi.ignore = "true")
+    Req ListUsers(1: Req req) (api.get = "/users/list", openapi.ignore = "false")
+}
+
+service InternalService {
+    Req Flush(1: Req req) (api.post = "/internal/flush")
+}(openapi.ignore = "true")
+
+service DebugService {
+    Req Dump(1: Req req) (api.get = "/debug/dump")
+}(openapi.ignore = "%s")
+`
+
+func TestIgnore(t *testing.T) {
+	idl := writeMain(t, fmt.Sprintf(ignoreIDL, "false"))
+	g, generated := generateFiles(t, idl, &args.Arguments{})
+	content := generatedFile(t, generated, "openapi.yaml")
+	for path, want := range map[string]bool{
+		"/users":          true,
+		"/users/list":     true,
+		"/users/reset":    false,
+		"/internal/flush": false,
+		"/debug/dump":     true,
+	} {
+		if got := lookup(t, content, "paths", path) != nil; got != want {
+			t.Errorf("%s: got documented %v, want %v", path, got, want)
+		}
+	}
+	var tags []string
+	for _, tag := range g.Document().Tags {
+		tags = append(tags, tag.Name)
+	}
+	if want := []string{"DebugService", "UserService"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %v, want %v", tags, want)
+	}
+	if len(g.Warnings()) != 0 {
+		t.Errorf("got warnings %q", g.Warnings())
+	}
+
+	// an invalid value is reported and does not ignore the service
+	idl = writeMain(t, fmt.Sprintf(ignoreIDL, "yes"))
+	g, generated = generateFiles(t, idl, &args.Arguments{})
+	if lookup(t, generatedFile(t, generated, "openapi.yaml"), "paths", "/debug/dump") == nil {
+		t.Error("got /debug/dump ignored by an invalid value")
+	}
+	want := "service 'DebugService': invalid openapi.ignore 'yes', use 'true' or 'false'"
+	if warnings := g.Warnings(); len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+	if err := buildError(t, idl, &args.Arguments{Strict: true}); err == nil {
+		t.Error("built without error in strict mode")
+	}
+}
+
+// servicesIDL declares three services, one function each.
+const servicesIDL = `
+struct Req {
+    1: i64 id (api.query = "id")
+}
+
+service UserService {
+    Req GetUser(1: Req req) (api.get = "/users")
+}
+
+service OrderService {
+    Req GetOrder(1: Req req) (api.get = "/orders")
+}
+
+service InternalService {
+    Req Flush(1: Req req) (api.post = "/internal/flush")
+}
+`
+
+func TestServiceFilters(t *testing.T) {
+	idl := writeMain(t, servicesIDL)
+	tests := []struct {
+		name       string
+		parameters []string
+		paths      []string
+		warnings   int
+	}{
+		{"all", nil, []string{"/internal/flush", "/orders", "/users"}, 0},
+		{"exclude", []string{"ExcludeServices=InternalService"}, []string{"/orders", "/users"}, 0},
+		{"include", []string{"IncludeServices=UserService;InternalService"}, []string{"/internal/flush", "/users"}, 0},
+		{"both", []string{"IncludeServices=UserService;OrderService", "ExcludeServices=OrderService"}, []string{"/users"}, 0},
+		{"unknown", []string{"ExcludeServices=BillingService"}, []string{"/internal/flush", "/orders", "/users"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arguments := new(args.Arguments)
+			if err := arguments.Unpack(tt.parameters); err != nil {
+				t.Fatal(err)
+			}
+			g, d := buildDocument(t, idl, arguments)
+			var paths []string
+			for _, item := range d.Paths.Path {
+				paths = append(paths, item.Name)
+			}
+			sort.Strings(paths)
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("got paths %v, want %v", paths, tt.paths)
+			}
+			if len(g.Warnings()) != tt.warnings {
+				t.Errorf("got warnings %q", g.Warnings())
+			}
+		})
+	}
+}
+
+// findOperation returns the operation of the method and path, failing when there is none.
+func findOperation(t *testing.T, d *openapi.Document, method, path string) *openapi.Operation {
+	t.Helper()
+	if item := findPathItem(d, path); item != nil {
+		if op := operationOf(item.Value, method); op != nil {
+			return op
+		}
+	}
+	t.Fatalf("no operation %s %s", method, path)
+	return nil
+}
+
+// extension returns the YAML of the extension of the operation, empty when absent.
+func extension(op *openapi.Operation, name string) string {
+	for _, ext := range op.SpecificationExtension {
+		if ext.Name == name {
+			return ext.Value.Yaml
+		}
+	}
+	return ""
+}
+
+const sunsetIDL = `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service LegacyService {
+    Req Old(1: Req req) (api.get = "/old", openapi.sunset = "2020-01-01")
+    Req Soon(1: Req req) (api.get = "/soon", openapi.sunset = "2999-12-31")
+    Req Current(1: Req req) (api.get = "/current")
+}
+`
+
+func TestSunset(t *testing.T) {
+	idl := writeMain(t, sunsetIDL)
+	tests := []struct {
+		name       string
+		sunsetDate string
+		deprecated map[string]bool
+	}{
+		{"today", "", map[string]bool{"/old": true, "/soon": false, "/current": false}},
+		{"before the sunsets", "2019-12-31", map[string]bool{"/old": false, "/soon": false, "/current": false}},
+		{"past date", "2025-06-01", map[string]bool{"/old": true, "/soon": false, "/current": false}},
+		{"same day", "2020-01-01", map[string]bool{"/old": true, "/soon": false, "/current": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, d := buildDocument(t, idl, &args.Arguments{SunsetDate: tt.sunsetDate})
+			for path, deprecated := range tt.deprecated {
+				if op := findOperation(t, d, "GET", path); op.Deprecated != deprecated {
+					t.Errorf("%s: got deprecated %v, want %v", path, op.Deprecated, deprecated)
+				}
+			}
+			if got := extension(findOperation(t, d, "GET", "/old"), "x-sunset"); !strings.Contains(got, "2020-01-01") {
+				t.Errorf("got x-sunset %q, want 2020-01-01", got)
+			}
+			if got := extension(findOperation(t, d, "GET", "/current"), "x-sunset"); got != "" {
+				t.Errorf("got x-sunset %q without openapi.sunset", got)
+			}
+		})
+	}
+
+	err := buildError(t, idl, &args.Arguments{SunsetDate: "tomorrow"})
+	if err == nil || !strings.Contains(err.Error(), "invalid SunsetDate 'tomorrow'") {
+		t.Errorf("got error %v, want the invalid SunsetDate", err)
+	}
+}
+
+const byteIDL = `
+struct Bytes {
+    1: byte flag
+    2: i8 level
+    3: binary data
+    4: list<byte> flags
+}
+
+struct BytesReq {
+    1: Bytes bytes (api.body = "bytes")
+}
+
+service ByteService {
+    BytesReq Echo(1: BytesReq req) (api.post = "/bytes")
+}
+`
+
+func TestByteAndBinarySchemas(t *testing.T) {
+	idl := writeMain(t, byteIDL)
+	_, d := buildDocument(t, idl, &args.Arguments{})
+	shapes, _ := propertyShapes(t, d, "Bytes")
+	want := map[string]string{
+		"flag":  "integer/int8",
+		"level": "integer/int8",
+		"data":  "string/byte",
+		"flags": "[integer/int8]",
+	}
+	if !reflect.DeepEqual(shapes, want) {
+		t.Errorf("got %v, want %v", shapes, want)
+	}
+
+	_, d = buildDocument(t, idl, &args.Arguments{StandardFormats: true})
+	for _, property := range componentSchema(t, d, "Bytes").Properties.AdditionalProperties {
+		if property.Name != "flag" {
+			continue
+		}
+		schema := property.Value.Schema
+		if schema.Format != "int32" || schema.Minimum != -128 || schema.Maximum != 127 {
+			t.Errorf("got byte schema %s [%v, %v] with StandardFormats, want int32 [-128, 127]", schema.Format, schema.Minimum, schema.Maximum)
+		}
+	}
+}
+
+func TestNonStructResult(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service NameService {
+    string GetName(1: Req req) (api.get = "/name")
+}
+`)
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	op := findOperation(t, d, "GET", "/name")
+	if op.Responses == nil || len(op.Responses.ResponseOrReference) != 1 {
+		t.Fatalf("got responses %v, want a default response", op.Responses)
+	}
+	response := op.Responses.ResponseOrReference[0]
+	if response.Name != "default" || response.Value.Response.Description == "" {
+		t.Errorf("got response %s %q, want default with a description", response.Name, response.Value.Response.Description)
+	}
+	want := "NameService.GetName: the result 'string' is not a struct, the response is not documented"
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, []string{want}) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+}
+
+// multiArgsIDL declares a function taking several arguments.
+const multiArgsIDL = `
+struct Order {
+    1: i64 id (api.body = "id")
+}
+
+struct Options {
+    1: bool dry_run (api.body = "dry_run")
+}
+
+service OrderService {
+    Order CreateOrder(1: Order order, 2: Options options, 3: string note) (api.post = "/orders")
+}
+`
+
+func TestWrapMultiArgs(t *testing.T) {
+	idl := writeMain(t, multiArgsIDL)
+	g, d := buildDocument(t, idl, &args.Arguments{WrapMultiArgs: true})
+	shapes, names := propertyShapes(t, d, "OrderService_CreateOrder_Request")
+	want := map[string]string{"order": "ref(Order)", "options": "ref(Options)", "note": "string"}
+	if !reflect.DeepEqual(shapes, want) || !reflect.DeepEqual(names, []string{"order", "options", "note"}) {
+		t.Errorf("got properties %v in order %v, want %v", shapes, names, want)
+	}
+	body := findOperation(t, d, "POST", "/orders").RequestBody.RequestBody
+	if shape := schemaShape(body.Content.AdditionalProperties[0].Value.Schema); !body.Required || shape != "ref(OrderService_CreateOrder_Request)" {
+		t.Errorf("got request body %s, required %v, want the arguments schema", shape, body.Required)
+	}
+	if warnings := g.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q", warnings)
+	}
+
+	g, d = buildDocument(t, idl, &args.Arguments{})
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		if schema.Name == "OrderService_CreateOrder_Request" {
+			t.Error("got the arguments schema without WrapMultiArgs")
+		}
+	}
+	wantWarning := "function 'CreateOrder' has more than one argument, but only the first can be used in hertz now, set WrapMultiArgs to document them all"
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, []string{wantWarning}) {
+		t.Errorf("got warnings %q, want %q", warnings, wantWarning)
+	}
+}
+
+// parameterList renders the parameters of the operation as in:name, with a * for the
+// required ones.
+func parameterList(op *openapi.Operation) []string {
+	var list []string
+	for _, param := range op.Parameters {
+		entry := param.Parameter.In + ":" + param.Parameter.Name
+		if param.Parameter.Required {
+			entry += "*"
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+func TestServiceParameters(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+struct TenantReq {
+    1: string tenant (api.header = "x-tenant-id")
+}
+
+service TenantService {
+    Req GetUser(1: Req req) (api.get = "/users")
+    Req GetTenant(1: TenantReq req) (api.get = "/tenant")
+} (
+    openapi.parameter = '{name: "X-Tenant-ID", in: "header", required: true}',
+    openapi.parameter = '{name: "locale", in: "query"}',
+    openapi.parameter = '{name: "broken"}'
+)
+`)
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/users", []string{"header:X-Tenant-ID*", "query:locale", "query:name"}},
+		// the header of the operation replaces the one of the service
+		{"/tenant", []string{"query:locale", "header:x-tenant-id"}},
+	}
+	for _, tt := range tests {
+		if got := parameterList(findOperation(t, d, "GET", tt.path)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got parameters %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if warnings := g.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "has no name or location") {
+		t.Errorf("got warnings %q, want the parameter without location", warnings)
+	}
+}
+
+// serverURLs returns the URLs of the servers.
+func serverURLs(servers []*openapi.Server) []string {
+	var urls []string
+	for _, server := range servers {
+		urls = append(urls, server.URL)
+	}
+	return urls
+}
+
+func TestBaseURLPath(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service GatewayService {
+    Req GetUser(1: Req req) (api.get = "/users", api.baseurl = "gateway.internal:8080/v2")
+    Req GetPlain(1: Req req) (api.get = "/plain")
+}
+`)
+	tests := []struct {
+		baseURLPath string
+		path        string
+		servers     []string
+	}{
+		{"", "/users", []string{"http://gateway.internal:8080/v2"}},
+		{"server", "/users", []string{"http://gateway.internal:8080/v2"}},
+		{"operation", "/v2/users", []string{"http://gateway.internal:8080"}},
+	}
+	for _, tt := range tests {
+		t.Run("BaseURLPath="+tt.baseURLPath, func(t *testing.T) {
+			_, d := buildDocument(t, idl, &args.Arguments{BaseURLPath: tt.baseURLPath})
+			// The only server of the operations is moved to the document.
+			if got := serverURLs(d.Servers); !reflect.DeepEqual(got, tt.servers) {
+				t.Errorf("got servers %v, want %v", got, tt.servers)
+			}
+			if got := serverURLs(findOperation(t, d, "GET", tt.path).Servers); len(got) != 0 {
+				t.Errorf("got servers %v left on the operation", got)
+			}
+		})
+	}
+
+	err := buildError(t, idl, &args.Arguments{BaseURLPath: "path"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported BaseURLPath 'path'") {
+		t.Errorf("got error %v, want an unsupported BaseURLPath", err)
+	}
+}
+
+func TestVersionInPath(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: i64 id (api.path = "id")
+}
+
+service UserService {
+    Req GetUser(1: Req req) (api.get = "/users/:id")
+} (
+    openapi.base_path = "/api",
+    openapi.document = '{info: {title: "Users", version: "2.1.0"}}'
+)
+`)
+	tests := []struct {
+		name      string
+		arguments *args.Arguments
+		path      string
+	}{
+		{"disabled", &args.Arguments{}, "/api/users/{id}"},
+		{"info version", &args.Arguments{VersionInPath: true}, "/v2/api/users/{id}"},
+		{"ApiVersion", &args.Arguments{VersionInPath: true, ApiVersion: "v3"}, "/v3/api/users/{id}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, d := buildDocument(t, idl, tt.arguments)
+			if len(d.Paths.Path) != 1 || d.Paths.Path[0].Name != tt.path {
+				t.Errorf("got %d paths, the first %s, want %s", len(d.Paths.Path), d.Paths.Path[0].Name, tt.path)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		arguments *args.Arguments
+		want      string
+	}{
+		{&args.Arguments{ApiVersion: "3"}, "ApiVersion requires VersionInPath"},
+		{&args.Arguments{VersionInPath: true, ApiVersion: "beta"}, "invalid version 'beta' for VersionInPath"},
+	}
+	for _, tt := range errorTests {
+		if err := buildError(t, idl, tt.arguments); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("got error %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestServerOption(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service RegionService {
+    Req GetA(1: Req req) (api.get = "/a")
+    Req GetB(1: Req req) (
+        api.get = "/b",
+        openapi.server = '{"url": "https://{region}.api.example.com", "description": "Regional", "variables": {"region": {"default": "us", "enum": ["us", "eu"]}}}',
+        openapi.server = '{"url": "https://backup.example.com"}'
+    )
+    Req GetC(1: Req req) (api.get = "/c", openapi.server = '{"description": "no url"}')
+} (
+    api.base_domain = "internal.example.com",
+    openapi.server = '{"url": "https://api.example.com"}'
+)
+`)
+	g, d := buildDocument(t, idl, &args.Arguments{})
+	// The server of the service replaces the one of api.base_domain, GetA and GetC
+	// inherit it and it is moved to the document.
+	if got := serverURLs(d.Servers); !reflect.DeepEqual(got, []string{"https://api.example.com"}) {
+		t.Errorf("got document servers %v, want the server of the service", got)
+	}
+	for _, path := range []string{"/a", "/c"} {
+		if got := serverURLs(findOperation(t, d, "GET", path).Servers); len(got) != 0 {
+			t.Errorf("%s: got servers %v, want the ones of the document", path, got)
+		}
+	}
+
+	servers := findOperation(t, d, "GET", "/b").Servers
+	if got := serverURLs(servers); !reflect.DeepEqual(got, []string{"https://{region}.api.example.com", "https://backup.example.com"}) {
+		t.Fatalf("/b: got servers %v, want both servers of the method", got)
+	}
+	if servers[0].Description != "Regional" || servers[0].Variables == nil || len(servers[0].Variables.AdditionalProperties) != 1 {
+		t.Fatalf("got server %+v, want the description and the region variable", servers[0])
+	}
+	region := servers[0].Variables.AdditionalProperties[0]
+	if region.Name != "region" || region.Value.Get_Default() != "us" || !reflect.DeepEqual(region.Value.Enum, []string{"us", "eu"}) {
+		t.Errorf("got variable %s %+v, want region us [us eu]", region.Name, region.Value)
+	}
+
+	want := "RegionService.GetC: invalid openapi.server annotation: the server has no url"
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, []string{want}) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+}
+
+// commentsIDL declares structs documented by one-line and block comments with
+// Markdown paragraphs and code blocks.
+const commentsIDL = `
+// Lists the users.
+//
+//
+//     users := List()
+//     fmt.Println(users)
+struct Listing {
+    1: string name
+}
+
+/*
+   Returns the user.
+
+     user := Get(id)
+*/
+struct User {
+    1: string name
+    2: list<Listing> listings
+}
+
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service UserService {
+    User GetUser(1: Req req) (api.get = "/user")
+}
+`
+
+func TestMarkdownDescriptions(t *testing.T) {
+	idl := writeMain(t, commentsIDL)
+	tests := []struct {
+		markdown bool
+		listing  string
+		user     string
+	}{
+		{false, "Lists the users.\n\nusers := List()\nfmt.Println(users)", "Returns the user.\n\nuser := Get(id)"},
+		{true, "Lists the users.\n\n\n    users := List()\n    fmt.Println(users)", "Returns the user.\n\n  user := Get(id)"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("MarkdownDescriptions=%v", tt.markdown), func(t *testing.T) {
+			_, d := buildDocument(t, idl, &args.Arguments{MarkdownDescriptions: tt.markdown})
+			if got := componentSchema(t, d, "Listing").Description; got != tt.listing {
+				t.Errorf("got Listing description %q, want %q", got, tt.listing)
+			}
+			if got := componentSchema(t, d, "User").Description; got != tt.user {
+				t.Errorf("got User description %q, want %q", got, tt.user)
+			}
+		})
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		max         int
+		want        string
+	}{
+		{"Lists the users", 0, "Lists the users"},
+		{"Lists the users", 15, "Lists the users"},
+		{"Lists the users of the team", 12, "Lists the..."},
+		// the limit falls on a space
+		{"Lists the users", 9, "Lists the..."},
+		{"Lists the  users", 10, "Lists the..."},
+		// a word longer than the limit is cut
+		{"Supercalifragilistic", 5, "Super..."},
+		// the limit counts runes, not bytes
+		{"héllo wörld", 8, "héllo..."},
+	}
+	for _, tt := range tests {
+		if got := truncateDescription(tt.description, tt.max); got != tt.want {
+			t.Errorf("truncateDescription(%q, %d) = %q, want %q", tt.description, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestMaxDescriptionLength(t *testing.T) {
+	idl := writeMain(t, commentsIDL)
+	_, d := buildDocument(t, idl, &args.Arguments{MaxDescriptionLength: 12})
+	if got := componentSchema(t, d, "User").Description; got != "Returns the..." {
+		t.Errorf("got User description %q, want it truncated", got)
+	}
+
+	err := buildError(t, idl, &args.Arguments{MaxDescriptionLength: -1})
+	if err == nil || !strings.Contains(err.Error(), "MaxDescriptionLength must be positive, got -1") {
+		t.Errorf("got error %v, want a negative MaxDescriptionLength", err)
+	}
+}
+
+func TestSecuritySchemes(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+} (
+    openapi.security_scheme = '{"name": "oidc", "securityScheme": {"type": "openIdConnect"}}'
+)
+
+service AuthService {
+    Req GetUser(1: Req req) (api.get = "/users")
+} (
+    openapi.security_scheme = '{"name": "bearerAuth", "securityScheme": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}}',
+    openapi.security_scheme = '{"name": "apiKey", "securityScheme": {"type": "apiKey", "name": "X-API-Key", "in": "header"}}',
+    openapi.security_scheme = '{"name": "oauth", "securityScheme": {"type": "oauth2", "flows": {"clientCredentials": {"tokenUrl": "https://auth.example.com/token", "scopes": {"write": "Write", "read": "Read"}}}}}',
+    openapi.security_scheme = '{"name": "bearerAuth", "securityScheme": {"type": "http", "scheme": "basic"}}',
+    openapi.security_scheme = '{"name": "broken", "securityScheme": {"type": "apiKey", "name": "key"}}'
+)
+`)
+	g, generated := generateFiles(t, idl, &args.Arguments{})
+	var names []string
+	for _, scheme := range g.Document().Components.SecuritySchemes.AdditionalProperties {
+		names = append(names, scheme.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"bearerAuth", "apiKey", "oauth"}) {
+		t.Errorf("got security schemes %v, want bearerAuth, apiKey and oauth", names)
+	}
+
+	content := generatedFile(t, generated, "openapi.yaml")
+	tests := []struct {
+		path []interface{}
+		want interface{}
+	}{
+		// the first declaration of a name wins
+		{[]interface{}{"bearerAuth", "scheme"}, "bearer"},
+		{[]interface{}{"bearerAuth", "bearerFormat"}, "JWT"},
+		{[]interface{}{"apiKey", "type"}, "apiKey"},
+		{[]interface{}{"apiKey", "in"}, "header"},
+		{[]interface{}{"apiKey", "name"}, "X-API-Key"},
+		{[]interface{}{"oauth", "flows", "clientCredentials", "tokenUrl"}, "https://auth.example.com/token"},
+		{[]interface{}{"oauth", "flows", "clientCredentials", "scopes"}, map[string]interface{}{"read": "Read", "write": "Write"}},
+	}
+	for _, tt := range tests {
+		path := append([]interface{}{"components", "securitySchemes"}, tt.path...)
+		if got := lookup(t, content, path...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	want := []string{
+		"service 'AuthService': security scheme 'bearerAuth' is already declared",
+		"service 'AuthService': invalid openapi.security_scheme annotation: an apiKey scheme requires a name and in 'query', 'header' or 'cookie'",
+		"struct 'Req': invalid openapi.security_scheme annotation: an openIdConnect scheme requires an openIdConnectUrl",
+	}
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+}
+
+func TestOperationSecurity(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service AuthService {
+    Req GetUser(1: Req req) (api.get = "/users")
+    Req GetHealth(1: Req req) (api.get = "/health", openapi.operation = '{security: []}')
+    Req GetAdmin(1: Req req) (
+        api.get = "/admin",
+        openapi.operation = '{security: [{additional_properties: [{name: "adminKey", value: {values: []}}]}]}'
+    )
+    Req GetToken(1: Req req) (
+        api.get = "/token",
+        openapi.operation = '{security: [{additional_properties: [{name: "missing", value: {values: []}}]}]}'
+    )
+} (
+    openapi.document = '{security: [{additional_properties: [{name: "bearerAuth", value: {values: []}}]}]}',
+    openapi.security_scheme = '{"name": "bearerAuth", "securityScheme": {"type": "http", "scheme": "bearer"}}',
+    openapi.security_scheme = '{"name": "adminKey", "securityScheme": {"type": "apiKey", "name": "X-Admin-Key", "in": "header"}}'
+)
+`)
+	g, generated := generateFiles(t, idl, &args.Arguments{})
+	content := generatedFile(t, generated, "openapi.yaml")
+	requirement := func(name string) []interface{} {
+		return []interface{}{map[string]interface{}{name: []interface{}{}}}
+	}
+	tests := []struct {
+		path []interface{}
+		want interface{}
+	}{
+		{[]interface{}{"security"}, requirement("bearerAuth")},
+		// the operations without security inherit the one of the document
+		{[]interface{}{"paths", "/users", "get", "security"}, nil},
+		// an empty security leaves the operation public
+		{[]interface{}{"paths", "/health", "get", "security"}, []interface{}{}},
+		{[]interface{}{"paths", "/admin", "get", "security"}, requirement("adminKey")},
+	}
+	for _, tt := range tests {
+		if got := lookup(t, content, tt.path...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	want := "GET /token: security requirement 'missing' names no declared security scheme"
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, []string{want}) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+
+	if err := buildError(t, idl, &args.Arguments{Strict: true}); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v with Strict, want %q", err, want)
+	}
+}
+
+func TestAudience(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service UserService {
+    Req GetA(1: Req req) (api.get = "/a")
+    Req GetB(1: Req req) (api.get = "/b", openapi.audience = "internal")
+    Req GetC(1: Req req) (api.get = "/c", openapi.audience = "secret")
+}
+
+service AdminService {
+    Req GetD(1: Req req) (api.get = "/d")
+    Req GetE(1: Req req) (api.get = "/e", openapi.audience = "public")
+} (openapi.audience = "internal")
+`)
+	tests := []struct {
+		audience string
+		paths    []string
+	}{
+		{"", []string{"/a", "/b", "/c", "/d", "/e"}},
+		{"all", []string{"/a", "/b", "/c", "/d", "/e"}},
+		// the invalid audience of GetC falls back to the one of its service
+		{"public", []string{"/a", "/c", "/e"}},
+		{"internal", []string{"/b", "/d"}},
+	}
+	for _, tt := range tests {
+		t.Run("Audience="+tt.audience, func(t *testing.T) {
+			g, d := buildDocument(t, idl, &args.Arguments{Audience: tt.audience})
+			var paths []string
+			for _, path := range d.Paths.Path {
+				paths = append(paths, path.Name)
+			}
+			sort.Strings(paths)
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("got paths %v, want %v", paths, tt.paths)
+			}
+			want := "UserService.GetC: invalid openapi.audience 'secret', use 'public' or 'internal'"
+			if warnings := g.Warnings(); !reflect.DeepEqual(warnings, []string{want}) {
+				t.Errorf("got warnings %q, want %q", warnings, want)
+			}
+		})
+	}
+
+	err := buildError(t, idl, &args.Arguments{Audience: "partner"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported Audience 'partner'") {
+		t.Errorf("got error %v, want an unsupported Audience", err)
+	}
+}
+
+func TestSchemaExamples(t *testing.T) {
+	idl := writeMain(t, `
+struct Item {
+    1: string name
+    2: i32 count
+} (
+    openapi.examples = '[{"summary": "Apple", "value": {"name": "apple", "count": 3}}, {"summary": "Remote", "externalValue": "https://example.com/item.json"}, {"value": {"name": "pear"}}]'
+)
+
+struct ListResp {
+    1: list<Item> items (api.body = "items")
+} (openapi.examples = '[{"summary": "Empty", "value": {"items": []}}]')
+
+struct Bad {
+    1: string name (api.body = "name")
+} (openapi.examples = '{"value": "not a list"}')
+
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service ItemService {
+    ListResp ListItems(1: Req req) (api.get = "/items")
+    Bad GetBad(1: Req req) (api.get = "/bad")
+    Bad FindBad(1: Req req) (api.get = "/bad/find")
+}
+`)
+	tests := []struct {
+		version  string
+		openapi  string
+		examples interface{}
+	}{
+		{"", "3.0.3", nil},
+		// the values of the examples, without summaries nor external values
+		{"3.1.0", "3.1.0", []interface{}{
+			map[string]interface{}{"name": "apple", "count": 3},
+			map[string]interface{}{"name": "pear"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run("OpenapiVersion="+tt.version, func(t *testing.T) {
+			g, generated := generateFiles(t, idl, &args.Arguments{OpenapiVersion: tt.version})
+			content := generatedFile(t, generated, "openapi.yaml")
+			if got := lookup(t, content, "openapi"); got != tt.openapi {
+				t.Errorf("got version %v, want %s", got, tt.openapi)
+			}
+			if got := lookup(t, content, "components", "schemas", "Item", "examples"); !reflect.DeepEqual(got, tt.examples) {
+				t.Errorf("got examples of Item %v, want %v", got, tt.examples)
+			}
+			// The media types of the responses keep the examples in every version.
+			summary := lookup(t, content, "paths", "/items", "get", "responses", "200", "content", "application/json", "examples", "example1", "summary")
+			if summary != "Empty" {
+				t.Errorf("got the summary %v of the response example, want Empty", summary)
+			}
+			// The invalid examples of Bad are reported once for both functions.
+			warnings := g.Warnings()
+			if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "struct 'Bad': invalid openapi.examples: ") {
+				t.Errorf("got warnings %q, want the invalid examples of Bad", warnings)
+			}
+		})
+	}
+
+	err := buildError(t, idl, &args.Arguments{OpenapiVersion: "2.0"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported OpenapiVersion '2.0'") {
+		t.Errorf("got error %v, want an unsupported OpenapiVersion", err)
+	}
+}
+
+func TestPruneUnusedSchemas(t *testing.T) {
+	ref := func(name string) *openapi.SchemaOrReference {
+		return &openapi.SchemaOrReference{Reference: &openapi.Reference{Xref: schemaRefPrefix + name}}
+	}
+	schema := func(name string, value *openapi.SchemaOrReference) *openapi.NamedSchemaOrReference {
+		return &openapi.NamedSchemaOrReference{Name: name, Value: value}
+	}
+	item := &openapi.Schema{Type: "object", Properties: &openapi.Properties{
+		AdditionalProperties: []*openapi.NamedSchemaOrReference{schema("tag", ref("Tag"))},
+	}}
+	d := &openapi.Document{
+		Paths: &openapi.Paths{Path: []*openapi.NamedPathItem{{Name: "/items", Value: &openapi.PathItem{Get: &openapi.Operation{
+			Parameters: []*openapi.ParameterOrReference{{Parameter: &openapi.Parameter{Name: "item", In: "query", Schema: ref("Item")}}},
+		}}}}},
+		Components: &openapi.Components{Schemas: &openapi.SchemasOrReferences{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+			schema("Item", &openapi.SchemaOrReference{Schema: item}),
+			schema("Unused", &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "object"}}),
+			schema("Tag", &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "string"}}),
+		}}},
+	}
+	// The removal is reported even in Strict mode, but does not fail the build.
+	g := &OpenAPIGenerator{arguments: &args.Arguments{Strict: true}}
+	g.pruneUnusedSchemas(d)
+	var kept []string
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		kept = append(kept, schema.Name)
+	}
+	if want := []string{"Item", "Tag"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("got schemas %v, want %v", kept, want)
+	}
+	want := []string{"unreferenced schemas are removed, set KeepUnused to keep them: Unused"}
+	if warnings := g.Warnings(); !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+	if len(g.strictErrors) != 0 {
+		t.Errorf("got Strict errors %q for the removed schemas", g.strictErrors)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// operationIDRegistry records the operationIds of the document and the operations
+// using them, so that the same id is never given twice.
+type operationIDRegistry struct {
+	ids     []string
+	sources map[string]string
+}
+
+func newOperationIDRegistry() *operationIDRegistry {
+	return &operationIDRegistry{sources: make(map[string]string)}
+}
+
+// register reserves id for the operation described by source. When the id is
+// taken, the lower case method is appended, then an index from 2, and the operation
+// which took it first is returned along with the id given.
+func (r *operationIDRegistry) register(id, method, source string) (string, string) {
+	previous, taken := r.sources[id]
+	if !taken {
+		r.add(id, source)
+		return id, ""
+	}
+	unique := operationIDSuffix(id, strings.ToLower(method))
+	for i := 2; r.sources[unique] != ""; i++ {
+		unique = operationIDSuffix(id, strconv.Itoa(i))
+	}
+	r.add(unique, source)
+	return unique, previous
+}
+
+func (r *operationIDRegistry) add(id, source string) {
+	r.ids = append(r.ids, id)
+	r.sources[id] = source
+}
+
+// truncate forgets the ids registered after the first n ones.
+func (r *operationIDRegistry) truncate(n int) {
+	for _, id := range r.ids[n:] {
+		delete(r.sources, id)
+	}
+	r.ids = r.ids[:n]
+}
+
+// operationIDSuffix appends suffix to id, after an underscore when the id already
+// has one and capitalized otherwise, so that it matches the naming strategy.
+func operationIDSuffix(id, suffix string) string {
+	if strings.Contains(id, "_") || id == "" {
+		return id + "_" + suffix
+	}
+	return id + upperFirst(suffix)
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+// PostmanName is the name of the collection generated with the postman Format.
+const PostmanName = "postman_collection.json"
+
+// postmanSchema identifies the version of the collection format.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// postmanBaseURL is the collection variable the URLs of the requests start with.
+const postmanBaseURL = "baseUrl"
+
+type postmanCollection struct {
+	Info     postmanInfo        `json:"info"`
+	Item     []*postmanItem     `json:"item"`
+	Variable []*postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Request *postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method      string             `json:"method"`
+	Header      []*postmanKeyValue `json:"header"`
+	URL         *postmanURL        `json:"url"`
+	Body        *postmanBody       `json:"body,omitempty"`
+	Description string             `json:"description,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string             `json:"raw"`
+	Host     []string           `json:"host"`
+	Path     []string           `json:"path"`
+	Query    []*postmanKeyValue `json:"query,omitempty"`
+	Variable []*postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string              `json:"mode"`
+	Raw        string              `json:"raw,omitempty"`
+	URLEncoded []*postmanKeyValue  `json:"urlencoded,omitempty"`
+	FormData   []*postmanKeyValue  `json:"formdata,omitempty"`
+	Options    *postmanBodyOptions `json:"options,omitempty"`
+}
+
+type postmanBodyOptions struct {
+	Raw postmanRawOptions `json:"raw"`
+}
+
+type postmanRawOptions struct {
+	Language string `json:"language"`
+}
+
+type postmanKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// ConvertToPostman returns a Postman Collection v2.1 of the document, one request
+// per operation in the order of the paths. The requests are sent to the baseUrl
+// variable, set to the first server of the document, and are filled with the
+// examples of the parameters and of the JSON bodies.
+func ConvertToPostman(d *openapi.Document) ([]byte, error) {
+	collection := &postmanCollection{
+		Item: []*postmanItem{},
+		Info: postmanInfo{Schema: postmanSchema},
+	}
+	if d.Info != nil {
+		collection.Info.Name = d.Info.Title
+		collection.Info.Description = d.Info.Description
+	}
+	baseURL := ""
+	if len(d.Servers) > 0 {
+		baseURL = strings.TrimSuffix(d.Servers[0].URL, "/")
+	}
+	collection.Variable = []*postmanKeyValue{{Key: postmanBaseURL, Value: baseURL}}
+
+	if d.Paths != nil {
+		for _, path := range d.Paths.Path {
+			for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"} {
+				op := operationOf(path.Value, method)
+				if op == nil {
+					continue
+				}
+				collection.Item = append(collection.Item, postmanItemOf(d, path.Name, path.Value, method, op))
+			}
+		}
+	}
+
+	content, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error converting to postman collection: %s", err)
+	}
+	return content, nil
+}
+
+func postmanItemOf(d *openapi.Document, path string, item *openapi.PathItem, method string, op *openapi.Operation) *postmanItem {
+	name := op.Summary
+	if name == "" {
+		name = op.OperationID
+	}
+	if name == "" {
+		name = method + " " + path
+	}
+	request := &postmanRequest{
+		Method:      method,
+		Header:      []*postmanKeyValue{},
+		URL:         &postmanURL{Host: []string{"{{" + postmanBaseURL + "}}"}, Path: []string{}},
+		Description: op.Description,
+	}
+
+	// Postman marks the path variables with a colon rather than braces.
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segment = ":" + strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		}
+		request.URL.Path = append(request.URL.Path, segment)
+	}
+
+	// The parameters shared by the operations of the path are set on its item.
+	parameters := append(append([]*openapi.ParameterOrReference{}, item.Parameters...), op.Parameters...)
+	var cookies []string
+	for _, parameterOrRef := range parameters {
+		param := resolveParameter(d, parameterOrRef)
+		if param == nil {
+			continue
+		}
+		value := &postmanKeyValue{
+			Key:         param.Name,
+			Value:       sampleParameter(param),
+			Description: param.Description,
+		}
+		switch param.In {
+		case "path":
+			request.URL.Variable = append(request.URL.Variable, value)
+		case "query":
+			value.Disabled = !param.Required
+			request.URL.Query = append(request.URL.Query, value)
+		case "header":
+			value.Disabled = !param.Required
+			request.Header = append(request.Header, value)
+		case "cookie":
+			cookies = append(cookies, value.Key+"="+value.Value)
+		}
+	}
+	if len(cookies) > 0 {
+		request.Header = append(request.Header, &postmanKeyValue{Key: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+
+	if body := resolveRequestBody(d, op.RequestBody); body != nil {
+		for _, content := range mediaTypesOf(body.Content) {
+			if content.Value == nil {
+				continue
+			}
+			if request.Body = postmanBodyOf(d, content.Name, content.Value.Schema); request.Body != nil {
+				request.Header = append(request.Header, &postmanKeyValue{Key: "Content-Type", Value: content.Name})
+				break
+			}
+		}
+	}
+
+	// The raw URL holds the enabled query parameters only, as Postman renders it.
+	var query []string
+	for _, value := range request.URL.Query {
+		if !value.Disabled {
+			query = append(query, value.Key+"="+value.Value)
+		}
+	}
+	request.URL.Raw = "{{" + postmanBaseURL + "}}/" + strings.Join(request.URL.Path, "/")
+	if len(query) > 0 {
+		request.URL.Raw += "?" + strings.Join(query, "&")
+	}
+	return &postmanItem{Name: name, Request: request}
+}
+
+// postmanBodyOf returns the body of a request sent as the media type, JSON bodies
+// are raw and forms list their properties. It returns nil for the other media types.
+func postmanBodyOf(d *openapi.Document, mediaType string, schemaOrRef *openapi.SchemaOrReference) *postmanBody {
+	switch mediaType {
+	case "application/json":
+		var buf bytes.Buffer
+		writeSampleJSON(&buf, d, schemaOrRef, 0)
+		return &postmanBody{
+			Mode:    "raw",
+			Raw:     buf.String(),
+			Options: &postmanBodyOptions{Raw: postmanRawOptions{Language: "json"}},
+		}
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		var fields []*postmanKeyValue
+		if schema := resolveSchema(d, schemaOrRef); schema != nil && schema.Properties != nil {
+			for _, property := range schema.Properties.AdditionalProperties {
+				field := &postmanKeyValue{Key: property.Name, Type: "text"}
+				if propertySchema := resolveSchema(d, property.Value); propertySchema != nil {
+					field.Description = propertySchema.Description
+					if propertySchema.Format == "binary" {
+						field.Type = "file"
+					}
+				}
+				if field.Type == "text" {
+					field.Value = sampleParameter(&openapi.Parameter{Schema: property.Value})
+				}
+				fields = append(fields, field)
+			}
+		}
+		if mediaType == "multipart/form-data" {
+			return &postmanBody{Mode: "formdata", FormData: fields}
+		}
+		return &postmanBody{Mode: "urlencoded", URLEncoded: fields}
+	}
+	return nil
+}
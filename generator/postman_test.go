@@ -0,0 +1,176 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+// postmanIDL declares a request of each kind of parameter with a JSON body, one
+// with a form and an operation sharing its path parameter, moved to the path item.
+const postmanIDL = `
+struct UserReq {
+    1: string id (api.path = "id")
+    2: bool notify (api.query = "notify")
+    3: string tenant (api.query = "tenant", openapi.parameter = '{required: true}')
+    4: string token (api.header = "X-Token")
+    5: string session (api.cookie = "session")
+    6: string name (api.body = "name")
+    7: i32 age (api.body = "age")
+}
+
+struct DeleteReq {
+    1: string id (api.path = "id")
+}
+
+struct LoginReq {
+    1: string user (api.form = "user")
+    2: i32 pin (api.form = "pin")
+}
+
+struct UserResp {
+    1: string name (api.body = "name")
+}
+
+service UserService {
+    UserResp UpdateUser(1: UserReq req) (api.put = "/users/:id")
+    UserResp DeleteUser(1: DeleteReq req) (api.delete = "/users/:id")
+    UserResp Login(1: LoginReq req) (api.post = "/login")
+} (api.base_domain = "api.example.com")
+`
+
+// postmanRequests decodes the collection and returns its requests by item name.
+func postmanRequests(t *testing.T, content string) (*postmanCollection, map[string]*postmanRequest) {
+	t.Helper()
+	var collection *postmanCollection
+	if err := json.Unmarshal([]byte(content), &collection); err != nil {
+		t.Fatalf("decode the collection: %s", err)
+	}
+	requests := make(map[string]*postmanRequest)
+	for _, item := range collection.Item {
+		requests[item.Name] = item.Request
+	}
+	return collection, requests
+}
+
+func TestConvertToPostman(t *testing.T) {
+	_, generated := generateFiles(t, writeMain(t, postmanIDL), &args.Arguments{Format: FormatPostman})
+	collection, requests := postmanRequests(t, generatedFile(t, generated, PostmanName))
+	if collection.Info.Schema != postmanSchema {
+		t.Errorf("got schema %s, want %s", collection.Info.Schema, postmanSchema)
+	}
+	if want := []*postmanKeyValue{{Key: postmanBaseURL, Value: "http://api.example.com"}}; !reflect.DeepEqual(collection.Variable, want) {
+		t.Errorf("got variables %+v, want the server of the document", collection.Variable)
+	}
+
+	pathVariable := []*postmanKeyValue{{Key: "id", Value: "string"}}
+	want := map[string]*postmanRequest{
+		"UserService_UpdateUser": {
+			Method: "PUT",
+			Header: []*postmanKeyValue{
+				{Key: "X-Token", Value: "string", Disabled: true},
+				{Key: "Cookie", Value: "session=string"},
+				{Key: "Content-Type", Value: "application/json"},
+			},
+			URL: &postmanURL{
+				// the optional query parameters are disabled and left out of the raw URL
+				Raw:  "{{baseUrl}}/users/:id?tenant=string",
+				Host: []string{"{{baseUrl}}"},
+				Path: []string{"users", ":id"},
+				Query: []*postmanKeyValue{
+					{Key: "notify", Value: "false", Disabled: true},
+					{Key: "tenant", Value: "string"},
+				},
+				Variable: pathVariable,
+			},
+			Body: &postmanBody{
+				Mode:    "raw",
+				Raw:     `{"name":"string","age":0}`,
+				Options: &postmanBodyOptions{Raw: postmanRawOptions{Language: "json"}},
+			},
+		},
+		"UserService_DeleteUser": {
+			Method: "DELETE",
+			Header: []*postmanKeyValue{},
+			URL: &postmanURL{
+				Raw:      "{{baseUrl}}/users/:id",
+				Host:     []string{"{{baseUrl}}"},
+				Path:     []string{"users", ":id"},
+				Variable: pathVariable,
+			},
+		},
+		"UserService_Login": {
+			Method: "POST",
+			Header: []*postmanKeyValue{{Key: "Content-Type", Value: "multipart/form-data"}},
+			URL: &postmanURL{
+				Raw:  "{{baseUrl}}/login",
+				Host: []string{"{{baseUrl}}"},
+				Path: []string{"login"},
+			},
+			Body: &postmanBody{
+				Mode: "formdata",
+				FormData: []*postmanKeyValue{
+					{Key: "user", Value: "string", Type: "text"},
+					{Key: "pin", Value: "0", Type: "text"},
+				},
+			},
+		},
+	}
+	if len(requests) != len(want) {
+		t.Errorf("got %d requests, want one per operation", len(requests))
+	}
+	for name, wantRequest := range want {
+		got, err := json.Marshal(requests[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantJSON, err := json.Marshal(wantRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(wantJSON) {
+			t.Errorf("%s: got request\n%s\nwant\n%s", name, got, wantJSON)
+		}
+	}
+}
+
+func TestPostmanFormat(t *testing.T) {
+	idl := writeMain(t, postmanIDL)
+	_, generated := generateFiles(t, idl, &args.Arguments{Format: FormatPostman, Stdout: true})
+	if len(generated) != 1 || generated[0].GetName() != StdoutName {
+		t.Fatalf("got %d files, want the collection on the standard output", len(generated))
+	}
+	if _, requests := postmanRequests(t, generated[0].Content); len(requests) != 3 {
+		t.Errorf("got %d requests on the standard output, want 3", len(requests))
+	}
+
+	_, generated = generateFiles(t, idl, &args.Arguments{})
+	for _, file := range generated {
+		if strings.HasSuffix(file.GetName(), PostmanName) {
+			t.Error("got a collection without the postman Format")
+		}
+	}
+
+	if err := buildError(t, idl, &args.Arguments{Format: "xml"}); err == nil || !strings.Contains(err.Error(), "unsupported Format 'xml'") {
+		t.Errorf("got error %v, want an unsupported Format", err)
+	}
+}
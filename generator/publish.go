@@ -0,0 +1,175 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// SpecMetadata describes a published document.
+type SpecMetadata struct {
+	// Services are the services documented.
+	Services []string `json:"services"`
+	// IDL is the path of the IDL the document is generated from.
+	IDL string `json:"idl"`
+	// IDLRevision is the SHA-256 of the IDL and of the files it includes.
+	IDLRevision      string `json:"idlRevision"`
+	GeneratorVersion string `json:"generatorVersion"`
+}
+
+// SpecPublisher publishes a generated document, given as JSON, e.g. to a registry.
+// HTTPPublisher uploads it with a PUT, other registries such as object stores can
+// be added by implementing Publish.
+type SpecPublisher interface {
+	Publish(ctx context.Context, document []byte, metadata SpecMetadata) error
+}
+
+// HTTPPublisher publishes the document and its metadata with an HTTP PUT of
+// {"metadata": ..., "document": ...}. Network errors, 429 and 5xx responses are
+// retried with exponential backoff, the other responses fail at once.
+type HTTPPublisher struct {
+	URL string
+	// Token is sent as a bearer token when set.
+	Token  string
+	Client *http.Client
+	// Retries is the number of attempts after the first one.
+	Retries int
+	// Backoff is the delay before the first retry, doubled for each next one.
+	Backoff time.Duration
+}
+
+// NewHTTPPublisher creates a publisher uploading to url, retrying 3 times.
+func NewHTTPPublisher(url, token string) *HTTPPublisher {
+	return &HTTPPublisher{
+		URL:     url,
+		Token:   token,
+		Client:  &http.Client{Timeout: 30 * time.Second},
+		Retries: 3,
+		Backoff: 500 * time.Millisecond,
+	}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, document []byte, metadata SpecMetadata) error {
+	body, err := json.Marshal(struct {
+		Metadata SpecMetadata    `json:"metadata"`
+		Document json.RawMessage `json:"document"`
+	}{metadata, document})
+	if err != nil {
+		return fmt.Errorf("error encoding published document: %s", err)
+	}
+
+	backoff := p.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := p.put(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= p.Retries {
+			return fmt.Errorf("error publishing document to %s: %s", p.URL, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error publishing document to %s: %s", p.URL, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// put uploads the body once and reports whether a failure can be retried.
+func (p *HTTPPublisher) put(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// DocumentJSON converts a generated YAML document to JSON.
+func DocumentJSON(content []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("error decoding document: %s", err)
+	}
+	converted, err := json.Marshal(plainValue(doc))
+	if err != nil {
+		return nil, fmt.Errorf("error converting to json: %s", err)
+	}
+	return converted, nil
+}
+
+// IDLRevision returns the SHA-256 of the IDL and of the files it includes, in the
+// order they are included.
+func IDLRevision(ast *parser.Thrift) (string, error) {
+	hash := sha256.New()
+	seen := make(map[*parser.Thrift]bool)
+	var walk func(t *parser.Thrift) error
+	walk = func(t *parser.Thrift) error {
+		if seen[t] {
+			return nil
+		}
+		seen[t] = true
+		content, err := ioutil.ReadFile(t.Filename)
+		if err != nil {
+			return err
+		}
+		hash.Write(content)
+		for _, include := range t.Includes {
+			if include.Reference != nil {
+				if err := walk(include.Reference); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(ast); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
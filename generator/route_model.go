@@ -0,0 +1,298 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/thrift_reflection"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/annotations"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+)
+
+// RouteModel is the HTTP binding of the functions of an IDL, built once from the
+// annotations and shared by the document and the server generators.
+type RouteModel struct {
+	// Functions are listed in declaration order, service by service.
+	Functions []*FunctionBinding
+
+	byFunction map[*parser.Function]*FunctionBinding
+}
+
+// FunctionBinding is the HTTP binding of a function.
+type FunctionBinding struct {
+	Service  *parser.Service
+	Function *parser.Function
+	// Enabled is false when the function is disabled by the active profiles or the
+	// service filters, the generated server answers its routes with 404.
+	Enabled bool
+	// Ignored is true when the function or its service is left out of the
+	// documentation by openapi.ignore, or the function by openapi.skip, the
+	// generated server still proxies it.
+	Ignored bool
+	// Host is the host of api.baseurl, or of api.base_domain on the service.
+	Host   string
+	Routes []*RouteBinding
+	// InvalidRoutes are the routes left out because their path cannot be routed.
+	InvalidRoutes []InvalidRoute
+	// Default is true when the function declares no route and is routed to
+	// POST /Service/Method by DefaultMapping or AutoOperations, its argument and
+	// result are then the JSON bodies of the request and of the response.
+	Default bool
+	// Fields are the bindings of the fields of the request.
+	Fields []FieldBinding
+	// Exceptions are the exceptions declared by the function.
+	Exceptions []ExceptionBinding
+	// StatusField is the name of the body field carrying the status of the response.
+	StatusField string
+	// RawBody is the name of the binary api.raw_body field of the response.
+	RawBody string
+	// Void is true when the function returns nothing, answered with 204.
+	Void bool
+	// Oneway is true when the client does not wait for the function, answered with 202.
+	Oneway bool
+}
+
+// RouteBinding is a route declared on a function.
+type RouteBinding struct {
+	annotations.Route
+	// Shadowed is true when the route is also declared by a disabled function or
+	// an earlier function, which then wins.
+	Shadowed bool
+}
+
+// InvalidRoute is a route declared on a function whose path cannot be routed.
+type InvalidRoute struct {
+	annotations.Route
+	Err error
+}
+
+// FieldBinding tells where a field of a request is carried.
+type FieldBinding struct {
+	// Field is the name of the field in the IDL.
+	Field    string
+	Bindings []annotations.Binding
+}
+
+// ExceptionBinding is an exception declared by a function, answered by the generated
+// server with the status of its api.http_code annotation.
+type ExceptionBinding struct {
+	// Field is the name of the throws field.
+	Field  string
+	Status int
+	// Fields are the names of the fields of the exception in JSON.
+	Fields []string
+}
+
+// NewRouteModel builds the HTTP binding of the functions of the IDL.
+func NewRouteModel(ast *parser.Thrift, args *args.Arguments) *RouteModel {
+	m := &RouteModel{byFunction: make(map[*parser.Function]*FunctionBinding)}
+	for _, s := range ast.Services {
+		// The invalid values are reported by the generator, see checkAnnotations.
+		serviceIgnored, _ := annotations.IsIgnored(utils.GetAnnotation(s.Annotations, annotations.OpenapiIgnore))
+		for _, f := range s.Functions {
+			ignored, _ := annotations.IsIgnored(utils.GetAnnotation(f.Annotations, annotations.OpenapiIgnore))
+			skipped, _ := annotations.IsSkipped(utils.GetAnnotation(f.Annotations, annotations.OpenapiSkip))
+			fb := &FunctionBinding{
+				Service:  s,
+				Function: f,
+				Enabled: args.ServiceSelected(s.GetName()) &&
+					annotations.ProfileActive(utils.GetAnnotation(f.Annotations, annotations.OpenapiOnlyIf), args.Profiles),
+				Ignored: serviceIgnored || ignored || skipped,
+				Host:    functionHost(s, f),
+				Void:    f.Void,
+				Oneway:  f.Oneway,
+			}
+			for _, route := range annotations.Routes(f) {
+				if err := annotations.ValidatePath(route.Path); err != nil {
+					fb.InvalidRoutes = append(fb.InvalidRoutes, InvalidRoute{Route: route, Err: err})
+					continue
+				}
+				fb.Routes = append(fb.Routes, &RouteBinding{Route: route})
+			}
+			if len(f.Arguments) > 0 {
+				fb.Fields = fieldBindings(lookupStructLike(ast, f.Arguments[0].Type, (*parser.Thrift).GetStructs))
+			}
+			fb.Exceptions = exceptionBindings(ast, f)
+			fb.StatusField, fb.RawBody = responseBindings(lookupStructLike(ast, f.FunctionType, (*parser.Thrift).GetStructs))
+			m.Functions = append(m.Functions, fb)
+			m.byFunction[f] = fb
+		}
+	}
+
+	// DefaultMapping routes every function declaring no route, AutoOperations only
+	// when no function of the IDL declares one.
+	if args.DefaultMapping || args.AutoOperations && !m.HasRoutes() {
+		for _, fb := range m.Functions {
+			if len(fb.Routes) > 0 || len(fb.InvalidRoutes) > 0 {
+				continue
+			}
+			fb.Default = true
+			fb.Routes = []*RouteBinding{{Route: annotations.Route{
+				Method: "POST",
+				Path:   "/" + fb.Service.GetName() + "/" + fb.Function.GetName(),
+			}}}
+		}
+	}
+
+	// The routes of the disabled functions are answered with 404, so they shadow
+	// the same routes of the enabled functions.
+	seen := m.Disabled()
+	for _, fb := range m.Functions {
+		if !fb.Enabled {
+			continue
+		}
+		for _, route := range fb.Routes {
+			if containsRoute(seen, route.Route) {
+				route.Shadowed = true
+				continue
+			}
+			seen = append(seen, route.Route)
+		}
+	}
+	return m
+}
+
+// Function returns the binding of a function of the IDL.
+func (m *RouteModel) Function(f *parser.Function) *FunctionBinding {
+	return m.byFunction[f]
+}
+
+// HasRoutes reports whether a function of the IDL declares a route, even one which
+// cannot be routed.
+func (m *RouteModel) HasRoutes() bool {
+	for _, fb := range m.Functions {
+		if !fb.Default && (len(fb.Routes) > 0 || len(fb.InvalidRoutes) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Disabled returns the routes of the disabled functions, without duplicates.
+func (m *RouteModel) Disabled() []annotations.Route {
+	var routes []annotations.Route
+	for _, fb := range m.Functions {
+		if fb.Enabled {
+			continue
+		}
+		for _, route := range fb.Routes {
+			if !containsRoute(routes, route.Route) {
+				routes = append(routes, route.Route)
+			}
+		}
+	}
+	return routes
+}
+
+func functionHost(s *parser.Service, f *parser.Function) string {
+	if values := utils.GetAnnotation(f.Annotations, annotations.ApiBaseURL); len(values) != 0 && values[0] != "" {
+		return values[0]
+	}
+	if values := utils.GetAnnotation(s.Annotations, annotations.ApiBaseDomain); len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func fieldBindings(req *parser.StructLike) []FieldBinding {
+	if req == nil {
+		return nil
+	}
+	var fields []FieldBinding
+	for _, field := range req.Fields {
+		// The annotations are read the way thrift_reflection registers them.
+		desc := &thrift_reflection.FieldDescriptor{
+			Name:        field.Name,
+			Annotations: make(map[string][]string),
+		}
+		for _, anno := range field.Annotations {
+			desc.Annotations[anno.Key] = anno.Values
+		}
+		fields = append(fields, FieldBinding{Field: field.Name, Bindings: annotations.Bindings(desc)})
+	}
+	return fields
+}
+
+func exceptionBindings(ast *parser.Thrift, f *parser.Function) []ExceptionBinding {
+	var exceptions []ExceptionBinding
+	for _, field := range f.Throws {
+		exception := ExceptionBinding{Field: field.Name, Status: annotations.DefaultExceptionStatus}
+		if e := lookupStructLike(ast, field.Type, (*parser.Thrift).GetExceptions); e != nil {
+			// An invalid annotation is reported with the document.
+			exception.Status, _ = annotations.ExceptionStatus(utils.GetAnnotation(e.Annotations, annotations.ApiHttpCode))
+			for _, member := range e.Fields {
+				exception.Fields = append(exception.Fields, bodyName(member))
+			}
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions
+}
+
+// responseBindings returns the name of the status field and of the binary raw body
+// field of a response, empty when it has none.
+func responseBindings(resp *parser.StructLike) (statusField, rawBody string) {
+	if resp == nil {
+		return "", ""
+	}
+	var rawBodies []*parser.Field
+	for _, member := range resp.Fields {
+		if statusField == "" && annotations.IsStatusField(utils.GetAnnotation(member.Annotations, annotations.ApiHttpCode)) {
+			statusField = bodyName(member)
+		}
+		if len(utils.GetAnnotation(member.Annotations, annotations.ApiRawBody)) > 0 {
+			rawBodies = append(rawBodies, member)
+		}
+	}
+	if len(rawBodies) == 1 && rawBodies[0].Type.GetName() == "binary" {
+		rawBody = rawBodies[0].Name
+		if values := utils.GetAnnotation(rawBodies[0].Annotations, annotations.ApiRawBody); len(values) > 0 && values[0] != "" {
+			rawBody = values[0]
+		}
+	}
+	return statusField, rawBody
+}
+
+// bodyName returns the name of the field in a JSON body.
+func bodyName(field *parser.Field) string {
+	if values := utils.GetAnnotation(field.Annotations, annotations.ApiBody); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return field.Name
+}
+
+// lookupStructLike returns the struct-like named by the type among the ones listed
+// by structs, in the IDL or in one of its includes.
+func lookupStructLike(ast *parser.Thrift, t *parser.Type, structs func(*parser.Thrift) []*parser.StructLike) *parser.StructLike {
+	if t == nil {
+		return nil
+	}
+	name := t.GetName()
+	if ref := t.GetReference(); ref != nil {
+		if int(ref.Index) >= len(ast.Includes) || ast.Includes[ref.Index].Reference == nil {
+			return nil
+		}
+		ast, name = ast.Includes[ref.Index].Reference, ref.Name
+	}
+	for _, st := range structs(ast) {
+		if st.Name == name {
+			return st
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/annotations"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+// routeModelIDL declares the bindings the route model carries.
+const routeModelIDL = `
+struct Req {
+    1: i64 id (api.path = "id")
+    2: string token (api.header = "X-Token")
+    3: string name (api.body = "name")
+    4: string note
+}
+
+struct Resp {
+    1: i32 code (api.http_code = "true")
+    2: binary data (api.raw_body = "data")
+}
+
+exception NotFound {
+    1: string message
+} (api.http_code = "404")
+
+service UserService {
+    Resp GetUser(1: Req req) throws (1: NotFound notFound) (api.get = "/users/:id", api.head = "/users/:id")
+    void DeleteUser(1: Req req) (api.delete = "/users/:id", api.baseurl = "users.example.com")
+    oneway void Notify(1: Req req) (api.post = "/notify")
+    Resp Export(1: Req req) (api.get = "/export", openapi.only_if = "beta")
+    Resp Hidden(1: Req req) (api.get = "/hidden", openapi.ignore = "true")
+    Resp Broken(1: Req req) (api.get = "/broken/:")
+    Resp Unrouted(1: Req req)
+}(api.base_domain = "api.example.com")
+`
+
+func TestRouteModel(t *testing.T) {
+	ast := parseIDL(t, writeMain(t, routeModelIDL))
+	m := NewRouteModel(ast, &args.Arguments{})
+	if len(m.Functions) != 7 {
+		t.Fatalf("got %d functions, want 7", len(m.Functions))
+	}
+	bindings := make(map[string]*FunctionBinding)
+	for i, fb := range m.Functions {
+		if fb.Function != ast.Services[0].Functions[i] || m.Function(fb.Function) != fb {
+			t.Errorf("got function %s out of declaration order", fb.Function.Name)
+		}
+		bindings[fb.Function.Name] = fb
+	}
+
+	get := bindings["GetUser"]
+	var routes []annotations.Route
+	for _, route := range get.Routes {
+		routes = append(routes, route.Route)
+	}
+	if want := []annotations.Route{{Method: "GET", Path: "/users/:id"}, {Method: "HEAD", Path: "/users/:id"}}; !reflect.DeepEqual(routes, want) {
+		t.Errorf("GetUser: got routes %v, want %v", routes, want)
+	}
+	if !get.Enabled || get.Ignored || get.Host != "api.example.com" {
+		t.Errorf("GetUser: got enabled %v, ignored %v, host %s", get.Enabled, get.Ignored, get.Host)
+	}
+	wantFields := []FieldBinding{
+		{"id", []annotations.Binding{{Annotation: annotations.ApiPath, In: annotations.InPath, Name: "id"}}},
+		{"token", []annotations.Binding{{Annotation: annotations.ApiHeader, In: annotations.InHeader, Name: "X-Token"}}},
+		{"name", []annotations.Binding{{Annotation: annotations.ApiBody, In: annotations.InBody, Name: "name"}}},
+		{"note", nil},
+	}
+	if !reflect.DeepEqual(get.Fields, wantFields) {
+		t.Errorf("GetUser: got fields %v, want %v", get.Fields, wantFields)
+	}
+	if want := []ExceptionBinding{{"notFound", 404, []string{"message"}}}; !reflect.DeepEqual(get.Exceptions, want) {
+		t.Errorf("GetUser: got exceptions %v, want %v", get.Exceptions, want)
+	}
+	if get.StatusField != "code" || get.RawBody != "data" {
+		t.Errorf("GetUser: got status field %q and raw body %q", get.StatusField, get.RawBody)
+	}
+
+	if fb := bindings["DeleteUser"]; !fb.Void || fb.Oneway || fb.Host != "users.example.com" {
+		t.Errorf("DeleteUser: got void %v, oneway %v, host %s", fb.Void, fb.Oneway, fb.Host)
+	}
+	if fb := bindings["Notify"]; !fb.Oneway {
+		t.Error("Notify: got not oneway")
+	}
+	if fb := bindings["Export"]; fb.Enabled {
+		t.Error("Export: got enabled without the beta profile")
+	}
+	if fb := bindings["Hidden"]; !fb.Enabled || !fb.Ignored {
+		t.Errorf("Hidden: got enabled %v and ignored %v, want it proxied but not documented", fb.Enabled, fb.Ignored)
+	}
+	if fb := bindings["Broken"]; len(fb.Routes) != 0 || len(fb.InvalidRoutes) != 1 || fb.InvalidRoutes[0].Err == nil {
+		t.Errorf("Broken: got routes %v and invalid routes %v", fb.Routes, fb.InvalidRoutes)
+	}
+	if fb := bindings["Unrouted"]; len(fb.Routes) != 0 || fb.Default {
+		t.Errorf("Unrouted: got routes %v without DefaultMapping", fb.Routes)
+	}
+	if want := []annotations.Route{{Method: "GET", Path: "/export"}}; !reflect.DeepEqual(m.Disabled(), want) {
+		t.Errorf("got disabled routes %v, want %v", m.Disabled(), want)
+	}
+	if !m.HasRoutes() {
+		t.Error("got no routes")
+	}
+
+	m = NewRouteModel(ast, &args.Arguments{DefaultMapping: true, Profiles: []string{"beta"}})
+	for _, fb := range m.Functions {
+		if fb.Function.Name != "Unrouted" {
+			continue
+		}
+		if want := []annotations.Route{{Method: "POST", Path: "/UserService/Unrouted"}}; !fb.Default || len(fb.Routes) != 1 || fb.Routes[0].Route != want[0] {
+			t.Errorf("Unrouted: got default %v and routes %v with DefaultMapping", fb.Default, fb.Routes)
+		}
+	}
+	if len(m.Disabled()) != 0 {
+		t.Errorf("got disabled routes %v with the beta profile", m.Disabled())
+	}
+}
+
+func TestRouteModelShadowed(t *testing.T) {
+	idl := writeMain(t, `
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service UserService {
+    Req Get(1: Req req) (api.get = "/users")
+    Req GetBeta(1: Req req) (api.get = "/users", openapi.only_if = "beta")
+    Req List(1: Req req) (api.get = "/users")
+}
+`)
+	m := NewRouteModel(parseIDL(t, idl), &args.Arguments{})
+	var shadowed []bool
+	for _, fb := range m.Functions {
+		if fb.Enabled {
+			shadowed = append(shadowed, fb.Routes[0].Shadowed)
+		}
+	}
+	// the route of the disabled function is answered with 404, it shadows the others
+	if want := []bool{true, true}; !reflect.DeepEqual(shadowed, want) {
+		t.Errorf("got shadowed %v, want %v", shadowed, want)
+	}
+
+	m = NewRouteModel(parseIDL(t, idl), &args.Arguments{Profiles: []string{"beta"}})
+	shadowed = shadowed[:0]
+	for _, fb := range m.Functions {
+		shadowed = append(shadowed, fb.Routes[0].Shadowed)
+	}
+	if want := []bool{false, true, true}; !reflect.DeepEqual(shadowed, want) {
+		t.Errorf("got shadowed %v with the profile, want %v", shadowed, want)
+	}
+}
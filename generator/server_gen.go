@@ -18,26 +18,95 @@ package generator
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"github.com/cloudwego/hertz/cmd/hz/util/logs"
+	"go/format"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/cloudwego/thriftgo/parser"
 	"github.com/cloudwego/thriftgo/plugin"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/annotations"
 	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
 )
 
 type ServerGenerator struct {
-	IdlPath   string
-	HertzAddr string
-	KitexAddr string
-	OutputDir string
+	IdlPath        string
+	HertzAddr      string
+	KitexAddr      string
+	OutputDir      string
+	ServiceName    string
+	ResolverImport string
+	ResolverExpr   string
+
+	ServerCert       string
+	ServerKey        string
+	ClientCA         string
+	ClientCert       string
+	ClientKey        string
+	ClientServerName string
+
+	AuthType        string
+	AuthUserEnv     string
+	AuthPasswordEnv string
+	AuthHeader      string
+	AuthKeyEnv      string
+	AuthProxy       bool
+
+	StreamThreshold  int
+	MaxResponseBytes int
+	StripHeaders     []string
+	ForwardHeaders   []string
+	Debug            bool
+	DebugBodyLimit   int
+
+	DisabledRoutes   []annotations.Route
+	ProxyRoutes      []proxyRoute
+	MethodRoutes     []methodRoute
+	DefaultRoutes    []methodRoute
+	Metrics          bool
+	MetricsPort      int
+	RequestID        bool
+	Tracing          string
+	ConfirmMutations bool
+	ValidateRequests bool
+	ReadinessProbe   bool
+	RateLimit        int
+	RateBurst        int
+	InvokeAPI        bool
+	StripStatusField bool
+
+	SpecFile  string
+	SpecURL   string
+	SpecParts []SpecPart
+	UI        string
+	Minify    bool
+
+	uiDist   string
+	uiAssets []*plugin.Generated
 }
 
-func NewServerGenerator(ast *parser.Thrift, args *args.Arguments) *ServerGenerator {
+// defaultStreamThreshold is the default StreamThreshold, the request body size
+// Hertz accepts by default.
+const defaultStreamThreshold = 4 << 20
+
+// defaultDebugBodyLimit is the default DebugBodyLimit.
+const defaultDebugBodyLimit = 1024
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewServerGenerator creates the generator of the server proxying the routes of
+// the model, built from the IDL when nil.
+func NewServerGenerator(ast *parser.Thrift, args *args.Arguments, routes *RouteModel) (*ServerGenerator, error) {
 	defaultHertzAddr := "127.0.0.1:8080"
 	defaultKitexAddr := "127.0.0.1:8888"
 	defaultOutputDir := "."
+	defaultServiceName := "swagger"
 
 	idlPath := ast.Filename
 	if idlPath == "" {
@@ -59,11 +128,467 @@ func NewServerGenerator(ast *parser.Thrift, args *args.Arguments) *ServerGenerat
 		outputDir = defaultOutputDir
 	}
 
+	serviceName := args.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	resolverImport, resolverExpr, err := parseResolver(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkTLS(args); err != nil {
+		return nil, err
+	}
+
+	if args.ReadinessProbe && resolverExpr != "" {
+		return nil, errors.New("ReadinessProbe dials KitexAddr and can not be used with a resolver")
+	}
+
+	switch args.Tracing {
+	case "", "otel":
+	default:
+		return nil, fmt.Errorf("unsupported Tracing '%s', use 'otel'", args.Tracing)
+	}
+
+	// The server routes the paths of the IDL, the versioned paths of the document
+	// would reach the catch-all route and match none of the operations of the spec.
+	if args.VersionInPath {
+		return nil, errors.New("VersionInPath documents paths the generated server does not route, set NoServer")
+	}
+
+	streamThreshold := args.StreamThreshold
+	switch {
+	case streamThreshold == 0:
+		streamThreshold = defaultStreamThreshold
+	case streamThreshold < 0:
+		return nil, fmt.Errorf("StreamThreshold must be positive, got %d", streamThreshold)
+	}
+
+	if args.MaxResponseBytes < 0 {
+		return nil, fmt.Errorf("MaxResponseBytes must be positive, got %d", args.MaxResponseBytes)
+	}
+
+	switch {
+	case args.MetricsPort < 0 || args.MetricsPort > 65535:
+		return nil, fmt.Errorf("MetricsPort must be a port number, got %d", args.MetricsPort)
+	case args.MetricsPort > 0 && !args.Metrics:
+		return nil, errors.New("MetricsPort requires Metrics")
+	}
+
+	if args.RateLimit < 0 || args.RateBurst < 0 {
+		return nil, fmt.Errorf("RateLimit and RateBurst must be positive, got %d and %d", args.RateLimit, args.RateBurst)
+	}
+	rateBurst := args.RateBurst
+	if rateBurst == 0 {
+		rateBurst = args.RateLimit
+	}
+
+	debugBodyLimit := args.DebugBodyLimit
+	switch {
+	case debugBodyLimit == 0:
+		debugBodyLimit = defaultDebugBodyLimit
+	case debugBodyLimit < 0:
+		return nil, fmt.Errorf("DebugBodyLimit must be positive, got %d", debugBodyLimit)
+	}
+
+	auth, err := parseAuth(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var specURL string
+	switch args.UISpec {
+	case "", "yaml":
+		specURL = "/openapi.yaml"
+	case "json":
+		specURL = "/openapi.json"
+	default:
+		return nil, fmt.Errorf("unsupported UISpec '%s', use 'yaml' or 'json'", args.UISpec)
+	}
+
+	ui := args.UI
+	if ui == "" {
+		ui = uiSwaggo
+	}
+	assets, err := uiAssets(ui, args.UIDist, outputDir, specURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if routes == nil {
+		routes = NewRouteModel(ast, args)
+	}
+
+	// In file mode the server reads the spec written next to it, so that
+	// regenerating it does not require rebuilding the server.
+	var specFile string
+	switch args.SpecMode {
+	case "", "embed":
+	case "file":
+		specFile = filepath.Join(outputDir, "openapi.yaml")
+	default:
+		return nil, fmt.Errorf("unsupported SpecMode '%s', use 'embed' or 'file'", args.SpecMode)
+	}
+
 	return &ServerGenerator{
-		IdlPath:   idlPath,
-		HertzAddr: hertzAddr,
-		KitexAddr: kitexAddr,
-		OutputDir: outputDir,
+		IdlPath:        idlPath,
+		HertzAddr:      hertzAddr,
+		KitexAddr:      kitexAddr,
+		OutputDir:      outputDir,
+		ServiceName:    serviceName,
+		ResolverImport: resolverImport,
+		ResolverExpr:   resolverExpr,
+
+		ServerCert:       args.ServerCert,
+		ServerKey:        args.ServerKey,
+		ClientCA:         args.ClientCA,
+		ClientCert:       args.ClientCert,
+		ClientKey:        args.ClientKey,
+		ClientServerName: args.ClientServerName,
+
+		AuthType:        auth.AuthType,
+		AuthUserEnv:     auth.AuthUserEnv,
+		AuthPasswordEnv: auth.AuthPasswordEnv,
+		AuthHeader:      auth.AuthHeader,
+		AuthKeyEnv:      auth.AuthKeyEnv,
+		AuthProxy:       args.AuthProxy,
+
+		StreamThreshold:  streamThreshold,
+		MaxResponseBytes: args.MaxResponseBytes,
+		StripHeaders:     lowerAll(args.StripHeaders),
+		ForwardHeaders:   lowerAll(args.ForwardHeaders),
+		Debug:            args.Debug,
+		DebugBodyLimit:   debugBodyLimit,
+
+		DisabledRoutes:   routes.Disabled(),
+		ProxyRoutes:      proxyRoutes(routes),
+		MethodRoutes:     methodRoutes(routes),
+		DefaultRoutes:    defaultRoutes(routes),
+		Metrics:          args.Metrics,
+		MetricsPort:      args.MetricsPort,
+		RequestID:        args.RequestID,
+		Tracing:          args.Tracing,
+		ConfirmMutations: args.ConfirmMutations,
+		ValidateRequests: args.ValidateRequests,
+		ReadinessProbe:   args.ReadinessProbe,
+		RateLimit:        args.RateLimit,
+		RateBurst:        rateBurst,
+		InvokeAPI:        args.InvokeAPI,
+		StripStatusField: args.StripStatusField,
+
+		SpecFile: specFile,
+		SpecURL:  specURL,
+		UI:       ui,
+		Minify:   args.Minify,
+
+		uiDist:   args.UIDist,
+		uiAssets: assets,
+	}, nil
+}
+
+// SetSpecParts makes the server serve the parts of a split document and the UI
+// list them instead of loading the complete document.
+func (g *ServerGenerator) SetSpecParts(parts []SpecPart) error {
+	assets, err := uiAssets(g.UI, g.uiDist, g.OutputDir, g.SpecURL, parts)
+	if err != nil {
+		return err
+	}
+	g.SpecParts = parts
+	g.uiAssets = assets
+	return nil
+}
+
+// EmbedUI reports whether the server embeds the ui directory.
+func (g *ServerGenerator) EmbedUI() bool {
+	return g.UI != uiSwaggo || len(g.SpecParts) > 0
+}
+
+// methodRoute is a route of a function of a service.
+type methodRoute struct {
+	annotations.Route
+	Service  string
+	Function string
+	// NoContent is true when the function is void, answered with 204.
+	NoContent bool
+	// Accepted is true when the function is oneway, answered with 202 instead.
+	Accepted bool
+}
+
+// Key identifies the route in the tables of the generated server.
+func (r methodRoute) Key() string {
+	return r.Method + " " + r.Path
+}
+
+// methodRoutes returns the routes of the enabled functions, the first function
+// declaring a route wins and disabled routes are left out.
+func methodRoutes(model *RouteModel) []methodRoute {
+	var routes []methodRoute
+	for _, fb := range model.Functions {
+		if !fb.Enabled {
+			continue
+		}
+		for _, route := range fb.Routes {
+			if !route.Shadowed {
+				routes = append(routes, methodRoute{Route: route.Route, Service: fb.Service.Name, Function: fb.Function.Name, NoContent: fb.Void, Accepted: fb.Oneway})
+			}
+		}
+	}
+	return routes
+}
+
+// defaultRoutes returns the routes of the enabled functions routed by DefaultMapping
+// or AutoOperations, which the generated server calls with the JSON generic client.
+func defaultRoutes(model *RouteModel) []methodRoute {
+	var routes []methodRoute
+	for _, fb := range model.Functions {
+		if !fb.Enabled || !fb.Default {
+			continue
+		}
+		for _, route := range fb.Routes {
+			if !route.Shadowed {
+				routes = append(routes, methodRoute{Route: route.Route, Service: fb.Service.Name, Function: fb.Function.Name, NoContent: fb.Void, Accepted: fb.Oneway})
+			}
+		}
+	}
+	return routes
+}
+
+// HandledRoutes returns the routes registered besides the catch-all route of the
+// proxy: the routes whose response is adapted and, with metrics, confirmed mutations,
+// request validation or rate limits, every route so that requests are matched with
+// their operation.
+func (g *ServerGenerator) HandledRoutes() []annotations.Route {
+	var routes []annotations.Route
+	for _, route := range g.ProxyRoutes {
+		routes = append(routes, route.Route)
+	}
+	if g.Metrics || g.ConfirmMutations || g.ValidateRequests || g.RateLimit > 0 {
+		for _, route := range g.MethodRoutes {
+			if !containsRoute(routes, route.Route) {
+				routes = append(routes, route.Route)
+			}
+		}
+	}
+	return routes
+}
+
+// proxyRoute is a route of a function whose response the generated server adapts,
+// because the function declares exceptions, is void, or its response carries its
+// status or is a download.
+type proxyRoute struct {
+	annotations.Route
+	Exceptions []ExceptionBinding
+	// StatusField is the name of the body field carrying the status of the response.
+	StatusField string
+	// RawBody is the name of the binary api.raw_body field of the response.
+	RawBody string
+	// NoContent is true when the function is void, answered with 204.
+	NoContent bool
+	// Accepted is true when the function is oneway, answered with 202 instead.
+	Accepted bool
+}
+
+// Key identifies the route in the tables of the generated server.
+func (r proxyRoute) Key() string {
+	return r.Method + " " + r.Path
+}
+
+// proxyRoutes returns the routes of the enabled functions declaring exceptions, void
+// or whose response has a status field or a binary raw body, the first function
+// declaring a route wins and disabled routes are left out. The routes of
+// DefaultMapping are called with the JSON generic client instead.
+func proxyRoutes(model *RouteModel) []proxyRoute {
+	var routes []proxyRoute
+	for _, fb := range model.Functions {
+		if !fb.Enabled || fb.Default || len(fb.Exceptions) == 0 && fb.StatusField == "" && fb.RawBody == "" && !fb.Void {
+			continue
+		}
+		for _, route := range fb.Routes {
+			if !route.Shadowed {
+				routes = append(routes, proxyRoute{
+					Route:       route.Route,
+					Exceptions:  fb.Exceptions,
+					StatusField: fb.StatusField,
+					RawBody:     fb.RawBody,
+					NoContent:   fb.Void,
+					Accepted:    fb.Oneway,
+				})
+			}
+		}
+	}
+	return routes
+}
+
+// HasExceptions reports whether a proxied route declares exceptions.
+func (g *ServerGenerator) HasExceptions() bool {
+	for _, route := range g.ProxyRoutes {
+		if len(route.Exceptions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNoContent reports whether a proxied route is answered with 204 or 202.
+func (g *ServerGenerator) HasNoContent() bool {
+	for _, route := range g.ProxyRoutes {
+		if route.NoContent {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRawBodies reports whether a proxied route answers with a download.
+func (g *ServerGenerator) HasRawBodies() bool {
+	for _, route := range g.ProxyRoutes {
+		if route.RawBody != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasStatusFields reports whether a proxied route answers with a status field.
+func (g *ServerGenerator) HasStatusFields() bool {
+	for _, route := range g.ProxyRoutes {
+		if route.StatusField != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerAll returns the header names in lower case, the keys of the header tables of
+// the generated server.
+func lowerAll(names []string) []string {
+	var lower []string
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" && !utils.Contains(lower, name) {
+			lower = append(lower, name)
+		}
+	}
+	return lower
+}
+
+func containsRoute(routes []annotations.Route, route annotations.Route) bool {
+	for _, r := range routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAuth parses the Auth argument, either 'basic[:USER_ENV:PASSWORD_ENV]' or
+// 'apikey:Header[:KEY_ENV]'. Only the names of the environment variables holding
+// the credentials are generated, the credentials are read when the server starts.
+func parseAuth(args *args.Arguments) (*ServerGenerator, error) {
+	auth := &ServerGenerator{}
+	if args.Auth == "" {
+		if args.AuthProxy {
+			return nil, errors.New("AuthProxy requires Auth")
+		}
+		return auth, nil
+	}
+
+	parts := strings.Split(args.Auth, ":")
+	switch parts[0] {
+	case "basic":
+		auth.AuthUserEnv, auth.AuthPasswordEnv = "SWAGGER_USER", "SWAGGER_PASSWORD"
+		if len(parts) == 3 {
+			auth.AuthUserEnv, auth.AuthPasswordEnv = parts[1], parts[2]
+		} else if len(parts) != 1 {
+			return nil, fmt.Errorf("invalid Auth '%s', expected 'basic' or 'basic:USER_ENV:PASSWORD_ENV'", args.Auth)
+		}
+	case "apikey":
+		auth.AuthKeyEnv = "SWAGGER_API_KEY"
+		if len(parts) == 3 {
+			auth.AuthKeyEnv = parts[2]
+		} else if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid Auth '%s', expected 'apikey:Header' or 'apikey:Header:KEY_ENV'", args.Auth)
+		}
+		auth.AuthHeader = parts[1]
+		if auth.AuthHeader == "" || strings.ContainsAny(auth.AuthHeader, " \t\"") {
+			return nil, fmt.Errorf("invalid Auth '%s', bad header name '%s'", args.Auth, auth.AuthHeader)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Auth type '%s', use 'basic' or 'apikey'", parts[0])
+	}
+	for _, env := range []string{auth.AuthUserEnv, auth.AuthPasswordEnv, auth.AuthKeyEnv} {
+		if env != "" && !envNamePattern.MatchString(env) {
+			return nil, fmt.Errorf("invalid Auth '%s', bad environment variable name '%s'", args.Auth, env)
+		}
+	}
+	auth.AuthType = parts[0]
+	return auth, nil
+}
+
+// checkTLS rejects partial certificate/key pairs.
+func checkTLS(args *args.Arguments) error {
+	if (args.ServerCert == "") != (args.ServerKey == "") {
+		return errors.New("ServerCert and ServerKey must be set together")
+	}
+	if (args.ClientCert == "") != (args.ClientKey == "") {
+		return errors.New("ClientCert and ClientKey must be set together")
+	}
+	return nil
+}
+
+// ServerTLS reports whether the Hertz server serves HTTPS.
+func (g *ServerGenerator) ServerTLS() bool {
+	return g.ServerCert != ""
+}
+
+// RedactedHeaders returns the request headers whose value the debug logs never show,
+// in lower case: the credentials, the cookies and the header of the API key.
+func (g *ServerGenerator) RedactedHeaders() []string {
+	return lowerAll([]string{"authorization", "proxy-authorization", "cookie", "set-cookie", "x-api-key", g.AuthHeader})
+}
+
+// ClientTLS reports whether the generic client dials the Kitex service over TLS.
+func (g *ServerGenerator) ClientTLS() bool {
+	return g.ClientCA != "" || g.ClientCert != "" || g.ClientServerName != ""
+}
+
+// parseResolver returns the import path and the constructor expression of the
+// resolver used by the generic client, both are empty when the client dials KitexAddr.
+// The expression must evaluate to (discovery.Resolver, error).
+func parseResolver(args *args.Arguments) (string, string, error) {
+	if args.ResolverImport != "" || args.ResolverExpr != "" {
+		if args.Resolver != "" {
+			return "", "", errors.New("Resolver can not be used together with ResolverImport and ResolverExpr")
+		}
+		if args.ResolverImport == "" || args.ResolverExpr == "" {
+			return "", "", errors.New("ResolverImport and ResolverExpr must be set together")
+		}
+		return args.ResolverImport, args.ResolverExpr, nil
+	}
+	if args.Resolver == "" {
+		return "", "", nil
+	}
+
+	// The endpoints are separated by '|', which is not valid in the host of a URL.
+	i := strings.Index(args.Resolver, "://")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid Resolver '%s': missing scheme", args.Resolver)
+	}
+	scheme, hosts := args.Resolver[:i], args.Resolver[i+len("://"):]
+	if hosts == "" {
+		return "", "", fmt.Errorf("invalid Resolver '%s': missing registry address", args.Resolver)
+	}
+	switch scheme {
+	case "etcd":
+		var endpoints []string
+		for _, endpoint := range strings.Split(hosts, "|") {
+			endpoints = append(endpoints, strconv.Quote(endpoint))
+		}
+		return "github.com/kitex-contrib/registry-etcd",
+			"etcd.NewEtcdResolver([]string{" + strings.Join(endpoints, ", ") + "})", nil
+	default:
+		return "", "", fmt.Errorf("unsupported Resolver scheme '%s', use ResolverImport and ResolverExpr instead", scheme)
 	}
 }
 
@@ -78,15 +603,23 @@ func (g *ServerGenerator) Generate() []*plugin.Generated {
 	if err != nil {
 		logs.Errorf("failed to execute template: %v", err)
 	}
+	// The tables of the routes are aligned by gofmt.
+	content := buf.Bytes()
+	if formatted, err := format.Source(content); err != nil {
+		logs.Errorf("failed to format server: %v", err)
+	} else {
+		content = formatted
+	}
 
 	filePath := filepath.Clean(g.OutputDir)
 	filePath = filepath.Join(filePath, "swagger.go")
 
 	var ret []*plugin.Generated
 	ret = append(ret, &plugin.Generated{
-		Content: buf.String(),
+		Content: string(content),
 		Name:    &filePath,
 	})
+	ret = append(ret, g.uiAssets...)
 
 	return ret
 }
@@ -96,41 +629,221 @@ const serverTemplate = `package main
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+{{- if eq .AuthType "apikey"}}
+	"crypto/subtle"
+{{- end}}
+{{- if or .ServerTLS .ClientTLS}}
+	"crypto/tls"
+{{- end}}
+{{- if .ClientCA}}
+	"crypto/x509"
+{{- end}}
+{{- if .EmbedUI}}
+	"embed"
+{{- else}}
 	_ "embed"
+{{- end}}
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+{{- if .ValidateRequests}}
+	"fmt"
+{{- end}}
+	"io"
+{{- if .RateLimit}}
+	"math"
+{{- end}}
+{{- if ne .UI "swaggo"}}
+	"mime"
+{{- end}}
+{{- if or .ClientTLS .ReadinessProbe}}
+	"net"
+{{- end}}
 	"net/http"
+{{- if .InvokeAPI}}
+	"net/url"
+{{- end}}
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-
+{{- if or .SpecFile .RateLimit}}
+	"sync"
+{{- end}}
+{{- if or .ClientTLS .SpecFile .Metrics .ReadinessProbe .RateLimit}}
+	"time"
+{{- end}}
+{{if .RequestID}}
+	"github.com/bytedance/gopkg/cloud/metainfo"
+{{- end}}
 	"github.com/cloudwego/hertz/pkg/app"
+{{- if eq .AuthType "basic"}}
+	"github.com/cloudwego/hertz/pkg/app/middlewares/server/basic_auth"
+{{- end}}
 	"github.com/cloudwego/hertz/pkg/app/server"
+{{- if and .Metrics (not .MetricsPort)}}
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+{{- end}}
 	"github.com/cloudwego/hertz/pkg/common/hlog"
+{{- if .ServerTLS}}
+	"github.com/cloudwego/hertz/pkg/network/standard"
+{{- end}}
 	"github.com/cloudwego/kitex/client"
 	"github.com/cloudwego/kitex/client/genericclient"
 	"github.com/cloudwego/kitex/pkg/generic"
+{{- if .ClientTLS}}
+	"github.com/cloudwego/kitex/pkg/remote/trans/gonet"
+{{- end}}
+{{- if .RequestID}}
+	"github.com/cloudwego/kitex/pkg/transmeta"
+	"github.com/cloudwego/kitex/transport"
+{{- end}}
 	"github.com/hertz-contrib/cors"
+{{- if eq .Tracing "otel"}}
+	"github.com/hertz-contrib/obs-opentelemetry/provider"
+	hertztracing "github.com/hertz-contrib/obs-opentelemetry/tracing"
+{{- end}}
+{{- if .RequestID}}
+	"github.com/hertz-contrib/requestid"
+{{- end}}
+{{- if eq .UI "swaggo"}}
 	"github.com/hertz-contrib/swagger"
+{{- end}}
+{{- if .Metrics}}
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+{{- end}}
+{{- if eq .UI "swaggo"}}
 	swaggerFiles "github.com/swaggo/files"
+{{- end}}
+{{- if eq .Tracing "otel"}}
+	kitextracing "github.com/kitex-contrib/obs-opentelemetry/tracing"
+{{- end}}
+	"gopkg.in/yaml.v3"
+{{- if .ResolverImport}}
+
+	"{{.ResolverImport}}"
+{{- end}}
 )
 
 //go:embed openapi.yaml
 var openapiYAML []byte
+{{- if .Minify}}
+
+//go:embed openapi.min.json
+var openapiMinJSON []byte
+{{- end}}
+{{- if .SpecParts}}
+
+//go:embed{{range .SpecParts}} {{.File}}{{end}}
+var specParts embed.FS
+{{- end}}
+{{- if .EmbedUI}}
+
+//go:embed ui
+var uiFiles embed.FS
+{{- end}}
+{{- if .SpecFile}}
+
+// specFile is served instead of the embedded spec when it exists, it is
+// re-read whenever its modification time changes.
+const specFile = {{printf "%q" .SpecFile}}
+
+var specCache struct {
+	sync.Mutex
+	modTime time.Time
+	specs   *specs
+}
+{{- end}}
+
+// spec is a rendering of the OpenAPI document served with an ETag.
+type spec struct {
+	content     []byte
+	contentType string
+	etag        string
+}
+
+// specs holds the YAML and the JSON renderings of the same document.
+type specs struct {
+	yaml *spec
+	json *spec
+}
 
 func main() {
-	h := server.Default(server.WithHostPorts("{{.HertzAddr}}"))
+{{- if eq .Tracing "otel"}}
+	p := newTracerProvider()
+	defer p.Shutdown(context.Background())
+	tracer, tracerConfig := hertztracing.NewServerTracer()
 
+{{- end}}
+{{- if .ServerTLS}}
+	h := server.Default(
+		server.WithHostPorts("{{.HertzAddr}}"),
+		server.WithTLS(serverTLSConfig()),
+		server.WithTransport(standard.NewTransporter),
+		server.WithStreamBody(true),
+{{- if eq .Tracing "otel"}}
+		tracer,
+{{- end}}
+	)
+{{- else}}
+	h := server.Default(server.WithHostPorts("{{.HertzAddr}}"), server.WithStreamBody(true){{if eq .Tracing "otel"}}, tracer{{end}})
+{{- end}}
+{{- if eq .Tracing "otel"}}
+	h.Use(hertztracing.ServerMiddleware(tracerConfig))
+{{- end}}
+{{if .RequestID}}
+	h.Use(requestid.New(), forwardRequestID)
+{{- end}}
 	h.Use(cors.Default())
 
 	cli := initializeGenericClient()
+{{- if .DefaultRoutes}}
+	jsonCli := initializeJSONClient()
+{{- end}}
+	setupHealthRoutes(h, cli)
 	setupSwaggerRoutes(h)
-	setupProxyRoutes(h, cli)
+{{- if .DisabledRoutes}}
+	setupDisabledRoutes(h)
+{{- end}}
+{{- if .Metrics}}
+	setupMetrics(h)
+{{- end}}
+	setupProxyRoutes(h, cli{{if .DefaultRoutes}}, jsonCli{{end}})
 
-	hlog.Info("Swagger UI is available at: http://127.0.0.1:8080/swagger/index.html")
+	hlog.Info("Swagger UI is available at: http{{if .ServerTLS}}s{{end}}://127.0.0.1:8080/swagger/index.html")
 
 	h.Spin()
 }
+{{- if eq .Tracing "otel"}}
+
+// newTracerProvider exports the spans of the proxy and of its calls to the Kitex
+// service, the exporter is configured by the standard OTEL_* environment variables,
+// e.g. OTEL_EXPORTER_OTLP_ENDPOINT.
+func newTracerProvider() provider.OtelProvider {
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = {{printf "%q" .ServiceName}} + "-swagger"
+	}
+	return provider.NewOpenTelemetryProvider(
+		provider.WithServiceName(serviceName),
+		provider.WithEnableMetrics(false),
+	)
+}
+{{- end}}
+{{- if .ServerTLS}}
+
+func serverTLSConfig() *tls.Config {
+	cert, err := tls.LoadX509KeyPair({{printf "%q" .ServerCert}}, {{printf "%q" .ServerKey}})
+	if err != nil {
+		hlog.Fatal("Failed to load server certificate:", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+{{- end}}
 
 func findThriftFile(fileName string) (string, error) {
 	workingDir, err := os.Getwd()
@@ -181,116 +894,1801 @@ func initializeGenericClient() genericclient.Client {
 	if err != nil {
 		hlog.Fatal("Failed to create HTTPThriftGeneric:", err)
 	}
-
-	cli, err := genericclient.NewClient("swagger", g, client.WithHostPorts("{{.KitexAddr}}"))
-	if err != nil {
-		hlog.Fatal("Failed to create generic client:", err)
-	}
-
-	return cli
+{{template "genericClient" .}}
 }
+{{- if .DefaultRoutes}}
 
-func setupSwaggerRoutes(h *server.Hertz) {
-	h.GET("swagger/*any", swagger.WrapHandler(swaggerFiles.Handler, swagger.URL("/openapi.yaml")))
-
-	h.GET("/openapi.yaml", func(c context.Context, ctx *app.RequestContext) {
-		ctx.Header("Content-Type", "application/x-yaml")
-		ctx.Write(openapiYAML)
-	})
+// defaultRoutes are the paths of the functions routed to POST /Service/Method by
+// DefaultMapping or AutoOperations, the HTTP generic client can not route them since
+// they declare no HTTP annotation.
+var defaultRoutes = map[string]defaultRoute{
+{{- range .DefaultRoutes}}
+	{{printf "%q" .Path}}: {method: {{printf "%q" .Function}}{{if .NoContent}}, noContent: true{{end}}{{if .Accepted}}, accepted: true{{end}}},
+{{- end}}
 }
 
-func setupProxyRoutes(h *server.Hertz, cli genericclient.Client) {
-	h.Any("/*ServiceMethod", func(c context.Context, ctx *app.RequestContext) {
-		serviceMethod := ctx.Param("ServiceMethod")
-		if serviceMethod == "" {
-			handleError(ctx, "ServiceMethod not provided", http.StatusBadRequest)
-			return
-		}
-
-		queryString := formatQueryParams(ctx)
-		bodyBytes := ctx.Request.Body()
-		contentType := string(ctx.Request.Header.ContentType())
-
-		url := "http://{{.HertzAddr}}/" + serviceMethod
-		if len(queryString) > 0 {
-			url += "?" + queryString
-		}
-
-		req, err := http.NewRequest(string(ctx.Request.Method()), url, bytes.NewBuffer(bodyBytes))
-		if err != nil {
-			handleError(ctx, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// defaultRoute is the function of a route of defaultRoutes, a void function being
+// answered with 204 and a oneway one with 202.
+type defaultRoute struct {
+	method    string
+	noContent bool
+	accepted  bool
+}
 
-		ctx.Request.Header.VisitAll(func(key, value []byte) {
-			req.Header.Set(string(key), string(value))
-		})
+// initializeJSONClient returns the client calling the functions of defaultRoutes with
+// the JSON of their argument.
+func initializeJSONClient() genericclient.Client {
+	thriftFile, err := findThriftFile("{{.IdlPath}}")
+	if err != nil {
+		hlog.Fatal("Failed to locate Thrift file:", err)
+	}
 
-		req.Header.Set("Content-Type", contentType)
+	p, err := generic.NewThriftFileProvider(thriftFile)
+	if err != nil {
+		hlog.Fatal("Failed to create ThriftFileProvider:", err)
+	}
 
-		handleProxyRequest(ctx, cli, req)
-	})
+	g, err := generic.JSONThriftGeneric(p)
+	if err != nil {
+		hlog.Fatal("Failed to create JSONThriftGeneric:", err)
+	}
+{{template "genericClient" .}}
 }
 
-func formatQueryParams(ctx *app.RequestContext) string {
-	var newQueryParams []string
-	ctx.Request.URI().QueryArgs().VisitAll(func(key, value []byte) {
-		newQueryParams = append(newQueryParams, string(key)+"="+string(value))
-	})
-	return strings.Join(newQueryParams, "&")
-}
+// handleJSONCall calls a function of defaultRoutes with the body of the request as
+// its argument and answers with its result.
+func handleJSONCall(c context.Context, ctx *app.RequestContext, cli genericclient.Client, route defaultRoute) {
+	body := ctx.Request.Body()
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+	if debugEnabled {
+		debugf(ctx, "stage=json_request method=%s body=%s", route.method, truncateBody(body))
+	}
 
-func handleProxyRequest(ctx *app.RequestContext, cli genericclient.Client, req *http.Request) {
-	customReq, err := generic.FromHTTPRequest(req)
+	resp, err := cli.GenericCall(c, route.method, string(body))
 	if err != nil {
-		handleError(ctx, "Failed to create generic request", http.StatusInternalServerError)
+		debugf(ctx, "stage=upstream_error error=%q", err.Error())
+		handleTransportError(ctx, "GenericCall error: "+err.Error())
 		return
 	}
-
-	resp, err := cli.GenericCall(context.Background(), "", customReq)
-	if err != nil {
-		handleError(ctx, "GenericCall error: "+err.Error(), http.StatusInternalServerError)
+	if route.accepted {
+		ctx.SetStatusCode(http.StatusAccepted)
 		return
 	}
-
-	if resp == nil {
-		handleError(ctx, "Received nil response from the service", http.StatusInternalServerError)
+	if route.noContent {
+		ctx.SetStatusCode(http.StatusNoContent)
 		return
 	}
-
-	realResp, ok := resp.(*generic.HTTPResponse)
+	result, ok := resp.(string)
 	if !ok {
 		handleError(ctx, "Invalid response format", http.StatusInternalServerError)
 		return
 	}
+	if debugEnabled {
+		debugf(ctx, "stage=upstream_response body=%s", truncateBody([]byte(result)))
+	}
+{{- if .MaxResponseBytes}}
+	if responseTooLarge(ctx, len(result)) {
+		return
+	}
+{{- end}}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", []byte(result))
+}
+{{- end}}
+{{- if .ClientTLS}}
 
-	sendResponse(ctx, realResp)
+// tlsDialer dials the Kitex service over TLS, it is used with the gonet transport
+// since netpoll connections can not be wrapped.
+type tlsDialer struct {
+	config *tls.Config
 }
 
-func sendResponse(ctx *app.RequestContext, realResp *generic.HTTPResponse) {
-	if realResp.StatusCode == 0 {
-		realResp.StatusCode = http.StatusOK
+func (d *tlsDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, d.config)
+}
+
+func clientTLSConfig() *tls.Config {
+	config := &tls.Config{
+{{- if .ClientServerName}}
+		ServerName: {{printf "%q" .ClientServerName}},
+{{- end}}
 	}
+{{- if .ClientCA}}
 
-	for key, values := range realResp.Header {
-		for _, value := range values {
-			ctx.Response.Header.Set(key, value)
-		}
+	ca, err := os.ReadFile({{printf "%q" .ClientCA}})
+	if err != nil {
+		hlog.Fatal("Failed to read CA certificate:", err)
 	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		hlog.Fatal("Failed to parse CA certificate")
+	}
+	config.RootCAs = pool
+{{- end}}
+{{- if .ClientCert}}
 
-	respBody, err := json.Marshal(realResp.Body)
+	cert, err := tls.LoadX509KeyPair({{printf "%q" .ClientCert}}, {{printf "%q" .ClientKey}})
 	if err != nil {
-		handleError(ctx, "Failed to marshal response body", http.StatusInternalServerError)
-		return
+		hlog.Fatal("Failed to load client certificate:", err)
 	}
+	config.Certificates = []tls.Certificate{cert}
+{{- end}}
 
-	ctx.Data(int(realResp.StatusCode), string(realResp.ContentType), respBody)
+	return config
 }
+{{- end}}
 
-func handleError(ctx *app.RequestContext, errMsg string, statusCode int) {
-	hlog.Errorf("Error: %s", errMsg)
-	ctx.JSON(statusCode, map[string]interface{}{
-		"error": errMsg,
+{{- if .ReadinessProbe}}
+
+// readinessTimeout bounds the dial of the Kitex service by /readyz.
+const readinessTimeout = time.Second
+{{- end}}
+
+// setupHealthRoutes serves the probes: /healthz answers once the server is up,
+// /readyz once the generic client is created
+{{- if .ReadinessProbe}} and the Kitex service accepts
+// connections, no method of the service is called{{end}}.
+func setupHealthRoutes(h *server.Hertz, cli genericclient.Client) {
+	h.GET("/healthz", func(c context.Context, ctx *app.RequestContext) {
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"status": "ok",
+		})
+	})
+
+	h.GET("/readyz", func(c context.Context, ctx *app.RequestContext) {
+		if cli == nil {
+			ctx.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+				"status": "unavailable",
+				"error":  "generic client not created",
+			})
+			return
+		}
+{{- if .ReadinessProbe}}
+		conn, err := net.DialTimeout("tcp", "{{.KitexAddr}}", readinessTimeout)
+		if err != nil {
+			ctx.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+				"status": "unavailable",
+				"error":  err.Error(),
+			})
+			return
+		}
+		conn.Close()
+{{- end}}
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"status": "ok",
+		})
 	})
 }
+
+func setupSwaggerRoutes(h *server.Hertz) {
+	embedded, err := newSpecs(openapiYAML)
+	if err != nil {
+		hlog.Fatal("Failed to convert the spec to JSON:", err)
+	}
+{{- if .AuthType}}
+
+	auth := authMiddleware()
+
+	h.GET("swagger/*any", auth, {{template "uiHandler" .}})
+
+	h.GET("/openapi.yaml", auth, func(c context.Context, ctx *app.RequestContext) {
+		serveSpec(ctx, {{template "currentSpecs" .}}.yaml)
+	})
+
+	h.GET("/openapi.json", auth, func(c context.Context, ctx *app.RequestContext) {
+		serveSpec(ctx, {{template "currentSpecs" .}}.json)
+	})
+{{- template "specPartRoutes" .}}
+{{- template "minifiedSpecRoute" .}}
+{{- else}}
+
+	h.GET("swagger/*any", {{template "uiHandler" .}})
+
+	h.GET("/openapi.yaml", func(c context.Context, ctx *app.RequestContext) {
+		serveSpec(ctx, {{template "currentSpecs" .}}.yaml)
+	})
+
+	h.GET("/openapi.json", func(c context.Context, ctx *app.RequestContext) {
+		serveSpec(ctx, {{template "currentSpecs" .}}.json)
+	})
+{{- template "specPartRoutes" .}}
+{{- template "minifiedSpecRoute" .}}
+{{- end}}
+}
+
+// newSpecs converts the YAML document to JSON once, so that neither rendering is
+// converted again when it is served.
+func newSpecs(content []byte) (*specs, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	jsonContent, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &specs{
+		yaml: newSpec(content, "application/x-yaml"),
+		json: newSpec(jsonContent, "application/json"),
+	}, nil
+}
+
+func newSpec(content []byte, contentType string) *spec {
+	sum := sha256.Sum256(content)
+	return &spec{
+		content:     content,
+		contentType: contentType,
+		etag:        ` + "`" + `"` + "`" + ` + hex.EncodeToString(sum[:16]) + ` + "`" + `"` + "`" + `,
+	}
+}
+
+func serveSpec(ctx *app.RequestContext, s *spec) {
+	ctx.Header("ETag", s.etag)
+	if string(ctx.Request.Header.Peek("If-None-Match")) == s.etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, s.contentType, s.content)
+}
+{{- if .SpecFile}}
+
+// loadSpecs returns the spec on disk, falling back to the embedded spec when the
+// file is missing or can not be read.
+func loadSpecs(embedded *specs) *specs {
+	specCache.Lock()
+	defer specCache.Unlock()
+
+	info, err := os.Stat(specFile)
+	if err != nil {
+		specCache.modTime = time.Time{}
+		specCache.specs = nil
+		return embedded
+	}
+	if specCache.specs == nil || !info.ModTime().Equal(specCache.modTime) {
+		content, err := os.ReadFile(specFile)
+		if err != nil {
+			hlog.Errorf("Failed to read %s: %s", specFile, err)
+			return embedded
+		}
+		loaded, err := newSpecs(content)
+		if err != nil {
+			hlog.Errorf("Failed to convert %s to JSON: %s", specFile, err)
+			return embedded
+		}
+		specCache.modTime = info.ModTime()
+		specCache.specs = loaded
+	}
+	return specCache.specs
+}
+{{- end}}
+
+{{if and (eq .UI "swaggo") .SpecParts -}}
+var swaggerHandler = swagger.WrapHandler(swaggerFiles.Handler)
+
+// serveUI serves the index page listing the spec parts, the other files of
+// swagger-ui are served by hertz-contrib/swagger.
+func serveUI(c context.Context, ctx *app.RequestContext) {
+	name := strings.TrimPrefix(ctx.Param("any"), "/")
+	if name != "" && name != "index.html" {
+		swaggerHandler(c, ctx)
+		return
+	}
+	content, err := uiFiles.ReadFile("ui/index.html")
+	if err != nil {
+		ctx.NotFound()
+		return
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", content)
+}
+
+{{else if ne .UI "swaggo" -}}
+// serveUI serves the embedded {{.UI}} UI.
+func serveUI(c context.Context, ctx *app.RequestContext) {
+	name := strings.TrimPrefix(ctx.Param("any"), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	content, err := uiFiles.ReadFile("ui/" + name)
+	if err != nil {
+		ctx.NotFound()
+		return
+	}
+	ctx.Data(http.StatusOK, mime.TypeByExtension(filepath.Ext(name)), content)
+}
+
+{{end -}}
+{{if .DisabledRoutes -}}
+// setupDisabledRoutes answers the routes excluded by the generation profiles.
+func setupDisabledRoutes(h *server.Hertz) {
+	disabled := func(c context.Context, ctx *app.RequestContext) {
+		handleError(ctx, "route disabled", http.StatusNotFound)
+	}
+{{range .DisabledRoutes}}
+{{- if eq .Method "ANY"}}
+	h.Any({{printf "%q" .Path}}, disabled)
+{{- else}}
+	h.Handle({{printf "%q" .Method}}, {{printf "%q" .Path}}, disabled)
+{{- end}}
+{{- end}}
+}
+
+{{end -}}
+func setupProxyRoutes(h *server.Hertz, cli genericclient.Client{{if .DefaultRoutes}}, jsonCli genericclient.Client{{end}}) {
+	proxy := func(c context.Context, ctx *app.RequestContext) {
+{{- if .DefaultRoutes}}
+		if route, ok := defaultRoutes[string(ctx.Path())]; ok && string(ctx.Method()) == http.MethodPost {
+			handleJSONCall(c, ctx, jsonCli, route)
+			return
+		}
+
+{{- end}}
+		serviceMethod := strings.TrimPrefix(string(ctx.Path()), "/")
+		if serviceMethod == "" {
+			handleError(ctx, "ServiceMethod not provided", http.StatusBadRequest)
+			return
+		}
+
+		queryString := formatQueryParams(ctx)
+		// The content type is forwarded as is, so that multipart bodies keep
+		// their boundary.
+		contentType := string(ctx.Request.Header.ContentType())
+
+		url := "http://{{.HertzAddr}}/" + serviceMethod
+		if len(queryString) > 0 {
+			url += "?" + queryString
+		}
+
+		req, cleanup, err := newProxyRequest(ctx, url)
+		if err != nil {
+			handleError(ctx, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer cleanup()
+
+		connection := connectionHeaders(string(ctx.Request.Header.Peek("Connection")))
+		ctx.Request.Header.VisitAll(func(key, value []byte) {
+			if forwardRequestHeader(string(key), connection) {
+				req.Header.Set(string(key), string(value))
+			}
+		})
+
+		req.Header.Set("Content-Type", contentType)
+
+		handleProxyRequest(c, ctx, cli, req)
+	}
+{{- if .Metrics}}
+
+	metrics := metricsMiddleware()
+{{- end}}
+{{- if .RateLimit}}
+
+	ratelimit := rateLimitMiddleware()
+{{- end}}
+{{- if .AuthProxy}}
+
+	auth := authMiddleware()
+{{- end}}
+{{- if .ConfirmMutations}}
+
+	confirm := confirmMiddleware()
+{{- end}}
+{{- if .ValidateRequests}}
+
+	validate := validationMiddleware()
+{{- end}}
+
+	debug := debugMiddleware()
+
+	h.Any("/*ServiceMethod", {{template "proxyHandlers" .}})
+{{- range .HandledRoutes}}
+{{- if eq .Method "ANY"}}
+	h.Any({{printf "%q" .Path}}, {{template "proxyHandlers" $}})
+{{- else}}
+	h.Handle({{printf "%q" .Method}}, {{printf "%q" .Path}}, {{template "proxyHandlers" $}})
+{{- end}}
+{{- end}}
+{{- if .InvokeAPI}}
+
+	setupInvokeAPI(h, proxy)
+{{- end}}
+}
+{{- if .InvokeAPI}}
+
+// apiOperation is an operation of the spec, listed by GET /_api/operations and
+// called by POST /_api/invoke/{operationId}.
+type apiOperation struct {
+	ID         string         ` + "`json:\"id\"`" + `
+	Method     string         ` + "`json:\"method\"`" + `
+	Path       string         ` + "`json:\"path\"`" + `
+	Parameters []apiParameter ` + "`json:\"parameters\"`" + `
+	HasBody    bool           ` + "`json:\"hasBody\"`" + `
+}
+
+type apiParameter struct {
+	Name     string ` + "`json:\"name\"`" + `
+	In       string ` + "`json:\"in\"`" + `
+	Type     string ` + "`json:\"type,omitempty\"`" + `
+	Required bool   ` + "`json:\"required\"`" + `
+	// Wildcard is true for the catch-all parameter of a path, whose value may hold
+	// slashes.
+	Wildcard bool ` + "`json:\"wildcard,omitempty\"`" + `
+}
+
+// invocation is the body of POST /_api/invoke/{operationId}: the parameters by name
+// and the JSON body of the operation.
+type invocation struct {
+	Params map[string]interface{} ` + "`json:\"params\"`" + `
+	Body   json.RawMessage        ` + "`json:\"body\"`" + `
+}
+
+var apiVerbs = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// apiOperations returns the operations of the spec which have an operationId, sorted
+// by operationId.
+func apiOperations(content []byte) ([]*apiOperation, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	paths, _ := doc["paths"].(map[string]interface{})
+	var operations []*apiOperation
+	for path, value := range paths {
+		item, _ := value.(map[string]interface{})
+		for _, verb := range apiVerbs {
+			op, ok := item[verb].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := op["operationId"].(string)
+			if id == "" {
+				continue
+			}
+			_, hasBody := op["requestBody"]
+			operation := &apiOperation{ID: id, Method: strings.ToUpper(verb), Path: path, HasBody: hasBody}
+			operation.Parameters = append(apiParameters(item["parameters"]), apiParameters(op["parameters"])...)
+			operations = append(operations, operation)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].ID < operations[j].ID
+	})
+	return operations, nil
+}
+
+func apiParameters(value interface{}) []apiParameter {
+	list, _ := value.([]interface{})
+	var parameters []apiParameter
+	for _, entry := range list {
+		param, _ := entry.(map[string]interface{})
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" || in == "" {
+			continue
+		}
+		parameter := apiParameter{Name: name, In: in}
+		parameter.Required, _ = param["required"].(bool)
+		parameter.Wildcard, _ = param["x-wildcard"].(bool)
+		if schema, ok := param["schema"].(map[string]interface{}); ok {
+			parameter.Type, _ = schema["type"].(string)
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// setupInvokeAPI serves the operation catalog of the spec and the invocation of the
+// operations by operationId, the parameters being placed as the spec documents them.
+func setupInvokeAPI(h *server.Hertz, proxy app.HandlerFunc) {
+	operations, err := apiOperations(openapiYAML)
+	if err != nil {
+		hlog.Fatal("Failed to read the operations of the spec:", err)
+	}
+	byID := make(map[string]*apiOperation, len(operations))
+	for _, op := range operations {
+		byID[op.ID] = op
+	}
+
+	handlers := []app.HandlerFunc{debugMiddleware()}
+{{- if .AuthType}}
+	handlers = append(handlers, authMiddleware())
+{{- end}}
+
+	h.GET("/_api/operations", append(handlers, func(c context.Context, ctx *app.RequestContext) {
+		ctx.JSON(http.StatusOK, operations)
+	})...)
+
+	h.POST("/_api/invoke/:operationId", append(handlers, func(c context.Context, ctx *app.RequestContext) {
+		op, ok := byID[ctx.Param("operationId")]
+		if !ok {
+			handleError(ctx, "unknown operation '"+ctx.Param("operationId")+"'", http.StatusNotFound)
+			return
+		}
+		var call invocation
+		if body := bytes.TrimSpace(ctx.Request.Body()); len(body) > 0 {
+			if err := json.Unmarshal(body, &call); err != nil {
+				handleError(ctx, "invalid invocation: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+{{- if .ConfirmMutations}}
+		if isMutatingVerb(op.Method) && string(ctx.Request.Header.Peek("X-Confirm")) != "yes" {
+			ctx.AbortWithStatusJSON(http.StatusPreconditionRequired, map[string]interface{}{
+				"error": "this operation modifies data, set the X-Confirm header to 'yes' to confirm it",
+			})
+			return
+		}
+{{- end}}
+		if err := placeInvocation(ctx, op, call); err != nil {
+			handleError(ctx, err.Error(), http.StatusBadRequest)
+			return
+		}
+		proxy(c, ctx)
+	})...)
+}
+{{- if .ConfirmMutations}}
+
+func isMutatingVerb(method string) bool {
+	for _, verb := range mutatingVerbs {
+		if strings.EqualFold(verb, method) {
+			return true
+		}
+	}
+	return false
+}
+{{- end}}
+
+// placeInvocation rewrites the request into the request of the operation, with the
+// parameters in its path, query, headers and cookies, and the body as JSON.
+func placeInvocation(ctx *app.RequestContext, op *apiOperation, call invocation) error {
+	path := op.Path
+	query := url.Values{}
+	known := make(map[string]bool, len(op.Parameters))
+	var cookies []string
+	for _, param := range op.Parameters {
+		known[param.Name] = true
+		value, ok := call.Params[param.Name]
+		if !ok || value == nil {
+			if param.Required {
+				return errors.New("missing required " + param.In + " parameter '" + param.Name + "'")
+			}
+			continue
+		}
+		values := invocationValues(value)
+		switch param.In {
+		case "path":
+			value := url.PathEscape(strings.Join(values, ","))
+			if param.Wildcard {
+				value = strings.ReplaceAll(value, "%2F", "/")
+			}
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", value)
+		case "query":
+			query[param.Name] = values
+		case "header":
+			ctx.Request.Header.Set(param.Name, strings.Join(values, ","))
+		case "cookie":
+			cookies = append(cookies, param.Name+"="+strings.Join(values, ","))
+		}
+	}
+	for name := range call.Params {
+		if !known[name] {
+			return errors.New("operation '" + op.ID + "' has no parameter '" + name + "'")
+		}
+	}
+	if len(cookies) > 0 {
+		ctx.Request.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	uri := path
+	if encoded := query.Encode(); encoded != "" {
+		uri += "?" + encoded
+	}
+	ctx.Request.Header.SetMethod(op.Method)
+	ctx.Request.SetRequestURI(uri)
+	body := bytes.TrimSpace(call.Body)
+	if len(body) == 0 || bytes.Equal(body, []byte("null")) {
+		body = nil
+		ctx.Request.Header.Del("Content-Type")
+	} else {
+		ctx.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	}
+	ctx.Request.SetBody(body)
+	ctx.Request.Header.SetContentLength(len(body))
+	return nil
+}
+
+// invocationValues returns the values of a parameter, a list giving a value per item.
+func invocationValues(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+			continue
+		}
+		content, _ := json.Marshal(item)
+		values = append(values, string(content))
+	}
+	return values
+}
+{{- end}}
+{{- if .ConfirmMutations}}
+
+// mutatingVerbs are the verbs of the operations which must be confirmed with the
+// X-Confirm header.
+var mutatingVerbs = []string{"post", "put", "patch", "delete"}
+
+// mutatingRoutes returns the routes of the operations documented with a mutating
+// verb by the spec, keyed by method and route shape.
+func mutatingRoutes(content []byte) (map[string]bool, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	paths, _ := doc["paths"].(map[string]interface{})
+	routes := make(map[string]bool)
+	for path, item := range paths {
+		operations, _ := item.(map[string]interface{})
+		for _, verb := range mutatingVerbs {
+			if _, ok := operations[verb]; ok {
+				routes[strings.ToUpper(verb)+" "+routeShape(path)] = true
+			}
+		}
+	}
+	return routes, nil
+}
+
+// confirmMiddleware answers 428 to the requests of mutating operations which are
+// not confirmed with "X-Confirm: yes", the verbs are taken from the embedded spec.
+func confirmMiddleware() app.HandlerFunc {
+	routes, err := mutatingRoutes(openapiYAML)
+	if err != nil {
+		hlog.Fatal("Failed to read the operations of the spec:", err)
+	}
+	return func(c context.Context, ctx *app.RequestContext) {
+		if routes[string(ctx.Method())+" "+routeShape(ctx.FullPath())] && string(ctx.Request.Header.Peek("X-Confirm")) != "yes" {
+			ctx.AbortWithStatusJSON(http.StatusPreconditionRequired, map[string]interface{}{
+				"error": "this operation modifies data, set the X-Confirm header to 'yes' to confirm it",
+			})
+			return
+		}
+		ctx.Next(c)
+	}
+}
+{{- end}}
+{{- if .ValidateRequests}}
+
+// validatedParameter is a required or enumerated parameter of an operation, named as
+// the spec documents it.
+type validatedParameter struct {
+	Name        string ` + "`json:\"name\"`" + `
+	In          string ` + "`json:\"in\"`" + `
+	Description string ` + "`json:\"description,omitempty\"`" + `
+	// Enum holds the values allowed for the parameter, any value when empty.
+	Enum     []string ` + "`json:\"enum,omitempty\"`" + `
+	required bool
+}
+
+// validatedOperation holds the validated parameters of an operation and the link to
+// the operation in the UI.
+type validatedOperation struct {
+	docsURL    string
+	parameters []validatedParameter
+}
+
+var validatedVerbs = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// validatedParameters returns the operations of the spec with required query, header
+// or cookie parameters, or parameters restricted to an enum, keyed by method and
+// route shape.
+func validatedParameters(content []byte) (map[string]validatedOperation, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	paths, _ := doc["paths"].(map[string]interface{})
+	operations := make(map[string]validatedOperation)
+	for path, value := range paths {
+		item, _ := value.(map[string]interface{})
+		for _, verb := range validatedVerbs {
+			op, ok := item[verb].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			params, _ := item["parameters"].([]interface{})
+			opParams, _ := op["parameters"].([]interface{})
+			var validated []validatedParameter
+			for _, entry := range append(params[:len(params):len(params)], opParams...) {
+				param, _ := entry.(map[string]interface{})
+				parameter := validatedParameter{}
+				parameter.In, _ = param["in"].(string)
+				parameter.Name, _ = param["name"].(string)
+				parameter.Description, _ = param["description"].(string)
+				parameter.required, _ = param["required"].(bool)
+				schema, _ := param["schema"].(map[string]interface{})
+				enum, _ := schema["enum"].([]interface{})
+				for _, value := range enum {
+					parameter.Enum = append(parameter.Enum, fmt.Sprint(value))
+				}
+				switch parameter.In {
+				case "query", "header", "cookie":
+					if !parameter.required && len(parameter.Enum) == 0 {
+						continue
+					}
+				case "path":
+					if len(parameter.Enum) == 0 {
+						continue
+					}
+				default:
+					continue
+				}
+				validated = append(validated, parameter)
+			}
+			if len(validated) == 0 {
+				continue
+			}
+			operations[strings.ToUpper(verb)+" "+routeShape(path)] = validatedOperation{
+				docsURL:    operationDocsURL(op),
+				parameters: validated,
+			}
+		}
+	}
+	return operations, nil
+}
+
+// operationDocsURL returns the link to the operation in the UI.
+func operationDocsURL(op map[string]interface{}) string {
+	id, _ := op["operationId"].(string)
+{{- if eq .UI "redoc"}}
+	return "/swagger/index.html#operation/" + id
+{{- else}}
+	tag := "default"
+	if tags, _ := op["tags"].([]interface{}); len(tags) > 0 {
+		if name, ok := tags[0].(string); ok {
+			tag = name
+		}
+	}
+	return "/swagger/index.html#/" + strings.ReplaceAll(tag, " ", "_") + "/" + id
+{{- end}}
+}
+
+// validationMiddleware answers 400 to the requests missing required query, header
+// or cookie parameters of their operation, or whose parameters are not among the
+// values of their enum, instead of letting the service fail on the fields.
+func validationMiddleware() app.HandlerFunc {
+	operations, err := validatedParameters(openapiYAML)
+	if err != nil {
+		hlog.Fatal("Failed to read the parameters of the spec:", err)
+	}
+	return func(c context.Context, ctx *app.RequestContext) {
+		op, ok := operations[string(ctx.Method())+" "+routeShape(ctx.FullPath())]
+		if !ok {
+			ctx.Next(c)
+			return
+		}
+		var missing, invalid []validatedParameter
+		for _, param := range op.parameters {
+			value, present := parameterValue(ctx, param)
+			if !present {
+				if param.required {
+					missing = append(missing, param)
+				}
+				continue
+			}
+			if len(param.Enum) > 0 && !enumContains(param.Enum, value) {
+				invalid = append(invalid, param)
+			}
+		}
+		if len(missing) > 0 {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, map[string]interface{}{
+				"error":      "missing required parameters",
+				"missing":    missing,
+				"x-docs-url": op.docsURL,
+			})
+			return
+		}
+		if len(invalid) > 0 {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, map[string]interface{}{
+				"error":      "invalid parameter values",
+				"invalid":    invalid,
+				"x-docs-url": op.docsURL,
+			})
+			return
+		}
+		ctx.Next(c)
+	}
+}
+
+// parameterValue returns the value of the parameter in the request and whether it
+// is present.
+func parameterValue(ctx *app.RequestContext, param validatedParameter) (string, bool) {
+	var value []byte
+	switch param.In {
+	case "query":
+		if !ctx.QueryArgs().Has(param.Name) {
+			return "", false
+		}
+		value = ctx.QueryArgs().Peek(param.Name)
+		return string(value), true
+	case "header":
+		value = ctx.Request.Header.Peek(param.Name)
+	case "cookie":
+		value = ctx.Cookie(param.Name)
+	case "path":
+		return ctx.Param(param.Name), true
+	}
+	return string(value), len(value) > 0
+}
+
+func enumContains(enum []string, value string) bool {
+	for _, v := range enum {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+{{- end}}
+{{- if or .ConfirmMutations .ValidateRequests}}
+
+// routeShape replaces the parameters of a spec path, e.g. {id}, and of a route,
+// e.g. :id or *filepath, with the same placeholder, the names of the parameters may
+// differ.
+func routeShape(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") || strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+{{- end}}
+{{- if or .Metrics .RateLimit}}
+
+// thriftMethod is the method of a Thrift service, the labels of the metrics and
+// the key of the rate limits.
+type thriftMethod struct {
+	service string
+	method  string
+}
+
+// routeMethods holds the Thrift methods of the routes, keyed by method and route, so
+// that the methods do not depend on the requested URL.
+var routeMethods = map[string]thriftMethod{
+{{- range .MethodRoutes}}
+	{{printf "%q" .Key}}: {service: {{printf "%q" .Service}}, method: {{printf "%q" .Function}}},
+{{- end}}
+}
+
+// methodOf returns the method of the route of ctx, the catch-all route has the
+// unknown method.
+func methodOf(ctx *app.RequestContext) thriftMethod {
+	if m, ok := routeMethods[string(ctx.Method())+" "+ctx.FullPath()]; ok {
+		return m
+	}
+	if m, ok := routeMethods["ANY "+ctx.FullPath()]; ok {
+		return m
+	}
+	return thriftMethod{service: "unknown", method: "unknown"}
+}
+{{- end}}
+{{- if .RateLimit}}
+
+// rateLimit is the number of requests per second allowed to each method of the
+// routes, the requests of the catch-all route share one limit. rateBurst is the
+// number of requests allowed at once.
+const (
+	rateLimit = {{.RateLimit}}
+	rateBurst = {{.RateBurst}}
+)
+
+// tokenBucket holds the requests allowed to a method.
+type tokenBucket struct {
+	sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token from the bucket, or returns the time until the next token
+// when the bucket is empty.
+func (b *tokenBucket) take(now time.Time) (time.Duration, bool) {
+	b.Lock()
+	defer b.Unlock()
+	b.tokens = math.Min(rateBurst, b.tokens+now.Sub(b.last).Seconds()*rateLimit)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / rateLimit * float64(time.Second)), false
+}
+
+// rateLimitMiddleware answers 429 to the requests exceeding the limit of their
+// method, with the seconds until the next allowed request in Retry-After.
+func rateLimitMiddleware() app.HandlerFunc {
+	var mu sync.Mutex
+	buckets := make(map[thriftMethod]*tokenBucket)
+	return func(c context.Context, ctx *app.RequestContext) {
+		m := methodOf(ctx)
+		mu.Lock()
+		bucket, ok := buckets[m]
+		if !ok {
+			bucket = &tokenBucket{tokens: rateBurst, last: time.Now()}
+			buckets[m] = bucket
+		}
+		mu.Unlock()
+
+		if wait, ok := bucket.take(time.Now()); !ok {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, map[string]interface{}{
+				"error": "rate limit exceeded",
+			})
+			return
+		}
+		ctx.Next(c)
+	}
+}
+{{- end}}
+{{- if .Metrics}}
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swagger_proxy_requests_total",
+		Help: "Requests proxied to the Kitex service.",
+	}, []string{"service", "method", "code"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "swagger_proxy_request_duration_seconds",
+		Help:    "Latency of the requests proxied to the Kitex service.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+	upstreamErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swagger_proxy_upstream_errors_total",
+		Help: "Failed calls to the Kitex service.",
+	}, []string{"service", "method"})
+)
+
+func setupMetrics(h *server.Hertz) {
+	prometheus.MustRegister(requestsTotal, requestDuration, upstreamErrorsTotal)
+{{- if .MetricsPort}}
+	// The metrics are served by their own listener, out of reach of the API clients.
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(":{{.MetricsPort}}", mux); err != nil {
+			hlog.Errorf("Metrics listener stopped: %s", err)
+		}
+	}()
+{{- else}}
+	h.GET("/metrics", adaptor.HertzHandler(promhttp.Handler()))
+{{- end}}
+}
+
+// metricsMiddleware records the requests with the Thrift service and method of their
+// route, which the tracer of hertz-contrib/monitor-prometheus does not know about.
+func metricsMiddleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+		ctx.Next(c)
+		m := methodOf(ctx)
+		requestsTotal.WithLabelValues(m.service, m.method, strconv.Itoa(ctx.Response.StatusCode())).Inc()
+		requestDuration.WithLabelValues(m.service, m.method).Observe(time.Since(start).Seconds())
+	}
+}
+{{- end}}
+{{- if .HasExceptions}}
+
+// thriftException is an exception declared by the function of a route, answered
+// with the status of its api.http_code annotation and its fields as body.
+type thriftException struct {
+	field  string
+	status int
+	fields []string
+}
+
+// routeExceptions holds the exceptions of the routes, keyed by method and route.
+var routeExceptions = map[string][]thriftException{
+{{- range .ProxyRoutes}}
+{{- if .Exceptions}}
+	{{printf "%q" .Key}}: {
+{{- range .Exceptions}}
+		{field: {{printf "%q" .Field}}, status: {{.Status}}, fields: []string{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}{{printf "%q" $f}}{{end -}} }},
+{{- end}}
+	},
+{{- end}}
+{{- end}}
+}
+
+func exceptionsOf(ctx *app.RequestContext) []thriftException {
+	if exceptions, ok := routeExceptions[string(ctx.Method())+" "+ctx.FullPath()]; ok {
+		return exceptions
+	}
+	return routeExceptions["ANY "+ctx.FullPath()]
+}
+
+// exceptionFromBody returns the exception set in the response body under the name
+// of its throws field.
+func exceptionFromBody(exceptions []thriftException, body map[string]interface{}) (int, interface{}, bool) {
+	for _, e := range exceptions {
+		if payload, ok := body[e.field]; ok && payload != nil {
+			return e.status, payload, true
+		}
+	}
+	return 0, nil, false
+}
+
+// exceptionFromError returns the exception carried by the error of the generic call,
+// whose message ends with the JSON encoded exception. The payload belongs to the
+// first exception declaring all of its fields.
+func exceptionFromError(exceptions []thriftException, err error) (int, interface{}, bool) {
+	msg := err.Error()
+	i := strings.Index(msg, "{")
+	if i < 0 {
+		return 0, nil, false
+	}
+	var payload map[string]interface{}
+	if json.Unmarshal([]byte(msg[i:]), &payload) != nil || len(payload) == 0 {
+		return 0, nil, false
+	}
+	for _, e := range exceptions {
+		declared := true
+		for name := range payload {
+			found := false
+			for _, field := range e.fields {
+				if field == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				declared = false
+				break
+			}
+		}
+		if declared {
+			return e.status, payload, true
+		}
+	}
+	return 0, nil, false
+}
+{{- end}}
+{{- if .HasStatusFields}}
+
+// routeStatusFields holds the body field carrying the status of the responses of the
+// routes, keyed by method and route.
+var routeStatusFields = map[string]string{
+{{- range .ProxyRoutes}}
+{{- if .StatusField}}
+	{{printf "%q" .Key}}: {{printf "%q" .StatusField}},
+{{- end}}
+{{- end}}
+}
+
+func statusFieldOf(ctx *app.RequestContext) string {
+	if field, ok := routeStatusFields[string(ctx.Method())+" "+ctx.FullPath()]; ok {
+		return field
+	}
+	return routeStatusFields["ANY "+ctx.FullPath()]
+}
+
+// statusFromBody returns the HTTP status set in the response body under field.
+func statusFromBody(field string, body map[string]interface{}) (int, bool) {
+	var status int64
+	switch v := body[field].(type) {
+	case int8:
+		status = int64(v)
+	case int16:
+		status = int64(v)
+	case int32:
+		status = int64(v)
+	case int64:
+		status = v
+	case int:
+		status = int64(v)
+	case float64:
+		status = int64(v)
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, false
+		}
+		status = n
+	default:
+		return 0, false
+	}
+	if status < 100 || status > 599 {
+		return 0, false
+	}
+	return int(status), true
+}
+{{- end}}
+{{- if .HasNoContent}}
+
+// routeNoContent holds the status of the routes of the void functions, 204 or 202 for
+// the oneway ones, keyed by method and route.
+var routeNoContent = map[string]int{
+{{- range .ProxyRoutes}}
+{{- if .NoContent}}
+	{{printf "%q" .Key}}: {{if .Accepted}}http.StatusAccepted{{else}}http.StatusNoContent{{end}},
+{{- end}}
+{{- end}}
+}
+
+// noContentOf returns the status answering the route without a body, 0 when the
+// route has one.
+func noContentOf(ctx *app.RequestContext) int {
+	if status, ok := routeNoContent[string(ctx.Method())+" "+ctx.FullPath()]; ok {
+		return status
+	}
+	return routeNoContent["ANY "+ctx.FullPath()]
+}
+{{- end}}
+{{- if .HasRawBodies}}
+
+// routeRawBodies holds the binary raw body field of the downloads of the routes,
+// keyed by method and route.
+var routeRawBodies = map[string]string{
+{{- range .ProxyRoutes}}
+{{- if .RawBody}}
+	{{printf "%q" .Key}}: {{printf "%q" .RawBody}},
+{{- end}}
+{{- end}}
+}
+
+func rawBodyFieldOf(ctx *app.RequestContext) string {
+	if field, ok := routeRawBodies[string(ctx.Method())+" "+ctx.FullPath()]; ok {
+		return field
+	}
+	return routeRawBodies["ANY "+ctx.FullPath()]
+}
+{{- end}}
+
+{{if eq .AuthType "basic" -}}
+func authMiddleware() app.HandlerFunc {
+	user, password := os.Getenv("{{.AuthUserEnv}}"), os.Getenv("{{.AuthPasswordEnv}}")
+	if user == "" || password == "" {
+		hlog.Fatal("{{.AuthUserEnv}} and {{.AuthPasswordEnv}} must be set")
+	}
+	return basic_auth.BasicAuth(basic_auth.Accounts{user: password})
+}
+
+{{else if eq .AuthType "apikey" -}}
+func authMiddleware() app.HandlerFunc {
+	key := os.Getenv("{{.AuthKeyEnv}}")
+	if key == "" {
+		hlog.Fatal("{{.AuthKeyEnv}} must be set")
+	}
+	return func(c context.Context, ctx *app.RequestContext) {
+		if subtle.ConstantTimeCompare(ctx.Request.Header.Peek("{{.AuthHeader}}"), []byte(key)) != 1 {
+			ctx.Header("WWW-Authenticate", ` + "`" + `APIKey header="{{.AuthHeader}}"` + "`" + `)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "unauthorized",
+			})
+			return
+		}
+		ctx.Next(c)
+	}
+}
+
+{{end -}}
+// debugBodyLimit is the size at which the bodies logged in debug mode are truncated.
+const debugBodyLimit = {{.DebugBodyLimit}}
+
+// debugEnabled turns on the debug logging of the proxied requests, the Debug
+// generation option can be overridden by the PROXY_DEBUG environment variable.
+var debugEnabled = debugFromEnv({{.Debug}})
+
+// requestIDKey is the key of the request ID in the request context.
+const requestIDKey = "request_id"
+
+// redactedHeaders are the headers whose value is never logged.
+var redactedHeaders = map[string]bool{
+{{- range .RedactedHeaders}}
+	{{printf "%q" .}}: true,
+{{- end}}
+}
+
+func debugFromEnv(generated bool) bool {
+	value, ok := os.LookupEnv("PROXY_DEBUG")
+	if !ok {
+		return generated
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		hlog.Warnf("Invalid PROXY_DEBUG '%s', using %t: %s", value, generated, err)
+		return generated
+	}
+	return enabled
+}
+
+// debugMiddleware tags the proxied requests with a request ID, returned in the
+// X-Request-Id header, and logs them when debug logging is enabled.
+func debugMiddleware() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !debugEnabled {
+			ctx.Next(c)
+			return
+		}
+{{- if .RequestID}}
+		// The ID is set and returned in X-Request-Id by the requestid middleware.
+		requestID := requestid.Get(ctx)
+		ctx.Set(requestIDKey, requestID)
+{{- else}}
+		requestID := newRequestID()
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header("X-Request-Id", requestID)
+{{- end}}
+
+		// Streamed bodies are not logged, reading them would load them in memory.
+		body := "<streamed>"
+		if length := ctx.Request.Header.ContentLength(); length >= 0 && length <= streamThreshold {
+			body = truncateBody(ctx.Request.Body())
+		}
+		debugf(ctx, "stage=inbound method=%s path=%s query=%q headers=%q body=%s",
+			ctx.Method(), ctx.Path(), ctx.QueryArgs().String(), redactHeaders(ctx), body)
+		ctx.Next(c)
+	}
+}
+
+{{- if .RequestID}}
+
+// forwardRequestID passes the request ID, taken from the X-Request-Id header or
+// generated by the requestid middleware, to the Kitex service as the request_id
+// persistent metainfo of the calls, carried by their TTHeader.
+func forwardRequestID(c context.Context, ctx *app.RequestContext) {
+	ctx.Next(metainfo.WithPersistentValue(c, requestIDKey, requestid.Get(ctx)))
+}
+{{- end}}
+
+func debugf(ctx *app.RequestContext, format string, args ...interface{}) {
+	if !debugEnabled {
+		return
+	}
+	hlog.Infof("request_id=%s "+format, append([]interface{}{ctx.GetString(requestIDKey)}, args...)...)
+}
+
+func newRequestID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// redactHeaders returns the request headers with the values of the sensitive
+// headers replaced.
+func redactHeaders(ctx *app.RequestContext) []string {
+	var headers []string
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		if redactedHeaders[strings.ToLower(string(key))] {
+			value = []byte("<redacted>")
+		}
+		headers = append(headers, string(key)+": "+string(value))
+	})
+	return headers
+}
+
+func debugJSON(value interface{}) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	return truncateBody(content)
+}
+
+func truncateBody(body []byte) string {
+	if len(body) > debugBodyLimit {
+		return string(body[:debugBodyLimit]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// streamThreshold is the size above which a request body, e.g. a file upload, is
+// streamed to a temporary file instead of being held in memory.
+const streamThreshold = {{.StreamThreshold}}
+
+// newProxyRequest returns the request forwarding the body of ctx to url. The body
+// stays readable through GetBody, the generic call reading it before the form is
+// parsed. The returned cleanup removes the temporary file of a streamed body.
+func newProxyRequest(ctx *app.RequestContext, url string) (*http.Request, func(), error) {
+	method := string(ctx.Request.Method())
+	if length := ctx.Request.Header.ContentLength(); length >= 0 && length <= streamThreshold {
+		req, err := http.NewRequest(method, url, bytes.NewReader(ctx.Request.Body()))
+		return req, func() {}, err
+	}
+
+	f, err := os.CreateTemp("", "swagger-proxy-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	var opened []io.Closer
+	cleanup := func() {
+		for _, c := range opened {
+			c.Close()
+		}
+		f.Close()
+		os.Remove(f.Name())
+	}
+	size, err := io.Copy(f, ctx.RequestBodyStream())
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	open := func() (io.ReadCloser, error) {
+		body, err := os.Open(f.Name())
+		if err == nil {
+			opened = append(opened, body)
+		}
+		return body, err
+	}
+	body, err := open()
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	req.ContentLength = size
+	req.GetBody = open
+	return req, cleanup, nil
+}
+
+// hopHeaders are the hop-by-hop headers, which are not forwarded, along with Host and
+// Content-Length, set again for the forwarded body.
+var hopHeaders = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-authenticate":  true,
+	"proxy-authorization": true,
+	"te":                  true,
+	"trailer":             true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"host":                true,
+	"content-length":      true,
+}
+
+// strippedHeaders are the headers removed in both directions.
+var strippedHeaders = map[string]bool{
+{{- range .StripHeaders}}
+	{{printf "%q" .}}: true,
+{{- end}}
+}
+{{- if .ForwardHeaders}}
+
+// forwardedHeaders are the only request headers forwarded to the Kitex service.
+var forwardedHeaders = map[string]bool{
+{{- range .ForwardHeaders}}
+	{{printf "%q" .}}: true,
+{{- end}}
+}
+{{- end}}
+
+// connectionHeaders returns the headers named by a Connection header, which are
+// hop-by-hop too.
+func connectionHeaders(connection string) []string {
+	var headers []string
+	for _, header := range strings.Split(connection, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, strings.ToLower(header))
+		}
+	}
+	return headers
+}
+
+func forwardResponseHeader(key string, connection []string) bool {
+	key = strings.ToLower(key)
+	if hopHeaders[key] || strippedHeaders[key] {
+		return false
+	}
+	for _, header := range connection {
+		if header == key {
+			return false
+		}
+	}
+	return true
+}
+
+func forwardRequestHeader(key string, connection []string) bool {
+{{- if .ForwardHeaders}}
+	if !forwardedHeaders[strings.ToLower(key)] {
+		return false
+	}
+{{- end}}
+	return forwardResponseHeader(key, connection)
+}
+
+func formatQueryParams(ctx *app.RequestContext) string {
+	var newQueryParams []string
+	ctx.Request.URI().QueryArgs().VisitAll(func(key, value []byte) {
+		newQueryParams = append(newQueryParams, string(key)+"="+string(value))
+	})
+	return strings.Join(newQueryParams, "&")
+}
+
+// handleProxyRequest calls the Kitex service within the context of the request, which
+// carries its trace.
+func handleProxyRequest(c context.Context, ctx *app.RequestContext, cli genericclient.Client, req *http.Request) {
+	customReq, err := generic.FromHTTPRequest(req)
+	if err != nil {
+		handleError(ctx, "Failed to create generic request", http.StatusInternalServerError)
+		return
+	}
+	if debugEnabled {
+		debugf(ctx, "stage=generic_request method=%s path=%s query=%q body=%s",
+			customReq.Method, customReq.Path, customReq.Query.Encode(), debugJSON(customReq.Body))
+	}
+
+	resp, err := cli.GenericCall(c, "", customReq)
+	if err != nil {
+		debugf(ctx, "stage=upstream_error error=%q", err.Error())
+{{- if .HasExceptions}}
+		if status, payload, ok := exceptionFromError(exceptionsOf(ctx), err); ok {
+			ctx.JSON(status, payload)
+			return
+		}
+{{- end}}
+		handleTransportError(ctx, "GenericCall error: "+err.Error())
+		return
+	}
+
+	if resp == nil {
+		handleError(ctx, "Received nil response from the service", http.StatusInternalServerError)
+		return
+	}
+
+	realResp, ok := resp.(*generic.HTTPResponse)
+	if !ok {
+		handleError(ctx, "Invalid response format", http.StatusInternalServerError)
+		return
+	}
+	if debugEnabled {
+		debugf(ctx, "stage=upstream_response status=%d body=%s", realResp.StatusCode, debugJSON(realResp.Body))
+	}
+{{- if .HasExceptions}}
+
+	if status, payload, ok := exceptionFromBody(exceptionsOf(ctx), realResp.Body); ok {
+		ctx.JSON(status, payload)
+		return
+	}
+{{- end}}
+{{- if .HasNoContent}}
+
+	if status := noContentOf(ctx); status != 0 {
+		ctx.SetStatusCode(status)
+		return
+	}
+{{- end}}
+{{- if .HasStatusFields}}
+
+	if field := statusFieldOf(ctx); field != "" {
+		if status, ok := statusFromBody(field, realResp.Body); ok {
+			realResp.StatusCode = int32(status)
+		}
+{{- if .StripStatusField}}
+		delete(realResp.Body, field)
+{{- end}}
+	}
+{{- end}}
+
+	if body, ok := rawBody(ctx, realResp); ok {
+		sendRawResponse(ctx, realResp, body)
+		return
+	}
+
+	sendResponse(ctx, realResp)
+}
+
+// rawBody returns the bytes of a response which is not JSON, a documented download
+// or a response of another content type, to be written as they are.
+func rawBody(ctx *app.RequestContext, realResp *generic.HTTPResponse) ([]byte, bool) {
+	var value interface{}
+	documented := false
+{{- if .HasRawBodies}}
+	if field := rawBodyFieldOf(ctx); field != "" {
+		value, documented = realResp.Body[field], true
+	}
+{{- end}}
+	if !documented {
+		if isJSON(responseContentType(realResp)) {
+			return nil, false
+		}
+		if len(realResp.Body) == 1 {
+			for _, v := range realResp.Body {
+				value = v
+			}
+		}
+	}
+	if len(realResp.RawBody) > 0 {
+		return realResp.RawBody, true
+	}
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	return nil, false
+}
+
+// sendRawResponse writes the body as is, along with the headers of the service such
+// as Content-Disposition. The generic client already holds the whole body, it is
+// handed to the response without another copy.
+func sendRawResponse(ctx *app.RequestContext, realResp *generic.HTTPResponse, body []byte) {
+{{- if .MaxResponseBytes}}
+	if responseTooLarge(ctx, len(body)) {
+		return
+	}
+{{- end}}
+	if realResp.StatusCode == 0 {
+		realResp.StatusCode = http.StatusOK
+	}
+
+	connection := connectionHeaders(realResp.Header.Get("Connection"))
+	for key, values := range realResp.Header {
+		if !forwardResponseHeader(key, connection) {
+			continue
+		}
+		for _, value := range values {
+			ctx.Response.Header.Add(key, value)
+		}
+	}
+
+	contentType := responseContentType(realResp)
+	if isJSON(contentType) {
+		contentType = "application/octet-stream"
+	}
+	ctx.SetStatusCode(int(realResp.StatusCode))
+	ctx.SetContentType(contentType)
+	ctx.Response.SetBodyRaw(body)
+}
+
+func responseContentType(realResp *generic.HTTPResponse) string {
+	if contentType := realResp.Header.Get("Content-Type"); contentType != "" {
+		return contentType
+	}
+	return string(realResp.ContentType)
+}
+
+// isJSON reports whether a response of the content type is encoded as JSON, which
+// is the case of responses without content type.
+func isJSON(contentType string) bool {
+	return contentType == "" || strings.Contains(contentType, "json")
+}
+
+// sendResponse writes the JSON of the body decoded by the generic client. The JSON
+// is encoded straight into the response rather than into a buffer copied afterwards.
+func sendResponse(ctx *app.RequestContext, realResp *generic.HTTPResponse) {
+	if realResp.StatusCode == 0 {
+		realResp.StatusCode = http.StatusOK
+	}
+
+	var w io.Writer = ctx.Response.BodyWriter()
+{{- if .MaxResponseBytes}}
+	w = &limitedWriter{w: w, n: maxResponseBytes}
+{{- end}}
+	if err := writeJSON(w, realResp.Body); err != nil {
+		ctx.Response.ResetBody()
+{{- if .MaxResponseBytes}}
+		if err == errResponseTooLarge {
+			handleError(ctx, "Response body exceeds the limit of "+strconv.Itoa(maxResponseBytes)+" bytes", http.StatusBadGateway)
+			return
+		}
+{{- end}}
+		handleError(ctx, "Failed to marshal response body", http.StatusInternalServerError)
+		return
+	}
+
+	connection := connectionHeaders(realResp.Header.Get("Connection"))
+	for key, values := range realResp.Header {
+		if !forwardResponseHeader(key, connection) {
+			continue
+		}
+		for _, value := range values {
+			ctx.Response.Header.Set(key, value)
+		}
+	}
+
+	ctx.SetStatusCode(int(realResp.StatusCode))
+	ctx.SetContentType(string(realResp.ContentType))
+}
+
+// writeJSON writes the JSON of the value, the objects and the arrays element by
+// element so that only the encoding of one element is held in memory at a time. The
+// keys of the objects are sorted as json.Marshal does.
+func writeJSON(w io.Writer, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			break
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if _, err := io.WriteString(w, "{"); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			name, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				name = append([]byte{','}, name...)
+			}
+			if _, err := w.Write(append(name, ':')); err != nil {
+				return err
+			}
+			if err := writeJSON(w, v[key]); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "}")
+		return err
+	case []interface{}:
+		if v == nil {
+			break
+		}
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+		for i, element := range v {
+			if i > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			if err := writeJSON(w, element); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "]")
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+{{- if .MaxResponseBytes}}
+
+// maxResponseBytes is the largest response body of the service forwarded to the
+// client, a larger one is answered with 502.
+const maxResponseBytes = {{.MaxResponseBytes}}
+
+// responseTooLarge answers 502 when a response body of the given size exceeds
+// maxResponseBytes.
+func responseTooLarge(ctx *app.RequestContext, size int) bool {
+	if size <= maxResponseBytes {
+		return false
+	}
+	handleError(ctx, "Response body of "+strconv.Itoa(size)+" bytes exceeds the limit of "+strconv.Itoa(maxResponseBytes)+" bytes", http.StatusBadGateway)
+	return true
+}
+
+var errResponseTooLarge = errors.New("response body too large")
+
+// limitedWriter fails with errResponseTooLarge once more than n bytes are written,
+// the JSON of a response is not encoded any further past the limit.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		l.n = 0
+		return 0, errResponseTooLarge
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
+{{- end}}
+
+func handleError(ctx *app.RequestContext, errMsg string, statusCode int) {
+	hlog.Errorf("Error: %s", errMsg)
+	ctx.JSON(statusCode, map[string]interface{}{
+		"error": errMsg,
+	})
+}
+
+// handleTransportError answers a failed call to the Kitex service, the type field
+// tells it apart from the exceptions declared by the IDL.
+func handleTransportError(ctx *app.RequestContext, errMsg string) {
+	hlog.Errorf("Error: %s", errMsg)
+{{- if .Metrics}}
+	m := methodOf(ctx)
+	upstreamErrorsTotal.WithLabelValues(m.service, m.method).Inc()
+{{- end}}
+	ctx.JSON(http.StatusBadGateway, map[string]interface{}{
+		"error": errMsg,
+		"type":  "transport",
+	})
+}
+
+{{- define "specPartRoutes"}}
+{{- if .SpecParts}}
+
+	entries, err := specParts.ReadDir(".")
+	if err != nil {
+		hlog.Fatal("Failed to read the spec parts:", err)
+	}
+	for _, entry := range entries {
+		content, err := specParts.ReadFile(entry.Name())
+		if err != nil {
+			hlog.Fatal("Failed to read the spec parts:", err)
+		}
+		part := newSpec(content, "application/x-yaml")
+		h.GET("/"+entry.Name(), {{if .AuthType}}auth, {{end}}func(c context.Context, ctx *app.RequestContext) {
+			serveSpec(ctx, part)
+		})
+	}
+{{- end}}
+{{- end}}
+
+{{- define "minifiedSpecRoute"}}
+{{- if .Minify}}
+
+	minified := newSpec(openapiMinJSON, "application/json")
+	h.GET("/openapi.min.json", {{if .AuthType}}auth, {{end}}func(c context.Context, ctx *app.RequestContext) {
+		serveSpec(ctx, minified)
+	})
+{{- end}}
+{{- end}}
+
+{{- define "uiHandler"}}
+{{- if .EmbedUI}}serveUI
+{{- else}}swagger.WrapHandler(swaggerFiles.Handler, swagger.URL("{{.SpecURL}}"))
+{{- end}}
+{{- end}}
+
+{{- define "currentSpecs"}}
+{{- if .SpecFile}}loadSpecs(embedded)
+{{- else}}embedded
+{{- end}}
+{{- end}}
+
+{{- define "proxyHandlers"}}debug, 
+{{- if .Metrics}}metrics, {{end}}{{if .RateLimit}}ratelimit, {{end}}{{if .AuthProxy}}auth, {{end}}{{if .ConfirmMutations}}confirm, {{end}}{{if .ValidateRequests}}validate, {{end}}proxy
+{{- end}}
+
+{{- define "genericClient"}}
+{{- if .ResolverExpr}}
+	r, err := {{.ResolverExpr}}
+	if err != nil {
+		hlog.Fatal("Failed to create resolver:", err)
+	}
+
+	cli, err := genericclient.NewClient("{{.ServiceName}}", g, client.WithResolver(r){{template "clientOptions" .}})
+{{- else}}
+	cli, err := genericclient.NewClient("{{.ServiceName}}", g, client.WithHostPorts("{{.KitexAddr}}"){{template "clientOptions" .}})
+{{- end}}
+	if err != nil {
+		hlog.Fatal("Failed to create generic client:", err)
+	}
+
+	return cli
+{{- end}}
+
+{{- define "clientOptions"}}
+{{- if eq .Tracing "otel"}}, client.WithSuite(kitextracing.NewClientSuite()){{end}}
+{{- if .RequestID}},
+		client.WithTransportProtocol(transport.TTHeader),
+		client.WithMetaHandler(transmeta.ClientTTHeaderHandler)
+{{- end}}
+{{- if .ClientTLS}},
+		client.WithDialer(&tlsDialer{config: clientTLSConfig()}),
+		client.WithTransHandlerFactory(gonet.NewCliTransHandlerFactory())
+{{- end}}
+{{- end}}
 `
@@ -0,0 +1,838 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+var helloIDL = filepath.Join("..", "example", "hello.thrift")
+
+// renderServer renders the server of the IDL with the arguments and checks that it
+// parses.
+func renderServer(t *testing.T, idl string, arguments *args.Arguments) (string, error) {
+	t.Helper()
+	sg, err := NewServerGenerator(parseIDL(t, idl), arguments, nil)
+	if err != nil {
+		return "", err
+	}
+	content := sg.Generate()[0].Content
+	if _, err := goparser.ParseFile(token.NewFileSet(), "swagger.go", content, goparser.AllErrors); err != nil {
+		t.Fatalf("generated server does not parse: %s", err)
+	}
+	return content, nil
+}
+
+// checkServer renders the server of the IDL with the arguments and checks that it
+// holds each of the snippets.
+func checkServer(t *testing.T, idl string, arguments *args.Arguments, snippets ...string) string {
+	t.Helper()
+	content, err := renderServer(t, idl, arguments)
+	if err != nil {
+		t.Fatalf("render: %s", err)
+	}
+	for _, snippet := range snippets {
+		if !strings.Contains(content, snippet) {
+			t.Errorf("generated server is missing %q", snippet)
+		}
+	}
+	return content
+}
+
+func TestResolver(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments *args.Arguments
+		want      []string
+	}{
+		{
+			name:      "host and port",
+			arguments: &args.Arguments{KitexAddr: "10.0.0.1:9000"},
+			want:      []string{`genericclient.NewClient("swagger", g, client.WithHostPorts("10.0.0.1:9000")`},
+		},
+		{
+			name:      "etcd",
+			arguments: &args.Arguments{Resolver: "etcd://127.0.0.1:2379|127.0.0.2:2379", ServiceName: "user"},
+			want: []string{
+				`"github.com/kitex-contrib/registry-etcd"`,
+				`r, err := etcd.NewEtcdResolver([]string{"127.0.0.1:2379", "127.0.0.2:2379"})`,
+				`genericclient.NewClient("user", g, client.WithResolver(r)`,
+			},
+		},
+		{
+			name: "custom",
+			arguments: &args.Arguments{
+				ResolverImport: "github.com/kitex-contrib/registry-nacos/resolver",
+				ResolverExpr:   "resolver.NewDefaultNacosResolver()",
+			},
+			want: []string{
+				`"github.com/kitex-contrib/registry-nacos/resolver"`,
+				`r, err := resolver.NewDefaultNacosResolver()`,
+				`client.WithResolver(r)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := checkServer(t, helloIDL, tt.arguments, tt.want...)
+			if tt.arguments.KitexAddr == "" && strings.Contains(content, "client.WithHostPorts") {
+				t.Error("a resolver is used along with the host and port")
+			}
+		})
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	tests := []*args.Arguments{
+		{Resolver: "etcd://127.0.0.1:2379", ResolverImport: "example.com/resolver", ResolverExpr: "resolver.New()"},
+		{ResolverImport: "example.com/resolver"},
+		{ResolverExpr: "resolver.New()"},
+		{Resolver: "zookeeper://127.0.0.1:2181"},
+		{Resolver: "etcd://"},
+	}
+	for _, arguments := range tests {
+		if _, err := renderServer(t, helloIDL, arguments); err == nil {
+			t.Errorf("%+v: rendered without error", *arguments)
+		}
+	}
+}
+
+func TestTLS(t *testing.T) {
+	plain := checkServer(t, helloIDL, &args.Arguments{})
+	for _, snippet := range []string{"crypto/tls", "WithTLS", "tlsDialer"} {
+		if strings.Contains(plain, snippet) {
+			t.Errorf("server without TLS arguments has %q", snippet)
+		}
+	}
+
+	checkServer(t, helloIDL, &args.Arguments{ServerCert: "server.crt", ServerKey: "server.key"},
+		`server.WithTLS(serverTLSConfig())`,
+		`tls.LoadX509KeyPair("server.crt", "server.key")`,
+		"https://",
+	)
+	checkServer(t, helloIDL, &args.Arguments{ClientCA: "ca.crt", ClientCert: "client.crt", ClientKey: "client.key", ClientServerName: "rpc.internal"},
+		`client.WithDialer(&tlsDialer{config: clientTLSConfig()})`,
+		`ServerName: "rpc.internal"`,
+		`os.ReadFile("ca.crt")`,
+		`tls.LoadX509KeyPair("client.crt", "client.key")`,
+	)
+}
+
+func TestTLSErrors(t *testing.T) {
+	tests := []*args.Arguments{
+		{ServerCert: "server.crt"},
+		{ServerKey: "server.key"},
+		{ClientCert: "client.crt"},
+		{ClientCA: "ca.crt", ClientKey: "client.key"},
+	}
+	for _, arguments := range tests {
+		if _, err := renderServer(t, helloIDL, arguments); err == nil {
+			t.Errorf("%+v: rendered without error", *arguments)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments *args.Arguments
+		want      []string
+	}{
+		{
+			name:      "basic",
+			arguments: &args.Arguments{Auth: "basic"},
+			want:      []string{`os.Getenv("SWAGGER_USER"), os.Getenv("SWAGGER_PASSWORD")`, `basic_auth.BasicAuth(`},
+		},
+		{
+			name:      "basic with variables",
+			arguments: &args.Arguments{Auth: "basic:DOCS_USER:DOCS_PASSWORD"},
+			want:      []string{`os.Getenv("DOCS_USER"), os.Getenv("DOCS_PASSWORD")`},
+		},
+		{
+			name:      "apikey",
+			arguments: &args.Arguments{Auth: "apikey:X-Api-Key"},
+			want:      []string{`os.Getenv("SWAGGER_API_KEY")`, `Header.Peek("X-Api-Key")`, `"WWW-Authenticate"`, "http.StatusUnauthorized"},
+		},
+		{
+			name:      "apikey with variable",
+			arguments: &args.Arguments{Auth: "apikey:X-Api-Key:DOCS_KEY"},
+			want:      []string{`os.Getenv("DOCS_KEY")`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := checkServer(t, helloIDL, tt.arguments, append(tt.want, `h.GET("/openapi.yaml", auth,`, `h.GET("swagger/*any", auth,`)...)
+			if strings.Contains(content, "auth, proxy") {
+				t.Error("the proxy routes are protected without AuthProxy")
+			}
+		})
+	}
+
+	checkServer(t, helloIDL, &args.Arguments{Auth: "basic", AuthProxy: true}, "auth, proxy")
+	plain := checkServer(t, helloIDL, &args.Arguments{})
+	if strings.Contains(plain, "authMiddleware") {
+		t.Error("server without Auth has an auth middleware")
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+	for _, auth := range []string{"token", "basic:USER", "apikey", "apikey:", "apikey:X Key", "basic:1USER:PASSWORD"} {
+		if _, err := renderServer(t, helloIDL, &args.Arguments{Auth: auth}); err == nil {
+			t.Errorf("Auth '%s': rendered without error", auth)
+		}
+	}
+	if _, err := renderServer(t, helloIDL, &args.Arguments{AuthProxy: true}); err == nil {
+		t.Error("AuthProxy without Auth: rendered without error")
+	}
+}
+
+func TestVersionInPathErrors(t *testing.T) {
+	// The versioned paths of the document would match none of the routes.
+	tests := []*args.Arguments{
+		{VersionInPath: true},
+		{VersionInPath: true, ConfirmMutations: true},
+		{VersionInPath: true, ValidateRequests: true},
+	}
+	for _, arguments := range tests {
+		if _, err := renderServer(t, helloIDL, arguments); err == nil || !strings.Contains(err.Error(), "set NoServer") {
+			t.Errorf("%+v: got error %v, want VersionInPath rejected", *arguments, err)
+		}
+	}
+}
+
+func TestDisabledRoutes(t *testing.T) {
+	idl := writeMain(t, profilesIDL)
+	checkServer(t, idl, &args.Arguments{}, "setupDisabledRoutes(h)", `h.Handle("POST", "/license", disabled)`)
+	content := checkServer(t, idl, &args.Arguments{Profiles: []string{"enterprise"}})
+	if strings.Contains(content, "setupDisabledRoutes(h)") {
+		t.Error("routes are disabled with the profile of the only_if annotation active")
+	}
+}
+
+func TestSpecMode(t *testing.T) {
+	for _, mode := range []string{"", "embed"} {
+		content := checkServer(t, helloIDL, &args.Arguments{SpecMode: mode})
+		if strings.Contains(content, "specFile") {
+			t.Errorf("SpecMode '%s' reads the spec from disk", mode)
+		}
+	}
+
+	checkServer(t, helloIDL, &args.Arguments{SpecMode: "file"},
+		"const specFile = ",
+		`openapi.yaml"`,
+		"os.Stat(specFile)",
+		"ModTime()",
+		`ctx.Header("ETag"`,
+	)
+
+	if _, err := renderServer(t, helloIDL, &args.Arguments{SpecMode: "disk"}); err == nil || !strings.Contains(err.Error(), "unsupported SpecMode 'disk'") {
+		t.Errorf("got error %v, want an unsupported SpecMode error", err)
+	}
+}
+
+func TestJSONSpecRoute(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{},
+		`h.GET("/openapi.yaml"`,
+		`h.GET("/openapi.json"`,
+		`newSpec(jsonContent, "application/json")`,
+		`ctx.Header("ETag", s.etag)`,
+		`swagger.URL("/openapi.yaml")`,
+	)
+	checkServer(t, helloIDL, &args.Arguments{UISpec: "json"}, `swagger.URL("/openapi.json")`)
+
+	assets, err := uiAssets(uiEmbedded, writeDist(t), "out", "/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(assets[0].Content, `url: "/openapi.json"`) {
+		t.Errorf("the embedded UI does not load the JSON spec:\n%s", assets[0].Content)
+	}
+
+	if _, err := renderServer(t, helloIDL, &args.Arguments{UISpec: "xml"}); err == nil || !strings.Contains(err.Error(), "unsupported UISpec 'xml'") {
+		t.Errorf("got error %v, want an unsupported UISpec error", err)
+	}
+}
+
+func TestExceptionRoutes(t *testing.T) {
+	content := checkServer(t, writeMain(t, exceptionsIDL), &args.Arguments{},
+		`"GET /users/:id": {`,
+		`{field: "notFound", status: 404, fields: []string{"message"}}`,
+		`{field: "conflict", status: 409, fields: []string{"message", "version"}}`,
+		`{field: "internal", status: 500, fields: []string{"message"}}`,
+		`{field: "gone", status: 404, fields: []string{"reason"}}`,
+		"func exceptionFromError(",
+	)
+	if strings.Contains(content, `"POST /users/:id"`) {
+		t.Error("the exceptions are keyed by an undeclared route")
+	}
+
+	if content := checkServer(t, helloIDL, &args.Arguments{}); strings.Contains(content, "routeExceptions") {
+		t.Error("a server without exceptions declares routeExceptions")
+	}
+}
+
+func TestStatusFieldRoutes(t *testing.T) {
+	idl := writeMain(t, statusFieldIDL)
+	content := checkServer(t, idl, &args.Arguments{},
+		`"GET /users/:id":  "code",`,
+		`"GET /orders/:id": "code",`,
+		"statusFromBody(field, realResp.Body)",
+	)
+	if strings.Contains(content, "delete(realResp.Body, field)") {
+		t.Error("the status field is stripped without StripStatusField")
+	}
+	checkServer(t, idl, &args.Arguments{StripStatusField: true}, "delete(realResp.Body, field)")
+}
+
+func TestDownloadRoutes(t *testing.T) {
+	checkServer(t, writeMain(t, downloadIDL), &args.Arguments{},
+		`"GET /download": "data",`,
+		"if body, ok := rawBody(ctx, realResp); ok {",
+		"ctx.Response.SetBodyRaw(body)",
+	)
+	// Responses which are not JSON are forwarded as they are even without download.
+	content := checkServer(t, helloIDL, &args.Arguments{}, "if isJSON(responseContentType(realResp)) {")
+	if strings.Contains(content, "routeRawBodies") {
+		t.Error("a server without downloads declares routeRawBodies")
+	}
+}
+
+func TestStreamThreshold(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{},
+		"const streamThreshold = 4194304",
+		"server.WithStreamBody(true)",
+		"newProxyRequest(ctx, url)",
+		"io.Copy(f, ctx.RequestBodyStream())",
+	)
+	checkServer(t, helloIDL, &args.Arguments{StreamThreshold: 1024}, "const streamThreshold = 1024")
+
+	if _, err := renderServer(t, helloIDL, &args.Arguments{StreamThreshold: -1}); err == nil || !strings.Contains(err.Error(), "StreamThreshold must be positive") {
+		t.Errorf("got error %v, want a StreamThreshold error", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{Metrics: true},
+		`"github.com/prometheus/client_golang/prometheus/promhttp"`,
+		`setupMetrics(h)`,
+		`h.GET("/metrics", adaptor.HertzHandler(promhttp.Handler()))`,
+		`"swagger_proxy_requests_total"`,
+		`"swagger_proxy_request_duration_seconds"`,
+		`"swagger_proxy_upstream_errors_total"`,
+		// the labels come from the routes, not from the requested URL
+		`"GET /hello1":     {service: "HelloService1", method: "QueryMethod"},`,
+		`"GET /path:path1": {service: "HelloService1", method: "PathMethod"},`,
+		`routeMethods[string(ctx.Method())+" "+ctx.FullPath()]`,
+		`requestsTotal.WithLabelValues(m.service, m.method, strconv.Itoa(ctx.Response.StatusCode())).Inc()`,
+		`metrics := metricsMiddleware()`,
+	)
+
+	content := checkServer(t, helloIDL, &args.Arguments{})
+	for _, snippet := range []string{"prometheus", "/metrics", "routeMethods"} {
+		if strings.Contains(content, snippet) {
+			t.Errorf("got %q without Metrics", snippet)
+		}
+	}
+}
+
+func TestMetricsPort(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{Metrics: true, MetricsPort: 9100},
+		`mux.Handle("/metrics", promhttp.Handler())`,
+		`http.ListenAndServe(":9100", mux)`,
+		`setupMetrics(h)`,
+	)
+	if strings.Contains(content, "adaptor.HertzHandler") {
+		t.Error("got /metrics on the port of the API with MetricsPort")
+	}
+
+	tests := []struct {
+		arguments *args.Arguments
+		want      string
+	}{
+		{&args.Arguments{MetricsPort: 9100}, "MetricsPort requires Metrics"},
+		{&args.Arguments{Metrics: true, MetricsPort: 70000}, "MetricsPort must be a port number, got 70000"},
+	}
+	for _, tt := range tests {
+		if _, err := renderServer(t, helloIDL, tt.arguments); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("got error %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{Tracing: "otel"},
+		`hertztracing "github.com/hertz-contrib/obs-opentelemetry/tracing"`,
+		`kitextracing "github.com/kitex-contrib/obs-opentelemetry/tracing"`,
+		`tracer, tracerConfig := hertztracing.NewServerTracer()`,
+		`server.WithStreamBody(true), tracer)`,
+		// the incoming traceparent is extracted into the context of the generic call
+		`h.Use(hertztracing.ServerMiddleware(tracerConfig))`,
+		`client.WithSuite(kitextracing.NewClientSuite())`,
+		`os.Getenv("OTEL_SERVICE_NAME")`,
+		`defer p.Shutdown(context.Background())`,
+	)
+	checkServer(t, helloIDL, &args.Arguments{Tracing: "otel", ServerCert: "server.crt", ServerKey: "server.key"},
+		"\t\ttracer,\n\t)",
+	)
+
+	content := checkServer(t, helloIDL, &args.Arguments{})
+	if strings.Contains(content, "opentelemetry") || strings.Contains(content, "tracer") {
+		t.Error("got tracing without Tracing")
+	}
+
+	if _, err := renderServer(t, helloIDL, &args.Arguments{Tracing: "zipkin"}); err == nil || !strings.Contains(err.Error(), "unsupported Tracing 'zipkin'") {
+		t.Errorf("got error %v, want zipkin to be unsupported", err)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{RequestID: true},
+		`h.Use(requestid.New(), forwardRequestID)`,
+		`ctx.Next(metainfo.WithPersistentValue(c, requestIDKey, requestid.Get(ctx)))`,
+		// the persistent metainfo is carried by the TTHeader of the calls
+		`client.WithTransportProtocol(transport.TTHeader),`,
+		`client.WithMetaHandler(transmeta.ClientTTHeaderHandler)`,
+	)
+
+	content := checkServer(t, helloIDL, &args.Arguments{})
+	if strings.Contains(content, "requestid") || strings.Contains(content, "TTHeader") {
+		t.Error("got the requestid middleware without RequestID")
+	}
+}
+
+func TestConfirmMutations(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{ConfirmMutations: true},
+		`var mutatingVerbs = []string{"post", "put", "patch", "delete"}`,
+		// the verbs come from the embedded spec
+		`routes, err := mutatingRoutes(openapiYAML)`,
+		`string(ctx.Request.Header.Peek("X-Confirm")) != "yes"`,
+		`ctx.AbortWithStatusJSON(http.StatusPreconditionRequired`,
+		`confirm := confirmMiddleware()`,
+		`confirm, proxy`,
+	)
+
+	content := checkServer(t, helloIDL, &args.Arguments{})
+	if strings.Contains(content, "X-Confirm") {
+		t.Error("got X-Confirm without ConfirmMutations")
+	}
+}
+
+func TestHealthRoutes(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{},
+		`setupHealthRoutes(h, cli)`,
+		`h.GET("/healthz", `,
+		`h.GET("/readyz", `,
+		`"error":  "generic client not created",`,
+	)
+	if strings.Contains(content, "net.DialTimeout") {
+		t.Error("got a readiness probe without ReadinessProbe")
+	}
+
+	content = checkServer(t, helloIDL, &args.Arguments{KitexAddr: "10.0.0.1:9000", ReadinessProbe: true},
+		`const readinessTimeout = time.Second`,
+		`conn, err := net.DialTimeout("tcp", "10.0.0.1:9000", readinessTimeout)`,
+	)
+	// the probe only dials, no business method is called
+	if strings.Count(content, "GenericCall(") != strings.Count(checkServer(t, helloIDL, &args.Arguments{}), "GenericCall(") {
+		t.Error("got a generic call for the readiness probe")
+	}
+
+	_, err := renderServer(t, helloIDL, &args.Arguments{ReadinessProbe: true, Resolver: "etcd://127.0.0.1:2379", ServiceName: "user"})
+	if err == nil || !strings.Contains(err.Error(), "can not be used with a resolver") {
+		t.Errorf("got error %v, want ReadinessProbe to be rejected with a resolver", err)
+	}
+}
+
+func TestDebugLogging(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{},
+		`const debugBodyLimit = 1024`,
+		`var debugEnabled = debugFromEnv(false)`,
+		`os.LookupEnv("PROXY_DEBUG")`,
+		`ctx.Header("X-Request-Id", requestID)`,
+		`"authorization":       true,`,
+		`value = []byte("<redacted>")`,
+		`debug := debugMiddleware()`,
+	)
+	for _, stage := range []string{"inbound", "generic_request", "upstream_response", "upstream_error"} {
+		if !strings.Contains(content, `"stage=`+stage+` `) {
+			t.Errorf("got no %s stage in the debug logs", stage)
+		}
+	}
+
+	checkServer(t, helloIDL, &args.Arguments{Debug: true, DebugBodyLimit: 64},
+		`const debugBodyLimit = 64`,
+		`var debugEnabled = debugFromEnv(true)`,
+	)
+
+	// the header of the API key is redacted along with the credentials
+	content = checkServer(t, helloIDL, &args.Arguments{Auth: "apikey:X-Token"}, `"x-token":`, `"x-api-key":`)
+	if strings.Count(content, `"x-token":`) != 1 {
+		t.Error("got the header of the API key redacted more than once")
+	}
+	content = checkServer(t, helloIDL, &args.Arguments{Auth: "apikey:X-Api-Key"}, `"x-api-key":`)
+	if strings.Count(content, `"x-api-key":`) != 1 {
+		t.Error("got x-api-key redacted twice")
+	}
+
+	if _, err := renderServer(t, helloIDL, &args.Arguments{DebugBodyLimit: -1}); err == nil {
+		t.Error("rendered with a negative DebugBodyLimit")
+	}
+}
+
+func TestMinifiedSpecRoute(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{Minify: true},
+		"//go:embed openapi.min.json",
+		`minified := newSpec(openapiMinJSON, "application/json")`,
+		`h.GET("/openapi.min.json", `,
+	)
+	if content := checkServer(t, helloIDL, &args.Arguments{}); strings.Contains(content, "openapi.min.json") {
+		t.Error("got the minified document without Minify")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{RateLimit: 100},
+		"rateLimit = 100\n\trateBurst = 100",
+		`ratelimit := rateLimitMiddleware()`,
+		`h.Any("/*ServiceMethod", debug, ratelimit, proxy)`,
+		`ctx.Header("Retry-After", `,
+		`ctx.AbortWithStatusJSON(http.StatusTooManyRequests, `,
+		// the catch-all route shares the limit of the unknown method
+		`return thriftMethod{service: "unknown", method: "unknown"}`,
+	)
+	// the spec and UI routes are not limited
+	for _, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, "ratelimit") && (strings.Contains(line, "openapi") || strings.Contains(line, "/swagger")) {
+			t.Errorf("got a limited spec route: %s", line)
+		}
+	}
+
+	checkServer(t, helloIDL, &args.Arguments{RateLimit: 10, RateBurst: 50}, "rateLimit = 10\n\trateBurst = 50")
+
+	content = checkServer(t, helloIDL, &args.Arguments{})
+	if strings.Contains(content, "ratelimit") {
+		t.Error("got rate limiting without RateLimit")
+	}
+	if _, err := renderServer(t, helloIDL, &args.Arguments{RateLimit: -1}); err == nil {
+		t.Error("rendered with a negative RateLimit")
+	}
+}
+
+func TestExcludedServiceRoutes(t *testing.T) {
+	idl := writeMain(t, servicesIDL)
+	content := checkServer(t, idl, &args.Arguments{ExcludeServices: []string{"InternalService"}},
+		"setupDisabledRoutes(h)",
+		`h.Handle("POST", "/internal/flush", disabled)`,
+	)
+	if strings.Contains(content, `"/orders", disabled`) || strings.Contains(content, `"/users", disabled`) {
+		t.Error("got the routes of the selected services disabled")
+	}
+}
+
+// headerFilterProgram runs the header filters of a generated server on sample headers.
+const headerFilterProgram = `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+%s
+
+func main() {
+	connection := connectionHeaders("keep-alive, X-Hop")
+	for _, key := range []string{"Content-Type", "Content-Length", "Connection", "Transfer-Encoding", "Host", "X-Hop", "X-Internal-Token", "X-Trace"} {
+		fmt.Printf("%%s %%v %%v\n", key, forwardRequestHeader(key, connection), forwardResponseHeader(key, connection))
+	}
+}
+`
+
+func TestInvokeAPIRoutes(t *testing.T) {
+	checkServer(t, helloIDL, &args.Arguments{InvokeAPI: true},
+		"setupInvokeAPI(h, proxy)",
+		`h.GET("/_api/operations", append(handlers, func(c context.Context, ctx *app.RequestContext) {`,
+		`h.POST("/_api/invoke/:operationId", append(handlers, func(c context.Context, ctx *app.RequestContext) {`,
+		"if err := placeInvocation(ctx, op, call); err != nil {",
+	)
+
+	content := checkServer(t, helloIDL, &args.Arguments{})
+	for _, snippet := range []string{"/_api/", "placeInvocation", `"net/url"`} {
+		if strings.Contains(content, snippet) {
+			t.Errorf("got %q without InvokeAPI", snippet)
+		}
+	}
+}
+
+func TestHeaderFilter(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{StripHeaders: []string{"X-Internal-Token"}, ForwardHeaders: []string{"Content-Type", "Content-Length", "X-Hop", "X-Internal-Token"}},
+		`"x-internal-token": true,`,
+		// the body decoded by the generic client is encoded again, its length is the
+		// one of the new body
+		`if err := writeJSON(w, realResp.Body); err != nil {`,
+	)
+
+	output := runDecls(t, content, headerFilterProgram, "hopHeaders", "strippedHeaders", "forwardedHeaders", "connectionHeaders", "forwardResponseHeader", "forwardRequestHeader")
+	want := `Content-Type true true
+Content-Length false false
+Connection false false
+Transfer-Encoding false false
+Host false false
+X-Hop false false
+X-Internal-Token false false
+X-Trace false true
+`
+	if output != want {
+		t.Errorf("got forwarded headers:\n%swant:\n%s", output, want)
+	}
+}
+
+// runDecls runs the program with the named declarations of the generated server,
+// which only use the standard library, and returns its output.
+func runDecls(t *testing.T, content, program string, names ...string) string {
+	t.Helper()
+	file, err := goparser.ParseFile(token.NewFileSet(), "swagger.go", content, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var decls []string
+	for _, decl := range file.Decls {
+		if declares(decl, wanted) {
+			decls = append(decls, content[decl.Pos()-file.Pos():decl.End()-file.Pos()])
+		}
+	}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(source, []byte(fmt.Sprintf(program, strings.Join(decls, "\n\n"))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "run", source)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("run %s: %s\n%s", strings.Join(names, ", "), err, output)
+	}
+	return string(output)
+}
+
+// declares reports whether the declaration declares one of the names, the methods
+// belong to their receiver type.
+func declares(decl ast.Decl, names map[string]bool) bool {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil {
+			return names[d.Name.Name]
+		}
+		receiver := d.Recv.List[0].Type
+		if star, ok := receiver.(*ast.StarExpr); ok {
+			receiver = star.X
+		}
+		ident, ok := receiver.(*ast.Ident)
+		return ok && names[ident.Name]
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if names[s.Name.Name] {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					if names[name.Name] {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// responseProgram encodes a small body, compared with json.Marshal, then a large one
+// past the limit of a generated server, reporting the bytes allocated meanwhile.
+const responseProgram = `package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sort"
+	"strconv"
+)
+
+%s
+
+func main() {
+	small := map[string]interface{}{
+		"b": []interface{}{1, "<x>", nil, []interface{}(nil)},
+		"a": map[string]interface{}{"n": 1.5, "m": map[string]interface{}(nil)},
+	}
+	var buf bytes.Buffer
+	err := writeJSON(&buf, small)
+	want, _ := json.Marshal(small)
+	fmt.Println(buf.String() == string(want), err)
+
+	rows := make([]interface{}, 1<<18)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "row " + strconv.Itoa(i), "tags": []interface{}{"a", "b"}}
+	}
+	large := map[string]interface{}{"rows": rows}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	err = writeJSON(&limitedWriter{w: ioutil.Discard, n: maxResponseBytes}, large)
+	runtime.ReadMemStats(&after)
+	full, _ := json.Marshal(large)
+	fmt.Println(err == errResponseTooLarge, len(full) > 128*maxResponseBytes, after.TotalAlloc-before.TotalAlloc < 16*maxResponseBytes)
+}
+`
+
+func TestMaxResponseBytes(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{MaxResponseBytes: 1 << 16},
+		"const maxResponseBytes = 65536",
+		"w = &limitedWriter{w: w, n: maxResponseBytes}",
+		"if responseTooLarge(ctx, len(body)) {",
+		"ctx.Response.SetBodyRaw(body)",
+	)
+	// The JSON of a body larger than the limit is not encoded in full, the memory
+	// spent is about the one of the limit.
+	output := runDecls(t, content, responseProgram, "writeJSON", "limitedWriter", "errResponseTooLarge", "maxResponseBytes")
+	if want := "true <nil>\ntrue true true\n"; output != want {
+		t.Errorf("got %q, want %q", output, want)
+	}
+
+	content = checkServer(t, helloIDL, &args.Arguments{})
+	for _, snippet := range []string{"maxResponseBytes", "limitedWriter"} {
+		if strings.Contains(content, snippet) {
+			t.Errorf("got %q without MaxResponseBytes", snippet)
+		}
+	}
+}
+
+// stubImporter imports the standard library from source, the other packages are
+// empty: the generated server is type-checked without its dependencies.
+type stubImporter struct {
+	std   types.Importer
+	stubs map[string]*types.Package
+}
+
+func (i *stubImporter) Import(path string) (*types.Package, error) {
+	if !strings.Contains(strings.Split(path, "/")[0], ".") {
+		return i.std.Import(path)
+	}
+	if pkg, ok := i.stubs[path]; ok {
+		return pkg, nil
+	}
+	pkg := types.NewPackage(path, stubName(path))
+	pkg.MarkComplete()
+	i.stubs[path] = pkg
+	return pkg, nil
+}
+
+// stubName returns the name of the package imported from path, for example yaml for
+// gopkg.in/yaml.v3 and etcd for github.com/kitex-contrib/registry-etcd.
+func stubName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && strings.Trim(name, "v0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(strings.Split(name, ".")[0], "go-")
+	return strings.TrimPrefix(strings.ReplaceAll(name, "-", ""), "registry")
+}
+
+// typeCheckServer type-checks the generated server with the importer, the
+// identifiers of the empty packages are undefined and left unchecked.
+func typeCheckServer(t *testing.T, stubs *stubImporter, fset *token.FileSet, content string) {
+	t.Helper()
+	file, err := goparser.ParseFile(fset, "swagger.go", content, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stubbed := map[string]bool{}
+	for _, spec := range file.Imports {
+		path := strings.Trim(spec.Path.Value, `"`)
+		if !strings.Contains(strings.Split(path, "/")[0], ".") {
+			continue
+		}
+		if spec.Name != nil {
+			stubbed[spec.Name.Name] = true
+		} else {
+			stubbed[stubName(path)] = true
+		}
+	}
+	conf := types.Config{Importer: stubs, Error: func(err error) {
+		msg := err.(types.Error).Msg
+		if strings.HasPrefix(msg, "undefined: ") && stubbed[strings.Split(strings.TrimPrefix(msg, "undefined: "), ".")[0]] {
+			return
+		}
+		t.Errorf("generated server does not compile: %s", err)
+	}}
+	conf.Check("main", fset, []*ast.File{file}, nil)
+}
+
+func TestServerTypeChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments *args.Arguments
+	}{
+		{"default", &args.Arguments{}},
+		{"AuthProxy", &args.Arguments{Auth: "apikey:X-Token", AuthProxy: true}},
+		{"StripStatusField", &args.Arguments{StripStatusField: true}},
+		{"Debug", &args.Arguments{Debug: true}},
+		{"Metrics", &args.Arguments{Metrics: true}},
+		{"RequestID", &args.Arguments{RequestID: true}},
+		{"ConfirmMutations", &args.Arguments{ConfirmMutations: true}},
+		{"ValidateRequests", &args.Arguments{ValidateRequests: true}},
+		{"ReadinessProbe", &args.Arguments{ReadinessProbe: true}},
+		{"InvokeAPI", &args.Arguments{InvokeAPI: true}},
+		{"Minify", &args.Arguments{Minify: true}},
+		{"all", &args.Arguments{
+			Auth: "apikey:X-Token", AuthProxy: true, StripStatusField: true, Debug: true, Metrics: true, RequestID: true,
+			ConfirmMutations: true, ValidateRequests: true, ReadinessProbe: true, InvokeAPI: true, Minify: true,
+		}},
+	}
+	// The packages of the standard library are loaded once for every server.
+	fset := token.NewFileSet()
+	stubs := &stubImporter{std: importer.ForCompiler(fset, "source", nil), stubs: map[string]*types.Package{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typeCheckServer(t, stubs, fset, checkServer(t, helloIDL, tt.arguments))
+		})
+	}
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// SpecPart is one of the documents generated when the operations exceed
+// MaxOperationsPerDoc, each part holds the operations of a subset of the tags.
+type SpecPart struct {
+	// File is the name of the part in OutputDir, the server serves it at /File.
+	File string
+	// Name is the name of the part displayed by the UI.
+	Name string
+}
+
+// splitDocument partitions the operations of d into documents of at most max
+// operations. Path items are grouped by the tag of their first operation, a tag is
+// moved to the next part as a whole unless it exceeds max by itself, in which case
+// its path items are spread over several parts. A path item is never split, so a
+// part exceeds max only when a single path item does. Each part is self-contained
+// and duplicates the components it references.
+func splitDocument(d *openapi.Document, max int) ([]*openapi.Document, []SpecPart) {
+	groups := make(map[string][]*openapi.NamedPathItem)
+	var tags []string
+	for _, item := range d.Paths.Path {
+		tag := ""
+		if ops := operationsOf(item.Value); len(ops) > 0 && len(ops[0].Tags) > 0 {
+			tag = ops[0].Tags[0]
+		}
+		if _, ok := groups[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		groups[tag] = append(groups[tag], item)
+	}
+	sort.Strings(tags)
+
+	var chunks [][]*openapi.NamedPathItem
+	var chunk []*openapi.NamedPathItem
+	count := 0
+	flush := func() {
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+		chunk, count = nil, 0
+	}
+	for _, tag := range tags {
+		items := groups[tag]
+		total := 0
+		for _, item := range items {
+			total += len(operationsOf(item.Value))
+		}
+		if count > 0 && count+total > max {
+			flush()
+		}
+		for _, item := range items {
+			n := len(operationsOf(item.Value))
+			if count > 0 && count+n > max {
+				flush()
+			}
+			chunk = append(chunk, item)
+			count += n
+		}
+	}
+	flush()
+
+	docs := make([]*openapi.Document, 0, len(chunks))
+	parts := make([]SpecPart, 0, len(chunks))
+	for i, items := range chunks {
+		part := newDocumentPart(d, items)
+		if d.Info != nil {
+			info := *d.Info
+			info.Title = fmt.Sprintf("%s (%d/%d)", info.Title, i+1, len(chunks))
+			part.Info = &info
+		}
+		var names []string
+		for _, tag := range part.Tags {
+			names = append(names, tag.Name)
+		}
+		docs = append(docs, part)
+		parts = append(parts, SpecPart{
+			File: fmt.Sprintf("openapi.part%d.yaml", i+1),
+			Name: fmt.Sprintf("%d: %s", i+1, strings.Join(names, ", ")),
+		})
+	}
+	return docs, parts
+}
+
+// newDocumentPart returns a copy of d holding only the given path items, along with
+// their tags, request bodies and responses, and the schemas they reference directly
+// or through other schemas.
+func newDocumentPart(d *openapi.Document, items []*openapi.NamedPathItem) *openapi.Document {
+	part := *d
+	part.Paths = &openapi.Paths{
+		Path:                   items,
+		SpecificationExtension: d.Paths.SpecificationExtension,
+	}
+
+	used := make(map[string]bool)
+	for _, item := range items {
+		for _, op := range operationsOf(item.Value) {
+			for _, tag := range op.Tags {
+				used[tag] = true
+			}
+		}
+	}
+	part.Tags = nil
+	for _, tag := range d.Tags {
+		if used[tag.Name] {
+			part.Tags = append(part.Tags, tag)
+		}
+	}
+
+	if d.Components == nil {
+		return &part
+	}
+	components := *d.Components
+	components.Schemas = nil
+	part.Components = &components
+
+	// Keep the request bodies and the responses of the operations of the part.
+	components.RequestBodies, components.Responses = nil, nil
+	bodyRefs, responseRefs := make(map[string]bool), make(map[string]bool)
+	collectRefs(reflect.ValueOf(part.Paths), requestBodyRefPrefix, bodyRefs)
+	collectRefs(reflect.ValueOf(part.Paths), responseRefPrefix, responseRefs)
+	if bodies := d.Components.RequestBodies; bodies != nil {
+		components.RequestBodies = &openapi.RequestBodiesOrReferences{}
+		for _, body := range bodies.AdditionalProperties {
+			if bodyRefs[body.Name] {
+				components.RequestBodies.AdditionalProperties = append(components.RequestBodies.AdditionalProperties, body)
+			}
+		}
+	}
+	if responses := d.Components.Responses; responses != nil {
+		components.Responses = &openapi.ResponsesOrReferences{}
+		for _, response := range responses.AdditionalProperties {
+			if responseRefs[response.Name] {
+				components.Responses.AdditionalProperties = append(components.Responses.AdditionalProperties, response)
+			}
+		}
+	}
+	if d.Components.Schemas == nil {
+		return &part
+	}
+
+	refs := reachableSchemas(&part, d.Components.Schemas.AdditionalProperties)
+	components.Schemas = &openapi.SchemasOrReferences{}
+	for _, schema := range d.Components.Schemas.AdditionalProperties {
+		if refs[schema.Name] {
+			components.Schemas.AdditionalProperties = append(components.Schemas.AdditionalProperties, schema)
+		}
+	}
+	return &part
+}
+
+// reachableSchemas returns the names of the schemas referenced by root, which must
+// not hold the schemas, directly or through other schemas.
+func reachableSchemas(root interface{}, schemas []*openapi.NamedSchemaOrReference) map[string]bool {
+	// Collect the references of everything but the schemas, then follow the
+	// references of the schemas until no new schema is found.
+	refs := make(map[string]bool)
+	collectSchemaRefs(reflect.ValueOf(root), refs)
+	byName := make(map[string]*openapi.NamedSchemaOrReference)
+	for _, schema := range schemas {
+		byName[schema.Name] = schema
+	}
+	for done := false; !done; {
+		done = true
+		for name := range refs {
+			if schema, ok := byName[name]; ok {
+				delete(byName, name)
+				collectSchemaRefs(reflect.ValueOf(schema), refs)
+				done = false
+			}
+		}
+	}
+	return refs
+}
+
+var referenceType = reflect.TypeOf(openapi.Reference{})
+
+// collectSchemaRefs adds the names of the schemas referenced anywhere in v to refs.
+func collectSchemaRefs(v reflect.Value, refs map[string]bool) {
+	collectRefs(v, schemaRefPrefix, refs)
+}
+
+// collectRefs adds the names of the components referenced with prefix anywhere in v
+// to refs.
+func collectRefs(v reflect.Value, prefix string, refs map[string]bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectRefs(v.Elem(), prefix, refs)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			collectRefs(v.Index(i), prefix, refs)
+		}
+	case reflect.Struct:
+		if v.Type() == referenceType {
+			if xref := v.FieldByName("Xref").String(); strings.HasPrefix(xref, prefix) {
+				refs[strings.TrimPrefix(xref, prefix)] = true
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			collectRefs(v.Field(i), prefix, refs)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+// splitIDL declares the services Alpha, Beta and Gamma with 2, 4 and 1 functions
+// whose request bodies all reference Item.
+func splitIDL() string {
+	var b strings.Builder
+	b.WriteString(`
+struct Item {
+    1: string name
+}
+
+struct Req {
+    1: Item item (api.body = "item")
+    2: Item other (api.body = "other")
+}
+`)
+	for _, s := range []struct {
+		name  string
+		count int
+	}{{"Alpha", 2}, {"Beta", 4}, {"Gamma", 1}} {
+		fmt.Fprintf(&b, "\nservice %s {\n", s.name)
+		for i := 0; i < s.count; i++ {
+			fmt.Fprintf(&b, "    Req Call%d(1: Req req) (api.post = \"/%s/%d\")\n", i, strings.ToLower(s.name), i)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// countYAMLOperations counts the operations of the YAML document.
+func countYAMLOperations(t *testing.T, content string) int {
+	t.Helper()
+	paths, _ := lookup(t, content, "paths").(map[string]interface{})
+	count := 0
+	for _, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			if method != "parameters" {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestSplitDocument(t *testing.T) {
+	idl := writeMain(t, splitIDL())
+	arguments := &args.Arguments{OutputDir: "out", MaxOperationsPerDoc: 3}
+	g, generated := generateFiles(t, idl, arguments)
+
+	var parts []string
+	for _, part := range g.SpecParts() {
+		parts = append(parts, part.File+" "+part.Name)
+	}
+	want := []string{"openapi.part1.yaml 1: Alpha", "openapi.part2.yaml 2: Beta", "openapi.part3.yaml 3: Beta, Gamma"}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("got parts %q, want %q", parts, want)
+	}
+
+	total := countYAMLOperations(t, generatedFile(t, generated, "openapi.yaml"))
+	sum := 0
+	for _, part := range g.SpecParts() {
+		content := generatedFile(t, generated, part.File)
+		sum += countYAMLOperations(t, content)
+		// Each part duplicates the schemas it references.
+		if lookup(t, content, "components", "schemas", "Item") == nil {
+			t.Errorf("%s misses the Item schema", part.File)
+		}
+	}
+	if total != 7 || sum != total {
+		t.Errorf("got %d operations across the parts, want the %d of the document", sum, total)
+	}
+
+	_, again := generateFiles(t, idl, arguments)
+	for _, part := range g.SpecParts() {
+		if generatedFile(t, generated, part.File) != generatedFile(t, again, part.File) {
+			t.Errorf("%s is not deterministic", part.File)
+		}
+	}
+}
+
+func TestSplitDocumentBelowMax(t *testing.T) {
+	g, generated := generateFiles(t, writeMain(t, splitIDL()), &args.Arguments{MaxOperationsPerDoc: 7})
+	if parts := g.SpecParts(); len(parts) != 0 {
+		t.Errorf("got parts %v below MaxOperationsPerDoc", parts)
+	}
+	for _, file := range generated {
+		if strings.HasPrefix(filepath.Base(file.GetName()), "openapi.part") {
+			t.Errorf("%s is generated below MaxOperationsPerDoc", file.GetName())
+		}
+	}
+}
+
+func TestSplitDocumentUI(t *testing.T) {
+	parts := []SpecPart{{File: "openapi.part1.yaml", Name: "1: Alpha"}, {File: "openapi.part2.yaml", Name: "2: Beta"}}
+	assets, err := uiAssets(uiSwaggo, "", "out", "/openapi.yaml", parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || !strings.Contains(assets[0].Content, `urls: [{"url":"/openapi.part1.yaml","name":"1: Alpha"},{"url":"/openapi.part2.yaml","name":"2: Beta"}]`) {
+		t.Errorf("the UI does not list the parts: %v", assets)
+	}
+
+	sg, err := NewServerGenerator(parseIDL(t, helloIDL), &args.Arguments{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.SetSpecParts(parts); err != nil {
+		t.Fatal(err)
+	}
+	content := sg.Generate()[0].Content
+	for _, snippet := range []string{"//go:embed openapi.part1.yaml openapi.part2.yaml", `h.GET("/"+entry.Name()`, `uiFiles.ReadFile("ui/index.html")`} {
+		if !strings.Contains(content, snippet) {
+			t.Errorf("the server does not serve the parts, missing %q", snippet)
+		}
+	}
+}
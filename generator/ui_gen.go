@@ -0,0 +1,171 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/cloudwego/thriftgo/plugin"
+)
+
+const (
+	uiSwaggo   = "swaggo"
+	uiEmbedded = "embedded"
+	uiRedoc    = "redoc"
+)
+
+// uiDistFiles lists the files copied from UIDist for the self-contained UIs.
+var uiDistFiles = map[string][]string{
+	uiEmbedded: {"swagger-ui-bundle.js", "swagger-ui.css"},
+	uiRedoc:    {"redoc.standalone.js"},
+}
+
+// uiIndexPages holds the index page of each self-contained UI, formatted with the
+// URL of the spec.
+var uiIndexPages = map[string]string{
+	uiEmbedded: `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Swagger UI</title>
+  <link rel="stylesheet" href="swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="swagger-ui-bundle.js"></script>
+<script>
+  window.onload = function () {
+    window.ui = SwaggerUIBundle({url: %q, dom_id: "#swagger-ui"});
+  };
+</script>
+</body>
+</html>
+`,
+	uiRedoc: `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>API Reference</title>
+</head>
+<body>
+<redoc spec-url=%q></redoc>
+<script src="redoc.standalone.js"></script>
+</body>
+</html>
+`,
+}
+
+// uiPartsIndexPage is the index page of swagger-ui listing the parts of a split
+// document in the top bar, formatted with the JSON list of the parts.
+const uiPartsIndexPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Swagger UI</title>
+  <link rel="stylesheet" href="swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="swagger-ui-bundle.js"></script>
+<script src="swagger-ui-standalone-preset.js"></script>
+<script>
+  window.onload = function () {
+    window.ui = SwaggerUIBundle({
+      urls: %s,
+      dom_id: "#swagger-ui",
+      presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+      layout: "StandaloneLayout"
+    });
+  };
+</script>
+</body>
+</html>
+`
+
+// uiAssets returns the files of the self-contained UI, written to the ui directory
+// next to swagger.go and embedded into the server. The UI bundle is copied from
+// the dist directory so that neither generation nor the server needs network access.
+// The index page loads the spec from specURL, or lets the user pick one of the parts
+// when the document was split. The swaggo UI only gets an index page then, its
+// bundle is still served by hertz-contrib/swagger.
+func uiAssets(ui, dist, outputDir, specURL string, parts []SpecPart) ([]*plugin.Generated, error) {
+	switch ui {
+	case "", uiSwaggo:
+		if dist != "" {
+			return nil, fmt.Errorf("UIDist can only be used with UI=%s or UI=%s", uiEmbedded, uiRedoc)
+		}
+		if len(parts) == 0 {
+			return nil, nil
+		}
+	case uiEmbedded, uiRedoc:
+		if dist == "" {
+			return nil, fmt.Errorf("UI=%s requires UIDist, the directory holding %v", ui, uiDistFiles[ui])
+		}
+		if ui == uiRedoc && len(parts) > 0 {
+			return nil, fmt.Errorf("UI=%s can not switch between the parts of a document, raise MaxOperationsPerDoc or use UI=%s", uiRedoc, uiEmbedded)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported UI '%s', use '%s', '%s' or '%s'", ui, uiSwaggo, uiEmbedded, uiRedoc)
+	}
+
+	index := ""
+	distFiles := uiDistFiles[ui]
+	if len(parts) == 0 {
+		index = fmt.Sprintf(uiIndexPages[ui], specURL)
+	} else {
+		type partURL struct {
+			URL  string `json:"url"`
+			Name string `json:"name"`
+		}
+		var urls []partURL
+		for _, part := range parts {
+			urls = append(urls, partURL{URL: "/" + part.File, Name: part.Name})
+		}
+		content, err := json.Marshal(urls)
+		if err != nil {
+			return nil, err
+		}
+		index = fmt.Sprintf(uiPartsIndexPage, content)
+		if ui == uiEmbedded {
+			distFiles = append(append([]string{}, distFiles...), "swagger-ui-standalone-preset.js")
+		} else {
+			distFiles = nil
+		}
+	}
+
+	uiDir := filepath.Join(filepath.Clean(outputDir), "ui")
+	indexPath := filepath.Join(uiDir, "index.html")
+	assets := []*plugin.Generated{{
+		Content: index,
+		Name:    &indexPath,
+	}}
+	for _, name := range distFiles {
+		content, err := ioutil.ReadFile(filepath.Join(dist, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read UI asset: %s", err)
+		}
+		assetPath := filepath.Join(uiDir, name)
+		assets = append(assets, &plugin.Generated{
+			Content: string(content),
+			Name:    &assetPath,
+		})
+	}
+	return assets, nil
+}
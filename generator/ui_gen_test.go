@@ -0,0 +1,110 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+// writeDist writes a dist directory holding the bundle files of every UI.
+func writeDist(t *testing.T) string {
+	t.Helper()
+	dist := t.TempDir()
+	for _, files := range uiDistFiles {
+		for _, name := range files {
+			if err := ioutil.WriteFile(filepath.Join(dist, name), []byte("/* "+name+" */"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dist
+}
+
+func TestUIAssets(t *testing.T) {
+	dist := writeDist(t)
+	for _, test := range []struct {
+		ui    string
+		files []string
+		index string
+	}{
+		{uiEmbedded, []string{"index.html", "swagger-ui-bundle.js", "swagger-ui.css"}, `SwaggerUIBundle({url: "/openapi.yaml"`},
+		{uiRedoc, []string{"index.html", "redoc.standalone.js"}, `<redoc spec-url="/openapi.yaml">`},
+	} {
+		assets, err := uiAssets(test.ui, dist, "out", "/openapi.yaml", nil)
+		if err != nil {
+			t.Fatalf("%s: %s", test.ui, err)
+		}
+		var files []string
+		for _, asset := range assets {
+			if dir := filepath.Dir(asset.GetName()); dir != filepath.Join("out", "ui") {
+				t.Errorf("%s: %s is written to %s", test.ui, asset.GetName(), dir)
+			}
+			name := filepath.Base(asset.GetName())
+			files = append(files, name)
+			if name != "index.html" && asset.Content != "/* "+name+" */" {
+				t.Errorf("%s: %s is not copied from the dist directory", test.ui, name)
+			}
+		}
+		if !reflect.DeepEqual(files, test.files) {
+			t.Errorf("%s: got files %v, want %v", test.ui, files, test.files)
+		}
+		if !strings.Contains(assets[0].Content, test.index) {
+			t.Errorf("%s: the index page does not load the spec:\n%s", test.ui, assets[0].Content)
+		}
+	}
+
+	assets, err := uiAssets(uiSwaggo, "", "out", "/openapi.yaml", nil)
+	if err != nil || len(assets) != 0 {
+		t.Errorf("swaggo: got assets %v and error %v, want none", assets, err)
+	}
+}
+
+func TestUIAssetsErrors(t *testing.T) {
+	dist := writeDist(t)
+	for _, test := range []struct {
+		ui, dist, err string
+	}{
+		{uiSwaggo, dist, "UIDist can only be used with UI=embedded or UI=redoc"},
+		{uiEmbedded, "", "UI=embedded requires UIDist"},
+		{uiRedoc, t.TempDir(), "failed to read UI asset"},
+		{"rapidoc", "", "unsupported UI 'rapidoc'"},
+	} {
+		_, err := uiAssets(test.ui, test.dist, "out", "/openapi.yaml", nil)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.ui, err, test.err)
+		}
+	}
+}
+
+func TestServerUI(t *testing.T) {
+	content := checkServer(t, helloIDL, &args.Arguments{}, `swaggerFiles "github.com/swaggo/files"`)
+	if strings.Contains(content, "uiFiles") {
+		t.Error("the swaggo UI embeds the ui directory")
+	}
+
+	content = checkServer(t, helloIDL, &args.Arguments{UI: uiEmbedded, UIDist: writeDist(t)},
+		"//go:embed ui", `uiFiles.ReadFile("ui/" + name)`)
+	if strings.Contains(content, "github.com/swaggo/files") {
+		t.Error("the embedded UI depends on swaggo/files")
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/apache/thrift v0.13.0
 	github.com/cloudwego/hertz/cmd/hz v0.9.1
 	github.com/cloudwego/thriftgo v0.3.15
+	github.com/fsnotify/fsnotify v1.6.0
 	github.com/google/gnostic-models v0.6.8
 	github.com/google/go-cmp v0.5.7 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
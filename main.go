@@ -24,10 +24,11 @@ import (
 )
 
 func main() {
-	var queryVersion bool
+	var queryVersion, compare bool
 
 	f := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 	f.BoolVar(&queryVersion, "version", false, "Show the version of thrift-gen-rpc-swagger")
+	f.BoolVar(&compare, "compare", false, "Report the breaking changes between two IDLs: -compare old.thrift new.thrift")
 
 	if err := f.Parse(os.Args[1:]); err != nil {
 		println(err)
@@ -39,5 +40,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	if compare {
+		if f.NArg() != 2 {
+			println("-compare takes the old and the new IDL")
+			os.Exit(2)
+		}
+		os.Exit(plugins.Compare(f.Arg(0), f.Arg(1)))
+	}
+
 	os.Exit(plugins.Run())
 }
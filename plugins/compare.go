@@ -0,0 +1,71 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/generator"
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+// Compare prints the breaking changes between the documents of two revisions of an
+// IDL, one per line on stdout, and returns the exit code: 1 when there are some, 2
+// when an IDL cannot be documented.
+func Compare(oldIDL, newIDL string) int {
+	oldDoc, err := buildDocument(oldIDL)
+	if err != nil {
+		log.Printf("[Error]: document %s: %s", oldIDL, err.Error())
+		return 2
+	}
+	newDoc, err := buildDocument(newIDL)
+	if err != nil {
+		log.Printf("[Error]: document %s: %s", newIDL, err.Error())
+		return 2
+	}
+
+	changes := generator.CompareDocuments(oldDoc, newDoc)
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// buildDocument parses the IDL, looking up includes in its directory, and builds its
+// document with the default arguments.
+func buildDocument(idl string) (*openapi.Document, error) {
+	ast, err := parser.ParseFile(idl, []string{filepath.Dir(idl)}, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := semantic.ResolveSymbols(ast); err != nil {
+		return nil, err
+	}
+	og := generator.NewOpenAPIGenerator(ast)
+	if _, err := og.BuildDocument(&args.Arguments{}); err != nil {
+		return nil, err
+	}
+	return og.Document(), nil
+}
@@ -17,35 +17,38 @@
 package plugins
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 
+	"github.com/cloudwego/thriftgo/parser"
 	"github.com/cloudwego/thriftgo/plugin"
 	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
 	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/generator"
 )
 
+// Run handles the plugin request read from stdin and returns the exit code of the
+// plugin, 1 when generation fails, which includes warnings in strict mode.
 func Run() int {
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		println("Failed to get input:", err.Error())
-		os.Exit(1)
+		return 1
 	}
 
 	req, err := plugin.UnmarshalRequest(data)
 	if err != nil {
 		println("Failed to unmarshal request:", err.Error())
-		os.Exit(1)
+		return 1
 	}
 
 	if err := handleRequest(req); err != nil {
 		println("Failed to handle request:", err.Error())
-		os.Exit(1)
+		return 1
 	}
 
-	os.Exit(0)
 	return 0
 }
 
@@ -62,14 +65,25 @@ func handleRequest(req *plugin.Request) (err error) {
 
 	ast := req.GetAST()
 
-	og := generator.NewOpenAPIGenerator(ast)
-	openapiContent := og.BuildDocument(args)
+	contents, warnings, err := generate(ast, args)
+	if err != nil {
+		return err
+	}
 
-	sg := generator.NewServerGenerator(ast, args)
-	serverContent := sg.Generate()
+	if args.Watch {
+		for _, warning := range warnings {
+			log.Printf("[Warn]: %s", warning)
+		}
+		if err := writeFiles(contents); err != nil {
+			log.Printf("[Error]: write generated files failed: %s", err.Error())
+			return err
+		}
+		return WatchAndRegen(ast, args)
+	}
 
 	res := &plugin.Response{
-		Contents: append(openapiContent, serverContent...),
+		Contents: contents,
+		Warnings: warnings,
 	}
 	if err := handleResponse(res); err != nil {
 		return err
@@ -78,6 +92,82 @@ func handleRequest(req *plugin.Request) (err error) {
 	return err
 }
 
+// generate builds the document and the server of the IDL, along with the warnings
+// of the plugin response.
+func generate(ast *parser.Thrift, args *args.Arguments) ([]*plugin.Generated, []string, error) {
+	// The routes are built once, so that the server proxies what the document describes.
+	routes := generator.NewRouteModel(ast, args)
+	og := generator.NewOpenAPIGenerator(ast)
+	og.SetRouteModel(routes)
+	openapiContent, err := og.BuildDocument(args)
+	if err != nil {
+		log.Printf("[Error]: build openapi document failed: %s", err.Error())
+		return nil, nil, err
+	}
+
+	// The document is built even with NoOpenapi, so that the server gets the
+	// parts it serves and invalid IDLs are still reported.
+	var contents []*plugin.Generated
+	if !args.NoOpenapi {
+		contents = append(contents, openapiContent...)
+	}
+
+	if !args.NoServer && !args.Stdout {
+		sg, err := generator.NewServerGenerator(ast, args, routes)
+		if err != nil {
+			log.Printf("[Error]: create server generator failed: %s", err.Error())
+			return nil, nil, err
+		}
+		if err := sg.SetSpecParts(og.SpecParts()); err != nil {
+			log.Printf("[Error]: create server generator failed: %s", err.Error())
+			return nil, nil, err
+		}
+		contents = append(contents, sg.Generate()...)
+	}
+
+	warnings := og.Warnings()
+	if args.PublishURL != "" {
+		if err := publish(ast, args, openapiContent[0].Content); err != nil {
+			if args.PublishRequired {
+				log.Printf("[Error]: publish openapi document failed: %s", err.Error())
+				return nil, nil, err
+			}
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return contents, warnings, nil
+}
+
+// publish uploads the document to PublishURL once it is generated.
+func publish(ast *parser.Thrift, args *args.Arguments, content string) error {
+	var token string
+	if args.PublishTokenEnv != "" {
+		if token = os.Getenv(args.PublishTokenEnv); token == "" {
+			return fmt.Errorf("error publishing document: %s is not set", args.PublishTokenEnv)
+		}
+	}
+	document, err := generator.DocumentJSON([]byte(content))
+	if err != nil {
+		return err
+	}
+	revision, err := generator.IDLRevision(ast)
+	if err != nil {
+		return fmt.Errorf("error reading IDL revision: %s", err)
+	}
+	metadata := generator.SpecMetadata{
+		IDL:              ast.Filename,
+		IDLRevision:      revision,
+		GeneratorVersion: Version,
+	}
+	for _, s := range ast.Services {
+		if args.ServiceSelected(s.GetName()) {
+			metadata.Services = append(metadata.Services, s.GetName())
+		}
+	}
+	var publisher generator.SpecPublisher = generator.NewHTTPPublisher(args.PublishURL, token)
+	return publisher.Publish(context.Background(), document, metadata)
+}
+
 func handleResponse(res *plugin.Response) error {
 	data, err := plugin.MarshalResponse(res)
 	if err != nil {
@@ -0,0 +1,263 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/plugin"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/generator"
+	"gopkg.in/yaml.v3"
+)
+
+// duplicateRouteIDL declares GET /users twice, which is reported as a warning.
+const duplicateRouteIDL = `namespace go test
+
+struct Req {
+    1: string name (api.query = "name")
+}
+
+service UserService {
+    Req GetUser(1: Req req) (api.get = "/users")
+    Req ListUsers(1: Req req) (api.get = "/users")
+}
+`
+
+const helloIDL = `namespace go hello
+
+struct HelloReq {
+    1: string name (api.query = "name")
+}
+
+service HelloService {
+    HelloReq Hello(1: HelloReq req) (api.get = "/hello")
+}
+`
+
+// writeIDL writes the IDL to a temporary directory and returns its parsed AST.
+func writeIDL(t *testing.T, content string) *parser.Thrift {
+	t.Helper()
+	return writeIDLs(t, map[string]string{"main.thrift": content})
+}
+
+// writeIDLs writes the files to a temporary directory and returns the parsed AST of
+// main.thrift.
+func writeIDLs(t *testing.T, files map[string]string) *parser.Thrift {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idl := filepath.Join(dir, "main.thrift")
+	ast, err := parser.ParseFile(idl, nil, true)
+	if err != nil {
+		t.Fatalf("parse %s: %s", idl, err)
+	}
+	if err := semantic.ResolveSymbols(ast); err != nil {
+		t.Fatalf("resolve %s: %s", idl, err)
+	}
+	return ast
+}
+
+// runPlugin runs the plugin on the request the way thriftgo does, through stdin and
+// stdout, and returns its exit code and response.
+func runPlugin(t *testing.T, ast *parser.Thrift, parameters ...string) (int, *plugin.Response) {
+	t.Helper()
+	code, content := runPluginOutput(t, ast, parameters...)
+	if len(content) == 0 {
+		return code, nil
+	}
+	res, err := plugin.UnmarshalResponse(content)
+	if err != nil {
+		t.Fatalf("unmarshal response: %s", err)
+	}
+	return code, res
+}
+
+// runPluginOutput runs the plugin like runPlugin and returns everything it wrote to
+// stdout.
+func runPluginOutput(t *testing.T, ast *parser.Thrift, parameters ...string) (int, []byte) {
+	t.Helper()
+	data, err := plugin.MarshalRequest(&plugin.Request{
+		Version:          "0.3.15",
+		OutputPath:       filepath.Join(t.TempDir(), "output"),
+		AST:              ast,
+		PluginParameters: append([]string{"OutputDir=" + filepath.Join(t.TempDir(), "swagger")}, parameters...),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	stdin, stdout := filepath.Join(dir, "stdin"), filepath.Join(dir, "stdout")
+	if err := ioutil.WriteFile(stdin, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.Create(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	code := func() int {
+		savedIn, savedOut := os.Stdin, os.Stdout
+		defer func() { os.Stdin, os.Stdout = savedIn, savedOut }()
+		os.Stdin, os.Stdout = in, out
+		return Run()
+	}()
+
+	content, err := ioutil.ReadFile(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, content
+}
+
+func TestRunStrict(t *testing.T) {
+	ast := writeIDL(t, duplicateRouteIDL)
+
+	code, res := runPlugin(t, ast)
+	if code != 0 || res == nil {
+		t.Fatalf("got exit code %d, want 0 and a response", code)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "conflicts with") {
+		t.Errorf("got warnings %q, want the conflict", res.Warnings)
+	}
+
+	code, res = runPlugin(t, ast, "Strict=true")
+	if code == 0 {
+		t.Error("got exit code 0 in strict mode, want the warning to fail the plugin")
+	}
+	if res != nil {
+		t.Errorf("got a response %v in strict mode", res)
+	}
+}
+
+// generatedNames returns the base names of the files of the response.
+func generatedNames(res *plugin.Response) []string {
+	var names []string
+	for _, file := range res.Contents {
+		names = append(names, filepath.Base(file.GetName()))
+	}
+	return names
+}
+
+func TestNoServerNoOpenapi(t *testing.T) {
+	ast := writeIDL(t, helloIDL)
+	for _, test := range []struct {
+		parameters []string
+		files      []string
+	}{
+		{nil, []string{"openapi.yaml", "swagger.go"}},
+		{[]string{"NoServer=true"}, []string{"openapi.yaml"}},
+		{[]string{"NoOpenapi=true"}, []string{"swagger.go"}},
+	} {
+		code, res := runPlugin(t, ast, test.parameters...)
+		if code != 0 || res == nil {
+			t.Fatalf("%v: got exit code %d", test.parameters, code)
+		}
+		if files := generatedNames(res); !reflect.DeepEqual(files, test.files) {
+			t.Errorf("%v: got files %v, want %v", test.parameters, files, test.files)
+		}
+	}
+
+	if code, res := runPlugin(t, ast, "NoServer=true", "NoOpenapi=true"); code == 0 || res != nil {
+		t.Errorf("got exit code %d, want NoServer and NoOpenapi to be rejected", code)
+	}
+}
+
+// persist writes the files of the response the way thriftgo does once the plugin
+// exits, the document of Stdout going to stdout, and returns the other files.
+func persist(res *plugin.Response, stdout io.Writer) ([]string, error) {
+	var files []string
+	for _, file := range res.Contents {
+		if file.GetName() == generator.StdoutName {
+			if _, err := io.WriteString(stdout, file.Content); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		files = append(files, filepath.Base(file.GetName()))
+	}
+	return files, nil
+}
+
+func TestStdout(t *testing.T) {
+	ast := writeIDL(t, helloIDL)
+	for _, test := range []struct {
+		format string
+		prefix string
+	}{
+		{"yaml", "# Generated with thrift-gen-rpc-swagger"},
+		{"json", "{"},
+	} {
+		code, output := runPluginOutput(t, ast, "Stdout=true", "Format="+test.format)
+		if code != 0 {
+			t.Fatalf("%s: got exit code %d", test.format, code)
+		}
+		res, err := plugin.UnmarshalResponse(output)
+		if err != nil {
+			t.Fatalf("%s: unmarshal response: %s", test.format, err)
+		}
+		// The output of the plugin is read by thriftgo, it must only hold the response.
+		data, err := plugin.MarshalResponse(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(output) != len(data) {
+			t.Errorf("%s: got %d bytes after the response", test.format, len(output)-len(data))
+		}
+
+		var stdout bytes.Buffer
+		files, err := persist(res, &stdout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 0 {
+			t.Errorf("%s: got files %v, want the document on stdout only", test.format, files)
+		}
+		document := stdout.String()
+		if !strings.HasPrefix(document, test.prefix) {
+			t.Errorf("%s: got document %.40q on stdout", test.format, document)
+		}
+		var content map[string]interface{}
+		if err := yaml.Unmarshal([]byte(document), &content); err != nil || content["openapi"] != "3.0.3" {
+			t.Errorf("%s: got an invalid document: %v", test.format, err)
+		}
+	}
+
+	for _, conflict := range []string{"NoOpenapi=true", "Watch=true"} {
+		if code, res := runPlugin(t, ast, "Stdout=true", conflict); code == 0 || res != nil {
+			t.Errorf("got exit code %d, want Stdout and %s to be rejected", code, conflict)
+		}
+	}
+}
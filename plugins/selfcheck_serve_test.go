@@ -0,0 +1,278 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backendSource is a kitex generic server answering every method with an empty
+// JSON object but Download, which answers with downloadPayload, it is compiled next
+// to the generated server.
+const backendSource = `package main
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/cloudwego/kitex/pkg/generic"
+	"github.com/cloudwego/kitex/server"
+	"github.com/cloudwego/kitex/server/genericserver"
+)
+
+type stubService struct{}
+
+func (s *stubService) GenericCall(ctx context.Context, method string, request interface{}) (interface{}, error) {
+	if method == "Download" {
+		// binary fields are base64 encoded in JSON generic calls
+		return ` + "`" + `{"data":"AAEC/w=="}` + "`" + `, nil
+	}
+	return "{}", nil
+}
+
+func main() {
+	p, err := generic.NewThriftFileProvider(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	g, err := generic.JSONThriftGeneric(p)
+	if err != nil {
+		panic(err)
+	}
+	addr, err := net.ResolveTCPAddr("tcp", os.Args[2])
+	if err != nil {
+		panic(err)
+	}
+	svr := genericserver.NewServer(&stubService{}, g, server.WithServiceAddr(addr))
+	if err := svr.Run(); err != nil {
+		panic(err)
+	}
+}
+`
+
+// downloadPayload is the binary body of Download, which the server must forward
+// unchanged.
+var downloadPayload = []byte{0x00, 0x01, 0x02, 0xff}
+
+// streamThreshold is the StreamThreshold of the generated servers, the files uploaded
+// are larger so that they are streamed.
+const streamThreshold = 64
+
+const readyTimeout = 30 * time.Second
+
+// start starts the stub backend and the generated server, which are killed when the
+// test ends.
+func start(t *testing.T, idl, serverBin, backendBin, hertzAddr, kitexAddr string) error {
+	backend := exec.Command(backendBin, idl, kitexAddr)
+	backend.Stdout = os.Stderr
+	backend.Stderr = os.Stderr
+	if err := backend.Start(); err != nil {
+		return err
+	}
+	t.Cleanup(func() { backend.Process.Kill() })
+
+	// the server looks the IDL up from its working directory
+	server := exec.Command(serverBin)
+	server.Dir = filepath.Dir(idl)
+	server.Stdout = os.Stderr
+	server.Stderr = os.Stderr
+	if err := server.Start(); err != nil {
+		return err
+	}
+	t.Cleanup(func() { server.Process.Kill() })
+
+	if err := waitReady(kitexAddr); err != nil {
+		return fmt.Errorf("backend: %s", err)
+	}
+	if err := waitReady(hertzAddr); err != nil {
+		return fmt.Errorf("server: %s", err)
+	}
+	return nil
+}
+
+// serve starts the stub backend and the generated server, then drives one call per operation
+// plus the documentation routes and an unknown route.
+func serve(t *testing.T, doc *document, idl, serverBin, backendBin, hertzAddr, kitexAddr string) error {
+	if err := start(t, idl, serverBin, backendBin, hertzAddr, kitexAddr); err != nil {
+		return err
+	}
+	return drive(t, doc, "http://"+hertzAddr)
+}
+
+// drive calls every operation of the document on the server at base, plus the
+// documentation routes and an unknown route.
+func drive(t *testing.T, doc *document, base string) error {
+	for _, path := range []string{"/openapi.yaml", "/openapi.json", "/swagger/index.html"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: unexpected status %d", path, resp.StatusCode)
+		}
+	}
+
+	for _, op := range doc.operations() {
+		if err := call(base, op); err != nil {
+			return fmt.Errorf("%s %s: %s", strings.ToUpper(op.Method), op.Path, err)
+		}
+		t.Logf("%s %s ok", strings.ToUpper(op.Method), op.Path)
+	}
+
+	return callUnknown(base)
+}
+
+func call(base string, op *operation) error {
+	path := op.Path
+	query := url.Values{}
+	header := http.Header{}
+	for _, p := range op.Parameters {
+		value := sampleValue(p.Schema.Type)
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", value)
+		case "query":
+			query.Set(p.Name, value)
+		case "header":
+			header.Set(p.Name, value)
+		case "cookie":
+			header.Add("Cookie", p.Name+"="+value)
+		}
+	}
+	target := base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body string
+	if op.RequestBody != nil {
+		if _, ok := op.RequestBody.Content["application/json"]; ok {
+			body = "{}"
+			header.Set("Content-Type", "application/json")
+		} else if fields := op.formFields(); len(fields) > 0 {
+			var contentType string
+			body, contentType = multipartBody(fields)
+			header.Set("Content-Type", contentType)
+		} else {
+			header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(op.Method), target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if _, ok := op.Responses[fmt.Sprint(resp.StatusCode)]; !ok {
+		return fmt.Errorf("status %d is not documented, body: %s", resp.StatusCode, content)
+	}
+	if op.download() {
+		if !bytes.Equal(content, downloadPayload) {
+			return fmt.Errorf("download is not forwarded unchanged: %x", content)
+		}
+		return nil
+	}
+	if !json.Valid(content) {
+		return fmt.Errorf("response is not JSON: %s", content)
+	}
+	return nil
+}
+
+// callUnknown checks that a route unknown to the backend surfaces as a JSON error.
+func callUnknown(base string) error {
+	resp, err := http.Post(base+"/selfcheck/unknown", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusBadRequest {
+		return fmt.Errorf("POST /selfcheck/unknown: unexpected status %d", resp.StatusCode)
+	}
+	var payload map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("POST /selfcheck/unknown: %s", err)
+	}
+	if _, ok := payload["error"]; !ok {
+		return fmt.Errorf("POST /selfcheck/unknown: missing error in response")
+	}
+	return nil
+}
+
+// multipartBody returns a multipart body holding a text value per field and a file
+// larger than streamThreshold per file field, along with its content type.
+func multipartBody(fields map[string]bool) (string, string) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	for name, file := range fields {
+		if !file {
+			w.WriteField(name, "selfcheck")
+			continue
+		}
+		part, _ := w.CreateFormFile(name, "selfcheck.bin")
+		part.Write(bytes.Repeat([]byte{0x00, 0xff}, 4*streamThreshold))
+	}
+	w.Close()
+	return b.String(), w.FormDataContentType()
+}
+
+func sampleValue(typ string) string {
+	switch typ {
+	case "integer", "number":
+		return "1"
+	case "boolean":
+		return "true"
+	default:
+		return "selfcheck"
+	}
+}
+
+func waitReady(addr string) error {
+	deadline := time.Now().Add(readyTimeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("%s not ready after %s", addr, readyTimeout)
+}
@@ -0,0 +1,340 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"fmt"
+	goparser "go/parser"
+	"go/token"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"gopkg.in/yaml.v3"
+)
+
+// selfcheckIDLs are the IDL fixtures the pipeline is checked against.
+var selfcheckIDLs = []string{
+	filepath.Join("..", "example", "hello.thrift"),
+	filepath.Join("testdata", "download.thrift"),
+	filepath.Join("testdata", "upload.thrift"),
+}
+
+// TestSelfcheck runs the whole pipeline against the fixtures: it generates the
+// document and the server, validates them, then compiles the server, starts it in
+// front of a stub generic backend and drives HTTP calls derived from the document.
+// With -short only the generation is checked.
+func TestSelfcheck(t *testing.T) {
+	for _, idl := range selfcheckIDLs {
+		t.Run(filepath.Base(idl), func(t *testing.T) {
+			idl, err := filepath.Abs(idl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hertzAddr, kitexAddr := freeAddr(t), freeAddr(t)
+			outputDir := filepath.Join(t.TempDir(), "output")
+			generateFixture(t, idl, outputDir, hertzAddr, kitexAddr)
+
+			doc, err := checkGenerated(outputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("generated %d operations", len(doc.operations()))
+			if testing.Short() {
+				return
+			}
+
+			skipWithoutProxy(t)
+			serverBin, backendBin := compile(t, outputDir)
+			if err := serve(t, doc, idl, serverBin, backendBin, hertzAddr, kitexAddr); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// skipWithoutProxy skips the test when the dependencies of the generated server, which
+// is compiled in its own module, can not be fetched.
+func skipWithoutProxy(t *testing.T) {
+	t.Helper()
+	if proxy, _ := exec.Command("go", "env", "GOPROXY").Output(); strings.TrimSpace(string(proxy)) == "off" {
+		t.Skip("compiling the generated server needs GOPROXY")
+	}
+}
+
+// startFixture generates the document and the server of the IDL with the extra
+// arguments, compiles the server and starts it in front of the stub backend until the
+// test ends. It returns the base URL of the server and the generated document, the
+// test is skipped with -short.
+func startFixture(t *testing.T, idl string, extra ...string) (string, *document) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starting the generated server is skipped with -short")
+	}
+	skipWithoutProxy(t)
+	idl, err := filepath.Abs(idl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hertzAddr, kitexAddr := freeAddr(t), freeAddr(t)
+	outputDir := filepath.Join(t.TempDir(), "output")
+	generateFixture(t, idl, outputDir, hertzAddr, kitexAddr, extra...)
+	doc, err := checkGenerated(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverBin, backendBin := compile(t, outputDir)
+	if err := start(t, idl, serverBin, backendBin, hertzAddr, kitexAddr); err != nil {
+		t.Fatal(err)
+	}
+	return "http://" + hertzAddr, doc
+}
+
+// generateFixture generates the document and the server of the IDL in outputDir the
+// same way the plugin does, with the extra arguments.
+func generateFixture(t *testing.T, idl, outputDir, hertzAddr, kitexAddr string, extra ...string) {
+	t.Helper()
+	ast, err := parser.ParseFile(idl, []string{filepath.Dir(idl)}, true)
+	if err != nil {
+		t.Fatalf("parse %s: %s", idl, err)
+	}
+	if err := semantic.ResolveSymbols(ast); err != nil {
+		t.Fatalf("resolve %s: %s", idl, err)
+	}
+	arguments := new(args.Arguments)
+	err = arguments.Unpack(append([]string{
+		"OutputDir=" + outputDir,
+		"HertzAddr=" + hertzAddr,
+		"KitexAddr=" + kitexAddr,
+		// uploads larger than this are streamed through a temporary file
+		"StreamThreshold=" + strconv.Itoa(streamThreshold),
+	}, extra...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, warnings, err := generate(ast, arguments)
+	if err != nil {
+		t.Fatalf("generate: %s", err)
+	}
+	for _, warning := range warnings {
+		t.Logf("warning: %s", warning)
+	}
+	if err := writeFiles(contents); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// compile builds the generated server and the stub backend in a temporary module.
+func compile(t *testing.T, outputDir string) (string, string) {
+	t.Helper()
+	backendDir := filepath.Join(outputDir, "backend")
+	if err := os.MkdirAll(backendDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(backendDir, "main.go"), []byte(backendSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := [][]string{
+		{"go", "mod", "init", "selfcheck"},
+		{"go", "mod", "tidy"},
+		{"go", "build", "-o", "server", "."},
+		{"go", "build", "-o", "backend/backend", "./backend"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command(step[0], step[1:]...)
+		cmd.Dir = outputDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %s\n%s", strings.Join(step, " "), err, output)
+		}
+	}
+	return filepath.Join(outputDir, "server"), filepath.Join(backendDir, "backend")
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// document is the subset of the generated OpenAPI document the check relies on.
+type document struct {
+	Paths      map[string]map[string]yaml.Node `yaml:"paths"`
+	Components struct {
+		RequestBodies map[string]*requestBody `yaml:"requestBodies"`
+		Responses     map[string]interface{}  `yaml:"responses"`
+	} `yaml:"components"`
+}
+
+type parameter struct {
+	Name     string `yaml:"name"`
+	In       string `yaml:"in"`
+	Required bool   `yaml:"required"`
+	Schema   struct {
+		Type string `yaml:"type"`
+	} `yaml:"schema"`
+}
+
+type operation struct {
+	Path        string
+	Method      string
+	OperationID string                 `yaml:"operationId"`
+	Parameters  []parameter            `yaml:"parameters"`
+	RequestBody *requestBody           `yaml:"requestBody"`
+	Responses   map[string]interface{} `yaml:"responses"`
+}
+
+type requestBody struct {
+	Ref     string                 `yaml:"$ref"`
+	Content map[string]interface{} `yaml:"content"`
+}
+
+// formFields returns the properties of the multipart body of the operation, mapped to
+// whether they are files.
+func (op *operation) formFields() map[string]bool {
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, _ := op.RequestBody.Content["multipart/form-data"].(map[string]interface{})
+	schema, _ := media["schema"].(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	fields := make(map[string]bool)
+	for name, property := range properties {
+		property, _ := property.(map[string]interface{})
+		fields[name] = property["format"] == "binary" || property["format"] == "byte"
+	}
+	return fields
+}
+
+// download reports whether the successful response of the operation is a binary
+// download.
+func (op *operation) download() bool {
+	response, _ := op.Responses["200"].(map[string]interface{})
+	content, _ := response["content"].(map[string]interface{})
+	_, ok := content["application/octet-stream"]
+	return ok
+}
+
+// operations lists the operations of the document sorted by path and method.
+func (d *document) operations() []*operation {
+	var ops []*operation
+	for path, item := range d.Paths {
+		for _, method := range httpMethods {
+			node, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &operation{}
+			if err := node.Decode(op); err != nil {
+				continue
+			}
+			op.Path = path
+			op.Method = method
+			d.resolve(op)
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
+
+// resolve replaces the request body and the responses of the operation which reference
+// the ones of the components, with ReuseBodies, by them.
+func (d *document) resolve(op *operation) {
+	if op.RequestBody != nil && op.RequestBody.Ref != "" {
+		op.RequestBody = d.Components.RequestBodies[strings.TrimPrefix(op.RequestBody.Ref, "#/components/requestBodies/")]
+	}
+	for status, response := range op.Responses {
+		response, _ := response.(map[string]interface{})
+		if ref, ok := response["$ref"].(string); ok {
+			op.Responses[status] = d.Components.Responses[strings.TrimPrefix(ref, "#/components/responses/")]
+		}
+	}
+}
+
+// checkGenerated validates the generated document and makes sure the rendered server parses.
+func checkGenerated(outputDir string) (*document, error) {
+	if _, err := goparser.ParseFile(token.NewFileSet(), filepath.Join(outputDir, "swagger.go"), nil, goparser.AllErrors); err != nil {
+		return nil, fmt.Errorf("parse generated server: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(outputDir, "openapi.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	doc := &document{}
+	if err = yaml.Unmarshal(content, doc); err != nil {
+		return nil, fmt.Errorf("parse generated document: %s", err)
+	}
+
+	ops := doc.operations()
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("generated document has no operations")
+	}
+	operationIDs := make(map[string]bool)
+	for _, op := range ops {
+		name := op.Method + " " + op.Path
+		if op.OperationID == "" {
+			return nil, fmt.Errorf("%s: missing operationId", name)
+		}
+		if operationIDs[op.OperationID] {
+			return nil, fmt.Errorf("%s: duplicate operationId %s", name, op.OperationID)
+		}
+		operationIDs[op.OperationID] = true
+		if len(op.Responses) == 0 {
+			return nil, fmt.Errorf("%s: no responses", name)
+		}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			if !op.hasParameter(match[1], "path") {
+				return nil, fmt.Errorf("%s: path parameter %s is not declared", name, match[1])
+			}
+		}
+	}
+	return doc, nil
+}
+
+func (op *operation) hasParameter(name, in string) bool {
+	for _, p := range op.Parameters {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
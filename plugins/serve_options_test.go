@@ -0,0 +1,316 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exampleIDL is the IDL of the example, its GET /hello1 requires the query parameter
+// query2.
+var exampleIDL = filepath.Join("..", "example", "hello.thrift")
+
+// fixtureIDL writes the IDL to a temporary directory for a generated server and its
+// stub backend, and returns its path.
+func fixtureIDL(t *testing.T, content string) string {
+	t.Helper()
+	idl := filepath.Join(t.TempDir(), "main.thrift")
+	if err := ioutil.WriteFile(idl, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return idl
+}
+
+// send sends the request to the generated server and decodes its JSON body into v,
+// unless v is nil.
+func send(t *testing.T, method, url, body string, header http.Header, v interface{}) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, url, err)
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != nil {
+		if err := json.Unmarshal(content, v); err != nil {
+			t.Fatalf("%s %s: %s, body: %s", method, url, err, content)
+		}
+	}
+	return resp
+}
+
+func TestInvokeAPI(t *testing.T) {
+	base, _ := startFixture(t, exampleIDL, "InvokeAPI=true")
+
+	var operations []struct {
+		ID         string `json:"id"`
+		Method     string `json:"method"`
+		Path       string `json:"path"`
+		Parameters []struct {
+			Name     string `json:"name"`
+			In       string `json:"in"`
+			Required bool   `json:"required"`
+		} `json:"parameters"`
+	}
+	if resp := send(t, "GET", base+"/_api/operations", "", nil, &operations); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /_api/operations: got status %d", resp.StatusCode)
+	}
+	var id string
+	for _, op := range operations {
+		if op.Method != "GET" || op.Path != "/hello1" {
+			continue
+		}
+		id = op.ID
+		for _, param := range op.Parameters {
+			if param.Name == "query2" && (param.In != "query" || !param.Required) {
+				t.Errorf("got query2 in %s, required %v, want a required query parameter", param.In, param.Required)
+			}
+		}
+	}
+	if id == "" {
+		t.Fatalf("got operations %v, want GET /hello1", operations)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+		error  string
+	}{
+		{"parameters placed", id, `{"params": {"query2": "selfcheck", "items": ["a", "b"]}}`, http.StatusOK, ""},
+		{"missing required parameter", id, `{"params": {}}`, http.StatusBadRequest, "missing required query parameter 'query2'"},
+		{"unknown parameter", id, `{"params": {"query2": "selfcheck", "query3": "x"}}`, http.StatusBadRequest, "has no parameter 'query3'"},
+		{"invalid invocation", id, `{"params": `, http.StatusBadRequest, "invalid invocation"},
+		{"unknown operation", "NoSuchOperation", `{}`, http.StatusNotFound, "unknown operation 'NoSuchOperation'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			resp := send(t, "POST", base+"/_api/invoke/"+tt.id, tt.body, nil, &payload)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d, body: %v", resp.StatusCode, tt.status, payload)
+			}
+			if message, _ := payload["error"].(string); !strings.Contains(message, tt.error) {
+				t.Errorf("got error %q, want %q", message, tt.error)
+			}
+		})
+	}
+}
+
+// validateIDL declares an operation with required query and header parameters and a
+// query parameter restricted to an enum.
+const validateIDL = `namespace go validate
+
+struct ListReq {
+    // owner of the orders
+    1: string owner (api.query = "owner", openapi.parameter = '{required: true}')
+    2: string status (api.query = "status", openapi.enum = "pending,active")
+    3: string token (api.header = "X-Token", openapi.parameter = '{required: true}')
+}
+
+struct ListResp {
+    1: string body (api.body = "body")
+}
+
+service OrderService {
+    ListResp ListOrders(1: ListReq req) (api.get = "/orders")
+}
+`
+
+func TestValidateRequests(t *testing.T) {
+	base, _ := startFixture(t, fixtureIDL(t, validateIDL), "ValidateRequests=true")
+	token := http.Header{"X-Token": {"selfcheck"}}
+	tests := []struct {
+		name    string
+		query   string
+		header  http.Header
+		status  int
+		missing []string
+		invalid []string
+	}{
+		{"valid", "?owner=me&status=active", token, http.StatusOK, nil, nil},
+		{"missing query and header", "", nil, http.StatusBadRequest, []string{"owner", "X-Token"}, nil},
+		{"missing header", "?owner=me", nil, http.StatusBadRequest, []string{"X-Token"}, nil},
+		{"value out of the enum", "?owner=me&status=closed", token, http.StatusBadRequest, nil, []string{"status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload struct {
+				Error   string `json:"error"`
+				DocsURL string `json:"x-docs-url"`
+				Missing []struct {
+					Name        string `json:"name"`
+					Description string `json:"description"`
+				} `json:"missing"`
+				Invalid []struct {
+					Name string   `json:"name"`
+					Enum []string `json:"enum"`
+				} `json:"invalid"`
+			}
+			resp := send(t, "GET", base+"/orders"+tt.query, "", tt.header, &payload)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d, body: %+v", resp.StatusCode, tt.status, payload)
+			}
+			if tt.status == http.StatusOK {
+				return
+			}
+			var missing, invalid []string
+			for _, param := range payload.Missing {
+				missing = append(missing, param.Name)
+				if param.Name == "owner" && param.Description != "owner of the orders" {
+					t.Errorf("got description %q of owner, want the one of the IDL", param.Description)
+				}
+			}
+			for _, param := range payload.Invalid {
+				invalid = append(invalid, param.Name)
+				if !reflect.DeepEqual(param.Enum, []string{"pending", "active"}) {
+					t.Errorf("got enum %v of %s, want [pending active]", param.Enum, param.Name)
+				}
+			}
+			if !reflect.DeepEqual(missing, tt.missing) || !reflect.DeepEqual(invalid, tt.invalid) {
+				t.Errorf("got missing %v and invalid %v, want %v and %v", missing, invalid, tt.missing, tt.invalid)
+			}
+			if !strings.HasPrefix(payload.DocsURL, "/swagger/index.html#") {
+				t.Errorf("got x-docs-url %q, want a link to the operation in the UI", payload.DocsURL)
+			}
+		})
+	}
+}
+
+// multiArgsIDL declares a function taking several arguments.
+const multiArgsIDL = `namespace go multi
+
+struct Order {
+    1: i64 id (api.body = "id")
+}
+
+struct Options {
+    1: bool dry_run (api.body = "dry_run")
+}
+
+service OrderService {
+    Order CreateOrder(1: Order order, 2: Options options) (api.post = "/orders")
+}
+`
+
+func TestWrapMultiArgs(t *testing.T) {
+	base, doc := startFixture(t, fixtureIDL(t, multiArgsIDL), "WrapMultiArgs=true")
+	// The arguments are documented as one JSON body, which the server proxies.
+	ops := doc.operations()
+	if len(ops) != 1 || ops[0].RequestBody == nil || ops[0].RequestBody.Content["application/json"] == nil {
+		t.Fatalf("got operations %v, want POST /orders with a JSON body", ops)
+	}
+	if err := drive(t, doc, base); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReuseBodies(t *testing.T) {
+	base, doc := startFixture(t, exampleIDL, "ReuseBodies=true")
+	// The methods of the example answer the same HelloResp, their response is moved
+	// to the components and referenced.
+	if len(doc.Components.Responses) == 0 {
+		t.Fatal("got no shared responses")
+	}
+	for _, op := range doc.operations() {
+		if op.Responses["200"] == nil {
+			t.Errorf("%s %s: the shared response is not resolved", op.Method, op.Path)
+		}
+	}
+	if err := drive(t, doc, base); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetricsPort(t *testing.T) {
+	metricsAddr := freeAddr(t)
+	_, port, err := net.SplitHostPort(metricsAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := startFixture(t, exampleIDL, "Metrics=true", "MetricsPort="+port)
+	if err := waitReady(metricsAddr); err != nil {
+		t.Fatalf("metrics: %s", err)
+	}
+
+	if resp := send(t, "GET", base+"/hello1?query2=selfcheck", "", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /hello1: got status %d", resp.StatusCode)
+	}
+	// The metrics are out of reach of the API clients.
+	if resp := send(t, "GET", base+"/metrics", "", nil, nil); resp.StatusCode == http.StatusOK {
+		t.Error("GET /metrics on the port of the API: got status 200")
+	}
+
+	resp, err := http.Get("http://" + metricsAddr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `swagger_proxy_requests_total{code="200",method="QueryMethod",service="HelloService1"} 1`
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(content), want) {
+		t.Errorf("got status %d and metrics:\n%s\nwant %s", resp.StatusCode, content, want)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	base, _ := startFixture(t, exampleIDL, "RequestID=true")
+
+	header := http.Header{"X-Request-Id": []string{"selfcheck-id"}}
+	resp := send(t, "GET", base+"/hello1?query2=selfcheck", "", header, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /hello1: got status %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Request-Id"); got != "selfcheck-id" {
+		t.Errorf("got X-Request-Id %q, want the ID of the request", got)
+	}
+
+	// An ID is generated for each request without one.
+	ids := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		resp := send(t, "GET", base+"/hello1?query2=selfcheck", "", nil, nil)
+		id := resp.Header.Get("X-Request-Id")
+		if resp.StatusCode != http.StatusOK || id == "" || ids[id] {
+			t.Fatalf("GET /hello1: got status %d and X-Request-Id %q after %v", resp.StatusCode, id, ids)
+		}
+		ids[id] = true
+	}
+}
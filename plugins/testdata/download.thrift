@@ -0,0 +1,13 @@
+namespace go download
+
+struct DownloadReq {
+    1: string name (api.query = "name")
+}
+
+struct DownloadResp {
+    1: binary data (api.raw_body = "data")
+}
+
+service DownloadService {
+    DownloadResp Download(1: DownloadReq req) (api.get = "/download")
+}
@@ -0,0 +1,14 @@
+namespace go upload
+
+struct UploadReq {
+    1: string name (api.form = "name")
+    2: binary file (api.form = "file")
+}
+
+struct UploadResp {
+    1: string id (api.body = "id")
+}
+
+service UploadService {
+    UploadResp Upload(1: UploadReq req) (api.post = "/upload")
+}
@@ -16,4 +16,6 @@
 
 package plugins
 
-const Version = "v0.1.0"
+import "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/generator"
+
+const Version = generator.Version
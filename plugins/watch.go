@@ -0,0 +1,183 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/plugin"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/fsnotify/fsnotify"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/utils"
+)
+
+// watchDelay lets the events of a single save settle before regenerating, editors
+// often write a file in several steps.
+const watchDelay = 100 * time.Millisecond
+
+// WatchAndRegen regenerates the outputs whenever the IDL of ast or one of the files
+// it includes is written, until the process is stopped. thriftgo only writes the
+// files of a plugin once it exits, so in watch mode the plugin writes them itself
+// and thriftgo stays in the foreground. Each regeneration is logged with its time
+// on stderr, stdout being the channel of the plugin protocol, and failures are
+// logged without stopping the watch.
+func WatchAndRegen(ast *parser.Thrift, args *args.Arguments) error {
+	return watchAndRegen(ast, args, nil)
+}
+
+// watchAndRegen is WatchAndRegen returning once stop is closed.
+func watchAndRegen(ast *parser.Thrift, args *args.Arguments, stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	idl := ast.Filename
+	files, err := watchFiles(watcher, ast)
+	if err != nil {
+		return err
+	}
+	log.Printf("[Info]: watching %d IDL files for changes", len(files))
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 || !files[filepath.Clean(event.Name)] {
+				continue
+			}
+			pending = time.After(watchDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[Error]: watch IDL files failed: %s", err.Error())
+		case <-pending:
+			pending = nil
+			next, err := regenerate(idl, ast, args)
+			if err != nil {
+				log.Printf("[Error]: regenerate failed: %s", err.Error())
+				continue
+			}
+			ast = next
+			// Includes may have been added or removed.
+			if files, err = watchFiles(watcher, ast); err != nil {
+				log.Printf("[Error]: watch IDL files failed: %s", err.Error())
+			}
+			log.Printf("[Info]: regenerated from %s", idl)
+		}
+	}
+}
+
+// watchFiles watches the directories of the IDL and of the files it includes, so
+// that files replaced on save are still followed, and returns the watched files.
+func watchFiles(watcher *fsnotify.Watcher, ast *parser.Thrift) (map[string]bool, error) {
+	files := make(map[string]bool)
+	var walk func(t *parser.Thrift) error
+	walk = func(t *parser.Thrift) error {
+		path, err := filepath.Abs(t.Filename)
+		if err != nil {
+			return err
+		}
+		if files[path] {
+			return nil
+		}
+		files[path] = true
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return err
+		}
+		for _, include := range t.Includes {
+			if include.Reference != nil {
+				if err := walk(include.Reference); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(ast); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// regenerate parses the IDL again, looking up includes in the directories of the
+// files previously included, and writes the generated files.
+func regenerate(idl string, previous *parser.Thrift, args *args.Arguments) (*parser.Thrift, error) {
+	var includeDirs []string
+	seen := make(map[*parser.Thrift]bool)
+	var walk func(t *parser.Thrift)
+	walk = func(t *parser.Thrift) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		if dir := filepath.Dir(t.Filename); !utils.Contains(includeDirs, dir) {
+			includeDirs = append(includeDirs, dir)
+		}
+		for _, include := range t.Includes {
+			if include.Reference != nil {
+				walk(include.Reference)
+			}
+		}
+	}
+	walk(previous)
+
+	ast, err := parser.ParseFile(idl, includeDirs, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := semantic.ResolveSymbols(ast); err != nil {
+		return nil, err
+	}
+	contents, warnings, err := generate(ast, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		log.Printf("[Warn]: %s", warning)
+	}
+	return ast, writeFiles(contents)
+}
+
+// writeFiles writes the generated files the way thriftgo does with a response.
+func writeFiles(contents []*plugin.Generated) error {
+	for _, content := range contents {
+		if content.Name == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(*content.Name), 0o755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*content.Name, []byte(content.Content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
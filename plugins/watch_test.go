@@ -0,0 +1,93 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugins
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/args"
+)
+
+const watchBaseIDL = `namespace go base
+
+struct Item {
+    1: string name
+}
+`
+
+const watchMainIDL = `namespace go watch
+
+include "base.thrift"
+
+struct Req {
+    1: base.Item item (api.body = "item")
+}
+
+service ItemService {
+    Req Put(1: Req req) (api.put = "/items")
+}
+`
+
+// waitFor rewrites the file with content until the document contains want, the
+// first writes may happen before the directory is watched.
+func waitFor(t *testing.T, file, content, document, want string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := ioutil.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * watchDelay)
+		if generated, _ := ioutil.ReadFile(document); strings.Contains(string(generated), want) {
+			return
+		}
+	}
+	t.Fatalf("%s was not regenerated with %q", document, want)
+}
+
+func TestWatchAndRegen(t *testing.T) {
+	ast := writeIDLs(t, map[string]string{"main.thrift": watchMainIDL, "base.thrift": watchBaseIDL})
+	main := ast.Filename
+	base := filepath.Join(filepath.Dir(main), "base.thrift")
+
+	outputDir := filepath.Join(t.TempDir(), "swagger")
+	arguments := &args.Arguments{OutputDir: outputDir, NoServer: true, Watch: true}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- watchAndRegen(ast, arguments, stop) }()
+	defer func() {
+		close(stop)
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	document := filepath.Join(outputDir, "openapi.yaml")
+	waitFor(t, main, strings.Replace(watchMainIDL, `"/items"`, `"/items/v2"`, 1), document, "/items/v2:")
+	// The included files are watched once the IDL is parsed again.
+	waitFor(t, base, strings.Replace(watchBaseIDL, "1: string name", "1: string name\n    2: i64 count", 1), document, "count:")
+	// A failure does not stop the watch.
+	if err := ioutil.WriteFile(main, []byte("service {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * watchDelay)
+	waitFor(t, main, strings.Replace(watchMainIDL, `"/items"`, `"/items/v3"`, 1), document, "/items/v3:")
+}
@@ -34,6 +34,8 @@
 package openapi
 
 import (
+	"bytes"
+
 	"github.com/google/gnostic-models/compiler"
 	"gopkg.in/yaml.v3"
 )
@@ -46,7 +48,17 @@ func (m *Document) YAMLValue(comment string) ([]byte, error) {
 		Content:     []*yaml.Node{rawInfo},
 		HeadComment: comment,
 	}
-	return yaml.Marshal(rawInfo)
+	// The encoder never folds long lines, so the output only depends on the document.
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(4)
+	if err := encoder.Encode(rawInfo); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // ToRawInfo returns a description of AdditionalPropertiesItem suitable for JSON or YAML export.
@@ -1116,7 +1128,8 @@ func (m *Operation) ToRawInfo() *yaml.Node {
 		info.Content = append(info.Content, compiler.NewScalarNodeForString("deprecated"))
 		info.Content = append(info.Content, compiler.NewScalarNodeForBool(m.Deprecated))
 	}
-	if len(m.Security) != 0 {
+	// An empty list is kept, it removes the security requirements of the document.
+	if m.Security != nil {
 		items := compiler.NewSequenceNode()
 		for _, item := range m.Security {
 			items.Content = append(items.Content, item.ToRawInfo())
@@ -1548,6 +1561,20 @@ func (m *Schema) ToRawInfo() *yaml.Node {
 		info.Content = append(info.Content, compiler.NewScalarNodeForString("example"))
 		info.Content = append(info.Content, m.Example.ToRawInfo())
 	}
+	// The examples of a schema, from OAS 3.1, are plain values: the summaries and
+	// descriptions only apply to the examples of the media types.
+	if len(m.Examples) != 0 {
+		items := compiler.NewSequenceNode()
+		for _, item := range m.Examples {
+			if item != nil && item.Value != nil {
+				items.Content = append(items.Content, item.Value.ToRawInfo())
+			}
+		}
+		if len(items.Content) != 0 {
+			info.Content = append(info.Content, compiler.NewScalarNodeForString("examples"))
+			info.Content = append(info.Content, items)
+		}
+	}
 	if m.Deprecated {
 		info.Content = append(info.Content, compiler.NewScalarNodeForString("deprecated"))
 		info.Content = append(info.Content, compiler.NewScalarNodeForBool(m.Deprecated))
@@ -24,7 +24,8 @@ import (
 )
 
 type _ServiceOptions struct {
-	Document *Document `thrift:"document,1,required" json:"document"`
+	Document  *Document  `thrift:"document,1,required" json:"document"`
+	Parameter *Parameter `thrift:"parameter,2,required" json:"parameter"`
 }
 
 func New_ServiceOptions() *_ServiceOptions {
@@ -43,19 +44,34 @@ func (p *_ServiceOptions) GetDocument() (v *Document) {
 	return p.Document
 }
 
+var _ServiceOptions_Parameter_DEFAULT *Parameter
+
+func (p *_ServiceOptions) GetParameter() (v *Parameter) {
+	if !p.IsSetParameter() {
+		return _ServiceOptions_Parameter_DEFAULT
+	}
+	return p.Parameter
+}
+
 var fieldIDToName__ServiceOptions = map[int16]string{
 	1: "document",
+	2: "parameter",
 }
 
 func (p *_ServiceOptions) IsSetDocument() bool {
 	return p.Document != nil
 }
 
+func (p *_ServiceOptions) IsSetParameter() bool {
+	return p.Parameter != nil
+}
+
 func (p *_ServiceOptions) Read(iprot thrift.TProtocol) (err error) {
 
 	var fieldTypeId thrift.TType
 	var fieldId int16
 	var issetDocument bool = false
+	var issetParameter bool = false
 
 	if _, err = iprot.ReadStructBegin(); err != nil {
 		goto ReadStructBeginError
@@ -80,6 +96,15 @@ func (p *_ServiceOptions) Read(iprot thrift.TProtocol) (err error) {
 			} else if err = iprot.Skip(fieldTypeId); err != nil {
 				goto SkipFieldError
 			}
+		case 2:
+			if fieldTypeId == thrift.STRUCT {
+				if err = p.ReadField2(iprot); err != nil {
+					goto ReadFieldError
+				}
+				issetParameter = true
+			} else if err = iprot.Skip(fieldTypeId); err != nil {
+				goto SkipFieldError
+			}
 		default:
 			if err = iprot.Skip(fieldTypeId); err != nil {
 				goto SkipFieldError
@@ -97,6 +122,11 @@ func (p *_ServiceOptions) Read(iprot thrift.TProtocol) (err error) {
 		fieldId = 1
 		goto RequiredFieldNotSetError
 	}
+
+	if !issetParameter {
+		fieldId = 2
+		goto RequiredFieldNotSetError
+	}
 	return nil
 ReadStructBeginError:
 	return thrift.PrependError(fmt.Sprintf("%T read struct begin error: ", p), err)
@@ -123,6 +153,14 @@ func (p *_ServiceOptions) ReadField1(iprot thrift.TProtocol) error {
 	p.Document = _field
 	return nil
 }
+func (p *_ServiceOptions) ReadField2(iprot thrift.TProtocol) error {
+	_field := NewParameter()
+	if err := _field.Read(iprot); err != nil {
+		return err
+	}
+	p.Parameter = _field
+	return nil
+}
 
 func (p *_ServiceOptions) Write(oprot thrift.TProtocol) (err error) {
 	var fieldId int16
@@ -134,6 +172,10 @@ func (p *_ServiceOptions) Write(oprot thrift.TProtocol) (err error) {
 			fieldId = 1
 			goto WriteFieldError
 		}
+		if err = p.writeField2(oprot); err != nil {
+			fieldId = 2
+			goto WriteFieldError
+		}
 	}
 	if err = oprot.WriteFieldStop(); err != nil {
 		goto WriteFieldStopError
@@ -169,6 +211,23 @@ WriteFieldEndError:
 	return thrift.PrependError(fmt.Sprintf("%T write field 1 end error: ", p), err)
 }
 
+func (p *_ServiceOptions) writeField2(oprot thrift.TProtocol) (err error) {
+	if err = oprot.WriteFieldBegin("parameter", thrift.STRUCT, 2); err != nil {
+		goto WriteFieldBeginError
+	}
+	if err := p.Parameter.Write(oprot); err != nil {
+		return err
+	}
+	if err = oprot.WriteFieldEnd(); err != nil {
+		goto WriteFieldEndError
+	}
+	return nil
+WriteFieldBeginError:
+	return thrift.PrependError(fmt.Sprintf("%T write field 2 begin error: ", p), err)
+WriteFieldEndError:
+	return thrift.PrependError(fmt.Sprintf("%T write field 2 end error: ", p), err)
+}
+
 func (p *_ServiceOptions) String() string {
 	if p == nil {
 		return "<nil>"
@@ -486,8 +545,9 @@ func (p *_MethodOptions) String() string {
 }
 
 type _FieldOptions struct {
-	Parameter *Parameter `thrift:"parameter,1,required" json:"parameter"`
-	Property  *Schema    `thrift:"property,2,required" json:"property"`
+	Parameter *Parameter         `thrift:"parameter,1,required" json:"parameter"`
+	Property  *Schema            `thrift:"property,2,required" json:"property"`
+	Schema    *SchemaOrReference `thrift:"schema,3,required" json:"schema"`
 }
 
 func New_FieldOptions() *_FieldOptions {
@@ -515,9 +575,19 @@ func (p *_FieldOptions) GetProperty() (v *Schema) {
 	return p.Property
 }
 
+var _FieldOptions_Schema_DEFAULT *SchemaOrReference
+
+func (p *_FieldOptions) GetSchema() (v *SchemaOrReference) {
+	if !p.IsSetSchema() {
+		return _FieldOptions_Schema_DEFAULT
+	}
+	return p.Schema
+}
+
 var fieldIDToName__FieldOptions = map[int16]string{
 	1: "parameter",
 	2: "property",
+	3: "schema",
 }
 
 func (p *_FieldOptions) IsSetParameter() bool {
@@ -528,12 +598,17 @@ func (p *_FieldOptions) IsSetProperty() bool {
 	return p.Property != nil
 }
 
+func (p *_FieldOptions) IsSetSchema() bool {
+	return p.Schema != nil
+}
+
 func (p *_FieldOptions) Read(iprot thrift.TProtocol) (err error) {
 
 	var fieldTypeId thrift.TType
 	var fieldId int16
 	var issetParameter bool = false
 	var issetProperty bool = false
+	var issetSchema bool = false
 
 	if _, err = iprot.ReadStructBegin(); err != nil {
 		goto ReadStructBeginError
@@ -567,6 +642,15 @@ func (p *_FieldOptions) Read(iprot thrift.TProtocol) (err error) {
 			} else if err = iprot.Skip(fieldTypeId); err != nil {
 				goto SkipFieldError
 			}
+		case 3:
+			if fieldTypeId == thrift.STRUCT {
+				if err = p.ReadField3(iprot); err != nil {
+					goto ReadFieldError
+				}
+				issetSchema = true
+			} else if err = iprot.Skip(fieldTypeId); err != nil {
+				goto SkipFieldError
+			}
 		default:
 			if err = iprot.Skip(fieldTypeId); err != nil {
 				goto SkipFieldError
@@ -589,6 +673,11 @@ func (p *_FieldOptions) Read(iprot thrift.TProtocol) (err error) {
 		fieldId = 2
 		goto RequiredFieldNotSetError
 	}
+
+	if !issetSchema {
+		fieldId = 3
+		goto RequiredFieldNotSetError
+	}
 	return nil
 ReadStructBeginError:
 	return thrift.PrependError(fmt.Sprintf("%T read struct begin error: ", p), err)
@@ -623,6 +712,14 @@ func (p *_FieldOptions) ReadField2(iprot thrift.TProtocol) error {
 	p.Property = _field
 	return nil
 }
+func (p *_FieldOptions) ReadField3(iprot thrift.TProtocol) error {
+	_field := NewSchemaOrReference()
+	if err := _field.Read(iprot); err != nil {
+		return err
+	}
+	p.Schema = _field
+	return nil
+}
 
 func (p *_FieldOptions) Write(oprot thrift.TProtocol) (err error) {
 	var fieldId int16
@@ -638,6 +735,10 @@ func (p *_FieldOptions) Write(oprot thrift.TProtocol) (err error) {
 			fieldId = 2
 			goto WriteFieldError
 		}
+		if err = p.writeField3(oprot); err != nil {
+			fieldId = 3
+			goto WriteFieldError
+		}
 	}
 	if err = oprot.WriteFieldStop(); err != nil {
 		goto WriteFieldStopError
@@ -690,6 +791,23 @@ WriteFieldEndError:
 	return thrift.PrependError(fmt.Sprintf("%T write field 2 end error: ", p), err)
 }
 
+func (p *_FieldOptions) writeField3(oprot thrift.TProtocol) (err error) {
+	if err = oprot.WriteFieldBegin("schema", thrift.STRUCT, 3); err != nil {
+		goto WriteFieldBeginError
+	}
+	if err := p.Schema.Write(oprot); err != nil {
+		return err
+	}
+	if err = oprot.WriteFieldEnd(); err != nil {
+		goto WriteFieldEndError
+	}
+	return nil
+WriteFieldBeginError:
+	return thrift.PrependError(fmt.Sprintf("%T write field 3 begin error: ", p), err)
+WriteFieldEndError:
+	return thrift.PrependError(fmt.Sprintf("%T write field 3 end error: ", p), err)
+}
+
 func (p *_FieldOptions) String() string {
 	if p == nil {
 		return "<nil>"
@@ -18109,6 +18227,7 @@ type Schema struct {
 	Description            string                    `thrift:"description,34" json:"description"`
 	Format                 string                    `thrift:"format,35" json:"format"`
 	SpecificationExtension []*NamedAny               `thrift:"specification_extension,36" json:"specification_extension"`
+	Examples               []*Example                `thrift:"examples,37" json:"examples"`
 }
 
 func NewSchema() *Schema {
@@ -18307,6 +18426,10 @@ func (p *Schema) GetSpecificationExtension() (v []*NamedAny) {
 	return p.SpecificationExtension
 }
 
+func (p *Schema) GetExamples() (v []*Example) {
+	return p.Examples
+}
+
 var fieldIDToName_Schema = map[int16]string{
 	1:  "nullable",
 	2:  "discriminator",
@@ -18344,6 +18467,7 @@ var fieldIDToName_Schema = map[int16]string{
 	34: "description",
 	35: "format",
 	36: "specification_extension",
+	37: "examples",
 }
 
 func (p *Schema) IsSetDiscriminator() bool {
@@ -18689,6 +18813,14 @@ func (p *Schema) Read(iprot thrift.TProtocol) (err error) {
 			} else if err = iprot.Skip(fieldTypeId); err != nil {
 				goto SkipFieldError
 			}
+		case 37:
+			if fieldTypeId == thrift.LIST {
+				if err = p.ReadField37(iprot); err != nil {
+					goto ReadFieldError
+				}
+			} else if err = iprot.Skip(fieldTypeId); err != nil {
+				goto SkipFieldError
+			}
 		default:
 			if err = iprot.Skip(fieldTypeId); err != nil {
 				goto SkipFieldError
@@ -19159,6 +19291,29 @@ func (p *Schema) ReadField36(iprot thrift.TProtocol) error {
 	p.SpecificationExtension = _field
 	return nil
 }
+func (p *Schema) ReadField37(iprot thrift.TProtocol) error {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return err
+	}
+	_field := make([]*Example, 0, size)
+	values := make([]Example, size)
+	for i := 0; i < size; i++ {
+		_elem := &values[i]
+		_elem.InitDefault()
+
+		if err := _elem.Read(iprot); err != nil {
+			return err
+		}
+
+		_field = append(_field, _elem)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return err
+	}
+	p.Examples = _field
+	return nil
+}
 
 func (p *Schema) Write(oprot thrift.TProtocol) (err error) {
 	var fieldId int16
@@ -19310,6 +19465,10 @@ func (p *Schema) Write(oprot thrift.TProtocol) (err error) {
 			fieldId = 36
 			goto WriteFieldError
 		}
+		if err = p.writeField37(oprot); err != nil {
+			fieldId = 37
+			goto WriteFieldError
+		}
 	}
 	if err = oprot.WriteFieldStop(); err != nil {
 		goto WriteFieldStopError
@@ -19988,6 +20147,31 @@ WriteFieldEndError:
 	return thrift.PrependError(fmt.Sprintf("%T write field 36 end error: ", p), err)
 }
 
+func (p *Schema) writeField37(oprot thrift.TProtocol) (err error) {
+	if err = oprot.WriteFieldBegin("examples", thrift.LIST, 37); err != nil {
+		goto WriteFieldBeginError
+	}
+	if err := oprot.WriteListBegin(thrift.STRUCT, len(p.Examples)); err != nil {
+		return err
+	}
+	for _, v := range p.Examples {
+		if err := v.Write(oprot); err != nil {
+			return err
+		}
+	}
+	if err := oprot.WriteListEnd(); err != nil {
+		return err
+	}
+	if err = oprot.WriteFieldEnd(); err != nil {
+		goto WriteFieldEndError
+	}
+	return nil
+WriteFieldBeginError:
+	return thrift.PrependError(fmt.Sprintf("%T write field 37 begin error: ", p), err)
+WriteFieldEndError:
+	return thrift.PrependError(fmt.Sprintf("%T write field 37 end error: ", p), err)
+}
+
 func (p *Schema) String() string {
 	if p == nil {
 		return "<nil>"
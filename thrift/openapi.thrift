@@ -18,6 +18,7 @@ namespace go openapi
 
 struct _ServiceOptions {
       1:required Document document
+      2:required Parameter parameter
 }
 
 struct _StructOptions {
@@ -31,6 +32,7 @@ struct _MethodOptions {
 struct _FieldOptions {
       1:required Parameter parameter
       2:required Schema property
+      3:required SchemaOrReference schema
 }
 
 struct AdditionalPropertiesItem {
@@ -476,7 +478,8 @@ struct Schema {
   33: DefaultType default,
   34: string description,
   35: string format,
-  36: list<NamedAny> specification_extension
+  36: list<NamedAny> specification_extension,
+  37: list<Example> examples
 }
 
 struct SchemaOrReference {
@@ -559,4 +562,4 @@ struct Xml {
   4: bool attribute,
   5: bool wrapped,
   6: list<NamedAny> specification_extension
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openapi
+
+// The generated code escapes fields named after Go keywords into unexported
+// identifiers, the setters below make them reachable from the generator.
+
+// Set_Default sets the default value of the server variable.
+func (p *ServerVariable) Set_Default(val string) {
+	p._Default = val
+}
+
+// Set_Type sets the type of the security scheme.
+func (p *SecurityScheme) Set_Type(val string) {
+	p._Type = val
+}
+
+// Set_In sets the location of the API key of the security scheme.
+func (p *SecurityScheme) Set_In(val string) {
+	p._In = val
+}
@@ -0,0 +1,43 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+// FuzzDecodeOption decodes arbitrary openapi.schema annotations, the seeds are in
+// testdata/fuzz/FuzzDecodeOption. Any value must be decoded or reported as malformed,
+// never panic.
+func FuzzDecodeOption(f *testing.F) {
+	f.Add(`{title: "Req", max_length: 8}`)
+
+	desc := structDescriptor(f, `include "openapi.thrift" struct Req {}`)
+	f.Fuzz(func(t *testing.T, value string) {
+		desc.Annotations = map[string][]string{"openapi.schema": {value}}
+		err := ParseStructOption(desc, "openapi.schema", &openapi.Schema{})
+		if err != nil && !strings.HasPrefix(err.Error(), "struct 'Req': malformed openapi.schema ") {
+			t.Errorf("error not naming the option: %s", err)
+		}
+	})
+}
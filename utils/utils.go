@@ -39,84 +39,94 @@ func Contains(s []string, e string) bool {
 	return false
 }
 
+// MaxAnnotationLength caps the values of the annotations decoded as options or JSON,
+// longer values are rejected rather than parsed.
+const MaxAnnotationLength = 64 << 10
+
+// The options are thrift_option literals of the structs of thrift/openapi.thrift, whose keys
+// are the snake_case names of their fields, e.g. '{title: "Name", min_length: 1}'. A
+// malformed option is reported with its owner and its annotation text.
+
 func ParseStructOption(descriptor *thrift_reflection.StructDescriptor, optionName string, obj interface{}) error {
-	opt, err := thrift_option.ParseStructOption(descriptor, optionName)
-	if errors.Is(err, thrift_option.ErrKeyNotMatch) {
-		return nil
-	}
-	if err != nil {
-		return err
-	}
-	mapVal := opt.GetValue()
-	mapValMap := mapVal.(map[string]interface{})
-	jsonData, err := json.Marshal(mapValMap)
-	if err != nil {
-		return err
-	}
-	if err = json.Unmarshal(jsonData, obj); err != nil {
-		return err
-	}
-	return err
+	return decodeOption("struct", descriptor.GetName(), optionName, descriptor.Annotations[optionName], func() (*thrift_option.OptionData, error) {
+		return thrift_option.ParseStructOption(descriptor, optionName)
+	}, obj)
 }
 
 func ParseServiceOption(descriptor *thrift_reflection.ServiceDescriptor, optionName string, obj interface{}) error {
-	opt, err := thrift_option.ParseServiceOption(descriptor, optionName)
-	if errors.Is(err, thrift_option.ErrKeyNotMatch) {
+	return decodeOption("service", descriptor.GetName(), optionName, descriptor.Annotations[optionName], func() (*thrift_option.OptionData, error) {
+		return thrift_option.ParseServiceOption(descriptor, optionName)
+	}, obj)
+}
+
+func ParseMethodOption(descriptor *thrift_reflection.MethodDescriptor, optionName string, obj interface{}) error {
+	return decodeOption("method", descriptor.GetName(), optionName, descriptor.Annotations[optionName], func() (*thrift_option.OptionData, error) {
+		return thrift_option.ParseMethodOption(descriptor, optionName)
+	}, obj)
+}
+
+func ParseFieldOption(descriptor *thrift_reflection.FieldDescriptor, optionName string, obj interface{}) error {
+	return decodeOption("field", descriptor.GetName(), optionName, descriptor.Annotations[optionName], func() (*thrift_option.OptionData, error) {
+		return thrift_option.ParseFieldOption(descriptor, optionName)
+	}, obj)
+}
+
+// decodeOption decodes the option returned by parse into obj. An absent option, or
+// one whose IDL does not include openapi.thrift, leaves obj unchanged. A malformed
+// option is reported as an error naming its owner, even when the option parser
+// panics on it.
+func decodeOption(kind, owner, optionName string, values []string, parse func() (*thrift_option.OptionData, error), obj interface{}) (err error) {
+	if len(values) == 0 {
 		return nil
 	}
-	if err != nil {
-		return err
-	}
-	mapVal := opt.GetValue()
-	mapValMap := mapVal.(map[string]interface{})
-	jsonData, err := json.Marshal(mapValMap)
-	if err != nil {
-		return err
-	}
-	if err = json.Unmarshal(jsonData, obj); err != nil {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s '%s': malformed %s %.64q: %w", kind, owner, optionName, values[0], err)
+		}
+	}()
+	if err := checkAnnotationLength(values); err != nil {
 		return err
 	}
-	return err
-}
-
-func ParseMethodOption(descriptor *thrift_reflection.MethodDescriptor, optionName string, obj interface{}) error {
-	opt, err := thrift_option.ParseMethodOption(descriptor, optionName)
-	if errors.Is(err, thrift_option.ErrKeyNotMatch) {
+	opt, err := parse()
+	if errors.Is(err, thrift_option.ErrKeyNotMatch) || errors.Is(err, thrift_option.ErrNotIncluded) {
 		return nil
 	}
 	if err != nil {
 		return err
 	}
-	mapVal := opt.GetValue()
-	mapValMap := mapVal.(map[string]interface{})
+	mapValMap, ok := opt.GetValue().(map[string]interface{})
+	if !ok {
+		return errors.New("the value is not a struct")
+	}
 	jsonData, err := json.Marshal(mapValMap)
 	if err != nil {
 		return err
 	}
-	if err = json.Unmarshal(jsonData, obj); err != nil {
-		return err
+	return json.Unmarshal(jsonData, obj)
+}
+
+func checkAnnotationLength(values []string) error {
+	for _, value := range values {
+		if len(value) > MaxAnnotationLength {
+			return fmt.Errorf("annotation value of %d bytes is longer than %d bytes", len(value), MaxAnnotationLength)
+		}
 	}
-	return err
+	return nil
 }
 
-func ParseFieldOption(descriptor *thrift_reflection.FieldDescriptor, optionName string, obj interface{}) error {
-	opt, err := thrift_option.ParseFieldOption(descriptor, optionName)
-	if errors.Is(err, thrift_option.ErrKeyNotMatch) {
+// UnmarshalAnnotation decodes the first value of a JSON-valued annotation into obj,
+// it does nothing when the annotation is absent.
+func UnmarshalAnnotation(values []string, obj interface{}) error {
+	if len(values) == 0 || values[0] == "" {
 		return nil
 	}
-	if err != nil {
+	if err := checkAnnotationLength(values[:1]); err != nil {
 		return err
 	}
-	mapVal := opt.GetValue()
-	mapValMap := mapVal.(map[string]interface{})
-	jsonData, err := json.Marshal(mapValMap)
-	if err != nil {
-		return err
-	}
-	if err = json.Unmarshal(jsonData, obj); err != nil {
-		return err
-	}
-	return err
+	return json.Unmarshal([]byte(values[0]), obj)
 }
 
 func UnpackArgs(args []string, c interface{}) error {
@@ -150,6 +160,15 @@ func UnpackArgs(args []string, c interface{}) error {
 				return fmt.Errorf("field %s can't be assigned multi values: %v", n, values)
 			}
 			x.SetString(values[0])
+		case reflect.Int:
+			if len(values) != 1 {
+				return fmt.Errorf("field %s can't be assigned multi values: %v", n, values)
+			}
+			val, err := strconv.Atoi(values[0])
+			if err != nil {
+				return fmt.Errorf("field %s must be an integer: %v", n, values[0])
+			}
+			x.SetInt(int64(val))
 		case reflect.Slice:
 			if len(values) != 1 {
 				return fmt.Errorf("field %s can't be assigned multi values: %v", n, values)
@@ -207,7 +226,18 @@ func MapForm(input []string) (map[string][]string, error) {
 	return out, nil
 }
 
-// MergeStructs merges non-zero fields from src into dst.
+// MergeStructs merges the fields of src into dst, src and dst being pointers to
+// structs of the same type:
+//   - the zero fields of src are ignored, the other ones override the ones of dst.
+//     As the options are decoded into structs, a field set to false, 0 or "" can
+//     not be told from an absent one: e.g. required: false does not reset dst;
+//   - the pointers to structs are merged recursively, except the one-of wrappers
+//     such as SchemaOrReference, replaced when src sets them;
+//   - the elements of slices with a Name, e.g. the properties, are merged with the
+//     element of dst with the same name, the other elements are appended;
+//   - the elements of the other slices, e.g. the tags or the enum values, are
+//     appended unless dst already holds an equal one;
+//   - the entries of maps are set on the map of dst.
 func MergeStructs(dst, src interface{}) error {
 	dstVal := reflect.ValueOf(dst)
 	srcVal := reflect.ValueOf(src)
@@ -216,23 +246,134 @@ func MergeStructs(dst, src interface{}) error {
 	if dstVal.Kind() != reflect.Ptr || srcVal.Kind() != reflect.Ptr {
 		return errors.New("both dst and src must be pointers")
 	}
-	if dstVal.Elem().Kind() != reflect.Struct || srcVal.Elem().Kind() != reflect.Struct {
+	// The kinds are checked on the types as the elements of nil pointers are invalid.
+	if dstVal.Type().Elem().Kind() != reflect.Struct || srcVal.Type().Elem().Kind() != reflect.Struct {
 		return errors.New("both dst and src must be pointers to structs")
 	}
+	if dstVal.Type() != srcVal.Type() {
+		return fmt.Errorf("can not merge %s into %s", srcVal.Type(), dstVal.Type())
+	}
+	if dstVal.IsNil() || srcVal.IsNil() {
+		return nil
+	}
 
-	dstVal = dstVal.Elem()
-	srcVal = srcVal.Elem()
+	mergeStruct(dstVal.Elem(), srcVal.Elem())
+	return nil
+}
 
-	for i := 0; i < dstVal.NumField(); i++ {
-		field := dstVal.Field(i)
-		srcField := srcVal.Field(i)
+func mergeStruct(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Field(i)
+		srcField := src.Field(i)
+		if !field.CanSet() || srcField.IsZero() {
+			continue
+		}
+		mergeValue(field, srcField)
+	}
+}
 
-		if !srcField.IsZero() {
-			field.Set(srcField)
+func mergeValue(dst, src reflect.Value) {
+	switch {
+	case dst.IsZero():
+		dst.Set(src)
+	case dst.Kind() == reflect.Ptr && dst.Elem().Kind() == reflect.Struct && !isOneOf(dst.Type().Elem()):
+		mergeStruct(dst.Elem(), src.Elem())
+	case dst.Kind() == reflect.Slice:
+		dst.Set(mergeSlice(dst, src))
+	case dst.Kind() == reflect.Map:
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), iter.Value())
 		}
+	default:
+		dst.Set(src)
 	}
+}
 
-	return nil
+// mergeSlice returns dst merged with the elements of src in a new array, as the
+// one of dst may be shared.
+func mergeSlice(dst, src reflect.Value) reflect.Value {
+	named := hasName(dst.Type().Elem())
+	merged := reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len()), dst)
+	for i := 0; i < src.Len(); i++ {
+		elem := src.Index(i)
+		if !named {
+			if !containsElement(merged, elem) {
+				merged = reflect.Append(merged, elem)
+			}
+			continue
+		}
+		if j := indexOfElement(merged, elem); j >= 0 {
+			if !elem.IsNil() && !merged.Index(j).IsNil() {
+				mergeStruct(merged.Index(j).Elem(), elem.Elem())
+			}
+			continue
+		}
+		merged = reflect.Append(merged, elem)
+	}
+	return merged
+}
+
+// containsElement reports whether list holds an element deeply equal to elem.
+func containsElement(list, elem reflect.Value) bool {
+	for i := 0; i < list.Len(); i++ {
+		if reflect.DeepEqual(list.Index(i).Interface(), elem.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasName reports whether t is a pointer to a struct with a Name.
+func hasName(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	name, ok := t.Elem().FieldByName("Name")
+	return ok && name.Type.Kind() == reflect.String
+}
+
+// indexOfElement returns the index of the element of list with the name of elem,
+// -1 when there is none.
+func indexOfElement(list, elem reflect.Value) int {
+	name := elementName(elem)
+	if !name.IsValid() {
+		return -1
+	}
+	for i := 0; i < list.Len(); i++ {
+		if other := elementName(list.Index(i)); other.IsValid() && other.String() == name.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+// elementName returns the Name of a pointer to a struct, invalid when it has none.
+func elementName(elem reflect.Value) reflect.Value {
+	if elem.Kind() != reflect.Ptr || elem.IsNil() || elem.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	name := elem.Elem().FieldByName("Name")
+	if !name.IsValid() || name.Kind() != reflect.String {
+		return reflect.Value{}
+	}
+	return name
+}
+
+// oneOfTypes are the types holding one of several alternatives, besides the ones
+// named like SchemaOrReference.
+var oneOfTypes = map[string]bool{
+	"ItemsItem":                true,
+	"AdditionalPropertiesItem": true,
+	"AnyOrExpression":          true,
+	"DefaultType":              true,
+	"SpecificationExtension":   true,
+}
+
+// isOneOf reports whether t holds one of several alternatives, e.g. a schema or a
+// reference, which are not merged with each other.
+func isOneOf(t reflect.Type) bool {
+	return strings.HasSuffix(t.Name(), "OrReference") || oneOfTypes[t.Name()]
 }
 
 func GetAnnotation(input parser.Annotations, target string) []string {
@@ -248,6 +389,10 @@ func GetAnnotation(input parser.Annotations, target string) []string {
 	return []string{}
 }
 
+// GetAnnotations returns the values of the annotations of input named by the keys of
+// targets, keyed by the matching values of targets. Ranging over the result is in
+// random order, annotations.Routes lists the routes of a function in declaration
+// order.
 func GetAnnotations(input parser.Annotations, targets map[string]string) map[string][]string {
 	if len(input) == 0 || len(targets) == 0 {
 		return nil
@@ -0,0 +1,342 @@
+/*
+ * Copyright 2024 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/thriftgo/parser"
+	"github.com/cloudwego/thriftgo/semantic"
+	"github.com/cloudwego/thriftgo/thrift_reflection"
+	openapi "github.com/hertz-contrib/swagger-generate/thrift-gen-rpc-swagger/thrift"
+)
+
+type mergeLabels struct {
+	Tags   []string
+	Values map[string]string
+}
+
+type mergeOptions struct {
+	Title  string
+	Labels *mergeLabels
+}
+
+func TestMergeStructs(t *testing.T) {
+	stringSchema := &openapi.SchemaOrReference{Schema: &openapi.Schema{Type: "string"}}
+	reference := &openapi.SchemaOrReference{Reference: &openapi.Reference{Xref: "#/components/schemas/Item"}}
+
+	tests := []struct {
+		name string
+		dst  interface{}
+		src  interface{}
+		want interface{}
+	}{
+		{
+			name: "nil src",
+			dst:  &openapi.Parameter{Name: "id"},
+			src:  (*openapi.Parameter)(nil),
+			want: &openapi.Parameter{Name: "id"},
+		},
+		{
+			name: "nil dst",
+			dst:  (*openapi.Parameter)(nil),
+			src:  &openapi.Parameter{Name: "id"},
+			want: (*openapi.Parameter)(nil),
+		},
+		{
+			name: "zero fields of src",
+			dst:  &openapi.Parameter{Name: "id", In: "query", Required: true},
+			src:  &openapi.Parameter{Description: "the id"},
+			want: &openapi.Parameter{Name: "id", In: "query", Required: true, Description: "the id"},
+		},
+		{
+			// false and 0 can not be told from absent fields
+			name: "fields set to zero",
+			dst:  &openapi.Schema{Nullable: true, Minimum: 1},
+			src:  &openapi.Schema{Nullable: false, Minimum: 0, ReadOnly: true},
+			want: &openapi.Schema{Nullable: true, Minimum: 1, ReadOnly: true},
+		},
+		{
+			name: "nested pointers",
+			dst:  &openapi.Schema{Type: "object", XML: &openapi.Xml{Name: "item"}},
+			src:  &openapi.Schema{XML: &openapi.Xml{Prefix: "p"}},
+			want: &openapi.Schema{Type: "object", XML: &openapi.Xml{Name: "item", Prefix: "p"}},
+		},
+		{
+			name: "one-of replaced",
+			dst:  &openapi.Parameter{Schema: stringSchema},
+			src:  &openapi.Parameter{Schema: reference},
+			want: &openapi.Parameter{Schema: reference},
+		},
+		{
+			name: "slices of named elements",
+			dst: &openapi.Properties{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+				{Name: "id", Value: stringSchema},
+			}},
+			src: &openapi.Properties{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+				{Name: "id", Value: reference},
+				{Name: "item", Value: reference},
+			}},
+			want: &openapi.Properties{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+				{Name: "id", Value: reference},
+				{Name: "item", Value: reference},
+			}},
+		},
+		{
+			name: "named elements merged",
+			dst: &openapi.Properties{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+				{Name: "id", Value: stringSchema},
+			}},
+			src: &openapi.Properties{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+				{Name: "id"},
+			}},
+			want: &openapi.Properties{AdditionalProperties: []*openapi.NamedSchemaOrReference{
+				{Name: "id", Value: stringSchema},
+			}},
+		},
+		{
+			name: "slices of strings appended",
+			dst:  &openapi.Schema{Required: []string{"id"}},
+			src:  &openapi.Schema{Required: []string{"name"}},
+			want: &openapi.Schema{Required: []string{"id", "name"}},
+		},
+		{
+			name: "tags deduplicated",
+			dst:  &openapi.Operation{Tags: []string{"UserService"}, OperationID: "GetUser"},
+			src:  &openapi.Operation{Tags: []string{"users", "UserService", "users"}},
+			want: &openapi.Operation{Tags: []string{"UserService", "users"}, OperationID: "GetUser"},
+		},
+		{
+			name: "enum values deduplicated",
+			dst:  &openapi.Schema{Type: "string", Enum: []*openapi.Any{{Yaml: "a"}, {Yaml: "b"}}},
+			src:  &openapi.Schema{Enum: []*openapi.Any{{Yaml: "b"}, {Yaml: "c"}}},
+			want: &openapi.Schema{Type: "string", Enum: []*openapi.Any{{Yaml: "a"}, {Yaml: "b"}, {Yaml: "c"}}},
+		},
+		{
+			name: "empty slice of src",
+			dst:  &openapi.Operation{Tags: []string{"UserService"}},
+			src:  &openapi.Operation{Tags: []string{}},
+			want: &openapi.Operation{Tags: []string{"UserService"}},
+		},
+		{
+			name: "empty slice of dst",
+			dst:  &openapi.Operation{Summary: "Get a user"},
+			src:  &openapi.Operation{Security: []*openapi.SecurityRequirement{}},
+			want: &openapi.Operation{Summary: "Get a user", Security: []*openapi.SecurityRequirement{}},
+		},
+		{
+			name: "nil slice of src",
+			dst:  &openapi.Operation{Tags: []string{"UserService"}},
+			src:  &openapi.Operation{Summary: "Get a user"},
+			want: &openapi.Operation{Tags: []string{"UserService"}, Summary: "Get a user"},
+		},
+		{
+			name: "maps",
+			dst:  &mergeOptions{Title: "a", Labels: &mergeLabels{Values: map[string]string{"a": "1", "b": "2"}}},
+			src:  &mergeOptions{Labels: &mergeLabels{Tags: []string{"t"}, Values: map[string]string{"b": "3"}}},
+			want: &mergeOptions{Title: "a", Labels: &mergeLabels{Tags: []string{"t"}, Values: map[string]string{"a": "1", "b": "3"}}},
+		},
+		{
+			name: "zero struct of src",
+			dst:  &openapi.Schema{Type: "object", XML: &openapi.Xml{Name: "item"}},
+			src:  &openapi.Schema{XML: &openapi.Xml{}},
+			want: &openapi.Schema{Type: "object", XML: &openapi.Xml{Name: "item"}},
+		},
+		{
+			name: "nil pointer of dst",
+			dst:  &openapi.Schema{Type: "object"},
+			src:  &openapi.Schema{XML: &openapi.Xml{Name: "item"}},
+			want: &openapi.Schema{Type: "object", XML: &openapi.Xml{Name: "item"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := MergeStructs(tt.dst, tt.src); err != nil {
+				t.Fatalf("MergeStructs: %s", err)
+			}
+			if !reflect.DeepEqual(tt.dst, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.dst, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeStructsSharedSlice(t *testing.T) {
+	tags := make([]string, 1, 2)
+	tags[0] = "UserService"
+	dst := &openapi.Operation{Tags: tags}
+	if err := MergeStructs(dst, &openapi.Operation{Tags: []string{"users"}}); err != nil {
+		t.Fatalf("MergeStructs: %s", err)
+	}
+	if extra := tags[:2][1]; extra != "" {
+		t.Errorf("got %q written to the array of dst", extra)
+	}
+	if want := []string{"UserService", "users"}; !reflect.DeepEqual(dst.Tags, want) {
+		t.Errorf("got tags %v, want %v", dst.Tags, want)
+	}
+}
+
+func TestMergeStructsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  interface{}
+		src  interface{}
+	}{
+		{"not pointers", openapi.Parameter{}, openapi.Parameter{}},
+		{"not structs", new(string), new(string)},
+		{"different types", &openapi.Parameter{}, &openapi.Schema{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := MergeStructs(tt.dst, tt.src); err == nil {
+				t.Errorf("MergeStructs(%T, %T) succeeded", tt.dst, tt.src)
+			}
+		})
+	}
+}
+
+// structDescriptor parses the IDL, next to the openapi.thrift of the example, and
+// returns the descriptor of its struct Req.
+func structDescriptor(t testing.TB, idl string) *thrift_reflection.StructDescriptor {
+	t.Helper()
+	dir := t.TempDir()
+	annotations, err := ioutil.ReadFile(filepath.Join("..", "example", "openapi.thrift"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "openapi.thrift"), annotations, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "main.thrift")
+	if err := ioutil.WriteFile(path, []byte(idl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ast, err := parser.ParseFile(path, []string{dir}, true)
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if err := semantic.ResolveSymbols(ast); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	_, fileDesc := thrift_reflection.RegisterAST(ast)
+	return fileDesc.GetStructDescriptor("Req")
+}
+
+func TestParseStructOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		idl     string
+		want    *openapi.Schema
+		wantErr string
+	}{
+		{
+			name: "option",
+			idl:  `include "openapi.thrift" struct Req {} (openapi.schema = '{title: "Req", max_length: 8}')`,
+			want: &openapi.Schema{Title: "Req", MaxLength: 8},
+		},
+		{
+			name: "absent annotation",
+			idl:  `include "openapi.thrift" struct Req {}`,
+			want: &openapi.Schema{},
+		},
+		{
+			name: "openapi.thrift not included",
+			idl:  `struct Req {} (openapi.schema = '{title: "Req"}')`,
+			want: &openapi.Schema{},
+		},
+		{
+			name:    "truncated",
+			idl:     `include "openapi.thrift" struct Req {} (openapi.schema = '{title: "Req"')`,
+			wantErr: `struct 'Req': malformed openapi.schema "{title: \"Req\"": `,
+		},
+		{
+			name:    "wrong type",
+			idl:     `include "openapi.thrift" struct Req {} (openapi.schema = '{max_length: "eight"}')`,
+			wantErr: `struct 'Req': malformed openapi.schema "{max_length: \"eight\"}": `,
+		},
+		{
+			name:    "unknown key",
+			idl:     `include "openapi.thrift" struct Req {} (openapi.schema = '{titel: "Req"}')`,
+			wantErr: `struct 'Req': malformed openapi.schema "{titel: \"Req\"}": `,
+		},
+		{
+			name:    "not a struct",
+			idl:     `include "openapi.thrift" struct Req {} (openapi.schema = '"Req"')`,
+			wantErr: `struct 'Req': malformed openapi.schema "\"Req\"": `,
+		},
+		{
+			name:    "too long",
+			idl:     `include "openapi.thrift" struct Req {} (openapi.schema = '{description: "` + strings.Repeat("a", MaxAnnotationLength) + `"}')`,
+			wantErr: `struct 'Req': malformed openapi.schema "{description: \"aaaa`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &openapi.Schema{}
+			err := ParseStructOption(structDescriptor(t, tt.idl), "openapi.schema", got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %s...", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStructOption: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFieldOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  string
+		want    *openapi.Schema
+		wantErr string
+	}{
+		{"option", `{description: "The name"}`, &openapi.Schema{Description: "The name"}, ""},
+		{"truncated", `{description: "The name"`, nil, `field 'name': malformed openapi.property "{description: \"The name\"": `},
+		{"wrong type", `{max_length: [1]}`, nil, `field 'name': malformed openapi.property "{max_length: [1]}": `},
+		{"unknown key", `{descripton: "The name"}`, nil, `field 'name': malformed openapi.property "{descripton: \"The name\"}": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc := structDescriptor(t, `include "openapi.thrift" struct Req { 1: string name (openapi.property = '`+tt.option+`') }`)
+			got := &openapi.Schema{}
+			err := ParseFieldOption(desc.GetFields()[0], "openapi.property", got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %s...", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFieldOption: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}